@@ -1,7 +1,6 @@
 import Vue from "vue"
 import App from "./App.vue"
 import router from "./router.ts"
-import msgpack from "msgpack-lite/lib/browser.js"
 import Toasted from "vue-toasted"
 import {hexEncode} from "./hex-encode"
 
@@ -28,11 +27,6 @@ import "./editor-theme.css"
 // the main app. These files are not pre-processed by an CSS tool (TODO: should they be?).
 import layoutsCSS from "./layouts.rawcss"
 
-const MessagePackCodec = msgpack.createCodec({
-	uint8array: true
-})
-
-
 const mainAndIframeCSS = [layoutsCSS]
 
 mainAndIframeCSS.forEach(function(elem) {
@@ -98,6 +92,17 @@ Vue.prototype.$hideLoader = function() {
 // that requires the user to be authenticated.
 let doingAuthStep = false
 
+// csrfToken is the latest CSRF token the server gave in the X-CSRF-Token response header. Requests other
+// than GET must send it back when the user is logged in with the login cookie.
+let csrfToken = ""
+
+// getCSRFToken returns the CSRF token from the mwcsrf cookie or, if the cookie cannot be read (as when the
+// API is on another origin in development), the token last received in a response header.
+function getCSRFToken(): string {
+	const m = document.cookie.match(/(?:^|;\s*)mwcsrf=([^;]*)/)
+	return m !== null && m[1] !== "none" ? m[1] : csrfToken
+}
+
 // $req makes an API request. The handleGood argument should be a function that handles the decoded response.
 // The handleError argument is optional: authentication and server errors and alerts are handled by $req.
 Vue.prototype.$req = function(method, endpoint, data, handleGood, handleError = () => {
@@ -108,7 +113,8 @@ Vue.prototype.$req = function(method, endpoint, data, handleGood, handleError =
 	const opts = {
 		method: method,
 		credentials: "include",
-		cache: "no-store"
+		cache: "no-store",
+		headers: new Headers({"Accept": "application/json"})
 	}
 
 	if (data !== null) {
@@ -121,33 +127,44 @@ Vue.prototype.$req = function(method, endpoint, data, handleGood, handleError =
 			console.log("Request data:", data)
 		}
 
-		const dataEncoded = msgpack.encode(data, {codec: MessagePackCodec})
+		const dataEncoded = JSON.stringify(data)
 		if (method === "GET") {
 			// The endpoint URL may already have a "?" if this is a retry request, which means the hex-encoded data
 			// is already part of the URL.
 			if (!endpoint.includes("?")) {
-				endpoint += "?data=" + hexEncode(dataEncoded)
+				endpoint += "?data=" + hexEncode(new TextEncoder().encode(dataEncoded))
 			}
 		} else {
-			opts.headers = new Headers({"Content-Type": "application/x-msgpack"})
+			opts.headers.set("Content-Type", "application/json")
 			opts.body = dataEncoded
 		}
 	}
 
+	if (method !== "GET") {
+		const token = getCSRFToken()
+		if (token !== "") {
+			opts.headers.set("X-CSRF-Token", token)
+		}
+	}
+
 	// doingAuthStep should be set to true if we are already accessing the "auth" endpoint or if, as we
 	// can see below, there is a 403 error returned as a response.
-	if (endpoint === "auth") {
+	if (endpoint === "auth" || endpoint === "auth/2fa") {
 		doingAuthStep = true
 	}
 
 	fetch(apiPath + endpoint, opts).then(resp => {
+		const respToken = resp.headers.get("X-CSRF-Token")
+		if (respToken !== null) {
+			csrfToken = respToken
+		}
 		switch (resp.status) {
 			case 200:
-				resp.arrayBuffer().then(respData => {
-					respData = msgpack.decode(new Uint8Array(respData), {codec: MessagePackCodec})
-					if (respData.warn !== undefined) {
-						for (let i = 0; i < respData.warn.length; i++) {
-							alert(respData.warn[i]) // TODO: show each in a toast
+			case 202:
+				resp.json().then(respData => {
+					if (respData.warnings !== undefined) {
+						for (let i = 0; i < respData.warnings.length; i++) {
+							alert(respData.warnings[i]) // TODO: show each in a toast
 						}
 					}
 					handleGood(respData.body)
@@ -171,7 +188,8 @@ Vue.prototype.$req = function(method, endpoint, data, handleGood, handleError =
 				doingAuthStep = true
 				return
 			default:
-				resp.text().then(errMsg => {
+				resp.json().then(respData => {
+					const errMsg = respData.error
 					if (!doingAuthStep) {
 						this.$toasted.error(errMsg)
 					}
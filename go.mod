@@ -3,26 +3,30 @@ module github.com/dchenk/mazewire
 require (
 	cloud.google.com/go v0.36.0
 	github.com/dchenk/go-render-quill v0.0.0-20190103002240-ec868ac0fe4a
+	github.com/desertbit/timer v0.0.0-20180107155436-c41aec40b27f // indirect
 	github.com/fatih/color v1.7.0
 	github.com/getkin/kin-openapi v0.1.1-0.20190418174838-7eaf71972b66
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/golang/protobuf v1.3.0
+	github.com/gorilla/websocket v1.4.0 // indirect
 	github.com/hashicorp/go-hclog v0.8.0 // indirect
 	github.com/hashicorp/go-plugin v0.0.0-20190220160451-3f118e8ee104
 	github.com/hashicorp/golang-lru v0.5.1 // indirect
 	github.com/hashicorp/yamux v0.0.0-20181012175058-2f1d1f20f75d // indirect
+	github.com/improbable-eng/grpc-web v0.13.0
 	github.com/lib/pq v1.0.0
 	github.com/mattn/go-colorable v0.1.1 // indirect
 	github.com/mattn/go-isatty v0.0.6 // indirect
 	github.com/mitchellh/go-testing-interface v1.0.0 // indirect
 	github.com/pkg/errors v0.8.1
+	github.com/rs/cors v1.6.0 // indirect
 	github.com/satori/go.uuid v1.2.0
 	github.com/sendgrid/rest v2.4.1+incompatible
 	github.com/sendgrid/sendgrid-go v3.4.1+incompatible
 	github.com/xenolf/lego v2.2.0+incompatible
 	go.opencensus.io v0.19.1 // indirect
 	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
-	golang.org/x/net v0.0.0-20190310074541-c10a0554eabf // indirect
+	golang.org/x/net v0.0.0-20190310074541-c10a0554eabf
 	golang.org/x/oauth2 v0.0.0-20190226205417-e64efc72b421
 	golang.org/x/sys v0.0.0-20190310054646-10058d7d4faa // indirect
 	google.golang.org/api v0.1.0
@@ -3,6 +3,8 @@ package main
 import (
 	"net/http"
 
+	"github.com/golang/protobuf/ptypes/wrappers"
+
 	"github.com/dchenk/mazewire/pkg/data"
 	"github.com/dchenk/mazewire/pkg/log"
 	"github.com/dchenk/mazewire/pkg/users"
@@ -30,14 +32,17 @@ func (reqAdminGetSrcs) authorized(r *http.Request, _ *data.Site, _ *data.User) b
 
 // adminGetSrcs returns a map listing the current admin JS and CSS file hashes.
 func (reqAdminGetSrcs) handle(r *http.Request, _ *data.Site, _ *data.User) *APIResponse {
-	return &APIResponse{
-		Body: msgp.Raw(msgp.AppendMapStrStr(make([]byte, 0, 128), getSrcVersions(r))),
-	}
+	return &APIResponse{Body: &RespAdminSrcs{Srcs: getSrcVersions(r)}}
+}
+
+// RespAdminSrcs lists the names of the latest admin source files.
+type RespAdminSrcs struct {
+	Srcs map[string]string `json:"srcs"`
 }
 
 // ReqAdminUpdateSrcs: POST admin
 type ReqAdminUpdateSrcs struct {
-	Srcs map[string]string `msgp:"srcs"`
+	Srcs map[string]string `json:"srcs"`
 }
 
 // authorized says if the user is a super user.
@@ -78,9 +83,7 @@ outerLoop:
 	}
 
 	// The response is just the number of source file versions updated.
-	return &APIResponse{
-		Body: msgp.Raw(msgp.AppendInt64(make([]byte, 0, 9), changed)),
-	}
+	return &APIResponse{Body: &wrappers.Int64Value{Value: changed}}
 }
 
 // getSrcVersions retrieves the latest hashes of the admin static source files.
@@ -4,16 +4,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 
+	"github.com/dchenk/mazewire/pkg/api/response"
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/api_validate"
 	"github.com/dchenk/mazewire/pkg/data"
 	"github.com/dchenk/mazewire/pkg/env"
 	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/route"
 	"github.com/dchenk/mazewire/pkg/util"
 )
 
@@ -21,96 +29,135 @@ const (
 	errProcessingMsg  = "An error occurred when processing your request."
 	errIncompleteForm = "Not all required fields are filled out. Please complete the form."
 	errNoMembership   = "You do not yet have membership on this site."
+	errMustLoginMsg   = "You must log in to continue."
+	errLowPrivsMsg    = "Your privileges on this site are not high enough for this action."
 	errInvalidLogin   = "The login details you entered are incorrect."
 	errDecodingJSON   = "error decoding JSON request"
 	errDecodingProto  = "error decoding Protocol Buffers request"
 )
 
+// apiValidator, if not nil, validates API requests against the OpenAPI specification.
+var apiValidator *api_validate.Validator
+
+// newAPIValidator creates a validator for the OpenAPI specification in the file given. In development
+// mode, responses are validated as well.
+func newAPIValidator(specFile string) (*api_validate.Validator, error) {
+	spec, err := api_validate.Load(specFile)
+	if err != nil {
+		return nil, err
+	}
+	v, err := api_validate.New(spec, "/api")
+	if err != nil {
+		return nil, err
+	}
+	if !env.Prod() {
+		v.ValidateResponses = true
+		v.ErrorLog = func(r *http.Request, err error) {
+			log.Err(r, "API response does not match the specification", err)
+		}
+	}
+	return v, nil
+}
+
 func handleAPI(w http.ResponseWriter, r *http.Request, s *data.Site, u *data.User, slugs []string) {
-	// The request path may have up to two parts: The first is the API endpoint, and the second is
-	// the (optional) API sub-endpoint.
-	if len(slugs) < 1 {
-		writeBadAPIReq(w)
+	format, ok := response.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		// None of the formats is acceptable to the client, so the error is sent in the default format.
+		writeAPIError(w, r, response.JSON, http.StatusNotAcceptable, "Not Acceptable")
 		return
 	}
-	ep, ok := apiEndpoints[slugs[0]]
-	if !ok {
-		writeBadAPIReq(w)
+
+	v, params, allowed := apiRoutes.Match(r.Method, "/"+strings.Join(slugs, "/"))
+	if v == nil && allowed == nil {
+		writeAPIError(w, r, format, http.StatusNotFound, "Not Found")
 		return
 	}
 
-	// At this point, we verified that the request is likely going to a valid endpoint.
-	// For the development environment, let the browser make requests to the local server.
-	if !env.Prod() {
-		w.Header().Add("Access-Control-Allow-Origin", "http://localhost:8082")
-		w.Header().Add("Access-Control-Allow-Credentials", "true")
-		w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	// At this point, we verified that the request is going to a valid endpoint, so apply the CORS policy
+	// of the site. (For a preflight request, there is no route for the OPTIONS method, so allowed lists
+	// the methods of the resource.)
+	if handleCORS(w, r, s, allowed) {
+		return
+	}
+
+	if v == nil {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
 		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
+		writeAPIError(w, r, format, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
 	}
 
-	var handler APIHandler
+	rt := v.(*apiRoute)
+	r = route.WithParams(r, params)
+	handler := rt.newHandler()
 
-	if len(slugs) == 1 {
-		handler = ep.Points(r.Method)
-	} else {
-		if ep.SubPaths == nil {
-			writeBadAPIReq(w)
-			return
-		}
-		// Above we already checked that there are at least two parts in the slug, so here we know that there
-		// there are at least three parts in the slug.
-		ep, ok = ep.SubPaths[slugs[1]]
-		if !ok {
-			writeBadAPIReq(w)
-			return
-		}
-		handler = ep.Points(r.Method)
+	if !allowRequest(r, rt, handler) {
+		w.Header().Set("Retry-After", retryAfter(handler))
+		writeAPIError(w, r, format, http.StatusTooManyRequests, "Too Many Requests")
+		return
 	}
 
-	if handler == nil {
-		writeBadAPIReq(w)
+	if !checkCSRF(r, r.Method, u) {
+		writeAPIError(w, r, format, http.StatusBadRequest, errCSRFMsg)
 		return
 	}
+	setCSRFToken(w, r, u)
 
 	if r.Method == http.MethodGet {
-		params, err := url.ParseQuery(r.URL.RawQuery)
-		if err != nil {
-			log.Err(r, "could not parse query for GET request", err)
-			writeApiReqErr(w, http.StatusInternalServerError, errProcessingMsg)
-			return
+		if decoder, ok := handler.(queryDecoder); ok {
+			query, err := url.ParseQuery(r.URL.RawQuery)
+			if err != nil {
+				writeAPIError(w, r, format, http.StatusBadRequest, "Bad Request")
+				return
+			}
+			if err = decoder.decodeQuery(query); err != nil {
+				writeAPIError(w, r, format, http.StatusBadRequest, "Bad Request")
+				return
+			}
 		}
-		queryVals := r.URL.Query()
 	} else {
-		var contentType string
-		if cth := r.Header["Content-Type"]; len(cth) > 0 {
-			contentType = cth[0]
-		}
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
 
 		switch contentType {
+		case "":
+			// There is no body to decode.
 		case util.ContentTypeProtobuf:
 			body, err := ioutil.ReadAll(r.Body)
 			if err != nil {
 				log.Err(r, "reading request body", err)
-				writeApiReqErr(w, http.StatusInternalServerError, errProcessingMsg)
+				writeAPIError(w, r, format, http.StatusInternalServerError, errProcessingMsg)
 				return
 			}
 			decoder, ok := handler.(proto.Decoder)
 			if !ok {
-				log.Err(r, "got content-type MessagePack but handler is not a decoder", unexpectedContentType)
-				writeApiReqErr(w, http.StatusBadRequest, errProcessingMsg)
+				log.Err(r, "got content-type Protocol Buffers but handler is not a decoder", unexpectedContentType)
+				writeAPIError(w, r, format, http.StatusBadRequest, errProcessingMsg)
 				return
 			}
 			if err := proto.Unmarshal(body, decoder); err != nil {
 				log.Err(r, errDecodingProto, err)
-				writeApiReqErr(w, http.StatusInternalServerError, errProcessingMsg)
+				writeAPIError(w, r, format, http.StatusInternalServerError, errProcessingMsg)
+				return
+			}
+		case util.ContentTypeProtoJSON:
+			decoder, ok := handler.(proto.Message)
+			if !ok {
+				log.Err(r, "got content-type protojson but handler is not a decoder", unexpectedContentType)
+				writeAPIError(w, r, format, http.StatusBadRequest, errProcessingMsg)
+				return
+			}
+			if err := jsonpb.Unmarshal(r.Body, decoder); err != nil {
+				log.Err(r, errDecodingProto, err)
+				writeAPIError(w, r, format, http.StatusInternalServerError, errProcessingMsg)
 				return
 			}
 		case util.ContentTypeJSON:
 			if err := json.NewDecoder(r.Body).Decode(handler); err != nil {
 				log.Err(r, errDecodingJSON, err)
-				writeApiReqErr(w, http.StatusInternalServerError, errProcessingMsg)
+				writeAPIError(w, r, format, http.StatusInternalServerError, errProcessingMsg)
 				return
 			}
 		case util.ContentTypeFormURL:
@@ -118,55 +165,81 @@ func handleAPI(w http.ResponseWriter, r *http.Request, s *data.Site, u *data.Use
 			if !ok {
 				log.Err(r, fmt.Sprintf("got content-type form-urlencoded but handler of type %T is not a form decoder", handler),
 					unexpectedContentType)
-				writeBadAPIReq(w)
+				writeAPIError(w, r, format, http.StatusBadRequest, "Bad Request")
 				return
 			}
 			if err := decoder.decodeFormURL(r); err != nil {
 				log.Err(r, "could not decode form URL-encoded payload", err)
-				writeApiReqErr(w, http.StatusInternalServerError, errProcessingMsg)
+				writeAPIError(w, r, format, http.StatusInternalServerError, errProcessingMsg)
 				return
 			}
 		default:
-			// Somehow the Content-Type header is set to something unexpected. Assume the data is supposed to
-			// be MessagePack encoded. If the content type is not MessagePack, the decoder will give an error.
 			log.Err(r, fmt.Sprintf("got unexpected content type %q", contentType), badContentType)
-			decoder, ok := handler.(msgp.Decoder)
-			if !ok {
-				log.Err(r, "got unexpected content-type but handler is not a decoder", unexpectedContentType)
-				writeApiReqErr(w, http.StatusBadRequest, errProcessingMsg)
-				return
-			}
-			if err := msgp.Decode(r.Body, decoder); err != nil {
-				log.Err(r, errDecodingProto, err)
-				writeApiReqErr(w, http.StatusInternalServerError, errProcessingMsg)
-				return
-			}
+			writeAPIError(w, r, format, http.StatusUnsupportedMediaType, "Unsupported Media Type")
+			return
 		}
 	}
 
-	if !handler.authorized(r, s, u) {
+	if !tokenAllows(r, rt) {
+		writeAPIError(w, r, format, http.StatusForbidden, errTokenScopeMsg)
+		return
+	}
+
+	if !rt.auth.allows(r, s, u) || !handler.authorized(r, s, u) {
 		if u.Id == 0 {
 			resp := errMustLogin()
-			writeApiReqErr(w, resp.Status, resp.err)
+			writeAPIError(w, r, format, resp.Status, resp.err)
 			return
 		}
 		resp := errLowPrivileges()
-		writeApiReqErr(w, resp.Status, resp.err)
+		writeAPIError(w, r, format, resp.Status, resp.err)
 		return
 	}
 
 	resp := handler.handle(r, s, u)
 
 	// Handle logging in and logging out cookies and redirection.
-	if slugs[0] == "auth" {
-		usingForm := false
-		if len(slugs) > 2 && slugs[2] == "form" {
-			usingForm = true
+	if rt.path == "/auth" || strings.HasPrefix(rt.path, "/auth/") {
+		usingForm := strings.HasSuffix(rt.path, "/form")
+		formPage := r.URL.Path // the page to which a form redirects the user with a message
+		bf, isBrowserFlow := handler.(browserFlow)
+		if isBrowserFlow {
+			for _, c := range bf.cookies() {
+				w.Header().Add("Set-Cookie", c)
+			}
+			if loc := bf.location(); resp.err == "" && loc != "" {
+				http.Redirect(w, r, loc, http.StatusSeeOther)
+				return
+			}
+			// The browser navigated away from the login page, so the user is sent back to it as from a form.
+			usingForm, formPage = true, "/login"
 		}
-		if r.Method == http.MethodPost {
-			if resp.err == "" { // Ok to login (the resp.Warnings field is not used here).
-				w.Header().Add("Set-Cookie", createLoginCookie(r, u.Id, u.Pass, s.Id))
+		if r.Method == http.MethodPost || isBrowserFlow {
+			if c, ok := resp.Body.(*apiv1.TwoFactorChallenge); ok && resp.err == "" && usingForm {
+				// The login awaits a second factor, which the user enters on the page they came from.
+				q := url.Values{"challenge": {c.Challenge}, "msg": {"Enter the code from your authenticator app."}}
+				if c.Enroll {
+					// The key is given in a cookie rather than in the URL.
+					w.Header().Add("Set-Cookie", enrollKeyCookie(formPage, c.Secret, int(twoFactorChallengeTTL/time.Second)))
+					q.Set("msg", "Set up your authenticator app with the key shown and enter the code from the app.")
+				}
+				http.Redirect(w, r, formPage+"?"+q.Encode(), http.StatusSeeOther)
+				return
+			}
+			if resp.err == "" && u.Id != 0 { // Ok to login (the resp.Warnings field is not used here).
+				token, _, err := startSession(r, s, u)
+				if err != nil {
+					log.Err(r, "could not start session", err)
+					writeAPIError(w, r, format, http.StatusInternalServerError, errProcessingMsg)
+					return
+				}
+				w.Header().Add("Set-Cookie", createLoginCookie(token))
+				w.Header().Add("Set-Cookie", createCSRFCookie(token))
+				w.Header().Set(csrfHeader, csrfToken(token))
 				if usingForm {
+					if _, ok := handler.(*authTwoFactorForm); ok {
+						w.Header().Add("Set-Cookie", enrollKeyCookie(formPage, "", -1))
+					}
 					http.Redirect(w, r, loginRedirect(r, s, u), http.StatusSeeOther)
 					return
 				}
@@ -177,36 +250,70 @@ func handleAPI(w http.ResponseWriter, r *http.Request, s *data.Site, u *data.Use
 				return
 			} else if usingForm {
 				// Using a login form, the log in failed, so redirect the user to the page they came from.
-				http.Redirect(w, r, r.URL.Path+"?msg="+url.QueryEscape(resp.err), http.StatusSeeOther)
+				q := url.Values{"msg": {resp.err}}
+				if tf, ok := handler.(*authTwoFactorForm); ok && resp.err == errInvalid2FACode {
+					q.Set("challenge", tf.Challenge) // the user may try another code
+				}
+				http.Redirect(w, r, formPage+"?"+q.Encode(), http.StatusSeeOther)
 				return
 			}
 			// Else, there is an error. Below we write the error message to the user.
 		} else if r.Method == http.MethodDelete {
+			endSession(r)
 			w.Header().Add("Set-Cookie", createLogoutCookie())
+			w.Header().Add("Set-Cookie", createCSRFLogoutCookie())
 			http.Redirect(w, r, logoutRedirect(r, s, u), http.StatusSeeOther)
 			return
 		}
 	}
 
 	if resp.err != "" {
-		writeApiReqErr(w, resp.Status, resp.err)
+		writeAPIError(w, r, format, resp.Status, resp.err)
 		return
 	}
 
-	// The request succeeded, so write out the response in MessagePack format.
-	w.Header().Set("Content-Type", util.ContentTypeMessagePack)
-	if err := msgp.Encode(w, resp); err != nil {
-		log.Err(r, "error encoding or writing API response", err)
+	// The request succeeded, so write out the response in the format negotiated.
+	b, err := response.Marshal(format, resp.Body, resp.Warnings)
+	if err != nil {
+		log.Err(r, "could not encode API response", err)
+		writeAPIError(w, r, format, http.StatusInternalServerError, errProcessingMsg)
+		return
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if err = response.Write(w, format, status, b); err != nil {
+		log.Err(r, "could not write API response", err)
 	}
 }
 
-// An APIEndpoint endpoint represents a set of handlers of API requests at a particular path.
-type APIEndpoint struct {
-	// SubPathHandler, given the HTTP request method, returns the corresponding APIHandler.
-	Points func(string) APIHandler
+// writeAPIError writes out the error message of a failed API request in the format f. The HTTP status
+// set is always not 200. If the status given is 0 or 200, the status written is 500.
+func writeAPIError(w http.ResponseWriter, r *http.Request, f response.Format, status int, msg string) {
+	if status == 0 || status == http.StatusOK {
+		status = http.StatusInternalServerError
+	}
+	b, err := response.MarshalError(f, msg)
+	if err != nil {
+		log.Err(r, "could not encode API error", err)
+		return
+	}
+	if err = response.Write(w, f, status, b); err != nil {
+		log.Err(r, "could not write API error", err)
+	}
+}
 
-	// SubPaths contains APIEndpoint configurations for sub-paths of the endpoint.
-	SubPaths map[string]APIEndpoint
+// An apiRoute is the configuration of the API route for a method and path pattern.
+type apiRoute struct {
+	method string
+	path   string // the pattern of the path after "/api", with parameters like {id}
+
+	// newHandler returns a new APIHandler into which the request is decoded.
+	newHandler func() APIHandler
+
+	// auth is checked before the handler's own authorized method.
+	auth routeAuth
 }
 
 // APIHandler represents a handler of an API endpoint. The concrete type of an APIHandler is able to decode
@@ -221,146 +328,155 @@ type APIHandler interface {
 	handle(*http.Request, *data.Site, *data.User) *APIResponse
 }
 
-// apiEndpoints lists the handlers for all the API endpoints.
-// At the root level in this map and at each nested sub-map of APIEndpoint elements there must be
-// a non-nil Points function. The SubPathHandler may be nil for any endpoint.
-var apiEndpoints = map[string]APIEndpoint{
-	"user": {
-		Points: func(method string) APIHandler {
-			switch method {
-			case http.MethodGet: // TODO: this should only DEFAULT to getting the current user, but there should be an optional parameter to get another user
-				return reqUserCheckCurrent{}
-			case http.MethodPost:
-				return new(ReqUserCreate)
-			case http.MethodPatch:
-				return new(ReqUserEdit)
-			default:
-				return nil
-			}
-		},
-		SubPaths: map[string]APIEndpoint{
-			"sites": {
-				Points: func(method string) APIHandler {
-					switch method {
-					case http.MethodGet:
-						return userSitesList{}
-					default:
-						return nil
-					}
-				},
-			},
-		},
-	},
-	"auth": {
-		Points: func(method string) APIHandler {
-			switch method {
-			case http.MethodPost:
-				return new(AuthLogin)
-			case http.MethodDelete:
-				return authLogout{}
-			default:
-				return nil
-			}
-		},
-		SubPaths: map[string]APIEndpoint{
-			"form": {
-				Points: func(method string) APIHandler {
-					switch method {
-					case http.MethodPost:
-						return new(authLoginForm)
-					default:
-						return nil
-					}
-				},
-			},
-		},
-	},
-	"pagepost": {
-		Points: func(method string) APIHandler {
-			switch method {
-			case http.MethodGet:
-				return new(ReqPagepostList)
-			case http.MethodPost:
-				return new(ReqPagepostCreate)
-			case http.MethodPatch:
-				return new(ReqPageEditPublish)
-			case http.MethodDelete:
-				return new(SiteDelete)
-			default:
-				return nil
-			}
-		},
-		SubPaths: map[string]APIEndpoint{
-			"element": {
-				Points: func(method string) APIHandler {
-					switch method {
-					case http.MethodPost:
-						return new(ReqPagepostMakeDynElem)
-					default:
-						return nil
-					}
-				},
-			},
-		},
-	},
-	"page-edit": {
-		Points: func(method string) APIHandler {
-			switch method {
-			case http.MethodGet:
-				return new(ReqPageEditContent)
-			case http.MethodPost:
-				return new(ReqPageEditSave)
-			case http.MethodPatch:
-				return new(ReqPageEditPublish)
-			default:
-				return nil
-			}
-		},
-	},
-	"site": {
-		Points: func(method string) APIHandler {
-			switch method {
-			case http.MethodPost:
-				return new(SiteCreate)
-			case http.MethodDelete:
-				return new(SiteDelete)
-			default:
-				return nil
-			}
-		},
-		SubPaths: map[string]APIEndpoint{
-			"theme": {
-				Points: func(method string) APIHandler {
-					switch method {
-					case http.MethodGet:
-						return new(SiteGetTheme)
-					default:
-						return nil
-					}
-				},
-			},
-			"option": {
-				Points: func(method string) APIHandler {
-					switch method {
-					default:
-						return nil
-					}
-				},
-			},
-		},
-	},
-	"admin": {
-		Points: func(method string) APIHandler {
-			switch method {
-			case http.MethodGet:
-				return reqAdminGetSrcs{}
-			case http.MethodPost:
-				return new(ReqAdminUpdateSrcs) // need method set of pointer
-			default:
-				return nil
-			}
-		},
-	},
+// A routeAuth is the authorization required for a route, checked before the handler's authorized
+// method so that the requirement can be seen in the route table. The zero value permits everyone.
+type routeAuth struct {
+	login bool             // the user must be logged in
+	cap   roles.Capability // the capability the user must have on the current site
+	super bool             // the user must be a super user
+}
+
+var (
+	authPublic = routeAuth{}
+	authLogin  = routeAuth{login: true}
+	authSuper  = routeAuth{login: true, super: true}
+)
+
+// authCan requires the user to be logged in and have the capability on the current site.
+func authCan(c roles.Capability) routeAuth {
+	return routeAuth{login: true, cap: c}
+}
+
+// allows says if the user satisfies the authorization requirement.
+func (ra routeAuth) allows(r *http.Request, s *data.Site, u *data.User) bool {
+	if ra.login && u.Id == 0 {
+		return false
+	}
+	if ra.cap != "" && !can(r, s, u, ra.cap) {
+		return false
+	}
+	return !ra.super || isSuper(r, u.Id)
+}
+
+// apiRouteList lists the routes of all the API endpoints. A path may have handlers for several methods.
+var apiRouteList = []apiRoute{
+	{http.MethodGet, "/user", func() APIHandler { return reqUserCheckCurrent{} }, authPublic},
+	{http.MethodPost, "/user", func() APIHandler { return new(ReqUserCreate) }, authPublic},
+	{http.MethodPatch, "/user", func() APIHandler { return new(ReqUserEdit) }, authLogin},
+	{http.MethodDelete, "/user", func() APIHandler { return new(ReqUserDelete) }, authLogin},
+	{http.MethodGet, "/user/sites", func() APIHandler { return userSitesList{} }, authLogin},
+	{http.MethodPost, "/user/2fa", func() APIHandler { return new(ReqTwoFactorStart) }, authLogin},
+	{http.MethodPut, "/user/2fa", func() APIHandler { return new(ReqTwoFactorConfirm) }, authLogin},
+	{http.MethodDelete, "/user/2fa", func() APIHandler { return new(ReqTwoFactorDisable) }, authLogin},
+	{http.MethodPost, "/user/2fa/recovery-codes", func() APIHandler { return new(ReqTwoFactorRecoveryCodes) }, authLogin},
+	{http.MethodGet, "/user/exports", func() APIHandler { return new(ReqDataExportList) }, authLogin},
+	{http.MethodPost, "/user/exports", func() APIHandler { return new(ReqDataExportCreate) }, authLogin},
+	{http.MethodGet, "/user/identities", func() APIHandler { return new(ReqIdentityList) }, authLogin},
+	{http.MethodDelete, "/user/identities/{provider}", func() APIHandler { return new(ReqIdentityUnlink) }, authLogin},
+	{http.MethodGet, "/users/{id}", func() APIHandler { return new(ReqUserGet) }, authLogin},
+	{http.MethodDelete, "/users/{id}", func() APIHandler { return new(ReqUserDeleteOther) }, authSuper},
+	{http.MethodGet, "/users/{id}/roles", func() APIHandler { return new(ReqUserRoles) }, authLogin},
+
+	{http.MethodPost, "/auth", func() APIHandler { return new(AuthLogin) }, authPublic},
+	{http.MethodDelete, "/auth", func() APIHandler { return authLogout{} }, authPublic},
+	{http.MethodPost, "/auth/form", func() APIHandler { return new(authLoginForm) }, authPublic},
+	{http.MethodPost, "/auth/2fa", func() APIHandler { return new(AuthTwoFactor) }, authPublic},
+	{http.MethodPost, "/auth/2fa/form", func() APIHandler { return new(authTwoFactorForm) }, authPublic},
+	{http.MethodGet, "/auth/sso", func() APIHandler { return new(ReqSSOStart) }, authPublic},
+	{http.MethodGet, "/auth/sso/authorize", func() APIHandler { return new(ReqSSOAuthorize) }, authPublic},
+	{http.MethodGet, "/auth/sso/callback", func() APIHandler { return new(ReqSSOCallback) }, authPublic},
+	{http.MethodGet, "/auth/oidc", func() APIHandler { return new(ReqOIDCProviders) }, authPublic},
+	{http.MethodGet, "/auth/oidc/{provider}", func() APIHandler { return new(ReqOIDCStart) }, authPublic},
+	{http.MethodGet, "/auth/oidc/{provider}/callback", func() APIHandler { return new(ReqOIDCCallback) }, authPublic},
+
+	{http.MethodPost, "/password-reset", func() APIHandler { return new(ReqPasswordReset) }, authPublic},
+	{http.MethodPut, "/password-reset", func() APIHandler { return new(ReqPasswordResetConfirm) }, authPublic},
+	{http.MethodPost, "/email-verification", func() APIHandler { return new(ReqEmailVerificationResend) }, authPublic},
+	{http.MethodPut, "/email-verification", func() APIHandler { return new(ReqEmailVerificationConfirm) }, authPublic},
+
+	{http.MethodGet, "/sessions", func() APIHandler { return new(ReqSessionList) }, authLogin},
+	{http.MethodDelete, "/sessions", func() APIHandler { return new(ReqSessionRevokeOthers) }, authLogin},
+	{http.MethodDelete, "/sessions/{id}", func() APIHandler { return new(ReqSessionRevoke) }, authLogin},
+
+	{http.MethodGet, "/user/tokens", func() APIHandler { return new(ReqAPITokenList) }, authLogin},
+	{http.MethodPost, "/user/tokens", func() APIHandler { return new(ReqAPITokenCreate) }, authLogin},
+	{http.MethodDelete, "/user/tokens/{id}", func() APIHandler { return new(ReqAPITokenRevoke) }, authLogin},
+	{http.MethodGet, "/site/tokens", func() APIHandler { return &ReqAPITokenList{service: true} }, authCan(roles.CapManageSite)},
+	{http.MethodPost, "/site/tokens", func() APIHandler { return &ReqAPITokenCreate{service: true} }, authCan(roles.CapManageSite)},
+	{http.MethodDelete, "/site/tokens/{id}", func() APIHandler { return &ReqAPITokenRevoke{service: true} }, authCan(roles.CapManageSite)},
+
+	{http.MethodGet, "/pagepost", func() APIHandler { return new(ReqPagepostList) }, authCan(roles.CapEditPages)},
+	{http.MethodPost, "/pagepost", func() APIHandler { return new(ReqPagepostCreate) }, authCan(roles.CapEditPages)},
+	{http.MethodPatch, "/pagepost", func() APIHandler { return new(ReqPageEditPublish) }, authCan(roles.CapPublishPages)},
+	{http.MethodDelete, "/pagepost", func() APIHandler { return new(SiteDelete) }, authCan(roles.CapDeleteSite)},
+	{http.MethodPost, "/pagepost/element", func() APIHandler { return new(ReqPagepostMakeDynElem) }, authCan(roles.CapEditPages)},
+
+	{http.MethodGet, "/content", func() APIHandler { return new(ReqContentList) }, authCan(roles.CapEditPages)},
+	{http.MethodGet, "/content/{id}", func() APIHandler { return new(ReqContentGet) }, authCan(roles.CapEditPages)},
+
+	{http.MethodGet, "/page-edit", func() APIHandler { return new(ReqPageEditContent) }, authCan(roles.CapEditPages)},
+	{http.MethodPost, "/page-edit", func() APIHandler { return new(ReqPageEditSave) }, authCan(roles.CapEditPages)},
+	{http.MethodPatch, "/page-edit", func() APIHandler { return new(ReqPageEditPublish) }, authCan(roles.CapPublishPages)},
+
+	{http.MethodPost, "/site", func() APIHandler { return new(SiteCreate) }, authPublic},
+	{http.MethodDelete, "/site", func() APIHandler { return new(SiteDelete) }, authCan(roles.CapDeleteSite)},
+	{http.MethodGet, "/site/theme", func() APIHandler { return new(SiteGetTheme) }, authCan(roles.CapEditPages)},
+	{http.MethodGet, "/site/verify", func() APIHandler { return new(SiteVerifyStatus) }, authCan(roles.CapManageSite)},
+	{http.MethodPost, "/site/verify", func() APIHandler { return new(SiteVerify) }, authCan(roles.CapManageSite)},
+	{http.MethodGet, "/site/aliases", func() APIHandler { return new(SiteAliasList) }, authCan(roles.CapManageSite)},
+	{http.MethodPost, "/site/aliases", func() APIHandler { return new(SiteAliasAdd) }, authCan(roles.CapManageSite)},
+	{http.MethodPatch, "/site/aliases", func() APIHandler { return new(SiteAliasEdit) }, authCan(roles.CapManageSite)},
+	{http.MethodDelete, "/site/aliases", func() APIHandler { return new(SiteAliasRemove) }, authCan(roles.CapManageSite)},
+	{http.MethodGet, "/site/invitations", func() APIHandler { return new(ReqInvitationList) }, authCan(roles.CapManageUsers)},
+	{http.MethodPost, "/site/invitations", func() APIHandler { return new(ReqInvitationCreate) }, authCan(roles.CapManageUsers)},
+	{http.MethodPost, "/site/invitations/{id}/resend", func() APIHandler { return new(ReqInvitationResend) }, authCan(roles.CapManageUsers)},
+	{http.MethodDelete, "/site/invitations/{id}", func() APIHandler { return new(ReqInvitationRevoke) }, authCan(roles.CapManageUsers)},
+	{http.MethodGet, "/site/users", func() APIHandler { return new(ReqMemberList) }, authCan(roles.CapManageUsers)},
+	{http.MethodPut, "/site/users/{id}/role", func() APIHandler { return new(ReqMemberRole) }, authCan(roles.CapManageUsers)},
+	{http.MethodDelete, "/site/users/{id}", func() APIHandler { return new(ReqMemberRemove) }, authLogin},
+	{http.MethodGet, "/site/roles", func() APIHandler { return new(ReqRoleList) }, authCan(roles.CapManageUsers)},
+	{http.MethodPost, "/site/roles", func() APIHandler { return new(ReqRoleCreate) }, authCan(roles.CapManageRoles)},
+	{http.MethodPatch, "/site/roles/{id}", func() APIHandler { return new(ReqRoleUpdate) }, authCan(roles.CapManageRoles)},
+	{http.MethodDelete, "/site/roles/{id}", func() APIHandler { return new(ReqRoleDelete) }, authCan(roles.CapManageRoles)},
+	{http.MethodPut, "/invitations", func() APIHandler { return new(ReqInvitationAccept) }, authPublic},
+	{http.MethodGet, "/sites", func() APIHandler { return new(ReqSiteList) }, authLogin},
+	{http.MethodGet, "/sites/{id}", func() APIHandler { return new(ReqSiteGet) }, authLogin},
+
+	{http.MethodGet, "/options", func() APIHandler { return new(ReqOptionsGet) }, authCan(roles.CapManageSite)},
+	{http.MethodPatch, "/options", func() APIHandler { return new(ReqOptionsSet) }, authCan(roles.CapManageSite)},
+
+	{http.MethodGet, "/media", func() APIHandler { return new(ReqMediaList) }, authCan(roles.CapUploadMedia)},
+	{http.MethodGet, "/media/{id}", func() APIHandler { return new(ReqMediaGet) }, authCan(roles.CapUploadMedia)},
+
+	{http.MethodGet, "/dns", func() APIHandler { return dnsListZones{} }, authSuper},
+	{http.MethodPost, "/dns", func() APIHandler { return new(ReqDnsCreateZone) }, authSuper},
+	{http.MethodGet, "/dns/records", func() APIHandler { return new(ReqDnsListZoneRecords) }, authCan(roles.CapManageSite)},
+	{http.MethodPost, "/dns/records", func() APIHandler { return new(ReqDnsSetRecords) }, authSuper},
+	{http.MethodDelete, "/dns/records", func() APIHandler { return new(ReqDnsDeleteRecords) }, authSuper},
+
+	{http.MethodGet, "/admin", func() APIHandler { return reqAdminGetSrcs{} }, authPublic},
+	{http.MethodPost, "/admin", func() APIHandler { return new(ReqAdminUpdateSrcs) }, authSuper}, // need method set of pointer
+}
+
+// apiRoutes is the route table made from apiRouteList.
+var apiRoutes = newAPIRoutes(apiRouteList)
+
+// apiRouteFor returns the route with the method and path pattern given, or nil if there is none.
+func apiRouteFor(method, path string) *apiRoute {
+	for i := range apiRouteList {
+		if apiRouteList[i].method == method && apiRouteList[i].path == path {
+			return &apiRouteList[i]
+		}
+	}
+	return nil
+}
+
+func newAPIRoutes(list []apiRoute) *route.Table {
+	t := new(route.Table)
+	for i := range list {
+		t.Add(list[i].method, list[i].path, &list[i])
+	}
+	return t
 }
 
 // A formURLDecoder is able to take the data of a Request and decode the application/x-www-form-urlencoded data
@@ -369,9 +485,15 @@ type formURLDecoder interface {
 	decodeFormURL(*http.Request) error
 }
 
+// A queryDecoder is able to decode the URL query parameters of a GET request into the data structure.
+type queryDecoder interface {
+	decodeQuery(url.Values) error
+}
+
 var unexpectedContentType = errors.New("unexpected content type")
 
-// An APIResponse is a response by a handler of an API endpoint.
+// An APIResponse is a response by a handler of an API endpoint. It is sent to the client in the
+// envelope defined by the response.Response message, encoded as negotiated by the Accept header.
 type APIResponse struct {
 	Status   int           // the HTTP status code (not encoded in response); this should be left at 0 to indicate status OK
 	Body     proto.Message // the body of the response
@@ -379,44 +501,6 @@ type APIResponse struct {
 	err      string        // an error is set when the request fails, in which case only the error message is sent in the response
 }
 
-func (ar *APIResponse) EncodeMsg(w io.Writer) error {
-	// The map header indicates the number of elements (either 1 or 2).
-	if len(ar.Warnings) == 0 {
-		err := en.Append(0x81)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := en.Append(0x82)
-		if err != nil {
-			return err
-		}
-		err = en.Append(0xa4, 'w', 'a', 'r', 'n')
-		if err != nil {
-			return err
-		}
-		err = en.WriteArrayHeader(uint32(len(ar.Warnings)))
-		if err != nil {
-			return err
-		}
-		for i := range ar.Warnings {
-			err = en.WriteString(ar.Warnings[i])
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	// The body must not be nil.
-	err := en.Append(0xa4, 'b', 'o', 'd', 'y')
-	if err != nil {
-		return err
-	}
-
-	// Write the body.
-	return ar.Body.EncodeMsg(en)
-}
-
 // warn adds a warning message to the API response, which indicates to the user that some but not all of the
 // requested actions were completed successfully. An HTTP status code is not set.
 func (ar *APIResponse) warn(m string) {
@@ -437,13 +521,19 @@ func errProcessing() *APIResponse {
 // errMustLogin indicates that the user must log in.
 // The HTTP status code is 403.
 func errMustLogin() *APIResponse {
-	return &APIResponse{Status: http.StatusForbidden, err: "You must log in to continue."}
+	return &APIResponse{Status: http.StatusForbidden, err: errMustLoginMsg}
 }
 
 // errLowPrivileges indicates that the user does not have the privileges to do the request.
 // The HTTP status code is 400.
 func errLowPrivileges() *APIResponse {
-	return &APIResponse{Status: http.StatusBadRequest, err: "Your privileges on this site are not high enough for this action."}
+	return &APIResponse{Status: http.StatusBadRequest, err: errLowPrivsMsg}
+}
+
+// errNotFound indicates that the item requested does not exist.
+// The HTTP status code is 404.
+func errNotFound(msg string) *APIResponse {
+	return &APIResponse{Status: http.StatusNotFound, err: msg}
 }
 
 var badContentType = errors.New("bad content type")
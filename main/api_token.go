@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/route"
+	timeproto "github.com/dchenk/mazewire/pkg/types/time"
+)
+
+// API clients that are not browsers authenticate with API tokens sent in the Authorization header as
+// bearer tokens. A personal access token acts as the user who created it, with the user's role on the
+// site; a service token belongs to the site and acts with a role of its own, which is never more than the
+// current role of the admin who created it. Either kind of token may be used only for the API routes
+// allowed by its scopes (see requiredScope), and never for the pages of the site.
+//
+// A token looks like "mwat_<id>.<secret>". Only the SHA-256 hash of the secret is stored, so the token
+// is shown only when it is created.
+const apiTokenPrefix = "mwat_"
+
+// The scopes of API tokens.
+const (
+	scopeContentRead  = "content:read"
+	scopeContentWrite = "content:write"
+	scopeMediaWrite   = "media:write"
+	scopeUsersAdmin   = "users:admin"
+)
+
+// validScope says if the scope is one of the scopes of API tokens.
+func validScope(scope string) bool {
+	switch scope {
+	case scopeContentRead, scopeContentWrite, scopeMediaWrite, scopeUsersAdmin:
+		return true
+	}
+	return false
+}
+
+// apiTokenNoExpiry is the expiration time of the tokens that do not expire.
+var apiTokenNoExpiry = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+// apiTokenTouchInterval is how often at most the last use of a token is recorded.
+const apiTokenTouchInterval = time.Minute
+
+// maxAPITokenName is the maximum length of the name of a token.
+const maxAPITokenName = 100
+
+// apiTokenScopes lists, by the path of the resource, the scopes required to read the resource (with the
+// GET method) and to change it (with any other method). The path of a resource covers the paths below it.
+// An empty scope means that no token may be used.
+var apiTokenScopes = []struct {
+	path, read, write string
+}{
+	{"/content", scopeContentRead, scopeContentWrite},
+	{"/pagepost", scopeContentRead, scopeContentWrite},
+	{"/page-edit", scopeContentRead, scopeContentWrite},
+	{"/media", scopeContentRead, scopeMediaWrite},
+	{"/users", scopeUsersAdmin, ""}, // Deleting users is for super users only, in person.
+	{"/site/users", scopeUsersAdmin, scopeUsersAdmin},
+	{"/site/invitations", scopeUsersAdmin, scopeUsersAdmin},
+	{"/site/roles", scopeUsersAdmin, scopeUsersAdmin},
+}
+
+// requiredScope returns the scope that an API token must have to be used for the route, or the empty
+// string if no token may be used for the route.
+func requiredScope(rt *apiRoute) string {
+	for _, s := range apiTokenScopes {
+		if rt.path == s.path || strings.HasPrefix(rt.path, s.path+"/") {
+			if rt.method == http.MethodGet {
+				return s.read
+			}
+			return s.write
+		}
+	}
+	return ""
+}
+
+// apiTokenKey is the context key of the API token with which a request is authenticated.
+type apiTokenKey struct{}
+
+// withAPIToken returns the request with the API token with which the user is authenticated.
+func withAPIToken(r *http.Request, t *data.APIToken) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), apiTokenKey{}, t))
+}
+
+// currentAPIToken returns the API token with which the request is authenticated, or nil.
+func currentAPIToken(r *http.Request) *data.APIToken {
+	t, _ := r.Context().Value(apiTokenKey{}).(*data.APIToken)
+	return t
+}
+
+// tokenAllows says if the request to the route is allowed by the scopes of the API token with which
+// the request is authenticated. Requests not authenticated with an API token are allowed.
+func tokenAllows(r *http.Request, rt *apiRoute) bool {
+	t := currentAPIToken(r)
+	if t == nil {
+		return true
+	}
+	scope := requiredScope(rt)
+	if scope == "" {
+		return false
+	}
+	for _, s := range strings.Fields(t.Scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// errTokenScopeMsg is the error message for requests that the scopes of their API token do not allow.
+const errTokenScopeMsg = "The API token does not allow this request."
+
+// newTokenSecret generates the random secret part of a token of which only the hash is stored, such as
+// an API token or the token of an invitation.
+func newTokenSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashTokenSecret returns the hash of the secret part of a token.
+func hashTokenSecret(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// apiTokenUser resolves the API token to the user it acts as on the site of the token. The user is
+// given the role of the token. If the token is not valid, or the creator of a token with its own role can
+// no longer give that role, the returned user is nil.
+func apiTokenUser(r *http.Request, token string) (*data.User, *data.APIToken) {
+	parts := strings.Split(strings.TrimPrefix(token, apiTokenPrefix), ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, nil
+	}
+	t, err := data.Conn.APIToken(parts[0])
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Err(r, "could not get API token", err)
+		}
+		return nil, nil
+	}
+	if !hmac.Equal(hashTokenSecret(parts[1]), t.Hash) {
+		return nil, nil
+	}
+	now := time.Now()
+	expires, err := t.Expires.ToTime()
+	if err != nil {
+		log.Err(r, "invalid API token expiration time", err)
+		return nil, nil
+	}
+	if !now.Before(expires) {
+		return nil, nil
+	}
+
+	u, err := data.Conn.UserSiteInfoByID(t.Site, t.UserId)
+	if err != nil {
+		log.Err(r, "could not get user of API token", err)
+		return nil, nil
+	}
+	if u.Role == roles.Role_NONE {
+		return nil, nil
+	}
+	if t.Role != 0 {
+		// A service token acts with its own role, and only while the creator could still give that role.
+		role, ok := roles.ValidSiteRoleInt64(t.Role)
+		if !ok {
+			log.Err(r, "invalid role of API token", fmt.Errorf("role %d of token %s", t.Role, t.Id))
+			return nil, nil
+		}
+		ok, err := roles.CanGrant(t.Site, u.Role, role)
+		if err != nil {
+			log.Err(r, "could not get capabilities of role", err)
+			return nil, nil
+		}
+		if !ok {
+			// The creator's own role may have capabilities that the token was never given.
+			return nil, nil
+		}
+		u.Role = role
+	}
+
+	if lastUsed, err := t.LastUsed.ToTime(); err != nil || now.Sub(lastUsed) >= apiTokenTouchInterval {
+		if err = data.Conn.APITokenTouch(t.Id); err != nil {
+			log.Err(r, "could not record use of API token", err)
+		}
+	}
+	return u, t
+}
+
+// ReqAPITokenList: GET user/tokens, GET site/tokens
+// The user's personal access tokens on the site, or the service tokens of the site, are listed.
+type ReqAPITokenList struct {
+	service bool
+}
+
+// authorized returns true; the routes require the user to be logged in or an admin.
+func (*ReqAPITokenList) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqAPITokenList) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	var ts []data.APIToken
+	var err error
+	if req.service {
+		ts, err = data.Conn.APITokensBySite(s.Id)
+	} else {
+		ts, err = data.Conn.APITokensByUser(u.Id, s.Id)
+	}
+	if err != nil {
+		log.Err(r, "could not list API tokens", err)
+		return errProcessing()
+	}
+	resp := &apiv1.ListAPITokensResponse{Tokens: make([]*data.APIToken, len(ts))}
+	for i := range ts {
+		ts[i].Hash = nil
+		resp.Tokens[i] = &ts[i]
+	}
+	return &APIResponse{Body: resp}
+}
+
+// ReqAPITokenCreate: POST user/tokens, POST site/tokens
+// A personal access token of the user on the site, or a service token of the site, is created. The
+// complete token is in the response and cannot be retrieved again.
+type ReqAPITokenCreate struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int64    `json:"expires_in_days"` // 0 for a token that does not expire
+	Role          string   `json:"role"`            // the role of a service token, such as "EDITOR"
+
+	service bool
+}
+
+// authorized returns true; the routes require the user to be logged in or an admin.
+func (*ReqAPITokenCreate) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqAPITokenCreate) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxAPITokenName {
+		return APIResponseErr(fmt.Sprintf("The token must have a name of at most %d characters.", maxAPITokenName))
+	}
+	if len(req.Scopes) == 0 {
+		return APIResponseErr("The token must have at least one scope.")
+	}
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			return APIResponseErr(fmt.Sprintf("The scope %q is not valid.", scope))
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		return APIResponseErr("The expiration of the token is not valid.")
+	}
+
+	var role int64
+	if req.service {
+		rl, errResp := parseSiteRole(r, s, req.Role)
+		if errResp != nil {
+			return errResp
+		}
+		if errResp = canGrant(r, s, u, rl); errResp != nil {
+			return errResp
+		}
+		role = int64(rl)
+	}
+
+	expiresAt := apiTokenNoExpiry
+	if req.ExpiresInDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, int(req.ExpiresInDays))
+		if expiresAt.After(apiTokenNoExpiry) {
+			return APIResponseErr("The expiration of the token is not valid.")
+		}
+	}
+	expires, err := timeproto.TimeProto(expiresAt)
+	if err != nil {
+		log.Err(r, "could not convert API token expiration time", err)
+		return errProcessing()
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		log.Err(r, "could not generate API token ID", err)
+		return errProcessing()
+	}
+	secret, err := newTokenSecret()
+	if err != nil {
+		log.Err(r, "could not generate API token", err)
+		return errProcessing()
+	}
+
+	t := &data.APIToken{
+		Id:      id,
+		UserId:  u.Id,
+		Site:    s.Id,
+		Name:    req.Name,
+		Hash:    hashTokenSecret(secret),
+		Scopes:  strings.Join(req.Scopes, " "),
+		Role:    role,
+		Expires: expires,
+	}
+	if err = data.Conn.APITokenInsert(t); err != nil {
+		log.Err(r, "could not insert API token", err)
+		return errProcessing()
+	}
+	t.Hash = nil
+	return &APIResponse{Body: &apiv1.CreatedAPIToken{Token: t, Secret: apiTokenPrefix + id + "." + secret}}
+}
+
+// ReqAPITokenRevoke: DELETE user/tokens/{id}, DELETE site/tokens/{id}
+// One of the user's personal access tokens on the site, or a service token of the site, is deleted.
+type ReqAPITokenRevoke struct {
+	service bool
+}
+
+// authorized returns true; the handler accepts only the user's own tokens, or the service tokens of
+// the site on the route that requires an admin.
+func (*ReqAPITokenRevoke) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqAPITokenRevoke) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	const notFound = "The token was not found."
+	id := route.Param(r, "id")
+	t, err := data.Conn.APIToken(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errNotFound(notFound)
+		}
+		log.Err(r, "could not get API token", err)
+		return errProcessing()
+	}
+	if t.Site != s.Id || (t.Role != 0) != req.service || (!req.service && t.UserId != u.Id) {
+		return errNotFound(notFound)
+	}
+	if err = data.Conn.APITokenDelete(id); err != nil {
+		log.Err(r, "could not delete API token", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+}
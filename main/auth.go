@@ -1,20 +1,18 @@
 package main
 
 import (
-	"crypto/hmac"
 	"crypto/md5"
-	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
 	"net/http"
 	"strings"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
-
 	"github.com/dchenk/mazewire/pkg/data"
 	"github.com/dchenk/mazewire/pkg/env"
 	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/password"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
 	"github.com/dchenk/mazewire/pkg/roles"
 	"github.com/dchenk/mazewire/pkg/util"
 )
@@ -29,10 +27,21 @@ func (*AuthLogin) authorized(*http.Request, *data.Site, *data.User) bool {
 	return true
 }
 
+// rateLimit limits how often each IP address may try to log in.
+func (*AuthLogin) rateLimit() rate_limit.Bucket {
+	return loginRateLimit
+}
+
 // authLogin allows a user to log in via an HTTP request not using a form.
-// The response will have the err field either blank or providing an error message for the user.
+// The response will have the err field either blank or providing an error message for the user. If the
+// user must give a second factor, the response has the status 202 and gives the challenge to complete the
+// login with at auth/2fa.
 func (al *AuthLogin) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
-	return checkLoginCreds(r, s, u, al.User, al.Pass)
+	resp := checkLoginCreds(r, s, u, al.User, al.Pass)
+	if resp.err != "" {
+		return resp
+	}
+	return challengeTwoFactor(r, s, u, resp)
 }
 
 // RespUserLogin is the message sent with OK responses for login requests.
@@ -53,6 +62,11 @@ func (*authLoginForm) authorized(*http.Request, *data.Site, *data.User) bool {
 	return true
 }
 
+// rateLimit limits how often each IP address may try to log in.
+func (*authLoginForm) rateLimit() rate_limit.Bucket {
+	return loginRateLimit
+}
+
 // handle allows a user to log in via a form.
 // The APIResponse returned gets its Body set here because only the Err field is used to provide a (possibly blank) message.
 // User u is updated by pointer if found.
@@ -60,7 +74,11 @@ func (*authLoginForm) authorized(*http.Request, *data.Site, *data.User) bool {
 //  - user (username or email address)
 //  - pass (password)
 func (auf *authLoginForm) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
-	return checkLoginCreds(r, s, u, auf.User, auf.Pass)
+	resp := checkLoginCreds(r, s, u, auf.User, auf.Pass)
+	if resp.err != "" {
+		return resp
+	}
+	return challengeTwoFactor(r, s, u, resp)
 }
 
 func (auf *authLoginForm) decodeFormURL(r *http.Request) error {
@@ -73,6 +91,10 @@ func (auf *authLoginForm) decodeFormURL(r *http.Request) error {
 // that the user should be logged in.
 // The data.User passed in should be not nil in case a real user's data is retrieved and populated into the struct.
 // This function always returns a non-nil *APIResponse.
+// Failed logins are counted by IP address and by account, and the logins are locked out after too many
+// consecutive failures according to the lockout policies of the site. The failures of the account are
+// forgotten only once the login succeeds, which is after the second factor if one is required (see
+// challengeTwoFactor); those from the IP address expire after loginFailuresTTL.
 func checkLoginCreds(r *http.Request, s *data.Site, u *data.User, unameEmail string, pass string) *APIResponse {
 	if u.Id != 0 {
 		return APIResponseErr("You are already logged in as user: " + u.Uname)
@@ -84,6 +106,11 @@ func checkLoginCreds(r *http.Request, s *data.Site, u *data.User, unameEmail str
 		return APIResponseErr("You must provide a username and a password")
 	}
 
+	if resp := loginLockedOut(r, s, rate_limit.IPKey(rate_limit.RemoteIP(r))); resp != nil {
+		return resp
+	}
+	lockouts := siteLoginLockouts(r, s)
+
 	var (
 		err   error
 		tempU *data.User
@@ -95,23 +122,59 @@ func checkLoginCreds(r *http.Request, s *data.Site, u *data.User, unameEmail str
 	}
 	if err != nil {
 		if err == sql.ErrNoRows {
+			loginFailed(r, s, lockouts, 0)
 			return APIResponseErr(errInvalidLogin)
 		}
 		log.Err(r, "error checking if email exists", err)
 		return errProcessing()
 	}
 
+	// The account of a user being deleted is closed, though the user remains until the deletion is done.
+	if pending, err := deletionPending(tempU.Id); err != nil {
+		log.Err(r, "could not check for pending user deletion", err)
+		return errProcessing()
+	} else if pending {
+		loginFailed(r, s, lockouts, 0)
+		return APIResponseErr(errInvalidLogin)
+	}
+
 	if tempU.Role == roles.Role_NONE {
 		return APIResponseErr(errNoMembership)
 	}
 
-	if err = bcrypt.CompareHashAndPassword(tempU.Pass, []byte(pass)); err != nil {
-		if err != bcrypt.ErrMismatchedHashAndPassword {
-			log.Err(r, "could not compare bcrypt hashes", err)
-		}
+	if resp := loginLockedOut(r, s, rate_limit.UserKey(tempU.Id)); resp != nil {
+		return resp
+	}
+
+	ok, err := password.Verify(tempU.Pass, pass)
+	if err != nil {
+		log.Err(r, "could not verify password", err)
+	}
+	if !ok {
+		loginFailed(r, s, lockouts, tempU.Id)
 		return APIResponseErr(errInvalidLogin)
 	}
 
+	// Replace a hash made by an older scheme or with other parameters while the password is known.
+	if password.NeedsRehash(tempU.Pass, passwordParams) {
+		if passHash, err := hashPassword(pass); err != nil {
+			log.Err(r, "could not rehash password", err)
+		} else if err = data.Conn.UserPasswordUpdate(tempU.Id, passHash); err != nil {
+			log.Err(r, "could not update rehashed password", err)
+		} else {
+			tempU.Pass = passHash
+		}
+	}
+
+	verified, err := emailVerified(tempU.Id)
+	if err != nil {
+		log.Err(r, "could not check if email address is verified", err)
+		return errProcessing()
+	}
+	if !verified {
+		return APIResponseErr(errEmailUnverified)
+	}
+
 	// Copy tempU fields into u.
 	*u = *tempU
 
@@ -120,58 +183,60 @@ func checkLoginCreds(r *http.Request, s *data.Site, u *data.User, unameEmail str
 	return &APIResponse{Body: RespUserLogin}
 }
 
-// createLoginCookie creates a complete cookie for a user to log in.
-// The cookie's expiration is set to 4 hours. The hashedUserPass must be the current, validly hashed password
-// belonging to the user.
-// The first part of the cookie body, terminated by a dot, consists of five parts:
-//  - MessagePack-encoded int64 giving the cookie expiration as a Unix timestamp (seconds)
-//  - 32-byte hash of the user agent token
-//  - MessagePack-encoded int64 giving the user ID
-//  - MessagePack-encoded int64 giving the site ID (each cookie belongs to only one site)
-//  - 21-byte fragment of the hashed password
-func createLoginCookie(r *http.Request, userID int64, hashedUserPass []byte, siteID int64) string {
-	body := make([]byte, 0, msgp.Int64Size*3+md5.Size+21)
-
-	body = msgp.AppendInt64(body, time.Now().Add(time.Hour * 4).Unix())
+// loginTokenTTL is how long a login cookie or token is valid at most. The session that the token
+// identifies ends sooner if it is not used for sessionIdleTTL.
+const loginTokenTTL = time.Hour * 24 * 14
 
-	body = append(body, userAgentToken(r.UserAgent())...)
+// createLoginCookie creates a complete cookie for a user to log in, containing the login token. The
+// cookie is sent only with same-site requests and top-level navigations, which together with the CSRF
+// token keeps other sites from making requests on behalf of the user.
+func createLoginCookie(token string) string {
+	if env.Prod() {
+		return "mwuser=" + token + "; Secure; HttpOnly; SameSite=Lax; Path=/"
+	}
+	return "mwuser=" + token + "; HttpOnly; SameSite=Lax; Path=/"
+}
 
-	body = msgp.AppendInt64(body, userID)
+// createLoginToken creates a token for a user to log in, either as the value of the login cookie or
+// in the Authorization header as a bearer token. The token does not depend on the password: it is
+// valid only while its session lasts, and changing the password ends the sessions of the user.
+// The first part of the token, terminated by a dot, consists of five parts:
+//  - int64 giving the token expiration as a Unix timestamp (seconds)
+//  - 32-byte hash of the user agent token
+//  - int64 giving the user ID
+//  - int64 giving the site ID (each token belongs to only one site)
+//  - string giving the ID of the login session
+// The int64s and the string are encoded with appendTokenInt and appendTokenString.
+// The token is signed with signToken; the parts that follow the first part name the signing key and
+// give the signature.
+func createLoginToken(r *http.Request, userID int64, siteID int64, sessionID string, expires time.Time) string {
+	body := make([]byte, 0, tokenIntSize*3+md5.Size+tokenStringPrefixSize+len(sessionID))
 
-	body = msgp.AppendInt64(body, siteID)
+	body = appendTokenInt(body, expires.Unix())
 
-	// The password fragment does not including the bcrypt header identifying the
-	// hashing algorithm used.
-	body = append(body, hashedUserPass[10:31]...)
+	body = append(body, userAgentToken(r.UserAgent())...)
 
-	bodyEncoded := make([]byte, base64.RawURLEncoding.EncodedLen(len(body)))
-	base64.RawURLEncoding.Encode(bodyEncoded, body)
+	body = appendTokenInt(body, userID)
 
-	var cookie strings.Builder
-	cookie.Grow(cookieLenBase + len(bodyEncoded))
+	body = appendTokenInt(body, siteID)
 
-	cookie.WriteString("mwuser=")
-	cookie.Write(bodyEncoded)
-	cookie.WriteByte('.')
+	body = appendTokenString(body, sessionID)
 
 	// The signature is a signature of the base64-encoded body.
-	sig := hmac.New(sha256.New, TOKEN_KEY)
-	sig.Write(bodyEncoded)
-	cookie.WriteString(base64.RawURLEncoding.EncodeToString(sig.Sum(nil)))
+	return signToken("", base64.RawURLEncoding.EncodeToString(body))
+}
 
-	if env.Prod() {
-		cookie.WriteString("; Secure; HttpOnly; Path=/")
-	} else {
-		cookie.WriteString("; HttpOnly; Path=/")
+// bearerToken returns the token given in the Authorization header of the request with the Bearer
+// scheme, or the empty string if there is none.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	h := r.Header.Get("Authorization")
+	if len(h) <= len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return ""
 	}
-
-	return cookie.String()
+	return strings.TrimSpace(h[len(prefix):])
 }
 
-// cookieLenBase is the length of a constant string included in the auth cookie body.
-// The 43 represents base64.RawURLEncoding.EncodedLen(sha256.Size) from the cookie body signature.
-const cookieLenBase = len("mwuser=") + 1 + len("; Secure; HttpOnly; Path=/") + 43
-
 // authLogout: DELETE auth
 type authLogout struct{}
 
@@ -189,7 +254,7 @@ func (authLogout) handle(_ *http.Request, _ *data.Site, _ *data.User) *APIRespon
 
 func createLogoutCookie() string {
 	if env.Prod() {
-		return "mwuser=none; Secure; HttpOnly; Path=/"
+		return "mwuser=none; Secure; HttpOnly; SameSite=Lax; Path=/"
 	}
-	return "mwuser=none; HttpOnly; Path=/"
+	return "mwuser=none; HttpOnly; SameSite=Lax; Path=/"
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/keyring"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/totp"
+)
+
+// lockoutDB is a database with a single user who has two-factor authentication enabled. The login
+// throttles are kept in memory.
+type lockoutDB struct {
+	data.DB
+	user      data.User
+	meta      map[string][]byte
+	throttles map[string]*data.LoginThrottle
+}
+
+func (db *lockoutDB) UserSiteInfoByUsername(_ *data.Site, uname string) (*data.User, error) {
+	if uname != db.user.Uname {
+		return nil, sql.ErrNoRows
+	}
+	u := db.user
+	return &u, nil
+}
+
+func (db *lockoutDB) UserSiteInfoByID(_, userID int64) (*data.User, error) {
+	if userID != db.user.Id {
+		return nil, sql.ErrNoRows
+	}
+	u := db.user
+	return &u, nil
+}
+
+func (db *lockoutDB) UserDeletion(int64) (*data.UserDeletion, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (db *lockoutDB) UserMetaV(int64, string) ([]byte, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (db *lockoutDB) UserMetaByIdMapped(int64) (map[string][]byte, error) {
+	return db.meta, nil
+}
+
+func (db *lockoutDB) UserMessageInsert(int64, string, string) error {
+	return nil
+}
+
+func (db *lockoutDB) OptionsKeyInMappedStr(int64, []string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (db *lockoutDB) LoginThrottle(_ int64, k string) (*data.LoginThrottle, error) {
+	lt, ok := db.throttles[k]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return lt, nil
+}
+
+func (db *lockoutDB) LoginThrottleFail(_ int64, k string, _ time.Time) (int64, error) {
+	lt, ok := db.throttles[k]
+	if !ok {
+		lt = &data.LoginThrottle{K: k}
+		db.throttles[k] = lt
+	}
+	lt.Failures++
+	return lt.Failures, nil
+}
+
+func (db *lockoutDB) LoginThrottleLock(_ int64, k string, until time.Time) error {
+	db.throttles[k].LockedUntil = &until
+	return nil
+}
+
+func (db *lockoutDB) LoginThrottleDelete(_ int64, k string) error {
+	delete(db.throttles, k)
+	return nil
+}
+
+// wrongTOTP returns a six-digit code that is not accepted for the secret now.
+func wrongTOTP(secret []byte) string {
+	now := totp.Counter(time.Now())
+	for i := 0; ; i++ {
+		code := strconv.Itoa(100000 + i)
+		ok := true
+		for c := now - twoFactorSkew - 1; c <= now+twoFactorSkew+1; c++ {
+			ok = ok && totp.Code(secret, c) != code
+		}
+		if ok {
+			return code
+		}
+	}
+}
+
+func TestLogin_twoFactorLockout(t *testing.T) {
+	conn := data.Conn
+	defer func() { data.Conn = conn }()
+
+	key, err := keyring.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKeys.Store(keyring.New([]keyring.Key{key}))
+
+	const pass = "correct horse battery staple"
+	hash, err := hashPassword(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &lockoutDB{
+		user:      data.User{Id: 7, Uname: "alice", Pass: hash, Role: roles.Role_AUTHOR},
+		meta:      map[string][]byte{totpSecretKey: []byte(totp.EncodeSecret(secret))},
+		throttles: make(map[string]*data.LoginThrottle),
+	}
+	data.Conn = db
+	s := &data.Site{Id: 3, Domain: "example.com"}
+
+	// Each attempt gives the right password and a wrong code, so the account must be locked out once the
+	// wrong codes reach the threshold even though the password keeps being right.
+	threshold := int(defaultLoginLockouts.user.Threshold)
+	for i := 0; i <= threshold; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/api/auth", nil)
+		var u data.User
+		resp := (&AuthLogin{User: "alice", Pass: pass}).handle(r, s, &u)
+		if resp.Status == http.StatusTooManyRequests {
+			if i < threshold {
+				t.Fatalf("locked out after %d failures", i)
+			}
+			return
+		}
+		c, ok := resp.Body.(*apiv1.TwoFactorChallenge)
+		if !ok || resp.Status != http.StatusAccepted {
+			t.Fatalf("(%d) got no challenge; status %d, error %q", i, resp.Status, resp.err)
+		}
+		if u.Id != 0 {
+			t.Fatalf("(%d) logged in before the second factor", i)
+		}
+		resp = checkTwoFactor(r, s, &u, c.Challenge, wrongTOTP(secret), true)
+		if resp.err != errInvalid2FACode {
+			t.Fatalf("(%d) got error %q for a wrong code", i, resp.err)
+		}
+	}
+	t.Errorf("not locked out after %d wrong codes; throttle: %+v", threshold,
+		db.throttles[rate_limit.UserKey(db.user.Id)])
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/cors"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/env"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/util"
+)
+
+// Options with which the CORS policy of a site is configured, so that front ends on other origins can
+// use the API. The lists are comma-separated. Without the cors-origins option, no other origins are
+// allowed.
+const (
+	optCORSOrigins       = "cors-origins"        // the allowed origins, possibly with wildcards like "https://*.example.com"
+	optCORSMethods       = "cors-methods"        // the allowed methods, if not every method of each route
+	optCORSHeaders       = "cors-headers"        // the allowed request headers in addition to the defaults, or "*"
+	optCORSExposeHeaders = "cors-expose-headers" // the response headers readable in addition to the defaults
+	optCORSCredentials   = "cors-credentials"    // "true" to allow cookies to be sent, except by the origins allowed by "*"
+	optCORSMaxAge        = "cors-max-age"        // how long preflight results may be cached, like "10m"
+)
+
+// Headers that are always allowed to be sent and read by the origins that a site allows.
+var (
+	corsDefaultHeaders       = []string{"Content-Type", "Authorization", csrfHeader}
+	corsDefaultExposeHeaders = []string{csrfHeader, "Retry-After", "Allow"}
+)
+
+// devOrigin is the origin of the admin front end served by the development server, which is always
+// allowed, with credentials, in the development environment.
+const devOrigin = "http://localhost:8082"
+
+// siteCORSPolicy returns the CORS policy of the site. Invalid option values are logged and ignored.
+func siteCORSPolicy(r *http.Request, s *data.Site) *cors.Policy {
+	p := &cors.Policy{
+		Headers:       corsDefaultHeaders,
+		ExposeHeaders: corsDefaultExposeHeaders,
+	}
+	opts, err := data.Conn.OptionsKeyInMappedStr(s.Id, []string{optCORSOrigins, optCORSMethods, optCORSHeaders,
+		optCORSExposeHeaders, optCORSCredentials, optCORSMaxAge})
+	if err != nil {
+		log.Err(r, "could not get the CORS options", err)
+		opts = nil
+	}
+	for k, v := range opts {
+		var err error
+		switch k {
+		case optCORSOrigins:
+			p.Origins = cors.ParseList(v)
+		case optCORSMethods:
+			p.Methods = cors.ParseList(strings.ToUpper(v))
+		case optCORSHeaders:
+			p.Headers = append(cors.ParseList(v), corsDefaultHeaders...)
+		case optCORSExposeHeaders:
+			p.ExposeHeaders = append(cors.ParseList(v), corsDefaultExposeHeaders...)
+		case optCORSCredentials:
+			p.Credentials, err = strconv.ParseBool(v)
+		case optCORSMaxAge:
+			p.MaxAge, err = time.ParseDuration(v)
+		}
+		if err != nil {
+			log.Err(r, fmt.Sprintf("invalid value of option %q: %q", k, v), err)
+		}
+	}
+	if p.Credentials && util.SliceContainsString(p.Origins, "*") {
+		// The origins allowed by "*" are never allowed credentials, which would let any website use the
+		// API as the users and read their CSRF tokens.
+		log.Err(r, fmt.Sprintf("option %q is ignored for the origin \"*\" in option %q", optCORSCredentials,
+			optCORSOrigins), errors.New("credentials cannot be allowed for every origin"))
+	}
+	if !env.Prod() {
+		p.Origins = append(p.Origins, devOrigin)
+		p.Credentials = true
+	}
+	return p
+}
+
+// crossOrigin returns the origin of the request if it is a cross-origin request, or else an empty string.
+func crossOrigin(r *http.Request) string {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return ""
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if cors.SameOrigin(r, scheme, origin) {
+		return ""
+	}
+	return origin
+}
+
+// handleCORS applies the CORS policy of the site to an API request for a resource that exists for the
+// methods given. A preflight request is answered, in which case handleCORS returns true, and the
+// headers of any other cross-origin request are set for the response.
+func handleCORS(w http.ResponseWriter, r *http.Request, s *data.Site, methods []string) bool {
+	origin := crossOrigin(r)
+	if origin == "" {
+		return false
+	}
+	p := siteCORSPolicy(r, s)
+	if cors.IsPreflight(r) {
+		p.Preflight(w.Header(), r, methods)
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+	p.Actual(w.Header(), origin)
+	return false
+}
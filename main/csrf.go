@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/hmac"
+	"mime"
+	"net/http"
+
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/env"
+	"github.com/dchenk/mazewire/pkg/util"
+)
+
+// Requests authenticated with the login cookie that may change anything must prove that they were made
+// by the site itself and not forged by another site. The CSRF token of a user is derived from the login
+// token, so it needs no storage and changes with each login. The token is given to the browser in the
+// mwcsrf cookie, which scripts of the site (and only of the site) can read, and in the X-CSRF-Token
+// header of API responses; it must be sent back in the X-CSRF-Token request header or, by HTML forms,
+// in the csrf_token field.
+//
+// Clients authenticating with a bearer token are exempt because browsers never attach an Authorization
+// header on their own, so such requests cannot be forged.
+const (
+	csrfCookieName = "mwcsrf"
+	csrfHeader     = "X-CSRF-Token"
+	csrfFormField  = "csrf_token"
+)
+
+// csrfToken returns the CSRF token bound to the login token. The token is signed with the key that signed
+// the login token, so it stays the same for as long as the login token is valid.
+func csrfToken(loginToken string) string {
+	k := tokenKey(loginToken)
+	if k == nil {
+		return ""
+	}
+	return k.Sum("csrf:", loginToken)
+}
+
+// createCSRFCookie creates a complete cookie holding the CSRF token for the login token. Unlike the login
+// cookie, it is not HttpOnly so that the scripts of the site can read it.
+func createCSRFCookie(loginToken string) string {
+	if env.Prod() {
+		return csrfCookieName + "=" + csrfToken(loginToken) + "; Secure; SameSite=Strict; Path=/"
+	}
+	return csrfCookieName + "=" + csrfToken(loginToken) + "; SameSite=Strict; Path=/"
+}
+
+// createCSRFLogoutCookie creates a cookie that clears the CSRF token.
+func createCSRFLogoutCookie() string {
+	if env.Prod() {
+		return csrfCookieName + "=none; Secure; SameSite=Strict; Path=/; Max-Age=0"
+	}
+	return csrfCookieName + "=none; SameSite=Strict; Path=/; Max-Age=0"
+}
+
+// cookieLoginToken returns the login token of the request if the user is authenticated with the login
+// cookie, or else an empty string.
+func cookieLoginToken(r *http.Request, u *data.User) string {
+	if u.Id == 0 || bearerToken(r) != "" {
+		return ""
+	}
+	cookie, err := r.Cookie("mwuser")
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// csrfSafeMethod says if requests with the HTTP method must not change anything, so they need no CSRF
+// token.
+func csrfSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// checkCSRF says if the request to an API route with the method given may be handled for the user. The
+// request must carry the CSRF token if the route is not safe and the user is authenticated with the
+// login cookie.
+func checkCSRF(r *http.Request, method string, u *data.User) bool {
+	if csrfSafeMethod(method) {
+		return true
+	}
+	loginToken := cookieLoginToken(r, u)
+	if loginToken == "" {
+		return true
+	}
+	got := r.Header.Get(csrfHeader)
+	if got == "" {
+		if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == util.ContentTypeFormURL {
+			got = r.PostFormValue(csrfFormField)
+		}
+	}
+	return got != "" && hmac.Equal([]byte(got), []byte(csrfToken(loginToken)))
+}
+
+// setCSRFToken gives the CSRF token to a user authenticated with the login cookie: the token is sent in
+// the X-CSRF-Token header and, if the mwcsrf cookie of the request does not already hold it, in the
+// cookie as well.
+func setCSRFToken(w http.ResponseWriter, r *http.Request, u *data.User) {
+	loginToken := cookieLoginToken(r, u)
+	if loginToken == "" {
+		return
+	}
+	token := csrfToken(loginToken)
+	w.Header().Set(csrfHeader, token)
+	if cookie, err := r.Cookie(csrfCookieName); err != nil || cookie.Value != token {
+		w.Header().Add("Set-Cookie", createCSRFCookie(loginToken))
+	}
+}
+
+// errCSRFMsg is the error message of requests rejected for not having a valid CSRF token.
+const errCSRFMsg = "The request is missing a valid CSRF token. Please reload the page and try again."
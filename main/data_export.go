@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/storage"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/export"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/media"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+	"github.com/dchenk/mazewire/pkg/roles"
+	timeproto "github.com/dchenk/mazewire/pkg/types/time"
+	"github.com/dchenk/mazewire/pkg/util"
+)
+
+// Users export the personal data stored about them: the account, the user meta, the roles on the
+// sites, the content written by the user, the messages to the user, and the media uploaded by the user
+// along with the files. The archive is built in the background and stored in the exports bucket, and
+// a link to download it is sent by email from the site on which the export was requested. The links
+// are signed and expire, and the archive is deleted when it expires.
+const (
+	dataExportPath    = "data-export"      // the path at which archives are downloaded, followed by a token
+	dataExportLease   = time.Minute * 30   // how long a claimed export is left to the server building it
+	dataExportTTL     = time.Hour * 24 * 7 // how long an archive is kept
+	dataExportLinkTTL = time.Hour * 24     // how long a download link is valid
+)
+
+// The statuses of exports.
+const (
+	dataExportPending = "pending"
+	dataExportReady   = "ready"
+)
+
+const errDataExportLink = "The link is not valid or has expired."
+
+var dataExportRateLimit = rate_limit.PerMinute(1, 3)
+
+// redactedUserMeta lists the keys of the user meta holding credentials, the values of which are not
+// exported.
+var redactedUserMeta = map[string]bool{
+	totpSecretKey:                        true,
+	totpPendingKey:                       true,
+	totpRecoveryKey:                      true,
+	userTokenMetaKey(tokenPasswordReset): true,
+	userTokenMetaKey(tokenVerifyEmail):   true,
+}
+
+// ReqDataExportCreate: POST user/exports
+// An export of the personal data of the user is started, unless one is pending already, in which case
+// the pending export is given.
+type ReqDataExportCreate struct{}
+
+func (*ReqDataExportCreate) rateLimit() rate_limit.Bucket {
+	return dataExportRateLimit
+}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqDataExportCreate) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqDataExportCreate) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	if exportsBucket == nil {
+		return APIResponseErr("Exporting personal data is not available.")
+	}
+	es, err := data.Conn.DataExportsByUser(u.Id)
+	if err != nil {
+		log.Err(r, "could not get data exports of user", err)
+		return errProcessing()
+	}
+	for i := range es {
+		if es[i].Status == dataExportPending {
+			return &APIResponse{Body: &apiv1.DataExportInfo{Export: &es[i]}}
+		}
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		log.Err(r, "could not generate data export ID", err)
+		return errProcessing()
+	}
+	if err = data.Conn.DataExportInsert(&data.DataExport{Id: id, UserId: u.Id, Site: s.Id}); err != nil {
+		log.Err(r, "could not insert data export", err)
+		return errProcessing()
+	}
+	e, err := data.Conn.DataExport(id)
+	if err != nil {
+		log.Err(r, "could not get data export", err)
+		return errProcessing()
+	}
+	go claimDataExport(*e)
+	return &APIResponse{Body: &apiv1.DataExportInfo{Export: e}}
+}
+
+// ReqDataExportList: GET user/exports
+// The exports of the personal data of the user are listed, the most recent first, with the links at
+// which the archives that are ready are downloaded.
+type ReqDataExportList struct{}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqDataExportList) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqDataExportList) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	es, err := data.Conn.DataExportsByUser(u.Id)
+	if err != nil {
+		log.Err(r, "could not get data exports of user", err)
+		return errProcessing()
+	}
+	resp := &apiv1.ListDataExportsResponse{Exports: make([]*apiv1.DataExportInfo, len(es))}
+	for i := range es {
+		resp.Exports[i] = &apiv1.DataExportInfo{Export: &es[i]}
+		if es[i].Status == dataExportReady {
+			resp.Exports[i].DownloadUrl = dataExportLink(r, s, &es[i])
+		}
+	}
+	return &APIResponse{Body: resp}
+}
+
+// dataExportObject returns the name of the object in the exports bucket holding the archive of the
+// export.
+func dataExportObject(id string) string {
+	return id + ".zip"
+}
+
+// dataExportLink returns the link at which the archive of the export is downloaded. The link expires
+// after dataExportLinkTTL or when the archive expires, whichever is sooner.
+func dataExportLink(r *http.Request, s *data.Site, e *data.DataExport) string {
+	expires := time.Now().Add(dataExportLinkTTL)
+	if t, err := e.Expires.ToTime(); err == nil && t.Before(expires) {
+		expires = t
+	}
+	body := make([]byte, 0, tokenIntSize*2+tokenStringPrefixSize+len(e.Id))
+	body = appendTokenInt(body, expires.Unix())
+	body = appendTokenInt(body, e.UserId)
+	body = appendTokenString(body, e.Id)
+	token := signToken(dataExportPath+".", base64.RawURLEncoding.EncodeToString(body))
+	return siteRoot(r, s) + "/" + dataExportPath + "/" + token
+}
+
+// parseDataExportToken checks a token made by dataExportLink and returns the user ID and the export
+// ID that it holds if it is valid and has not expired.
+func parseDataExportToken(token string) (userID int64, id string, ok bool) {
+	bodyEncoded, ok := verifyToken(dataExportPath+".", token)
+	if !ok {
+		return 0, "", false
+	}
+	body, err := base64.RawURLEncoding.DecodeString(bodyEncoded)
+	if err != nil {
+		return 0, "", false
+	}
+	var expires int64
+	if expires, body, err = readTokenInt(body); err != nil || time.Now().Unix() > expires {
+		return 0, "", false
+	}
+	if userID, body, err = readTokenInt(body); err != nil {
+		return 0, "", false
+	}
+	if id, _, err = readTokenString(body); err != nil {
+		return 0, "", false
+	}
+	return userID, id, true
+}
+
+// serveDataExport responds to a request to download the archive of an export with the token of a
+// download link.
+func serveDataExport(w http.ResponseWriter, r *http.Request, token string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, id, ok := parseDataExportToken(token)
+	if !ok || exportsBucket == nil {
+		http.Error(w, errDataExportLink, http.StatusNotFound)
+		return
+	}
+	e, err := data.Conn.DataExport(id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Err(r, "could not get data export", err)
+			http.Error(w, "An error occurred.", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, errDataExportLink, http.StatusNotFound)
+		return
+	}
+	expires, err := e.Expires.ToTime()
+	if err != nil || e.UserId != userID || e.Status != dataExportReady || time.Now().After(expires) {
+		http.Error(w, errDataExportLink, http.StatusNotFound)
+		return
+	}
+
+	rd, err := exportsBucket.Object(dataExportObject(e.Id)).NewReader(r.Context())
+	if err != nil {
+		if err != storage.ErrObjectNotExist {
+			log.Err(r, "could not open data export archive", err)
+			http.Error(w, "An error occurred.", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, errDataExportLink, http.StatusNotFound)
+		return
+	}
+	defer rd.Close()
+
+	created, _ := e.Created.ToTime()
+	h := w.Header()
+	h.Set("Content-Type", util.ContentTypeZip)
+	h.Set("Content-Disposition", `attachment; filename="data-`+created.Format("2006-01-02")+`.zip"`)
+	h.Set("Content-Length", strconv.FormatInt(rd.Size(), 10))
+	h.Set("Cache-Control", "private, no-store")
+	if r.Method == http.MethodHead {
+		return
+	}
+	// An archive may take longer to download than the write timeout of the server allows.
+	if err = http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Err(r, "could not clear write deadline", err)
+	}
+	if _, err = io.Copy(w, rd); err != nil {
+		log.Err(r, "could not send data export archive", err)
+	}
+}
+
+// personalData is the personal data stored about a user, as it is exported.
+type personalData struct {
+	Exported time.Time          `json:"exported"`
+	User     *data.User         `json:"user"`
+	Meta     map[string]string  `json:"meta"`
+	Roles    []personalRole     `json:"roles"`
+	Content  []data.Content     `json:"content"`
+	Messages []data.UserMessage `json:"messages"`
+	Media    []data.Media       `json:"media"`
+}
+
+// personalRole is the role of a user on a site, as it is exported.
+type personalRole struct {
+	Site   int64  `json:"site"`
+	Domain string `json:"domain"`
+	Role   string `json:"role"`
+}
+
+// collectPersonalData gathers the personal data stored about the user along with the files of the
+// media uploaded by the user.
+func collectPersonalData(ctx context.Context, u data.User) (*personalData, []export.File, error) {
+	u.Pass = nil
+	d := &personalData{Exported: time.Now().UTC(), User: &u, Meta: make(map[string]string)}
+
+	metas, err := data.Conn.UserMetaById(u.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range metas {
+		if redactedUserMeta[m.K] {
+			d.Meta[m.K] = "[redacted]"
+			continue
+		}
+		d.Meta[m.K] = string(m.V)
+	}
+	ms, err := roles.UserMemberships(u.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ms.Sites) > 0 {
+		sites, err := data.Conn.SitesByIDs(ms.SiteIDs())
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range sites {
+			name, err := roles.SiteRoleName(sites[i].Id, ms.Role(sites[i].Id))
+			if err != nil {
+				return nil, nil, err
+			}
+			d.Roles = append(d.Roles, personalRole{Site: sites[i].Id, Domain: sites[i].Domain, Role: name})
+		}
+	}
+
+	if d.Content, err = data.Conn.ContentByAuthor(u.Id); err != nil {
+		return nil, nil, err
+	}
+	if d.Messages, err = data.Conn.UserMessagesByUser(u.Id); err != nil {
+		return nil, nil, err
+	}
+	if d.Media, err = data.Conn.MediaByUploader(u.Id); err != nil {
+		return nil, nil, err
+	}
+
+	var files []export.File
+	if contentBucket != nil {
+		for i := range d.Media {
+			obj := contentBucket.Object(media.ObjectName(&d.Media[i]))
+			if _, err := obj.Attrs(ctx); err != nil {
+				if err == storage.ErrObjectNotExist {
+					continue // The metadata is exported nevertheless.
+				}
+				return nil, nil, err
+			}
+			files = append(files, export.File{
+				Name: "media/" + obj.ObjectName(),
+				Open: func() (io.ReadCloser, error) { return obj.NewReader(ctx) },
+			})
+		}
+	}
+	return d, files, nil
+}
+
+// buildDataExport writes the archive of a claimed export to the exports bucket, marks the export
+// ready, and sends the user a link to download the archive.
+func buildDataExport(e *data.DataExport) error {
+	u, err := data.Conn.UserById(e.UserId)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, files, err := collectPersonalData(ctx, *u)
+	if err != nil {
+		return err
+	}
+
+	w := exportsBucket.Object(dataExportObject(e.Id)).NewWriter(ctx)
+	w.ContentType = util.ContentTypeZip
+	if err = export.Write(w, d, files); err != nil {
+		cancel() // Canceling the context aborts the upload.
+		w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	expires := time.Now().Add(dataExportTTL)
+	if err = data.Conn.DataExportReady(e.Id, w.Attrs().Size, expires); err != nil {
+		return err
+	}
+
+	sites, err := data.Conn.SitesByIDs([]int64{e.Site})
+	if err != nil {
+		return err
+	}
+	if len(sites) == 0 {
+		return nil // The site was deleted; the user may still list the exports on another site.
+	}
+	ready := *e
+	ready.Status = dataExportReady
+	ready.Expires, _ = timeproto.TimeProto(expires)
+	ed := emailData{Link: dataExportLink(nil, &sites[0], &ready), ValidFor: "24 hours"}
+	if err = sendUserEmail(nil, &sites[0], u, emailDataExport, ed); err != nil {
+		log.Err(nil, "could not send data export email", err)
+	}
+	return nil
+}
+
+// claimDataExport builds the pending export unless another server has claimed it. If building the
+// export fails, the export is marked failed.
+func claimDataExport(e data.DataExport) {
+	ok, err := data.Conn.DataExportClaim(e.Id, time.Now().Add(-dataExportLease))
+	if err != nil {
+		log.Err(nil, "could not claim data export", err)
+		return
+	}
+	if !ok {
+		return // Another server claimed it.
+	}
+	if err = buildDataExport(&e); err != nil {
+		log.Err(nil, fmt.Sprintf("could not build data export %s", e.Id), err)
+		if err = data.Conn.DataExportFail(e.Id, err.Error(), time.Now().Add(dataExportTTL)); err != nil {
+			log.Err(nil, "could not record failed data export", err)
+		}
+	}
+}
+
+// processDataExports builds in the background the pending exports that are not claimed by a server,
+// such as those of servers that stopped while building them, and deletes the expired exports along
+// with their archives.
+func processDataExports() {
+	if exportsBucket == nil {
+		return
+	}
+	for range time.Tick(time.Minute) {
+		es, err := data.Conn.DataExportsUnclaimed(time.Now().Add(-dataExportLease), 5)
+		if err != nil {
+			log.Err(nil, "could not get data exports", err)
+		}
+		for i := range es {
+			claimDataExport(es[i])
+		}
+
+		if es, err = data.Conn.DataExportsExpired(time.Now(), 100); err != nil {
+			log.Err(nil, "could not get expired data exports", err)
+			continue
+		}
+		for i := range es {
+			err = exportsBucket.Object(dataExportObject(es[i].Id)).Delete(context.Background())
+			if err != nil && err != storage.ErrObjectNotExist {
+				log.Err(nil, "could not delete data export archive", err)
+				continue
+			}
+			if err = data.Conn.DataExportDelete(es[i].Id); err != nil {
+				log.Err(nil, "could not delete data export", err)
+			}
+		}
+	}
+}
@@ -2,215 +2,218 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
-	"golang.org/x/oauth2"
-	"google.golang.org/api/dns/v1"
-
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
 	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/dns_provider"
+	"github.com/dchenk/mazewire/pkg/dns_provider/googlecloud"
+	"github.com/dchenk/mazewire/pkg/dns_provider/rfc2136"
 	"github.com/dchenk/mazewire/pkg/env"
 	"github.com/dchenk/mazewire/pkg/log"
 	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/users"
 	"github.com/dchenk/mazewire/pkg/util"
 )
 
-// list all zones for the Google project
-// API params: (none)
-func dnsListZones(r *http.Request, s *data.Site, u *data.User) *APIResponse {
-	if !users.IsSuper(u.Id) {
-		return errLowPrivileges()
-	}
-
-	ctx, service, err := setupDnsApi(r)
-	if err != nil {
-		return errProcessing()
-	}
-
-	body := make(RespDnsListZones, 0, 1)
-
-	call := service.ManagedZones.List(env.GCP_PROJECT)
-	if err := call.Pages(ctx, func(page *dns.ManagedZonesListResponse) error {
-		for _, zone := range page.ManagedZones {
-			body = append(body, &DnsManagedZone{
-				CreationTime:  zone.CreationTime,
-				DnsName:       zone.DnsName,
-				Id:            zone.Id,
-				Name:          zone.Name,
-				NameServerSet: zone.NameServerSet,
-				NameServers:   zone.NameServers,
-			})
+// dnsProvider manages the DNS zones and records of the domains served. It is set up at startup by
+// newDNSProvider according to the DNS_PROVIDER environment variable.
+var dnsProvider dns_provider.DNSProvider
+
+// newDNSProvider creates the DNSProvider configured by the environment variables.
+func newDNSProvider(ctx context.Context) (dns_provider.DNSProvider, error) {
+	vars := env.Vars()
+	switch name := vars[env.VarDnsProvider]; name {
+	case "", "google":
+		return googlecloud.New(ctx, vars[env.VarGcpProject], gcpDefaultCreds.TokenSource)
+	case "rfc2136":
+		var zones []string
+		for _, z := range strings.Split(vars[env.VarDnsZones], ",") {
+			if z = strings.TrimSpace(z); z != "" {
+				zones = append(zones, z)
+			}
 		}
-		return nil
-	}); err != nil {
-		log.Err(r, "could not list all DNS zones", err)
-		return errProcessing()
+		return rfc2136.New(rfc2136.Config{
+			Nameserver:    vars[env.VarDnsNameserver],
+			Zones:         zones,
+			TSIGKeyName:   vars[env.VarDnsTsigKey],
+			TSIGSecret:    vars[env.VarDnsTsigSecret],
+			TSIGAlgorithm: vars[env.VarDnsTsigAlgorithm],
+		})
+	default:
+		return nil, fmt.Errorf("unknown DNS provider %q", name)
 	}
-
-	return &APIResponse{Body: body}
 }
 
-type RespDnsListZones []*DnsManagedZone
+// dnsListZones: GET dns
+type dnsListZones struct{}
 
-// A mirror of the important fields of google.golang.org/api/dns.ManagedZone
-type DnsManagedZone struct {
-	CreationTime  string   `json:"created"`
-	DnsName       string   `json:"dns_name"`
-	Id            uint64   `json:"id"`
-	Name          string   `json:"name"`
-	NameServerSet string   `json:"ns_set"` // maybe this is not necessary?
-	NameServers   []string `json:"name_servers"`
+// authorized says if the user is a super user.
+func (dnsListZones) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
+	return users.IsSuper(u.Id)
 }
 
-// list all records for a zone
-func dnsListZoneRecords(r *http.Request, s *data.Site, u *data.User) *APIResponse {
-	var reqData ReqDnsListZoneRecords
-	err := json.Decode(r.Body, &reqData)
+// handle lists all zones managed by the DNS provider.
+func (dnsListZones) handle(r *http.Request, _ *data.Site, _ *data.User) *APIResponse {
+	zones, err := dnsProvider.Zones(r.Context())
 	if err != nil {
-		log.Err(r, errDecodingProto, err)
+		log.Err(r, "could not list all DNS zones", err)
 		return errProcessing()
 	}
-
-	// reqData.Site defaults to the current host
-	if reqData.Site == 0 {
-		reqData.Site = s.Id
+	resp := &apiv1.ListDnsZonesResponse{Zones: make([]*apiv1.DnsZone, len(zones))}
+	for i := range zones {
+		resp.Zones[i] = newDnsZone(&zones[i])
 	}
+	return &APIResponse{Body: resp}
+}
 
-	role := u.Role
-	if reqData.Site != s.Id {
-		role, err = users.SiteRole(u.Id, reqData.Site)
-		if err != nil {
-			log.Err(r, "could not get logged in site role for user", err)
-			return errProcessing()
+func newDnsZone(z *dns_provider.Zone) *apiv1.DnsZone {
+	return &apiv1.DnsZone{Name: z.Name, DnsName: z.DNSName, NameServers: z.NameServers}
+}
+
+// ReqDnsListZoneRecords: GET dns/records
+// The query has the provider-specific zone name, which is not necessarily the DNS name, and may have
+// the site ID, which defaults to the current host.
+type ReqDnsListZoneRecords struct {
+	Zone string
+	Site int64
+}
+
+func (req *ReqDnsListZoneRecords) decodeQuery(q url.Values) error {
+	req.Zone = q.Get("zone")
+	if site := q.Get("site"); site != "" {
+		var err error
+		if req.Site, err = strconv.ParseInt(site, 10, 64); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// authorized checks just if the user may manage the current site. The handler checks the
+// capability on other sites.
+func (*ReqDnsListZoneRecords) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapManageSite)
+}
 
-	if !roles.RoleAtLeast(role, roles.Role_ADMIN) {
-		if u.Id == 0 {
-			return errMustLogin()
+// handle lists all records for a zone.
+func (req *ReqDnsListZoneRecords) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	if req.Site != 0 && req.Site != s.Id {
+		if resp := userCan(r, u.Id, req.Site, roles.CapManageSite); resp != nil {
+			return resp
 		}
-		return errLowPrivileges()
 	}
 
-	if util.IsAnyStringBlank(reqData.Zone) {
+	if util.IsAnyStringBlank(req.Zone) {
 		return APIResponseErr("The 'zone' parameter is missing")
 	}
 
-	ctx, service, err := setupDnsApi(r)
+	sets, err := dnsProvider.Records(r.Context(), req.Zone)
 	if err != nil {
-		return errProcessing()
-	}
-
-	body := make(RespDnsListZoneRecords, 0, 1)
-
-	call := service.ResourceRecordSets.List(env.GCP_PROJECT, reqData.Zone)
-	if err := call.Pages(ctx, func(setsList *dns.ResourceRecordSetsListResponse) error {
-		for i := range setsList.Rrsets {
-			body = append(body, &DnsResourceRecordSet{
-				Name:    setsList.Rrsets[i].Name,
-				Rrdatas: setsList.Rrsets[i].Rrdatas,
-				Ttl:     setsList.Rrsets[i].Ttl,
-				Type:    setsList.Rrsets[i].Type,
-			})
+		if err == dns_provider.ErrZoneNotFound {
+			return APIResponseErr("The zone does not exist")
 		}
-		return nil
-	}); err != nil {
-		log.Err(r, "could not list all DNS records for zone "+reqData.Zone, err)
+		log.Err(r, "could not list all DNS records for zone "+req.Zone, err)
 		return errProcessing()
 	}
 
-	return &APIResponse{Body: body}
+	resp := &apiv1.ListDnsRecordSetsResponse{RecordSets: make([]*apiv1.DnsRecordSet, len(sets))}
+	for i, rs := range sets {
+		resp.RecordSets[i] = &apiv1.DnsRecordSet{Name: rs.Name, Type: rs.Type, Ttl: rs.TTL, Data: rs.Data}
+	}
+	return &APIResponse{Body: resp}
 }
 
-type ReqDnsListZoneRecords struct {
-	Zone string `json:"zone"` // the managed zone name or id -- not DNS name
-	Site int64  `json:"site"` // the site ID; defaults to ID of current host
+// ReqDnsSetRecords: POST dns/records
+type ReqDnsSetRecords struct {
+	Zone    string                 `json:"zone"`    // the provider-specific zone name
+	Records dns_provider.RecordSet `json:"records"` // replaces the record set with the same name and type
 }
 
-// A mirror of the important fields of google.golang.org/api/dns.ResourceRecordSet
-type DnsResourceRecordSet struct {
-	Name    string   `msgp:"name"`    // For example, www.example.com.
-	Rrdatas []string `msgp:"rrdatas"` // As defined in RFC 1035 (section 5) and RFC 1034 (section 3.6.1).
-	Ttl     int64    `msgp:"ttl"`     // Number of seconds that this ResourceRecordSet can be cached by resolvers.
-	Type    string   `msgp:"type"`    // The identifier of a supported record type, for example, A, AAAA, MX, TXT.
+// authorized says if the user is a super user.
+func (*ReqDnsSetRecords) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
+	return users.IsSuper(u.Id)
 }
 
-type RespDnsListZoneRecords []*DnsResourceRecordSet
-
-// create a zone
-func dnsCreateZone(r *http.Request, _ *data.Site, u *data.User) *APIResponse {
-	var reqData ReqDnsCreateZone
-	if err := msgp.Decode(r.Body, &reqData); err != nil {
-		log.Err(r, errDecodingProto, err)
-		return errProcessing()
+// handle creates or replaces a record set.
+func (req *ReqDnsSetRecords) handle(r *http.Request, _ *data.Site, _ *data.User) *APIResponse {
+	if util.IsAnyStringBlank(req.Zone, req.Records.Name, req.Records.Type) {
+		return APIResponseErr("The 'zone' parameter or the name or type of the records is missing")
 	}
-
-	// TODO: check if user has the right priveleges
-
-	reqData.Domain = strings.TrimSpace(reqData.Domain)
-	if reqData.Domain == "" {
-		return APIResponseErr("The 'domain' parameter is missing")
-	}
-
-	// TODO: validate the domain format by regexp
-
-	// the name of the zone in Google's database
-	name := strings.Replace(reqData.Domain, ".", "-", -1)
-
-	// Add a dot to the end of the domain (the way Google wants it).
-	if reqData.Domain[len(reqData.Domain)-1:] == "." {
-		name = name[:len(name)-2] // Remove the last dash that replaced the dot.
-	} else {
-		reqData.Domain += "."
+	if len(req.Records.Data) == 0 {
+		return APIResponseErr("The records have no data")
 	}
-
-	rb := &dns.ManagedZone{
-		DnsName:     reqData.Domain,
-		Name:        name,
-		Description: name,
+	if req.Records.TTL <= 0 {
+		req.Records.TTL = 3600
 	}
-
-	ctx, service, err := setupDnsApi(r)
-	if err != nil {
+	if err := dnsProvider.SetRecords(r.Context(), req.Zone, req.Records); err != nil {
+		if err == dns_provider.ErrZoneNotFound {
+			return APIResponseErr("The zone does not exist")
+		}
+		log.Err(r, "could not set DNS records in zone "+req.Zone, err)
 		return errProcessing()
 	}
+	return &APIResponse{}
+}
 
-	body := new(RespDnsCreateZone)
+// ReqDnsDeleteRecords: DELETE dns/records
+type ReqDnsDeleteRecords struct {
+	Zone string `json:"zone"` // the provider-specific zone name
+	Name string `json:"name"` // the fully qualified name of the records
+	Type string `json:"type"` // the record type
+}
 
-	managedZone, err := service.ManagedZones.Create(env.GCP_PROJECT, rb).Context(ctx).Do()
-	if err != nil {
-		log.Err(r, "could not set up DNS API service", err)
-		return errProcessing()
-	}
+// authorized says if the user is a super user.
+func (*ReqDnsDeleteRecords) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
+	return users.IsSuper(u.Id)
+}
 
-	if managedZone.DnsName != reqData.Domain {
-		log.Err(r, "could not zone for domain "+reqData.Domain, err)
+// handle deletes a record set.
+func (req *ReqDnsDeleteRecords) handle(r *http.Request, _ *data.Site, _ *data.User) *APIResponse {
+	if util.IsAnyStringBlank(req.Zone, req.Name, req.Type) {
+		return APIResponseErr("The 'zone', 'name', or 'type' parameter is missing")
+	}
+	if err := dnsProvider.DeleteRecords(r.Context(), req.Zone, req.Name, req.Type); err != nil {
+		if err == dns_provider.ErrZoneNotFound {
+			return APIResponseErr("The zone does not exist")
+		}
+		log.Err(r, "could not delete DNS records in zone "+req.Zone, err)
 		return errProcessing()
 	}
-
-	return &APIResponse{Body: body}
+	return &APIResponse{}
 }
 
+// ReqDnsCreateZone: POST dns
 type ReqDnsCreateZone struct {
 	Domain string `json:"domain"` // the domain name for the new zone
 }
 
-type RespDnsCreateZone struct {
-	// TODO
+// authorized says if the user is a super user.
+func (*ReqDnsCreateZone) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
+	return users.IsSuper(u.Id)
 }
 
-// set up context and service for Google DNS API
-func setupDnsApi(r *http.Request) (context.Context, *dns.Service, error) {
+// handle creates a zone.
+func (req *ReqDnsCreateZone) handle(r *http.Request, _ *data.Site, _ *data.User) *APIResponse {
+	req.Domain = strings.TrimSpace(req.Domain)
+	if req.Domain == "" {
+		return APIResponseErr("The 'domain' parameter is missing")
+	}
 
-	ctx := context.Background()
+	// TODO: validate the domain format by regexp
 
-	service, err := dns.New(oauth2.NewClient(ctx, gcpDefaultCreds.TokenSource))
+	zone, err := dnsProvider.CreateZone(r.Context(), dns_provider.Fqdn(req.Domain))
 	if err != nil {
-		log.Err(r, "could not set up DNS API service", err)
-		return ctx, nil, err
+		if err == dns_provider.ErrNotSupported {
+			return APIResponseErr("Zones cannot be created with the DNS provider in use")
+		}
+		log.Err(r, "could not create zone for domain "+req.Domain, err)
+		return errProcessing()
 	}
 
-	return ctx, service, nil
-
+	return &APIResponse{Body: newDnsZone(zone)}
 }
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/domain_verify"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/util"
+)
+
+// domainVerifier checks that the domains of new sites are controlled by the people who add them.
+var domainVerifier *domain_verify.Verifier
+
+// servePendingSite responds to requests to a domain that is not yet verified. Only the well-known
+// verification file is served, so that pointing the domain at this server suffices to verify it by
+// the HTTP method.
+func servePendingSite(w http.ResponseWriter, r *http.Request, domain string) {
+	if r.URL.Path != domain_verify.WellKnownPath {
+		http.Error(w, "This site is awaiting verification of its domain.", http.StatusServiceUnavailable)
+		return
+	}
+	dv, err := data.Conn.DomainVerification(domain)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Err(r, "could not get domain verification for "+domain, err)
+		}
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", util.ContentTypeTextPlainUTF8)
+	w.Write([]byte(dv.Token + "\n"))
+}
+
+// siteForAdmin retrieves the site with the ID given, or the current site if siteID is 0, after
+// checking that the user may manage the site.
+func siteForAdmin(r *http.Request, s *data.Site, u *data.User, siteID int64) (*data.Site, *APIResponse) {
+	if siteID == 0 || siteID == s.Id {
+		return s, nil
+	}
+	if resp := userCan(r, u.Id, siteID, roles.CapManageSite); resp != nil {
+		return nil, resp
+	}
+	sites, err := data.Conn.SitesByIDs([]int64{siteID})
+	if err != nil {
+		log.Err(r, "could not get site", err)
+		return nil, errProcessing()
+	}
+	if len(sites) == 0 {
+		return nil, APIResponseErr("The site does not exist.")
+	}
+	return &sites[0], nil
+}
+
+// siteDomainVerification retrieves the verification of a domain of the site with the ID given, or
+// of the current site if siteID is 0, after checking that the user is at least an admin on the site.
+// If domain is blank, the verification of the site's primary domain is retrieved.
+func siteDomainVerification(r *http.Request, s *data.Site, u *data.User, siteID int64, domain string) (*data.DomainVerification, *APIResponse) {
+	site, errResp := siteForAdmin(r, s, u, siteID)
+	if errResp != nil {
+		return nil, errResp
+	}
+	if domain == "" {
+		domain = site.Domain
+	}
+	dv, err := data.Conn.DomainVerification(strings.ToLower(domain))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, APIResponseErr("The domain has no verification.")
+		}
+		log.Err(r, "could not get domain verification for "+domain, err)
+		return nil, errProcessing()
+	}
+	if dv.Site != site.Id {
+		return nil, APIResponseErr("The domain does not belong to the site.")
+	}
+	return dv, nil
+}
+
+// SiteVerifyStatus: GET site/verify
+// The query may have the site ID, which defaults to the current host, and the domain, which is the
+// primary domain or an alias of the site and defaults to the primary domain.
+type SiteVerifyStatus struct {
+	Site   int64
+	Domain string
+}
+
+func (req *SiteVerifyStatus) decodeQuery(q url.Values) error {
+	req.Domain = q.Get("domain")
+	if site := q.Get("site"); site != "" {
+		var err error
+		if req.Site, err = strconv.ParseInt(site, 10, 64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorized checks just if the user may manage the current site. The capability on other
+// sites is checked by the handler.
+func (*SiteVerifyStatus) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapManageSite)
+}
+
+// handle responds with the status of the verification of the domain and how to complete it.
+func (req *SiteVerifyStatus) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	dv, errResp := siteDomainVerification(r, s, u, req.Site, req.Domain)
+	if errResp != nil {
+		return errResp
+	}
+	return &APIResponse{Body: newDomainVerificationStatus(dv)}
+}
+
+// newDomainVerificationStatus describes the status of the verification of the domain and how to
+// complete it.
+func newDomainVerificationStatus(dv *data.DomainVerification) *apiv1.DomainVerificationStatus {
+	if dv.Status == data.DomainVerified {
+		return &apiv1.DomainVerificationStatus{Domain: dv.Domain, Verified: true, Method: dv.Method}
+	}
+	return &apiv1.DomainVerificationStatus{
+		Domain:   dv.Domain,
+		TxtName:  domain_verify.RecordName(dv.Domain),
+		TxtValue: domain_verify.RecordValue(dv.Token),
+		HttpPath: domain_verify.WellKnownPath,
+		Token:    dv.Token,
+	}
+}
+
+// SiteVerify: POST site/verify
+type SiteVerify struct {
+	Site   int64  `json:"site"`   // the site ID; defaults to current host
+	Domain string `json:"domain"` // the primary domain or an alias of the site; defaults to the primary domain
+}
+
+// authorized checks just if the user may manage the current site. The capability on other
+// sites is checked by the handler.
+func (*SiteVerify) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapManageSite)
+}
+
+// handle checks whether the token is published for the domain and, if it is, marks the domain and
+// the site or alias as verified.
+func (req *SiteVerify) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	dv, errResp := siteDomainVerification(r, s, u, req.Site, req.Domain)
+	if errResp != nil {
+		return errResp
+	}
+	if dv.Status == data.DomainVerified {
+		return &APIResponse{Body: newDomainVerificationStatus(dv)}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+	method, err := domainVerifier.Verify(ctx, dv.Domain, dv.Token)
+	if err != nil {
+		if err == domain_verify.ErrNotVerified {
+			resp := &APIResponse{Body: newDomainVerificationStatus(dv)}
+			resp.warn("The verification token was not found. DNS changes may take some time to be visible.")
+			return resp
+		}
+		log.Err(r, "could not check domain verification for "+dv.Domain, err)
+		return errProcessing()
+	}
+
+	if err = data.Conn.DomainVerificationSetVerified(dv.Domain, method); err != nil {
+		log.Err(r, "could not save domain verification for "+dv.Domain, err)
+		return errProcessing()
+	}
+	dv.Status, dv.Method = data.DomainVerified, method
+	return &APIResponse{Body: newDomainVerificationStatus(dv)}
+}
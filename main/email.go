@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/email"
+	"github.com/dchenk/mazewire/pkg/email/sendgrid"
+	"github.com/dchenk/mazewire/pkg/env"
+	"github.com/dchenk/mazewire/pkg/log"
+)
+
+// emailSender sends the emails of all sites.
+var emailSender email.Sender
+
+// newEmailSender returns the Sender configured with the environment. In development mode without a
+// SendGrid API key, the emails are logged instead of being sent.
+func newEmailSender() (email.Sender, error) {
+	if key := env.Vars()[env.VarSendgridAPIKey]; key != "" {
+		return sendgrid.NewSender(key), nil
+	}
+	if env.Prod() {
+		return nil, fmt.Errorf("the %s variable is not set", env.VarSendgridAPIKey)
+	}
+	return logSender{}, nil
+}
+
+// logSender implements email.Sender by logging the emails.
+type logSender struct{}
+
+func (logSender) Send(m *email.Email) error {
+	d, _ := m.MarshalJSON()
+	log.Info(nil, "email not sent: "+string(d))
+	return nil
+}
+
+func (ls logSender) SendMulti(ms []email.Email) error {
+	for i := range ms {
+		ls.Send(&ms[i])
+	}
+	return nil
+}
+
+// The kinds of emails sent to users. A site may override the template of each kind with the options
+// "email-<kind>-subject" and "email-<kind>-body", written in the text/template syntax and executed with
+// an emailData.
+const (
+	emailPasswordReset = "password-reset"
+	emailVerify        = "verify-email"
+	emailInvitation    = "invitation"
+	emailDataExport    = "data-export"
+)
+
+// optEmailFrom is the option giving the sender of the emails of a site, such as
+// "Example <no-reply@example.com>". The default is given by the EMAIL_FROM variable.
+const optEmailFrom = "email-from"
+
+// defaultEmailTemplates gives the subject and the body of each kind of email.
+var defaultEmailTemplates = map[string][2]string{
+	emailPasswordReset: {
+		"Reset your password on {{.SiteName}}",
+		`Hi {{.Name}},
+
+Someone asked to reset the password of your account {{.Uname}} on {{.SiteName}}. To choose a new password,
+follow this link within {{.ValidFor}}:
+
+{{.Link}}
+
+If you did not ask to reset your password, you can ignore this email.
+`,
+	},
+	emailVerify: {
+		"Verify your email address on {{.SiteName}}",
+		`Hi {{.Name}},
+
+Welcome to {{.SiteName}}! To verify your email address and activate your account {{.Uname}}, follow this
+link within {{.ValidFor}}:
+
+{{.Link}}
+
+If you did not sign up, you can ignore this email.
+`,
+	},
+	emailInvitation: {
+		"You are invited to join {{.SiteName}}",
+		`Hi{{if .Name}} {{.Name}}{{end}},
+
+{{.Inviter}} invited you to join {{.SiteName}} as {{.Role}}. To accept the invitation, follow this link
+within {{.ValidFor}}:
+
+{{.Link}}
+
+If you do not want to join, you can ignore this email.
+`,
+	},
+	emailDataExport: {
+		"Your data from {{.SiteName}} is ready",
+		`Hi {{.Name}},
+
+The archive of the personal data stored about your account {{.Uname}} on {{.SiteName}} is ready. To
+download it, follow this link within {{.ValidFor}}:
+
+{{.Link}}
+
+If you did not ask for your data, please change your password.
+`,
+	},
+}
+
+// emailData is the data with which the email templates are executed.
+type emailData struct {
+	SiteName string // the name of the site
+	Domain   string // the domain of the site
+	Name     string // the first name of the user
+	Uname    string // the username of the user
+	Link     string // the link the user is asked to follow
+	ValidFor string // how long the link is valid, like "1 hour"
+	Inviter  string // the name of the user who sent an invitation
+	Role     string // the role given with an invitation, like "editor"
+}
+
+// siteEmailTemplate returns the template of the kind of email for the site along with the sender of
+// the site's emails. Invalid options are logged, and the defaults are used instead.
+func siteEmailTemplate(r *http.Request, s *data.Site, kind string) (*email.Template, email.Party, error) {
+	optSubject, optBody := "email-"+kind+"-subject", "email-"+kind+"-body"
+	opts, err := data.Conn.OptionsKeyInMappedStr(s.Id, []string{optEmailFrom, optSubject, optBody})
+	if err != nil {
+		return nil, email.Party{}, err
+	}
+
+	from := email.Party{Name: s.Name, Address: "no-reply@" + s.Domain}
+	if v := env.Vars()[env.VarEmailFrom]; v != "" {
+		if from, err = email.ParseParty(v); err != nil {
+			return nil, email.Party{}, fmt.Errorf("invalid %s variable: %v", env.VarEmailFrom, err)
+		}
+	}
+	if v := opts[optEmailFrom]; v != "" {
+		if p, err := email.ParseParty(v); err == nil {
+			from = p
+		} else {
+			log.Err(r, fmt.Sprintf("invalid value of option %q: %q", optEmailFrom, v), err)
+		}
+	}
+
+	def, ok := defaultEmailTemplates[kind]
+	if !ok {
+		return nil, email.Party{}, errors.New("unknown kind of email: " + kind)
+	}
+	subject, body := def[0], def[1]
+	if opts[optSubject] != "" || opts[optBody] != "" {
+		if opts[optSubject] != "" {
+			subject = opts[optSubject]
+		}
+		if opts[optBody] != "" {
+			body = opts[optBody]
+		}
+		t, err := email.NewTemplate(kind, subject, body)
+		if err == nil {
+			return t, from, nil
+		}
+		log.Err(r, fmt.Sprintf("invalid template of email %q on site %d", kind, s.Id), err)
+		subject, body = def[0], def[1]
+	}
+	t, err := email.NewTemplate(kind, subject, body)
+	return t, from, err
+}
+
+// sendUserEmail sends an email of the kind given to the user u from the site s. The email is sent
+// asynchronously; an error means that it could not be created or scheduled.
+func sendUserEmail(r *http.Request, s *data.Site, u *data.User, kind string, d emailData) error {
+	t, from, err := siteEmailTemplate(r, s, kind)
+	if err != nil {
+		return err
+	}
+	d.SiteName, d.Domain = s.Name, s.Domain
+	d.Name, d.Uname = u.Fname, u.Uname
+	if d.SiteName == "" {
+		d.SiteName = s.Domain
+	}
+	to := email.Party{Name: strings.TrimSpace(u.Fname + " " + u.Lname), Address: u.Email}
+	m, err := t.Execute(to, d)
+	if err != nil {
+		// A site's template may refer to data that does not exist.
+		log.Err(r, fmt.Sprintf("could not execute template of email %q on site %d", kind, s.Id), err)
+		def := defaultEmailTemplates[kind]
+		if t, err = email.NewTemplate(kind, def[0], def[1]); err != nil {
+			return err
+		}
+		if m, err = t.Execute(to, d); err != nil {
+			return err
+		}
+	}
+	m.From = from
+	return emailSender.Send(m)
+}
+
+// userTokenLink returns the link to the page of the site at which a token sent to a user is used. The
+// slug of the page is given by the option named, if it is set, or else it is defSlug.
+func userTokenLink(r *http.Request, s *data.Site, opt, defSlug, token string) string {
+	slug := defSlug
+	if v, err := data.Conn.OptionsKeyInMappedStr(s.Id, []string{opt}); err != nil {
+		log.Err(r, "could not get option "+opt, err)
+	} else if v[opt] != "" {
+		slug = strings.Trim(v[opt], "/")
+	}
+	return siteRoot(r, s) + "/" + slug + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+	"github.com/dchenk/mazewire/pkg/util"
+)
+
+// emailUnverifiedKey is the user meta key set for a user who has registered but not yet verified their
+// email address. Such a user cannot log in.
+const emailUnverifiedKey = "email-unverified"
+
+const (
+	verifyEmailTTL      = time.Hour * 48
+	verifyEmailValidFor = "2 days" // verifyEmailTTL, as written in the emails
+)
+
+// optVerifyEmailPage is the option giving the slug of the page of a site at which users verify their
+// email address with the token sent to them. The page must make the request to confirm the verification.
+const optVerifyEmailPage = "verify-email-page-slug"
+
+const errEmailUnverified = "You must verify your email address before logging in. Follow the link in the email " +
+	"we sent you, or ask for a new one."
+
+// sendVerificationEmail sends the user a link with which to verify their email address.
+func sendVerificationEmail(r *http.Request, s *data.Site, u *data.User) error {
+	token, err := createUserToken(tokenVerifyEmail, u.Id, s.Id, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	return sendUserEmail(r, s, u, emailVerify, emailData{
+		Link:     userTokenLink(r, s, optVerifyEmailPage, "verify-email", token),
+		ValidFor: verifyEmailValidFor,
+	})
+}
+
+// emailVerified says if the user has verified their email address. Users who registered before email
+// addresses were verified are treated as verified.
+func emailVerified(userID int64) (bool, error) {
+	v, err := data.Conn.UserMetaV(userID, emailUnverifiedKey)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	return len(v) == 0, err
+}
+
+// ReqEmailVerificationResend: POST email-verification
+// A user who has not verified their email address asks for a new verification email. Like a password
+// reset request, the response is the same whether or not an email is sent.
+type ReqEmailVerificationResend struct {
+	Email string `json:"email"`
+}
+
+// authorized returns true; users who have not verified their email address cannot log in.
+func (*ReqEmailVerificationResend) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+// rateLimit limits how often each IP address may ask for verification emails.
+func (*ReqEmailVerificationResend) rateLimit() rate_limit.Bucket {
+	return passwordResetRateLimit
+}
+
+func (req *ReqEmailVerificationResend) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	addr := strings.TrimSpace(req.Email)
+	if !util.ValidEmail(addr) {
+		return APIResponseErr("The email you entered seems to be invalid.")
+	}
+	ok := &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+
+	user, err := data.Conn.UserByEmail(addr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ok
+		}
+		log.Err(r, "could not get user by email for email verification", err)
+		return errProcessing()
+	}
+	verified, err := emailVerified(user.Id)
+	if err != nil {
+		log.Err(r, "could not check if email address is verified", err)
+		return errProcessing()
+	}
+	if verified {
+		return ok
+	}
+	if err = sendVerificationEmail(r, s, user); err != nil {
+		log.Err(r, "could not send verification email", err)
+		return errProcessing()
+	}
+	return ok
+}
+
+// ReqEmailVerificationConfirm: PUT email-verification
+// The user verifies their email address with the token sent by email, after which they may log in.
+type ReqEmailVerificationConfirm struct {
+	Token string `json:"token"`
+}
+
+// authorized returns true; the token identifies the user.
+func (*ReqEmailVerificationConfirm) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+// rateLimit limits how often each IP address may try tokens.
+func (*ReqEmailVerificationConfirm) rateLimit() rate_limit.Bucket {
+	return loginRateLimit
+}
+
+func (req *ReqEmailVerificationConfirm) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	if strings.TrimSpace(req.Token) == "" {
+		return APIResponseErr(errIncompleteForm)
+	}
+	userID, siteID, ok, err := consumeUserToken(tokenVerifyEmail, req.Token)
+	if err != nil {
+		log.Err(r, "could not check email verification token", err)
+		return errProcessing()
+	}
+	if !ok || siteID != s.Id {
+		return APIResponseErr(errUserTokenInvalid)
+	}
+	if _, err = data.Conn.UserMetaDelete(userID, emailUnverifiedKey); err != nil {
+		log.Err(r, "could not mark email address verified", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+}
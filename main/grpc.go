@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/cors"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/route"
+)
+
+// grpcServer serves the public gRPC API, mazewire.v1. Requests are routed to it by handler on the
+// HTTPS listener, which negotiates HTTP/2 with ALPN, after the site and the user are resolved.
+var grpcServer = func() *grpc.Server {
+	s := grpc.NewServer()
+	apiv1.RegisterMazewireServer(s, grpcAPI{})
+	return s
+}()
+
+// grpcWebServer serves the gRPC API to browsers with the gRPC-Web protocol. The origins of cross-origin
+// calls are checked against the CORS policy of the site by serveGRPC, so the server allows every origin
+// that gets to it.
+var grpcWebServer = grpcweb.WrapServer(grpcServer, grpcweb.WithOriginFunc(func(string) bool { return true }))
+
+// isGRPCRequest says if the request is a gRPC or gRPC-Web call (or a CORS preflight of a gRPC-Web call).
+func isGRPCRequest(r *http.Request) bool {
+	if grpcWebServer.IsGrpcWebRequest(r) || grpcWebServer.IsAcceptableGrpcCorsRequest(r) {
+		return true
+	}
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// serveGRPC serves a gRPC or gRPC-Web call on the site s by the user u. The user is identified like with
+// the REST API, so calls are authenticated with a login token given in the "authorization" metadata.
+func serveGRPC(w http.ResponseWriter, r *http.Request, s *data.Site, u *data.User) {
+	if origin := crossOrigin(r); origin != "" && !siteCORSPolicy(r, s).AllowsOrigin(origin) {
+		if cors.IsPreflight(r) {
+			w.Header().Add("Vary", "Origin")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		// Without the Origin header, the response has no CORS headers, so the browser does not let the
+		// caller read it.
+		r.Header.Del("Origin")
+	}
+	c := &grpcCaller{r: r, s: s, u: u}
+	r = r.WithContext(context.WithValue(r.Context(), grpcCallerKey{}, c))
+	if grpcWebServer.IsGrpcWebRequest(r) || grpcWebServer.IsAcceptableGrpcCorsRequest(r) {
+		grpcWebServer.ServeHTTP(w, r)
+		return
+	}
+	grpcServer.ServeHTTP(w, r)
+}
+
+// A grpcCaller is the HTTP request of a gRPC call along with the site and the user resolved for it.
+type grpcCaller struct {
+	r *http.Request
+	s *data.Site
+	u *data.User
+}
+
+// grpcCallerKey is the context key of the *grpcCaller of a call.
+type grpcCallerKey struct{}
+
+// callAPI handles a gRPC call with the handler of the REST API route with the method and path pattern
+// given, so that the call is authorized and handled exactly like the equivalent REST request. The
+// params are the path parameters that the REST request would have. Warnings are sent in the
+// "mazewire-warnings" header metadata.
+func callAPI(ctx context.Context, method, pattern string, params route.Params, h APIHandler) (*APIResponse, error) {
+	c, ok := ctx.Value(grpcCallerKey{}).(*grpcCaller)
+	if !ok {
+		return nil, status.Error(codes.Internal, errProcessingMsg)
+	}
+	rt := apiRouteFor(method, pattern)
+	if rt == nil {
+		log.Err(c.r, "no API route for gRPC call to "+method+" "+pattern, errNoAPIRoute)
+		return nil, status.Error(codes.Internal, errProcessingMsg)
+	}
+	r := route.WithParams(c.r, params)
+
+	if !allowRequest(r, rt, h) {
+		return nil, status.Error(codes.ResourceExhausted, "Too Many Requests")
+	}
+
+	// Browsers can make gRPC-Web calls only after a CORS preflight, but a call authenticated with the
+	// login cookie must still carry the CSRF token (in the "x-csrf-token" metadata) like a REST request.
+	if !checkCSRF(r, rt.method, c.u) {
+		return nil, status.Error(codes.PermissionDenied, errCSRFMsg)
+	}
+
+	if !tokenAllows(r, rt) {
+		return nil, status.Error(codes.PermissionDenied, errTokenScopeMsg)
+	}
+
+	if !rt.auth.allows(r, c.s, c.u) || !h.authorized(r, c.s, c.u) {
+		if c.u.Id == 0 {
+			return nil, status.Error(codes.Unauthenticated, errMustLoginMsg)
+		}
+		return nil, status.Error(codes.PermissionDenied, errLowPrivsMsg)
+	}
+
+	resp := h.handle(r, c.s, c.u)
+	if resp.err != "" {
+		return nil, status.Error(grpcCode(resp), resp.err)
+	}
+	if len(resp.Warnings) > 0 {
+		if err := grpc.SetHeader(ctx, metadata.Pairs(append([]string{"mazewire-warnings"}, resp.Warnings...)...)); err != nil {
+			log.Err(c.r, "could not set gRPC warnings header", err)
+		}
+	}
+	return resp, nil
+}
+
+// grpcCode returns the gRPC status code corresponding to the error of a failed APIResponse.
+func grpcCode(resp *APIResponse) codes.Code {
+	switch {
+	case resp.err == errProcessingMsg:
+		return codes.Internal
+	case resp.err == errMustLoginMsg:
+		return codes.Unauthenticated
+	case resp.err == errLowPrivsMsg:
+		return codes.PermissionDenied
+	case resp.Status == http.StatusNotFound:
+		return codes.NotFound
+	case resp.Status == http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.InvalidArgument
+	}
+}
+
+// grpcAPI implements apiv1.MazewireServer with the handlers of the REST API.
+type grpcAPI struct{}
+
+// Login checks the credentials of a user and responds with a login token for the site, which is bound
+// to the user agent of the call just like the login cookie. If the user must give a second factor, the
+// response gives the challenge to answer with VerifyTwoFactor instead.
+func (grpcAPI) Login(ctx context.Context, req *apiv1.LoginRequest) (*apiv1.LoginResponse, error) {
+	resp, err := callAPI(ctx, http.MethodPost, "/auth", nil, &AuthLogin{User: req.User, Pass: req.Pass})
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := resp.Body.(*apiv1.TwoFactorChallenge); ok {
+		return &apiv1.LoginResponse{TwoFactor: c}, nil
+	}
+	return grpcLogin(ctx)
+}
+
+// VerifyTwoFactor completes a login awaiting a second factor.
+func (grpcAPI) VerifyTwoFactor(ctx context.Context, req *apiv1.VerifyTwoFactorRequest) (*apiv1.LoginResponse, error) {
+	resp, err := callAPI(ctx, http.MethodPost, "/auth/2fa", nil, &AuthTwoFactor{Challenge: req.Challenge, Code: req.Code})
+	if err != nil {
+		return nil, err
+	}
+	lr, err := grpcLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lr.RecoveryCodes = resp.Body.(*apiv1.RecoveryCodes).Codes
+	return lr, nil
+}
+
+// grpcLogin starts a session for the user of the call, whom a successful login filled in, and returns
+// the login token.
+func grpcLogin(ctx context.Context) (*apiv1.LoginResponse, error) {
+	c := ctx.Value(grpcCallerKey{}).(*grpcCaller)
+	token, expires, err := startSession(c.r, c.s, c.u)
+	if err != nil {
+		log.Err(c.r, "could not start session", err)
+		return nil, status.Error(codes.Internal, errProcessingMsg)
+	}
+	return &apiv1.LoginResponse{Token: token, Expires: expires.Unix()}, nil
+}
+
+func (grpcAPI) GetSite(ctx context.Context, req *apiv1.GetSiteRequest) (*data.Site, error) {
+	resp, err := callAPI(ctx, http.MethodGet, "/sites/{id}", idParam(req.Id), new(ReqSiteGet))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.(*data.Site), nil
+}
+
+func (grpcAPI) ListSites(ctx context.Context, _ *apiv1.ListSitesRequest) (*apiv1.ListSitesResponse, error) {
+	resp, err := callAPI(ctx, http.MethodGet, "/sites", nil, new(ReqSiteList))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.(*apiv1.ListSitesResponse), nil
+}
+
+func (grpcAPI) GetContent(ctx context.Context, req *apiv1.GetContentRequest) (*data.Content, error) {
+	resp, err := callAPI(ctx, http.MethodGet, "/content/{id}", idParam(req.Id), new(ReqContentGet))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.(*data.Content), nil
+}
+
+func (grpcAPI) ListContent(ctx context.Context, req *apiv1.ListContentRequest) (*apiv1.ListContentResponse, error) {
+	h := &ReqContentList{Type: req.Type, Statuses: req.Statuses, Parents: req.Parents, Offset: req.Offset}
+	resp, err := callAPI(ctx, http.MethodGet, "/content", nil, h)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.(*apiv1.ListContentResponse), nil
+}
+
+func (grpcAPI) GetUser(ctx context.Context, req *apiv1.GetUserRequest) (*data.User, error) {
+	params := route.Params{"id": "self"}
+	if req.Id != 0 {
+		params = idParam(req.Id)
+	}
+	resp, err := callAPI(ctx, http.MethodGet, "/users/{id}", params, new(ReqUserGet))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.(*data.User), nil
+}
+
+func (grpcAPI) GetOptions(ctx context.Context, req *apiv1.GetOptionsRequest) (*apiv1.GetOptionsResponse, error) {
+	resp, err := callAPI(ctx, http.MethodGet, "/options", nil, &ReqOptionsGet{Keys: req.Keys})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.(*apiv1.GetOptionsResponse), nil
+}
+
+func (grpcAPI) SetOptions(ctx context.Context, req *apiv1.SetOptionsRequest) (*apiv1.SetOptionsResponse, error) {
+	resp, err := callAPI(ctx, http.MethodPatch, "/options", nil, &ReqOptionsSet{Options: req.Options})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.(*apiv1.SetOptionsResponse), nil
+}
+
+func (grpcAPI) GetMedia(ctx context.Context, req *apiv1.GetMediaRequest) (*data.Media, error) {
+	resp, err := callAPI(ctx, http.MethodGet, "/media/{id}", route.Params{"id": req.Id}, new(ReqMediaGet))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.(*data.Media), nil
+}
+
+func (grpcAPI) ListMedia(ctx context.Context, req *apiv1.ListMediaRequest) (*apiv1.ListMediaResponse, error) {
+	resp, err := callAPI(ctx, http.MethodGet, "/media", nil, &ReqMediaList{Offset: req.Offset})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.(*apiv1.ListMediaResponse), nil
+}
+
+func (grpcAPI) ListSessions(ctx context.Context, _ *apiv1.ListSessionsRequest) (*apiv1.ListSessionsResponse, error) {
+	resp, err := callAPI(ctx, http.MethodGet, "/sessions", nil, new(ReqSessionList))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.(*apiv1.ListSessionsResponse), nil
+}
+
+func (grpcAPI) RevokeSession(ctx context.Context, req *apiv1.RevokeSessionRequest) (*apiv1.RevokeSessionResponse, error) {
+	if _, err := callAPI(ctx, http.MethodDelete, "/sessions/{id}", route.Params{"id": req.Id}, new(ReqSessionRevoke)); err != nil {
+		return nil, err
+	}
+	return new(apiv1.RevokeSessionResponse), nil
+}
+
+// idParam returns the path parameters with the ID given.
+func idParam(id int64) route.Params {
+	return route.Params{"id": strconv.FormatInt(id, 10)}
+}
+
+var errNoAPIRoute = errors.New("no API route")
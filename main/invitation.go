@@ -0,0 +1,390 @@
+package main
+
+import (
+	"crypto/hmac"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/route"
+	timeproto "github.com/dchenk/mazewire/pkg/types/time"
+	"github.com/dchenk/mazewire/pkg/util"
+)
+
+// Members who may manage users invite people to join a site by email with a role whose capabilities
+// they have. The email has a link with a token, of which only the hash is stored, that looks like
+// "<id>.<secret>". The invited person accepts the invitation with the token: if the email address
+// belongs to a user, the user is given the role; otherwise, a user is created with the account details
+// given when accepting. Because the token was sent to the email address, the address of the user is
+// verified.
+const (
+	invitationTTL      = time.Hour * 24 * 7
+	invitationValidFor = "7 days" // invitationTTL, as written in the emails
+)
+
+// The statuses of invitations. The status invitationExpired is not stored but reported for pending
+// invitations that have expired.
+const (
+	invitationPending  = "pending"
+	invitationAccepted = "accepted"
+	invitationExpired  = "expired"
+)
+
+// optInvitationPage is the option giving the slug of the page of a site at which invited people accept
+// their invitations with the token sent to them. The page must make the request to accept.
+const optInvitationPage = "invitation-page-slug"
+
+// invitationRateLimit limits how often each IP address may send invitations.
+var invitationRateLimit = rate_limit.PerMinute(5, 20)
+
+// errInvitationNeedsAccount is the error message for accepting an invitation without the account
+// details needed to create a user, for which the page of the invitation may show a form.
+const errInvitationNeedsAccount = "Choose a username and a password, and enter your name, to create your account."
+
+// sendInvitation creates a new token for the invitation and sends it by email on behalf of the user u.
+// Returned is the hash of the token and the time it expires, to be stored with the invitation.
+func sendInvitation(r *http.Request, s *data.Site, u *data.User, inv *data.Invitation) ([]byte, time.Time, error) {
+	secret, err := newTokenSecret()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	roleName, err := roles.SiteRoleName(inv.Site, roles.Role(inv.Role))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	inviter := strings.TrimSpace(u.Fname + " " + u.Lname)
+	if inviter == "" {
+		inviter = u.Uname
+	}
+	err = sendUserEmail(r, s, &data.User{Email: inv.Email}, emailInvitation, emailData{
+		Link:     userTokenLink(r, s, optInvitationPage, "invitation", inv.Id+"."+secret),
+		ValidFor: invitationValidFor,
+		Inviter:  inviter,
+		Role:     strings.ToLower(roleName),
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return hashTokenSecret(secret), time.Now().Add(invitationTTL), nil
+}
+
+// invitationStatus returns the status of the invitation as reported to clients.
+func invitationStatus(inv *data.Invitation, now time.Time) string {
+	if inv.Status != invitationPending {
+		return inv.Status
+	}
+	if expires, err := inv.Expires.ToTime(); err != nil || !now.Before(expires) {
+		return invitationExpired
+	}
+	return invitationPending
+}
+
+// siteInvitation returns the invitation to the site with the ID in the path of the request, or an
+// error response if there is none.
+func siteInvitation(r *http.Request, s *data.Site) (*data.Invitation, *APIResponse) {
+	const notFound = "The invitation was not found."
+	inv, err := data.Conn.Invitation(route.Param(r, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errNotFound(notFound)
+		}
+		log.Err(r, "could not get invitation", err)
+		return nil, errProcessing()
+	}
+	if inv.Site != s.Id {
+		return nil, errNotFound(notFound)
+	}
+	return inv, nil
+}
+
+// inviterCanGrant checks that the user who sent the invitation is still a member of the site who may
+// manage its users and give the role of the invitation, so that the invitations sent by a user who was
+// since demoted or removed cannot be accepted. If the inviter may not, the error response to give is
+// returned.
+func inviterCanGrant(r *http.Request, s *data.Site, inv *data.Invitation, role roles.Role) *APIResponse {
+	inviterRole, err := siteRole(r, inv.InvitedBy, s.Id)
+	if err != nil {
+		log.Err(r, "could not get site role of inviter", err)
+		return errProcessing()
+	}
+	if inviterRole == roles.Role_NONE || !roleCan(r, s.Id, inviterRole, roles.CapManageUsers) {
+		return APIResponseErr(errUserTokenInvalid)
+	}
+	ok, err := roles.CanGrant(s.Id, inviterRole, role)
+	if err != nil {
+		log.Err(r, "could not get capabilities of role", err)
+		return errProcessing()
+	}
+	if !ok {
+		return APIResponseErr(errUserTokenInvalid)
+	}
+	return nil
+}
+
+// ReqInvitationList: GET site/invitations
+// The invitations to the site are listed.
+type ReqInvitationList struct{}
+
+// authorized returns true; the route requires the user to be able to manage users.
+func (*ReqInvitationList) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqInvitationList) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	invs, err := data.Conn.InvitationsBySite(s.Id)
+	if err != nil {
+		log.Err(r, "could not list invitations", err)
+		return errProcessing()
+	}
+	now := time.Now()
+	resp := &apiv1.ListInvitationsResponse{Invitations: make([]*data.Invitation, len(invs))}
+	for i := range invs {
+		invs[i].Hash = nil
+		invs[i].Status = invitationStatus(&invs[i], now)
+		resp.Invitations[i] = &invs[i]
+	}
+	return &APIResponse{Body: resp}
+}
+
+// ReqInvitationCreate: POST site/invitations
+// An email address is invited to join the site with a role no higher than the role of the user. Any
+// pending invitation of the address to the site is replaced.
+type ReqInvitationCreate struct {
+	Email string `json:"email"`
+	Role  string `json:"role"` // such as "EDITOR"
+}
+
+// authorized returns true; the route requires the user to be able to manage users.
+func (*ReqInvitationCreate) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+// rateLimit limits how often each IP address may send invitations.
+func (*ReqInvitationCreate) rateLimit() rate_limit.Bucket {
+	return invitationRateLimit
+}
+
+func (req *ReqInvitationCreate) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	addr := strings.TrimSpace(req.Email)
+	if !util.ValidEmail(addr) {
+		return APIResponseErr("The email you entered seems to be invalid.")
+	}
+	role, errResp := parseSiteRole(r, s, req.Role)
+	if errResp != nil {
+		return errResp
+	}
+	if errResp = canGrant(r, s, u, role); errResp != nil {
+		return errResp
+	}
+
+	member, err := data.Conn.UserSiteInfoByEmail(s, addr)
+	if err != nil && err != sql.ErrNoRows {
+		log.Err(r, "could not get user by email for invitation", err)
+		return errProcessing()
+	}
+	if err == nil && member.Role != roles.Role_NONE {
+		return APIResponseErr("The user with this email address is already a member of the site.")
+	}
+
+	if _, err = data.Conn.InvitationsDeletePending(s.Id, addr); err != nil {
+		log.Err(r, "could not delete pending invitations", err)
+		return errProcessing()
+	}
+	id, err := newSessionID()
+	if err != nil {
+		log.Err(r, "could not generate invitation ID", err)
+		return errProcessing()
+	}
+	inv := &data.Invitation{Id: id, Site: s.Id, Email: addr, Role: int64(role), InvitedBy: u.Id, Status: invitationPending}
+	hash, expiresAt, err := sendInvitation(r, s, u, inv)
+	if err != nil {
+		log.Err(r, "could not send invitation", err)
+		return errProcessing()
+	}
+	if inv.Expires, err = timeproto.TimeProto(expiresAt); err != nil {
+		log.Err(r, "could not convert invitation expiration time", err)
+		return errProcessing()
+	}
+	inv.Hash = hash
+	if err = data.Conn.InvitationInsert(inv); err != nil {
+		log.Err(r, "could not insert invitation", err)
+		return errProcessing()
+	}
+	inv.Hash = nil
+	return &APIResponse{Body: inv}
+}
+
+// ReqInvitationResend: POST site/invitations/{id}/resend
+// A pending invitation is sent again with a new token, which is valid for the full time again. The
+// token sent before is no longer valid. The user must be able to give the role of the invitation.
+type ReqInvitationResend struct{}
+
+// authorized returns true; the route requires the user to be able to manage users.
+func (*ReqInvitationResend) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+// rateLimit limits how often each IP address may send invitations.
+func (*ReqInvitationResend) rateLimit() rate_limit.Bucket {
+	return invitationRateLimit
+}
+
+func (*ReqInvitationResend) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	inv, errResp := siteInvitation(r, s)
+	if errResp != nil {
+		return errResp
+	}
+	if inv.Status != invitationPending {
+		return APIResponseErr("The invitation was already accepted.")
+	}
+	if errResp = canGrant(r, s, u, roles.Role(inv.Role)); errResp != nil {
+		return errResp
+	}
+	hash, expires, err := sendInvitation(r, s, u, inv)
+	if err != nil {
+		log.Err(r, "could not send invitation", err)
+		return errProcessing()
+	}
+	n, err := data.Conn.InvitationRenew(inv.Id, hash, expires)
+	if err != nil {
+		log.Err(r, "could not renew invitation", err)
+		return errProcessing()
+	}
+	if n == 0 {
+		return APIResponseErr("The invitation was already accepted.")
+	}
+	return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+}
+
+// ReqInvitationRevoke: DELETE site/invitations/{id}
+// An invitation is deleted; if it is pending, it can no longer be accepted.
+type ReqInvitationRevoke struct{}
+
+// authorized returns true; the route requires the user to be able to manage users.
+func (*ReqInvitationRevoke) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqInvitationRevoke) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	inv, errResp := siteInvitation(r, s)
+	if errResp != nil {
+		return errResp
+	}
+	if err := data.Conn.InvitationDelete(inv.Id); err != nil {
+		log.Err(r, "could not delete invitation", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+}
+
+// ReqInvitationAccept: PUT invitations
+// An invitation to the site is accepted with the token sent in it. If the invited email address does
+// not belong to a user, a user is created with the account details given. The user is given the role
+// of the invitation unless the user already has all of its capabilities on the site. The response gives the
+// username of the user, who may then log in. The invitation cannot be accepted once the user who sent it
+// may no longer give its role (see inviterCanGrant).
+type ReqInvitationAccept struct {
+	Token string `json:"token"`
+
+	// The account details of the user to be created, if there is no user with the email address.
+	Uname string `json:"uname"`
+	Pass  string `json:"pass"`
+	Fname string `json:"fname"`
+	Lname string `json:"lname"`
+}
+
+// authorized returns true; the token identifies the invitation.
+func (*ReqInvitationAccept) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+// rateLimit limits how often each IP address may try tokens.
+func (*ReqInvitationAccept) rateLimit() rate_limit.Bucket {
+	return loginRateLimit
+}
+
+func (req *ReqInvitationAccept) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	parts := strings.Split(req.Token, ".")
+	if len(parts) != 2 {
+		return APIResponseErr(errUserTokenInvalid)
+	}
+	inv, err := data.Conn.Invitation(parts[0])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return APIResponseErr(errUserTokenInvalid)
+		}
+		log.Err(r, "could not get invitation", err)
+		return errProcessing()
+	}
+	if inv.Site != s.Id || !hmac.Equal(hashTokenSecret(parts[1]), inv.Hash) ||
+		invitationStatus(inv, time.Now()) != invitationPending {
+		return APIResponseErr(errUserTokenInvalid)
+	}
+	role, ok := roles.ValidSiteRoleInt64(inv.Role)
+	if !ok {
+		log.Err(r, "invalid role of invitation", fmt.Errorf("role %d of invitation %s", inv.Role, inv.Id))
+		return errProcessing()
+	}
+	if errResp := inviterCanGrant(r, s, inv, role); errResp != nil {
+		return errResp
+	}
+
+	user, err := data.Conn.UserSiteInfoByEmail(s, inv.Email)
+	switch err {
+	case nil:
+		// A user whose account is closed is not given the role back.
+		if pending, err := deletionPending(user.Id); err != nil {
+			log.Err(r, "could not check for pending user deletion", err)
+			return errProcessing()
+		} else if pending {
+			return APIResponseErr(errUserTokenInvalid)
+		}
+	case sql.ErrNoRows:
+		if util.IsAnyStringBlank(req.Uname, req.Pass, req.Fname, req.Lname) {
+			return APIResponseErr(errInvitationNeedsAccount)
+		}
+		newID, errResp := insertUser(r, req.Uname, inv.Email, req.Pass, req.Fname, req.Lname)
+		if errResp != nil {
+			return errResp
+		}
+		user = &data.User{Id: newID, Uname: strings.ToLower(req.Uname), Email: inv.Email}
+	default:
+		log.Err(r, "could not get user by email for invitation", err)
+		return errProcessing()
+	}
+
+	// Accepting the invitation first makes sure that it is used only once.
+	n, err := data.Conn.InvitationAccept(inv.Id, user.Id)
+	if err != nil {
+		log.Err(r, "could not accept invitation", err)
+		return errProcessing()
+	}
+	if n == 0 {
+		return APIResponseErr(errUserTokenInvalid)
+	}
+	// A member who already has all of the capabilities of the role keeps their own role.
+	hasRole, err := roles.CanGrant(s.Id, user.Role, role)
+	if err != nil {
+		log.Err(r, "could not get capabilities of role", err)
+		return errProcessing()
+	}
+	if !hasRole {
+		if err = roles.SetSiteRole(user.Id, s.Id, inv.Role); err != nil {
+			log.Err(r, "could not set site role of invited user", err)
+			return errProcessing()
+		}
+	}
+	if _, err = data.Conn.UserMetaDelete(user.Id, emailUnverifiedKey); err != nil {
+		log.Err(r, "could not mark email address of invited user verified", err)
+	}
+	return &APIResponse{Body: &wrappers.StringValue{Value: user.Uname}}
+}
@@ -14,12 +14,16 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-plugin"
+	"golang.org/x/net/http2"
 	"golang.org/x/oauth2/google"
 
 	"github.com/dchenk/mazewire/pkg/data"
 	"github.com/dchenk/mazewire/pkg/default_cert"
+	"github.com/dchenk/mazewire/pkg/domain_verify"
 	"github.com/dchenk/mazewire/pkg/env"
 	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/password"
+	"github.com/dchenk/mazewire/pkg/roles"
 	"github.com/dchenk/mazewire/pkg/util"
 )
 
@@ -39,12 +43,49 @@ func main() {
 		return
 	}
 
+	domainVerifier = domain_verify.NewVerifier(env.Vars()[env.VarDomainVerifyResolver])
+
+	if v := env.Vars()[env.VarPasswordHashParams]; v != "" {
+		if passwordParams, err = password.ParseParams(v); err != nil {
+			log.Critical(nil, "invalid "+env.VarPasswordHashParams+" variable", err)
+			return
+		}
+	}
+
+	if emailSender, err = newEmailSender(); err != nil {
+		log.Critical(nil, "could not set up the email sender", err)
+		return
+	}
+
+	if specFile := env.Vars()[env.VarOpenAPISpec]; specFile != "" {
+		if apiValidator, err = newAPIValidator(specFile); err != nil {
+			log.Critical(nil, "could not set up the API validator", err)
+			return
+		}
+	}
+
+	dnsProvider, err = newDNSProvider(context.Background())
+	if err != nil {
+		log.Critical(nil, "could not set up the DNS provider", err)
+		return
+	}
+
+	if err = initBuckets(context.Background()); err != nil {
+		log.Critical(nil, "could not set up the storage buckets", err)
+		return
+	}
+
 	if err = data.Init(); err != nil {
 		log.Critical(nil, "could not initialize DB connection", err)
 		return
 	}
 	defer data.Conn.Close()
 
+	if err = loadSigningKeys(); err != nil {
+		log.Critical(nil, "could not load the signing keys", err)
+		return
+	}
+
 	defer plugin.CleanupClients()
 
 	http.HandleFunc("/", handler)
@@ -79,12 +120,19 @@ func main() {
 				// TODO: return a certificate based on the ServerName
 				return cert.DefaultCert()
 			},
+			// The gRPC API is served with HTTP/2 on the same listener.
+			NextProtos: []string{"h2", "http/1.1"},
 		},
 		ReadTimeout:  time.Second * 12,
 		WriteTimeout: time.Second * 12,
 		IdleTimeout:  time.Minute * 2,
 	}
 
+	if err = http2.ConfigureServer(serverHTTPS, nil); err != nil {
+		log.Critical(nil, "could not configure HTTP/2 for the HTTPS server", err)
+		return
+	}
+
 	httpsLn, err := net.Listen("tcp", addrHTTPS)
 	if err != nil {
 		log.Critical(nil, "could not start HTTPS listener", err)
@@ -95,6 +143,12 @@ func main() {
 		go serveHealthCheck()
 	}
 
+	go purgeRateLimits()
+	go purgeSessions()
+	go processUserDeletions()
+	go processDataExports()
+	go refreshSigningKeys()
+
 	err = serverHTTPS.ServeTLS(httpsLn, "", "")
 	if err != http.ErrServerClosed {
 		log.Critical(nil, "https server stopped", err)
@@ -103,10 +157,29 @@ func main() {
 
 func handler(w http.ResponseWriter, r *http.Request) {
 
+	// The roles of users are resolved at most once during each request.
+	r = r.WithContext(roles.NewContext(r.Context(), roles.NewResolver()))
+
 	userChan := make(chan *userSite, 1)
 	go getCurrentUser(r, userChan)
 
-	s, err := data.Conn.SiteByDomain(strings.ToLower(r.Host))
+	host := strings.ToLower(r.Host)
+	s, err := data.Conn.SiteByDomain(host)
+	if err == sql.ErrNoRows {
+		var alias *data.SiteAlias
+		s, alias, err = siteByAlias(host)
+		if err == nil {
+			if !alias.Verified {
+				servePendingSite(w, r, alias.Domain)
+				return
+			}
+			if alias.Redirect {
+				redirectToCanonical(w, r, s)
+				return
+			}
+			setCanonicalLink(w, r, s)
+		}
+	}
 	if err != nil {
 		if err != sql.ErrNoRows {
 			log.Err(r, "error looking up host "+r.Host, err)
@@ -117,6 +190,11 @@ func handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.Verified && s.Id != mainSite().Id {
+		servePendingSite(w, r, s.Domain)
+		return
+	}
+
 	if s.Tls == 2 && r.TLS == nil {
 		http.Redirect(w, r, "https://"+r.Host+r.RequestURI, http.StatusMovedPermanently)
 		return
@@ -136,13 +214,39 @@ func handler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "An error occurred.", http.StatusNotFound)
 			return
 		}
+		if userSite.token != nil {
+			r = withAPIToken(r, userSite.token)
+		} else {
+			r = withSessionID(r, userSite.sessionID)
+		}
+	}
+
+	if isGRPCRequest(r) {
+		serveGRPC(w, r, s, userSite.u)
+		return
+	}
+
+	if len(slugs) == 2 && slugs[0] == dataExportPath {
+		serveDataExport(w, r, slugs[1])
+		return
 	}
 
 	if slugs[0] == "api" {
-		handleAPI(w, r, s, userSite.u, slugs[1:])
+		if apiValidator == nil {
+			handleAPI(w, r, s, userSite.u, slugs[1:])
+			return
+		}
+		apiValidator.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handleAPI(w, r, s, userSite.u, slugs[1:])
+		})).ServeHTTP(w, r)
 		return
 	}
 
+	// API tokens authenticate only API requests.
+	if userSite.token != nil {
+		userSite.u = new(data.User)
+	}
+
 	w.Header().Set("Content-Type", util.ContentTypeHTML)
 
 	// Respond with a site page or the site admin page, always with the status 200 (even if showing a 404).
@@ -157,24 +261,10 @@ func handler(w http.ResponseWriter, r *http.Request) {
 // userSite conveniently wraps a pointer to a User and a site ID and is used as the value sent down the channel
 // by getCurrentUser.
 type userSite struct {
-	u      *data.User
-	siteID int64
-}
-
-// writeApiReqErr writes out an API request error message. The HTTP status set is always not 200. If status code
-// given is 0 or 200, the status written is 500.
-func writeApiReqErr(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", util.ContentTypeTextPlainUTF8)
-	if status > 0 && status != 200 {
-		w.WriteHeader(status)
-	} else {
-		w.WriteHeader(http.StatusInternalServerError)
-	}
-	w.Write([]byte(message))
-}
-
-func writeBadAPIReq(w http.ResponseWriter) {
-	writeApiReqErr(w, http.StatusBadRequest, "Bad Request")
+	u         *data.User
+	siteID    int64
+	sessionID string
+	token     *data.APIToken // the API token with which the user is authenticated, if any
 }
 
 // writeDocHTML writes a response to an HTTP request given the HTML head and body buffers.
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/route"
+)
+
+// ReqMediaGet: GET media/{id}
+type ReqMediaGet struct{}
+
+// authorized says if the user may upload media on the current site.
+func (*ReqMediaGet) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapUploadMedia)
+}
+
+// handle responds with the metadata of the media object identified in the path.
+func (*ReqMediaGet) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	m, err := data.Conn.MediaByID(s.Id, route.Param(r, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errNotFound("The media object does not exist.")
+		}
+		log.Err(r, "could not get media by ID", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: m}
+}
+
+// ReqMediaList: GET media
+type ReqMediaList struct {
+	Offset uint64 `json:"offset"` // the pagination offset; defaults to 0
+}
+
+// decodeQuery reads the "offset" query parameter.
+func (req *ReqMediaList) decodeQuery(q url.Values) error {
+	if o := q.Get("offset"); o != "" {
+		var err error
+		if req.Offset, err = strconv.ParseUint(o, 10, 64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorized says if the user may upload media on the current site.
+func (*ReqMediaList) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapUploadMedia)
+}
+
+// handle lists the metadata of the media objects of the current site, paged by 20 results.
+func (req *ReqMediaList) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	list, err := data.Conn.MediaList(s.Id, req.Offset)
+	if err != nil {
+		log.Err(r, "could not list media", err)
+		return errProcessing()
+	}
+	resp := &apiv1.ListMediaResponse{Media: make([]*data.Media, len(list))}
+	for i := range list {
+		resp.Media[i] = &list[i]
+	}
+	return &APIResponse{Body: resp}
+}
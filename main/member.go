@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/route"
+)
+
+// The members of a site are the users who have a role on it. Members who may manage users list the
+// members, change their roles, and remove them from the site, but only for roles whose capabilities
+// they have. A site always keeps at least one owner.
+
+const errLastOwner = "The site must have at least one owner."
+
+// ReqMemberList: GET site/users
+// The members of the site are listed 20 at a time in the order of their usernames. The query may have
+// a search string matched against the usernames, email addresses, and names of the members, a role
+// name, and an offset.
+type ReqMemberList struct {
+	Search string
+	Role   string
+	Offset uint64
+}
+
+func (req *ReqMemberList) decodeQuery(q url.Values) error {
+	req.Search = q.Get("search")
+	req.Role = q.Get("role")
+	if o := q.Get("offset"); o != "" {
+		var err error
+		if req.Offset, err = strconv.ParseUint(o, 10, 64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorized returns true; the route requires the user to be able to manage users.
+func (*ReqMemberList) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqMemberList) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	var roleFilter string
+	if req.Role != "" {
+		role, errResp := parseSiteRole(r, s, req.Role)
+		if errResp != nil {
+			return errResp
+		}
+		roleFilter = strconv.FormatInt(int64(role), 10)
+	}
+	us, err := data.Conn.SiteMembers(s.Id, req.Search, roleFilter, req.Offset)
+	if err != nil {
+		log.Err(r, "could not list site members", err)
+		return errProcessing()
+	}
+	resp := &apiv1.ListSiteMembersResponse{Members: make([]*apiv1.SiteMember, 0, len(us))}
+	for i := range us {
+		val, err := strconv.ParseInt(us[i].Role, 10, 64)
+		if err != nil {
+			continue
+		}
+		role, ok := roles.ValidSiteRoleInt64(val)
+		if !ok || role == roles.Role_NONE {
+			continue
+		}
+		name, err := roles.SiteRoleName(s.Id, role)
+		if err != nil {
+			log.Err(r, "could not get name of role", err)
+			return errProcessing()
+		}
+		us[i].Pass = nil
+		us[i].Role = ""
+		resp.Members = append(resp.Members, &apiv1.SiteMember{User: &us[i], Role: int64(role), RoleName: name})
+	}
+	return &APIResponse{Body: resp}
+}
+
+// memberParam returns the ID of the member of the site identified in the path, and the member's role.
+func memberParam(r *http.Request, s *data.Site) (int64, roles.Role, *APIResponse) {
+	id, err := strconv.ParseInt(route.Param(r, "id"), 10, 64)
+	if err != nil {
+		return 0, roles.Role_NONE, APIResponseErr("The user ID is not valid.")
+	}
+	role, err := siteRole(r, id, s.Id)
+	if err != nil {
+		log.Err(r, "could not get site role for user", err)
+		return 0, roles.Role_NONE, errProcessing()
+	}
+	if role == roles.Role_NONE || role == roles.Role_SUPER { // super users without a role are not members
+		return 0, roles.Role_NONE, errNotFound("The user is not a member of the site.")
+	}
+	return id, role, nil
+}
+
+// ReqMemberRole: PUT site/users/{id}/role
+// The role of a member of the site is changed. The user must be able to give both the member's current
+// role and the new role, and the last owner of the site cannot be given another role. The sessions of
+// the member on the site are ended.
+type ReqMemberRole struct {
+	Role string `json:"role"` // such as "EDITOR"
+}
+
+// authorized returns true; the route requires the user to be able to manage users.
+func (*ReqMemberRole) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqMemberRole) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	id, current, errResp := memberParam(r, s)
+	if errResp != nil {
+		return errResp
+	}
+	role, errResp := parseSiteRole(r, s, req.Role)
+	if errResp != nil {
+		return errResp
+	}
+	if role == current {
+		return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+	}
+	if errResp = canGrant(r, s, u, current); errResp != nil {
+		return errResp
+	}
+	if errResp = canGrant(r, s, u, role); errResp != nil {
+		return errResp
+	}
+	// The last owner is kept as the role is written, so that owners demoting each other at once cannot
+	// both succeed.
+	if err := roles.SetSiteRoleKeepOwner(id, s.Id, int64(role)); err != nil {
+		return memberRoleErr(r, err, "could not set site role of user")
+	}
+	return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+}
+
+// ReqMemberRemove: DELETE site/users/{id}
+// A member is removed from the site, and the sessions of the member on the site are ended. Members may
+// leave the site themselves; removing others requires being able to manage users and to give the
+// member's role. The last owner of the site cannot be removed.
+type ReqMemberRemove struct{}
+
+// authorized says if the user is logged in. Whether the user may remove the member identified in the
+// path is checked by the handler.
+func (*ReqMemberRemove) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
+	return u.Id != 0
+}
+
+func (*ReqMemberRemove) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	id, current, errResp := memberParam(r, s)
+	if errResp != nil {
+		return errResp
+	}
+	if id != u.Id {
+		if !can(r, s, u, roles.CapManageUsers) {
+			return errLowPrivileges()
+		}
+		if errResp = canGrant(r, s, u, current); errResp != nil {
+			return errResp
+		}
+	}
+	if err := roles.RemoveSiteRoleKeepOwner(id, s.Id); err != nil {
+		return memberRoleErr(r, err, "could not remove site role of user")
+	}
+	return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+}
+
+// memberRoleErr returns the response for an error from changing the role of a member of the site.
+func memberRoleErr(r *http.Request, err error, msg string) *APIResponse {
+	switch err {
+	case roles.ErrLastOwner:
+		return APIResponseErr(errLastOwner)
+	case sql.ErrNoRows:
+		return errNotFound("The user is not a member of the site.")
+	}
+	log.Err(r, msg, err)
+	return errProcessing()
+}
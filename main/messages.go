@@ -2,42 +2,23 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
 )
 
 // getUserMessages retrieves the stored notification messages for the user.
-func getUserMessages(ctx context.Context, userID int64) ([]data.UserMessage, error) { // TODO
-	//q := datastore.NewQuery(userMessageKind).Filter("uid =", userID)
-	//it := datastoreClient.Run(ctx, q)
-	//ums := make([]userMessage, 0, 2)
-	//for {
-	//	var um userMessage
-	//	_, err := it.Next(&um)
-	//	if err == iterator.Done {
-	//		break
-	//	}
-	//	if err != nil {
-	//		return ums, err
-	//	}
-	//	ums = append(ums, um)
-	//}
-	//return ums, nil
-	return nil, nil
+func getUserMessages(ctx context.Context, userID int64) ([]data.UserMessage, error) {
+	return data.Conn.UserMessagesByUser(userID)
 }
 
-func saveUserMessage(r *http.Request, userID int64, key string, msg string) { // TODO
-	m := &data.UserMessage{
-		UserId:  userID,
-		K:       key,
-		Message: msg,
+// saveUserMessage saves a notification message for the user. A failure is logged.
+func saveUserMessage(r *http.Request, userID int64, key string, msg string) {
+	if err := data.Conn.UserMessageInsert(userID, key, msg); err != nil {
+		log.Err(r, fmt.Sprintf("could not save user message: k = %q; v = %q", key, msg), err)
 	}
-	_ = m
-	//_, err := datastoreClient.Put(context.Background(), datastore.IncompleteKey(userMessageKind, nil), m)
-	//if err != nil {
-	//	log.Err(r, fmt.Sprintf("could not save user message: k = %q; v = %q", key, msg), err)
-	//}
 }
 
 // getSiteMessages retrieves the stored notification messages for the user.
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"golang.org/x/oauth2"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/env"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/oidc"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/route"
+)
+
+// Options with which the OpenID Connect providers of a site are configured. Each provider has a name,
+// made of lowercase letters, digits, and dashes, that appears in the names of its options and in the
+// paths of its login routes. For example, the provider "google" is configured with the options
+// "oidc-google-issuer", "oidc-google-client-id", and "oidc-google-client-secret" and optionally
+// "oidc-google-label" and "oidc-google-scopes". All of the options starting with "oidc-" may be set only
+// by owners.
+const (
+	optOIDCPrefix    = "oidc-"
+	optOIDCProviders = "oidc-providers" // the comma-separated names of the providers
+)
+
+// oidcOption returns the name of an option of the provider.
+func oidcOption(provider, name string) string {
+	return optOIDCPrefix + provider + "-" + name
+}
+
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// An oidcConfig is the configuration of an OpenID Connect provider on a site.
+type oidcConfig struct {
+	Name         string
+	Label        string // the name of the provider shown to users; the default is the name
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string // the scopes requested besides "openid" and "email"
+}
+
+// siteOIDCProviders returns the configurations of the OpenID Connect providers of the site. Providers
+// with invalid or incomplete configurations are logged and skipped.
+func siteOIDCProviders(r *http.Request, s *data.Site) ([]oidcConfig, error) {
+	opts, err := data.Conn.OptionsKeyInMappedStr(s.Id, []string{optOIDCProviders})
+	if err != nil {
+		return nil, err
+	}
+	var names, keys []string
+	for _, name := range strings.Split(opts[optOIDCProviders], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !oidcProviderName.MatchString(name) {
+			log.Err(r, fmt.Sprintf("invalid value of option %q: %q", optOIDCProviders, opts[optOIDCProviders]),
+				errors.New("invalid provider name "+name))
+			continue
+		}
+		names = append(names, name)
+		for _, o := range [...]string{"issuer", "client-id", "client-secret", "label", "scopes"} {
+			keys = append(keys, oidcOption(name, o))
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if opts, err = data.Conn.OptionsKeyInMappedStr(s.Id, keys); err != nil {
+		return nil, err
+	}
+	configs := make([]oidcConfig, 0, len(names))
+	for _, name := range names {
+		c := oidcConfig{
+			Name:         name,
+			Label:        opts[oidcOption(name, "label")],
+			Issuer:       strings.TrimSuffix(opts[oidcOption(name, "issuer")], "/"),
+			ClientID:     opts[oidcOption(name, "client-id")],
+			ClientSecret: opts[oidcOption(name, "client-secret")],
+			Scopes:       strings.Fields(opts[oidcOption(name, "scopes")]),
+		}
+		if c.Issuer == "" || c.ClientID == "" {
+			log.Err(r, fmt.Sprintf("incomplete configuration of OpenID Connect provider %q", name),
+				errors.New("the issuer and the client ID must be set"))
+			continue
+		}
+		if c.Label == "" {
+			c.Label = name
+		}
+		configs = append(configs, c)
+	}
+	return configs, nil
+}
+
+// siteOIDCProvider returns the configuration of the site's OpenID Connect provider with the name given,
+// or nil if the site has no such provider.
+func siteOIDCProvider(r *http.Request, s *data.Site, name string) (*oidcConfig, error) {
+	configs, err := siteOIDCProviders(r, s)
+	if err != nil {
+		return nil, err
+	}
+	for i := range configs {
+		if configs[i].Name == name {
+			return &configs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// oauth2Config returns the OAuth 2.0 configuration with which the site uses the provider p.
+func (c *oidcConfig) oauth2Config(r *http.Request, s *data.Site, p *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		Endpoint:     p.Endpoint(),
+		RedirectURL:  siteRoot(r, s) + "/api/auth/oidc/" + c.Name + "/callback",
+		Scopes:       append([]string{oidc.ScopeOpenID, "email"}, c.Scopes...),
+	}
+}
+
+// oidcDiscoveryTTL is how long the discovery documents of the providers are cached.
+const oidcDiscoveryTTL = time.Hour
+
+// oidcClient is the HTTP client with which the providers are reached.
+var oidcClient = &http.Client{Timeout: time.Second * 10}
+
+// oidcProviders caches the discovered providers by issuer URL, along with the time each was discovered.
+var oidcProviders = struct {
+	sync.Mutex
+	m map[string]discoveredProvider
+}{m: make(map[string]discoveredProvider)}
+
+type discoveredProvider struct {
+	p    *oidc.Provider
+	time time.Time
+}
+
+// discoverOIDC returns the provider with the issuer URL given, discovering it if it is not cached.
+func discoverOIDC(ctx context.Context, issuer string) (*oidc.Provider, error) {
+	oidcProviders.Lock()
+	d, ok := oidcProviders.m[issuer]
+	oidcProviders.Unlock()
+	if ok && time.Since(d.time) < oidcDiscoveryTTL {
+		return d.p, nil
+	}
+	p, err := oidc.Discover(ctx, oidcClient, issuer)
+	if err != nil {
+		return nil, err
+	}
+	oidcProviders.Lock()
+	oidcProviders.m[issuer] = discoveredProvider{p: p, time: time.Now()}
+	oidcProviders.Unlock()
+	return p, nil
+}
+
+// identityKeyPrefix starts the user meta keys of the external identities linked to a user. The key of
+// an identity is the prefix followed by the issuer URL, a "|", and the subject, and the value is a
+// JSON-encoded linkedIdentity.
+const identityKeyPrefix = "oidc|"
+
+func identityKey(issuer, subject string) string {
+	return identityKeyPrefix + issuer + "|" + subject
+}
+
+// linkedIdentity is the value stored with an external identity linked to a user.
+type linkedIdentity struct {
+	Provider string `json:"provider"` // the name of the provider on the site on which it was linked
+	Email    string `json:"email"`
+}
+
+// A browserFlow is an APIHandler of an auth route to which the browser navigates, such as the routes
+// of the OpenID Connect login flow. The response to the browser sets the cookies and redirects: either
+// to the location, if it is set and the request succeeded, or else as for a login form.
+type browserFlow interface {
+	cookies() []string
+	location() string
+}
+
+// The state of an OpenID Connect login or linking of an identity in progress is kept in a signed cookie
+// that is sent only to the OpenID Connect routes.
+const (
+	oidcCookieName = "mwoidc"
+	oidcCookiePath = "/api/auth/oidc/"
+	oidcStateTTL   = time.Minute * 10
+)
+
+// oidcState is the state of a login or linking of an identity in progress.
+type oidcState struct {
+	provider string
+	state    string // the value of the state parameter, which must come back with the code
+	nonce    string // the nonce to be included in the ID token
+	verifier string // the PKCE code verifier
+	linkUser int64  // the ID of the user linking an identity, or 0 for a login
+}
+
+// createOIDCCookie creates the cookie holding the state. The value is the base64-encoded body signed
+// with signToken; the signature covers a prefix as well so that no other kind of token can pass for the
+// state. The body consists of the expiration time (Unix seconds) followed by
+// the fields of the state in order, encoded with appendTokenInt and appendTokenString.
+func createOIDCCookie(st *oidcState, expires time.Time) string {
+	body := make([]byte, 0, tokenIntSize*2+tokenStringPrefixSize*4+len(st.provider)+len(st.state)+
+		len(st.nonce)+len(st.verifier))
+	body = appendTokenInt(body, expires.Unix())
+	body = appendTokenString(body, st.provider)
+	body = appendTokenString(body, st.state)
+	body = appendTokenString(body, st.nonce)
+	body = appendTokenString(body, st.verifier)
+	body = appendTokenInt(body, st.linkUser)
+	token := signToken("oidc.", base64.RawURLEncoding.EncodeToString(body))
+	return oidcCookie(token, int(oidcStateTTL/time.Second))
+}
+
+// oidcCookie returns the cookie with the value given. A negative maxAge deletes the cookie.
+func oidcCookie(value string, maxAge int) string {
+	c := fmt.Sprintf("%s=%s; HttpOnly; SameSite=Lax; Path=%s; Max-Age=%d", oidcCookieName, value, oidcCookiePath, maxAge)
+	if env.Prod() {
+		c += "; Secure"
+	}
+	return c
+}
+
+// readOIDCState returns the state in the cookie of the request if the cookie is valid and has not
+// expired.
+func readOIDCState(r *http.Request) (*oidcState, bool) {
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		return nil, false
+	}
+	bodyEncoded, ok := verifyToken("oidc.", cookie.Value)
+	if !ok {
+		return nil, false
+	}
+	body, err := base64.RawURLEncoding.DecodeString(bodyEncoded)
+	if err != nil {
+		return nil, false
+	}
+	var expires int64
+	if expires, body, err = readTokenInt(body); err != nil || time.Now().Unix() > expires {
+		return nil, false
+	}
+	st := new(oidcState)
+	for _, f := range [...]*string{&st.provider, &st.state, &st.nonce, &st.verifier} {
+		if *f, body, err = readTokenString(body); err != nil {
+			return nil, false
+		}
+	}
+	if st.linkUser, _, err = readTokenInt(body); err != nil {
+		return nil, false
+	}
+	return st, true
+}
+
+const (
+	errOIDCNotFound  = "The identity provider was not found."
+	errOIDCExpired   = "Your sign-in has expired. Please try again."
+	errOIDCNotLinked = "No account is linked to this identity. Log in with your password and link the identity " +
+		"to your account first."
+)
+
+// ReqOIDCProviders: GET auth/oidc
+// The OpenID Connect providers with which users may log in to the site are listed.
+type ReqOIDCProviders struct{}
+
+// authorized returns true; the providers are shown on the login page.
+func (*ReqOIDCProviders) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqOIDCProviders) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	configs, err := siteOIDCProviders(r, s)
+	if err != nil {
+		log.Err(r, "could not get OpenID Connect providers", err)
+		return errProcessing()
+	}
+	resp := &apiv1.ListOIDCProvidersResponse{Providers: make([]*apiv1.OIDCProvider, len(configs))}
+	for i, c := range configs {
+		resp.Providers[i] = &apiv1.OIDCProvider{Name: c.Name, Label: c.Label}
+	}
+	return &APIResponse{Body: resp}
+}
+
+// ReqOIDCStart: GET auth/oidc/{provider}
+// The browser is redirected to the provider to log in, or with the "link" query parameter set to "1" to
+// link an identity at the provider to the account of the logged in user.
+type ReqOIDCStart struct {
+	link bool
+
+	setCookies []string
+	redirectTo string
+}
+
+func (req *ReqOIDCStart) decodeQuery(q url.Values) error {
+	req.link = q.Get("link") == "1"
+	return nil
+}
+
+// authorized says if the user is logged in in case an identity is to be linked.
+func (req *ReqOIDCStart) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
+	return !req.link || u.Id != 0
+}
+
+func (req *ReqOIDCStart) cookies() []string { return req.setCookies }
+func (req *ReqOIDCStart) location() string  { return req.redirectTo }
+
+func (req *ReqOIDCStart) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	c, err := siteOIDCProvider(r, s, route.Param(r, "provider"))
+	if err != nil {
+		log.Err(r, "could not get OpenID Connect provider", err)
+		return errProcessing()
+	}
+	if c == nil {
+		return errNotFound(errOIDCNotFound)
+	}
+	if !req.link && u.Id != 0 {
+		return APIResponseErr("You are already logged in as user: " + u.Uname)
+	}
+	p, err := discoverOIDC(r.Context(), c.Issuer)
+	if err != nil {
+		log.Err(r, "could not discover OpenID Connect provider "+c.Issuer, err)
+		return APIResponseErr(fmt.Sprintf("Signing in with %s is not available right now.", c.Label))
+	}
+
+	st := &oidcState{provider: c.Name}
+	for _, f := range [...]*string{&st.state, &st.nonce, &st.verifier} {
+		if *f, err = oidc.RandomString(); err != nil {
+			log.Err(r, "could not generate OpenID Connect state", err)
+			return errProcessing()
+		}
+	}
+	if req.link {
+		st.linkUser = u.Id
+	}
+	req.setCookies = []string{createOIDCCookie(st, time.Now().Add(oidcStateTTL))}
+	req.redirectTo = c.oauth2Config(r, s, p).AuthCodeURL(st.state, oidc.AuthCodeOptions(st.nonce, st.verifier)...)
+	return &APIResponse{Body: &wrappers.StringValue{Value: req.redirectTo}}
+}
+
+// ReqOIDCCallback: GET auth/oidc/{provider}/callback
+// The provider redirects the browser back with an authorization code, which is exchanged for an ID
+// token identifying the user at the provider. The user whose account the identity is linked to is
+// logged in, subject to two-factor authentication; or, if the flow was started to link the identity, it
+// is linked to the account of the logged in user.
+type ReqOIDCCallback struct {
+	code    string
+	state   string
+	errCode string // the error reported by the provider
+
+	setCookies []string
+	redirectTo string
+}
+
+func (req *ReqOIDCCallback) decodeQuery(q url.Values) error {
+	req.code = q.Get("code")
+	req.state = q.Get("state")
+	req.errCode = q.Get("error")
+	return nil
+}
+
+// authorized returns true; the state cookie identifies the flow.
+func (*ReqOIDCCallback) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+// rateLimit limits how often each IP address may try to log in.
+func (*ReqOIDCCallback) rateLimit() rate_limit.Bucket {
+	return loginRateLimit
+}
+
+func (req *ReqOIDCCallback) cookies() []string { return req.setCookies }
+func (req *ReqOIDCCallback) location() string  { return req.redirectTo }
+
+func (req *ReqOIDCCallback) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	// The state is used only once.
+	req.setCookies = []string{oidcCookie("none", -1)}
+
+	name := route.Param(r, "provider")
+	st, ok := readOIDCState(r)
+	if !ok || st.provider != name || !hmac.Equal([]byte(st.state), []byte(req.state)) {
+		return APIResponseErr(errOIDCExpired)
+	}
+	c, err := siteOIDCProvider(r, s, name)
+	if err != nil {
+		log.Err(r, "could not get OpenID Connect provider", err)
+		return errProcessing()
+	}
+	if c == nil {
+		return errNotFound(errOIDCNotFound)
+	}
+	if req.errCode != "" || req.code == "" {
+		return APIResponseErr(fmt.Sprintf("Signing in with %s did not complete.", c.Label))
+	}
+	p, err := discoverOIDC(r.Context(), c.Issuer)
+	if err != nil {
+		log.Err(r, "could not discover OpenID Connect provider "+c.Issuer, err)
+		return APIResponseErr(fmt.Sprintf("Signing in with %s is not available right now.", c.Label))
+	}
+
+	tok, err := c.oauth2Config(r, s, p).Exchange(p.Context(r.Context()), req.code, oidc.ExchangeOptions(st.verifier)...)
+	if err != nil {
+		log.Err(r, "could not exchange OpenID Connect authorization code", err)
+		return APIResponseErr(fmt.Sprintf("Signing in with %s did not complete.", c.Label))
+	}
+	rawIDToken, ok := oidc.IDToken(tok)
+	if !ok {
+		log.Err(r, "could not complete OpenID Connect login", errors.New("no ID token in token response"))
+		return errProcessing()
+	}
+	claims, err := p.Verify(r.Context(), rawIDToken, c.ClientID, st.nonce, time.Now())
+	if err != nil {
+		log.Err(r, "invalid OpenID Connect ID token", err)
+		return APIResponseErr(fmt.Sprintf("Signing in with %s did not complete.", c.Label))
+	}
+
+	key := identityKey(claims.Issuer, claims.Subject)
+	linked, err := data.Conn.UserMetaByKey(key)
+	if err != nil {
+		log.Err(r, "could not get linked identity", err)
+		return errProcessing()
+	}
+
+	if st.linkUser != 0 {
+		return req.linkIdentity(r, s, u, st, c, claims, key, linked)
+	}
+
+	if u.Id != 0 {
+		return APIResponseErr("You are already logged in as user: " + u.Uname)
+	}
+	if len(linked) == 0 {
+		return APIResponseErr(errOIDCNotLinked)
+	}
+	userID := linked[0].UserId
+	if resp := loginLockedOut(r, s, rate_limit.UserKey(userID)); resp != nil {
+		return resp
+	}
+	tempU, err := data.Conn.UserSiteInfoByID(s.Id, userID)
+	if err != nil {
+		log.Err(r, "could not get user of linked identity", err)
+		return errProcessing()
+	}
+	if tempU.Role == roles.Role_NONE {
+		return APIResponseErr(errNoMembership)
+	}
+	verified, err := emailVerified(userID)
+	if err != nil {
+		log.Err(r, "could not check if email address is verified", err)
+		return errProcessing()
+	}
+	if !verified {
+		return APIResponseErr(errEmailUnverified)
+	}
+
+	// The failed logins are forgotten by challengeTwoFactor only if no second factor is needed.
+	*u = *tempU
+	return challengeTwoFactor(r, s, u, &APIResponse{Body: &wrappers.StringValue{Value: RespUserLogin}})
+}
+
+// linkIdentity links the identity verified by the callback to the account of the logged in user, who
+// must be the user who started the flow.
+func (req *ReqOIDCCallback) linkIdentity(r *http.Request, s *data.Site, u *data.User, st *oidcState,
+	c *oidcConfig, claims *oidc.Claims, key string, linked []data.UserMeta) *APIResponse {
+	if u.Id != st.linkUser {
+		return errMustLogin()
+	}
+	if len(linked) > 0 && linked[0].UserId != u.Id {
+		return APIResponseErr(fmt.Sprintf("This %s account is already linked to another user.", c.Label))
+	}
+	v, err := json.Marshal(linkedIdentity{Provider: c.Name, Email: claims.Email})
+	if err != nil {
+		log.Err(r, "could not encode linked identity", err)
+		return errProcessing()
+	}
+	if _, err = data.Conn.UserMetaUpdate(u.Id, key, v); err != nil {
+		log.Err(r, "could not link identity", err)
+		return errProcessing()
+	}
+	req.redirectTo = loginRedirect(r, s, u)
+	return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+}
+
+// userIdentities returns the external identities linked to the user.
+func userIdentities(userID int64) ([]*apiv1.Identity, error) {
+	metas, err := data.Conn.UserMetaByIdLikeKey(userID, identityKeyPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]*apiv1.Identity, 0, len(metas))
+	for _, m := range metas {
+		parts := strings.SplitN(strings.TrimPrefix(m.K, identityKeyPrefix), "|", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		var li linkedIdentity
+		if err := json.Unmarshal(m.V, &li); err != nil {
+			return nil, fmt.Errorf("invalid linked identity %q: %v", m.K, err)
+		}
+		ids = append(ids, &apiv1.Identity{Issuer: parts[0], Subject: parts[1], Provider: li.Provider, Email: li.Email})
+	}
+	return ids, nil
+}
+
+// ReqIdentityList: GET user/identities
+// The external identities linked to the user's account are listed.
+type ReqIdentityList struct{}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqIdentityList) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqIdentityList) handle(r *http.Request, _ *data.Site, u *data.User) *APIResponse {
+	ids, err := userIdentities(u.Id)
+	if err != nil {
+		log.Err(r, "could not list linked identities", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: &apiv1.ListIdentitiesResponse{Identities: ids}}
+}
+
+// ReqIdentityUnlink: DELETE user/identities/{provider}
+// The identities at the site's provider with the name given are unlinked from the user's account. The
+// response gives the number of identities unlinked.
+type ReqIdentityUnlink struct{}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqIdentityUnlink) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqIdentityUnlink) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	c, err := siteOIDCProvider(r, s, route.Param(r, "provider"))
+	if err != nil {
+		log.Err(r, "could not get OpenID Connect provider", err)
+		return errProcessing()
+	}
+	if c == nil {
+		return errNotFound(errOIDCNotFound)
+	}
+	ids, err := userIdentities(u.Id)
+	if err != nil {
+		log.Err(r, "could not list linked identities", err)
+		return errProcessing()
+	}
+	var n int64
+	for _, id := range ids {
+		if id.Issuer != c.Issuer {
+			continue
+		}
+		deleted, err := data.Conn.UserMetaDelete(u.Id, identityKey(id.Issuer, id.Subject))
+		if err != nil {
+			log.Err(r, "could not unlink identity", err)
+			return errProcessing()
+		}
+		n += deleted
+	}
+	return &APIResponse{Body: &wrappers.Int64Value{Value: n}}
+}
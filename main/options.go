@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/roles"
+)
+
+// ReqOptionsGet: GET options
+type ReqOptionsGet struct {
+	Keys []string `json:"keys"` // the keys of the options
+}
+
+// decodeQuery reads the keys from the repeated "key" query parameter.
+func (req *ReqOptionsGet) decodeQuery(q url.Values) error {
+	req.Keys = q["key"]
+	return nil
+}
+
+// authorized says if the user may manage the current site.
+func (*ReqOptionsGet) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapManageSite)
+}
+
+// handle responds with the options of the current site that have the keys requested.
+func (req *ReqOptionsGet) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	resp := &apiv1.GetOptionsResponse{}
+	if len(req.Keys) == 0 {
+		return &APIResponse{Body: resp}
+	}
+	opts, err := data.Conn.OptionsKeyIn(s.Id, req.Keys)
+	if err != nil {
+		log.Err(r, "could not get options", err)
+		return errProcessing()
+	}
+	resp.Options = make([]*data.Option, len(opts))
+	for i := range opts {
+		resp.Options[i] = &opts[i]
+	}
+	return &APIResponse{Body: resp}
+}
+
+// ReqOptionsSet: PATCH options
+type ReqOptionsSet struct {
+	Options map[string]string `json:"options"` // the values of the options by key
+}
+
+// ownerOptions are the options that only the owners of a site may set. The options configuring the
+// OpenID Connect providers of a site may be set only by owners as well.
+var ownerOptions = []string{opt2FARequiredRole}
+
+// ownerOption says if only the owners of a site may set the option.
+func ownerOption(name string) bool {
+	if strings.HasPrefix(name, optOIDCPrefix) {
+		return true
+	}
+	for _, o := range ownerOptions {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+// authorized says if the user may manage the current site.
+func (*ReqOptionsSet) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapManageSite)
+}
+
+// handle creates or updates the options of the current site. The options listing the admin source
+// files may be set only by super users, and the ownerOptions only by owners.
+func (req *ReqOptionsSet) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	if len(req.Options) == 0 {
+		return &APIResponse{Body: &apiv1.SetOptionsResponse{}}
+	}
+	if !isSuper(r, u.Id) {
+		for _, name := range adminSrcFullNames {
+			if _, ok := req.Options[name]; ok {
+				return APIResponseErr(fmt.Sprintf("The option %q may not be set.", name))
+			}
+		}
+	}
+	if !roles.RoleAtLeast(u.Role, roles.Role_OWNER) {
+		for name := range req.Options {
+			if ownerOption(name) {
+				return APIResponseErr(fmt.Sprintf("Only owners of the site may set the option %q.", name))
+			}
+		}
+	}
+	n, err := data.Conn.OptionsUpdate(s.Id, req.Options)
+	if err != nil {
+		log.Err(r, "could not update options", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: &apiv1.SetOptionsResponse{Updated: n}}
+}
@@ -15,9 +15,11 @@ import (
 	filter_payloads "github.com/dchenk/mazewire/pkg/filters/payloads"
 	"github.com/dchenk/mazewire/pkg/log"
 	"github.com/dchenk/mazewire/pkg/plugins"
+	"github.com/dchenk/mazewire/pkg/roles"
 	"github.com/dchenk/mazewire/pkg/room"
-	"github.com/dchenk/mazewire/pkg/users"
+	"github.com/dchenk/mazewire/pkg/room/modules"
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
 )
 
 const (
@@ -34,12 +36,12 @@ const (
 
 // ReqPageEditContent: GET page-edit
 type ReqPageEditContent struct {
-	Site int64 `msgp:"site"` // the site ID; defaults to current host
-	Page int64 `msgp:"page"` // the page ID
+	Site int64 `json:"site"` // the site ID; defaults to current host
+	Page int64 `json:"page"` // the page ID
 }
 
 func (req *ReqPageEditContent) authorized(r *http.Request, s *data.Site, u *data.User) bool {
-	return users.RoleAtLeast(u.Role, users.Role_AUTHOR)
+	return can(r, s, u, roles.CapEditPages)
 }
 
 // Get the content associated with a particular page, including all sections, rows, and modules of the page.
@@ -50,12 +52,12 @@ func (req *ReqPageEditContent) handle(r *http.Request, s *data.Site, u *data.Use
 		req.Site = s.Id // Site defaults to the current host.
 	} else {
 		var err error
-		role, err = users.SiteRole(u.Id, req.Site)
+		role, err = siteRole(r, u.Id, req.Site)
 		if err != nil {
 			log.Err(r, "could not get logged in site role for user", err)
 			return errProcessing()
 		}
-		if !users.RoleAtLeast(role, users.Role_AUTHOR) {
+		if !roleCan(r, req.Site, role, roles.CapEditPages) {
 			return errLowPrivileges()
 		}
 	}
@@ -66,8 +68,8 @@ func (req *ReqPageEditContent) handle(r *http.Request, s *data.Site, u *data.Use
 		return errProcessing()
 	}
 
-	// If the user has just author role on the site, the user must be the author of the page.
-	if role == users.Role_AUTHOR && content.Author != u.Id {
+	// If the user may not edit the pages of others, the user must be the author of the page.
+	if content.Author != u.Id && !roleCan(r, req.Site, role, roles.CapEditOthersPages) {
 		return errLowPrivileges()
 	}
 
@@ -129,25 +131,25 @@ func (req *ReqPageEditContent) handle(r *http.Request, s *data.Site, u *data.Use
 
 // RespPageEditContent is the response body for GET page-edit requests.
 type RespPageEditContent struct {
-	*data.Content `msgp:"content"`
-	Tree          room.Tree            `msgp:"tree"`     // the last saved (current) content tree; represents type PageTree
-	DynData       map[string]data.Blob `msgp:"dyn_data"` // data for dynamic elements within the tree
-	PageVersions  `msgp:"versions"`    // the tree structure versions and other details
-	UserCSS       string               `msgp:"user_css"` // copied from Pagepost so that client does not decode []byte to string
+	*data.Content `json:"content"`
+	Tree          room.Tree            `json:"tree"`     // the last saved (current) content tree; represents type PageTree
+	DynData       map[string]data.Blob `json:"dyn_data"` // data for dynamic elements within the tree
+	PageVersions  `json:"versions"`    // the tree structure versions and other details
+	UserCSS       string               `json:"user_css"` // copied from Pagepost so that client does not decode []byte to string
 }
 
 // ReqPageEditSave: POST page-edit
 type ReqPageEditSave struct {
-	Site         int64    `msgp:"site"` // the site ID; defaults to current host
-	Page         int64    `msgp:"page"` // the page ID
-	Tree         msgp.Raw `msgp:"tree"` // The entire new page structure (role = "body"); represents type room.Tree
-	data.Content `msgp:"content"`
-	PageVersion  `msgp:"version"` // The Id, Data, and Timestamp fields are not used.
+	Site         int64    `json:"site"` // the site ID; defaults to current host
+	Page         int64    `json:"page"` // the page ID
+	Tree         msgp.Raw `json:"tree"` // The entire new page structure (role = "body"); represents type room.Tree
+	data.Content `json:"content"`
+	PageVersion  `json:"version"` // The Id, Data, and Timestamp fields are not used.
 }
 
-// authorized checks just if the user is at least an author on the current site.
+// authorized checks just if the user may edit pages on the current site.
 func (req *ReqPageEditSave) authorized(r *http.Request, s *data.Site, u *data.User) bool {
-	return users.RoleAtLeast(u.Role, users.Role_AUTHOR)
+	return can(r, s, u, roles.CapEditPages)
 }
 
 // handle saving changes to a page.
@@ -158,12 +160,12 @@ func (req *ReqPageEditSave) handle(r *http.Request, s *data.Site, u *data.User)
 		req.Site = s.Id // Site defaults to the current host.
 	} else {
 		var err error
-		role, err = users.SiteRole(u.Id, req.Site)
+		role, err = siteRole(r, u.Id, req.Site)
 		if err != nil {
 			log.Err(r, "could not get logged in site role for user", err)
 			return errProcessing()
 		}
-		if !users.RoleAtLeast(role, users.Role_AUTHOR) {
+		if !roleCan(r, req.Site, role, roles.CapEditPages) {
 			return errLowPrivileges()
 		}
 	}
@@ -174,8 +176,8 @@ func (req *ReqPageEditSave) handle(r *http.Request, s *data.Site, u *data.User)
 		return errProcessing()
 	}
 
-	// If the user has just "author" role on the site, the user must be the author of the page.
-	if role == users.Role_AUTHOR && content.Author != u.Id {
+	// If the user may not edit the pages of others, the user must be the author of the page.
+	if content.Author != u.Id && !roleCan(r, req.Site, role, roles.CapEditOthersPages) {
 		return APIResponseErr("You must be the author of this page to edit it.")
 	}
 
@@ -237,6 +239,7 @@ func (req *ReqPageEditSave) handle(r *http.Request, s *data.Site, u *data.User)
 		}
 	}
 	req.PageVersion.Data = req.Tree
+	req.PageVersion.Saver = u.Id
 
 	versionData, err := req.PageVersion.MarshalMsg(nil)
 	if err != nil {
@@ -259,18 +262,38 @@ func (req *ReqPageEditSave) handle(r *http.Request, s *data.Site, u *data.User)
 }
 
 type RespPageEditSave struct {
-	InsertId   int64     `msgp:"insert_id"`
-	InsertTime time.Time `msgp:"insert_dt"`
+	InsertId   int64     `json:"insert_id"`
+	InsertTime time.Time `json:"insert_dt"`
+}
+
+// pagePolicy returns the room.Policy with which the modules of a page are compiled when a user with the
+// role publisher publishes it on the site. The HTML of the page is left unfiltered only if the publisher,
+// the author of the page, and the user who saved the version published may all publish unfiltered HTML,
+// since any of them may have written it. A version whose saver is unknown (0) is always filtered.
+func pagePolicy(r *http.Request, siteID int64, publisher roles.Role, authorID, saverID int64) (*room.Policy, error) {
+	if !roleCan(r, siteID, publisher, roles.CapUnfilteredHTML) || saverID == 0 {
+		return room.DefaultPolicy, nil
+	}
+	for _, userID := range [...]int64{authorID, saverID} {
+		role, err := siteRole(r, userID, siteID)
+		if err != nil {
+			return nil, err
+		}
+		if !roleCan(r, siteID, role, roles.CapUnfilteredHTML) {
+			return room.DefaultPolicy, nil
+		}
+	}
+	return room.UnfilteredPolicy, nil
 }
 
 // ReqPageEditPublish: PATCH pagepost
 type ReqPageEditPublish struct {
-	Site int64 `msgp:"site"` // the site ID; defaults to current host
-	Page int64 `msgp:"page"` // the page ID
+	Site int64 `json:"site"` // the site ID; defaults to current host
+	Page int64 `json:"page"` // the page ID
 }
 
-func (req *ReqPageEditPublish) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
-	return users.RoleAtLeast(u.Role, users.Role_AUTHOR)
+func (req *ReqPageEditPublish) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapPublishPages)
 }
 
 // pageEditPublish compiles and publishes a page.
@@ -282,12 +305,12 @@ func (req *ReqPageEditPublish) handle(r *http.Request, s *data.Site, u *data.Use
 		req.Site = s.Id // Site defaults to the current host.
 	} else {
 		var err error
-		role, err = users.SiteRole(u.Id, req.Site)
+		role, err = siteRole(r, u.Id, req.Site)
 		if err != nil {
 			log.Err(r, "could not get logged in site role for user", err)
 			return errProcessing()
 		}
-		if !users.RoleAtLeast(role, users.Role_AUTHOR) {
+		if !roleCan(r, req.Site, role, roles.CapPublishPages) {
 			return errLowPrivileges()
 		}
 	}
@@ -298,8 +321,8 @@ func (req *ReqPageEditPublish) handle(r *http.Request, s *data.Site, u *data.Use
 		return errProcessing()
 	}
 
-	// if the user has just "author" role on the site, the user must be the author of the page being published
-	if role == users.Role_AUTHOR && content.Author != u.Id {
+	// if the user may not edit the pages of others, the user must be the author of the page being published
+	if content.Author != u.Id && !roleCan(r, req.Site, role, roles.CapEditOthersPages) {
 		return APIResponseErr("You must be the author of this page to publish it.")
 	}
 
@@ -319,6 +342,12 @@ func (req *ReqPageEditPublish) handle(r *http.Request, s *data.Site, u *data.Use
 		return errProcessing()
 	}
 
+	policy, err := pagePolicy(r, req.Site, role, content.Author, latestVersion.Saver)
+	if err != nil {
+		log.Err(r, "could not get site role for page author or saver", err)
+		return errProcessing()
+	}
+
 	roomTreeModule := room.Module{
 		Type: room.TreeType,
 		Data: latestVersion.Data,
@@ -330,7 +359,7 @@ func (req *ReqPageEditPublish) handle(r *http.Request, s *data.Site, u *data.Use
 	// pt will be the compiled page.
 	pt := make(room.Tree, 0, 8)
 
-	compiledTree, allStatic, err := pt.Compile(&dataStore{s}, nil, &roomTreeModule, &pageCSS)
+	compiledTree, allStatic, err := pt.Compile(&dataStore{s}, modules.NewCustomizer(policy), &roomTreeModule, &pageCSS)
 	if err != nil {
 		log.Err(r, "could not compile a page tree", err)
 		return errProcessing()
@@ -379,7 +408,7 @@ func (req *ReqPageEditPublish) handle(r *http.Request, s *data.Site, u *data.Use
 
 	// Respond with true.
 	resp := APIResponse{
-		Body: msgp.Raw(msgp.AppendBool(nil, true)),
+		Body: &wrappers.BoolValue{Value: true},
 	}
 	return &resp
 }
@@ -9,16 +9,17 @@ type PageVersions []PageVersion
 // A PageVersion is a saved version of a page or post. The data may represent a working draft
 // or a compiled page.
 type PageVersion struct {
-	Id          int64                         `msgp:"id"`     // ID of the record in the Blobs table.
-	Data        []byte                        `msgp:"data"`   // The body of the page
-	Static      bool                          `msgp:"static"` // Whether Data contains the final, built static HTML to display.
-	CSS         string                        `msgp:"css"`    // The user's custom code if this is a draft, otherwise all compiled CSS.
-	Styles      map[string]string             `msgp:"styles"` // room-type key-value style pairs
-	FeaturedImg string                        `msgp:"img"`
-	Scripts     map[string]PageResourceConfig `msgp:"scripts"`
-	Stylesheets map[string]PageResourceConfig `msgp:"stylesheets"`
-	Meta        map[string]string             `msgp:"meta"` // various additional settings
-	Timestamp   time.Time                     `msgp:"ts"`
+	Id          int64                         `json:"id"`     // ID of the record in the Blobs table.
+	Data        []byte                        `json:"data"`   // The body of the page
+	Static      bool                          `json:"static"` // Whether Data contains the final, built static HTML to display.
+	CSS         string                        `json:"css"`    // The user's custom code if this is a draft, otherwise all compiled CSS.
+	Styles      map[string]string             `json:"styles"` // room-type key-value style pairs
+	FeaturedImg string                        `json:"img"`
+	Scripts     map[string]PageResourceConfig `json:"scripts"`
+	Stylesheets map[string]PageResourceConfig `json:"stylesheets"`
+	Meta        map[string]string             `json:"meta"`  // various additional settings
+	Saver       int64                         `json:"saver"` // ID of the user who saved the version; 0 if unknown
+	Timestamp   time.Time                     `json:"ts"`
 }
 
 // Len returns the number of elements in the slice.
@@ -40,6 +41,6 @@ func (pv PageVersions) Swap(i, j int) {
 
 // PageResourceConfig is a configuration of a static resource that should be included with a page.
 type PageResourceConfig struct {
-	Src  string `msgp:"src"`  // A complete URL to the resource's location.
-	Head bool   `msgp:"head"` // Whether the resource should be linked to in the head of the page.
+	Src  string `json:"src"`  // A complete URL to the resource's location.
+	Head bool   `json:"head"` // Whether the resource should be linked to in the head of the page.
 }
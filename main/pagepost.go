@@ -1,17 +1,21 @@
 package main
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
 	"github.com/dchenk/mazewire/pkg/data"
 	"github.com/dchenk/mazewire/pkg/log"
 	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/route"
 	"github.com/dchenk/mazewire/pkg/util"
 )
 
@@ -23,23 +27,25 @@ type ReqPagepostList struct {
 	Trashed bool   `json:"trashed"` // whether to get trashed items, otherwise defaults to getting "published", "draft", and "unsaved" items
 }
 
-// authorized checks just if the user is at least an author on the current site. A further check needs to ensure
+// authorized checks just if the user may edit pages on the current site. A further check needs to ensure
 // that the user can make changes to non-current sites.
-func (*ReqPagepostList) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
-	return roles.RoleAtLeast(u.Role, roles.Role_AUTHOR)
+func (*ReqPagepostList) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapEditPages)
 }
 
 // List either pages or posts (for the admin area), paged by 20 results.
 func (req *ReqPagepostList) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	role := u.Role
 	if req.Site == 0 {
 		req.Site = s.Id // Site defaults to the current host.
 	} else {
-		role, err := users.SiteRole(u.Id, req.Site)
+		var err error
+		role, err = siteRole(r, u.Id, req.Site)
 		if err != nil {
 			log.Err(r, "could not get logged in site role for user", err)
 			return errProcessing()
 		}
-		if !roles.RoleAtLeast(role, roles.Role_AUTHOR) {
+		if !roleCan(r, req.Site, role, roles.CapEditPages) {
 			return errLowPrivileges()
 		}
 	}
@@ -55,10 +61,10 @@ func (req *ReqPagepostList) handle(r *http.Request, s *data.Site, u *data.User)
 		statuses = []string{"published", "draft", "unsaved"}
 	}
 
-	// The authorCheck variable is 0 only if the user making this request has higher than author privileges,
+	// The authorCheck variable is 0 only if the user making this request may edit the pages of others,
 	// meaning that all pages/posts will be retrieved.
 	var authorCheck int64
-	if u.Role == roles.Role_AUTHOR {
+	if !roleCan(r, req.Site, role, roles.CapEditOthersPages) {
 		authorCheck = u.Id
 	}
 
@@ -151,9 +157,9 @@ type ReqPagepostCreate struct {
 	Title  string `json:"title"`   // title of the page or post, maximum 255 characters
 }
 
-// authorized checks just if the user has at least author role on the current site.
-func (req *ReqPagepostCreate) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
-	return roles.RoleAtLeast(u.Role, roles.Role_AUTHOR)
+// authorized checks just if the user may edit pages on the current site.
+func (req *ReqPagepostCreate) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapEditPages)
 }
 
 // handle creates either a page or a post.
@@ -162,13 +168,8 @@ func (req *ReqPagepostCreate) handle(r *http.Request, s *data.Site, u *data.User
 	if req.Site == 0 {
 		req.Site = s.Id // Site defaults to the current host.
 	} else {
-		role, err := data.Conn.SiteRole(req.Site)
-		if err != nil {
-			log.Err(r, "could not get logged in site role for user", err)
-			return errProcessing()
-		}
-		if !roles.RoleAtLeast(role, roles.Role_AUTHOR) {
-			return errLowPrivileges()
+		if resp := userCan(r, u.Id, req.Site, roles.CapEditPages); resp != nil {
+			return resp
 		}
 	}
 
@@ -238,9 +239,9 @@ type ReqPagepostMakeDynElem struct {
 	// TODO
 }
 
-// authorized says just if the user has at least author role on the current site.
+// authorized says just if the user may edit pages on the current site.
 func (req *ReqPagepostMakeDynElem) authorized(r *http.Request, s *data.Site, u *data.User) bool {
-	return roles.RoleAtLeast(u.Role, roles.Role_AUTHOR)
+	return can(r, s, u, roles.CapEditPages)
 }
 
 func (req *ReqPagepostMakeDynElem) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
@@ -278,13 +279,13 @@ func updateContentMeta(r *http.Request, u *data.User, s *data.Site, existing *da
 			case "author":
 				uIdStr := strconv.FormatInt(u.Id, 10) // string ID of current user
 				if uIdStr == val { // Check if the new author is the user making the API request.
-					// We already checked that the current user has at least author role on the site; just check
+					// We already checked that the current user may edit pages on the site; just check
 					// if the current user is already the author of the page (to avoid extra work for the DB).
 					if existing.Author == u.Id {
 						includeCol = false
 					}
 				} else {
-					// Check if the user being given authorship has at least "author" role on the site being edited now.
+					// Check if the user being given authorship may edit pages on the site being edited now.
 					parsedAuthorID, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
 					if err != nil {
 						log.Err(r, fmt.Sprintf("could not extract new author ID from string %q", val), err)
@@ -297,11 +298,11 @@ func updateContentMeta(r *http.Request, u *data.User, s *data.Site, existing *da
 						errs <- errProcessingMsg
 						return
 					}
-					// Check if the person who is supposed to be the new author has at least "author" role on the site.
-					if roles.RoleAtLeast(newAuthor.Role, roles.Role_AUTHOR) {
+					// Check if the person who is supposed to be the new author may edit pages on the site.
+					if roleCan(r, s.Id, newAuthor.Role, roles.CapEditPages) {
 						includeCol = true
 					} else {
-						errs <- "The new author must have at least 'author' role on this site."
+						errs <- "The new author must be able to edit pages on this site."
 						return
 					}
 				}
@@ -353,3 +354,107 @@ type PagepostListing struct {
 	*data.Content `json:"content"`
 	Children      []data.Content `json:"children"`
 }
+
+// ReqContentGet: GET content/{id}
+type ReqContentGet struct{}
+
+// authorized checks just if the user may edit pages on the current site. Users who may not edit the
+// pages of others may view only their own content, which is checked by the handler.
+func (*ReqContentGet) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapEditPages)
+}
+
+// handle responds with the content item identified in the path.
+func (*ReqContentGet) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	id, err := strconv.ParseInt(route.Param(r, "id"), 10, 64)
+	if err != nil {
+		return APIResponseErr("The content ID is not valid.")
+	}
+	c, err := data.Conn.ContentByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return APIResponseErr("The content does not exist.")
+		}
+		log.Err(r, "could not get content by ID", err)
+		return errProcessing()
+	}
+	if c.Site != s.Id {
+		return APIResponseErr("The content does not exist.")
+	}
+	if c.Author != u.Id && !can(r, s, u, roles.CapEditOthersPages) {
+		return errLowPrivileges()
+	}
+	return &APIResponse{Body: c}
+}
+
+// ReqContentList: GET content
+type ReqContentList struct {
+	Type     string   `json:"type"`     // either "page" or "post"; defaults to "page"
+	Statuses []string `json:"statuses"` // if not empty, only the content with one of these statuses is listed
+	Parents  []int64  `json:"parents"`  // if not empty, only the content with one of these parents is listed
+	Offset   uint64   `json:"offset"`   // the pagination offset; defaults to 0
+}
+
+// decodeQuery reads the "type", "status", "parent", and "offset" query parameters. The "status" and
+// "parent" parameters may be repeated.
+func (req *ReqContentList) decodeQuery(q url.Values) error {
+	req.Type = q.Get("type")
+	req.Statuses = q["status"]
+	for _, p := range q["parent"] {
+		parent, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return err
+		}
+		req.Parents = append(req.Parents, parent)
+	}
+	if o := q.Get("offset"); o != "" {
+		var err error
+		if req.Offset, err = strconv.ParseUint(o, 10, 64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorized checks just if the user may edit pages on the current site. Users who may not edit the
+// pages of others see only their own content.
+func (*ReqContentList) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapEditPages)
+}
+
+// handle lists the content items of the current site, paged by 20 results.
+func (req *ReqContentList) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	if req.Type == "" {
+		req.Type = "page"
+	}
+	for _, st := range req.Statuses {
+		if !contentStatuses[st] {
+			return APIResponseErr(fmt.Sprintf("The status %q is not valid.", st))
+		}
+	}
+
+	// The authorCheck variable is 0 only if the user may see the content of all authors.
+	var authorCheck int64
+	if !can(r, s, u, roles.CapEditOthersPages) {
+		authorCheck = u.Id
+	}
+
+	list, err := data.Conn.ContentsList(s.Id, req.Type, req.Parents, req.Statuses, authorCheck, req.Offset)
+	if err != nil {
+		log.Err(r, "error with content ContentsList query", err)
+		return errProcessing()
+	}
+	resp := &apiv1.ListContentResponse{Content: make([]*data.Content, len(list))}
+	for i := range list {
+		resp.Content[i] = &list[i]
+	}
+	return &APIResponse{Body: resp}
+}
+
+// contentStatuses is the set of the valid statuses of content.
+var contentStatuses = map[string]bool{
+	"draft":     true,
+	"published": true,
+	"unsaved":   true,
+	"trashed":   true,
+}
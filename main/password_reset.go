@@ -0,0 +1,152 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/util"
+)
+
+const (
+	passwordResetTTL      = time.Hour
+	passwordResetValidFor = "1 hour" // passwordResetTTL, as written in the emails
+)
+
+// optPasswordResetPage is the option giving the slug of the page of a site at which users choose a new
+// password with the token sent to them. The page must make the request to confirm the reset.
+const optPasswordResetPage = "password-reset-page-slug"
+
+const errUserTokenInvalid = "The link you followed is invalid or has expired."
+
+// passwordResetRateLimit limits how often each IP address may ask for password reset emails.
+var passwordResetRateLimit = rate_limit.PerMinute(2, 5)
+
+// ReqPasswordReset: POST password-reset
+// A user who forgot their password asks for an email with a link to reset it. So that the request
+// does not reveal which email addresses belong to users, the response is the same whether or not an
+// email is sent. An email is sent only to a user who has a role on the site.
+type ReqPasswordReset struct {
+	Email string `json:"email"`
+}
+
+// authorized returns true for password reset requests.
+func (*ReqPasswordReset) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+// rateLimit limits how often each IP address may ask for password reset emails.
+func (*ReqPasswordReset) rateLimit() rate_limit.Bucket {
+	return passwordResetRateLimit
+}
+
+func (req *ReqPasswordReset) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	addr := strings.TrimSpace(req.Email)
+	if !util.ValidEmail(addr) {
+		return APIResponseErr("The email you entered seems to be invalid.")
+	}
+	ok := &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+
+	user, err := data.Conn.UserSiteInfoByEmail(s, addr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ok
+		}
+		log.Err(r, "could not get user by email for password reset", err)
+		return errProcessing()
+	}
+	if user.Role == roles.Role_NONE {
+		return ok
+	}
+
+	token, err := createUserToken(tokenPasswordReset, user.Id, s.Id, passwordResetTTL)
+	if err != nil {
+		log.Err(r, "could not create password reset token", err)
+		return errProcessing()
+	}
+	err = sendUserEmail(r, s, user, emailPasswordReset, emailData{
+		Link:     userTokenLink(r, s, optPasswordResetPage, "password-reset", token),
+		ValidFor: passwordResetValidFor,
+	})
+	if err != nil {
+		log.Err(r, "could not send password reset email", err)
+		return errProcessing()
+	}
+	return ok
+}
+
+// ReqPasswordResetConfirm: PUT password-reset
+// The user sets a new password with the token sent by email. All of the user's sessions on all sites
+// are ended and all of the API tokens that the user created are deleted, so the login cookies and
+// tokens issued before are no longer valid.
+type ReqPasswordResetConfirm struct {
+	Token string `json:"token"`
+	Pass  string `json:"pass"`
+}
+
+// authorized returns true; the token identifies the user.
+func (*ReqPasswordResetConfirm) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+// rateLimit limits how often each IP address may try tokens.
+func (*ReqPasswordResetConfirm) rateLimit() rate_limit.Bucket {
+	return loginRateLimit
+}
+
+func (req *ReqPasswordResetConfirm) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	if util.IsAnyStringBlank(req.Token, req.Pass) {
+		return APIResponseErr(errIncompleteForm)
+	}
+	if len(req.Pass) < minPasswordLength {
+		return APIResponseErr(errPasswordTooShort)
+	}
+	// Hash the password before the token is used up so that a failure does not waste the token.
+	passHash, err := hashPassword(req.Pass)
+	if err != nil {
+		log.Err(r, "error hashing user password", err)
+		return errProcessing()
+	}
+
+	userID, siteID, ok, err := consumeUserToken(tokenPasswordReset, req.Token)
+	if err != nil {
+		log.Err(r, "could not check password reset token", err)
+		return errProcessing()
+	}
+	if !ok || siteID != s.Id {
+		return APIResponseErr(errUserTokenInvalid)
+	}
+	if pending, err := deletionPending(userID); err != nil {
+		log.Err(r, "could not check for pending user deletion", err)
+		return errProcessing()
+	} else if pending {
+		return APIResponseErr(errUserTokenInvalid)
+	}
+
+	if err = data.Conn.UserPasswordUpdate(userID, passHash); err != nil {
+		log.Err(r, "could not update user password", err)
+		return errProcessing()
+	}
+	if _, err = data.Conn.SessionsDeleteByUser(userID, 0, ""); err != nil {
+		log.Err(r, "could not delete sessions after password reset", err)
+	}
+	// Whoever took over the account may have created API tokens, which do not depend on the password.
+	if _, err = data.Conn.APITokensDeleteByUser(userID); err != nil {
+		log.Err(r, "could not delete API tokens after password reset", err)
+	}
+	// Following the link proves that the user controls the email address, and the account is no longer
+	// locked out after the failed logins that may have led to the reset.
+	if _, err = data.Conn.UserMetaDelete(userID, emailUnverifiedKey); err != nil {
+		log.Err(r, "could not mark email address verified", err)
+	}
+	loginSucceeded(r, s, userID)
+
+	return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+}
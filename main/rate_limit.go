@@ -0,0 +1,201 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+)
+
+// A rateLimited APIHandler has the requests to its route limited with a token bucket for each client
+// IP address. The buckets are kept in the database, so the limit is shared by all the instances.
+type rateLimited interface {
+	rateLimit() rate_limit.Bucket
+}
+
+// loginRateLimit limits how often each IP address may try to log in, in addition to the lockouts that
+// follow failed logins.
+var loginRateLimit = rate_limit.PerMinute(10, 10)
+
+// allowRequest says if the request to the route may be handled by h, taking a token from the bucket of
+// the client if h is rateLimited. If the bucket cannot be checked, the request is allowed.
+func allowRequest(r *http.Request, rt *apiRoute, h APIHandler) bool {
+	rl, ok := h.(rateLimited)
+	if !ok {
+		return true
+	}
+	k := "api:" + rt.method + " " + rt.path + " " + rate_limit.IPKey(rate_limit.RemoteIP(r))
+	ok, err := data.Conn.RateLimitTake(k, rl.rateLimit())
+	if err != nil {
+		log.Err(r, "could not take a rate limit token", err)
+		return true
+	}
+	return ok
+}
+
+// retryAfter returns the value of the Retry-After header for a request rejected by the limit of h, in
+// seconds: the time it takes for the bucket to get a token.
+func retryAfter(h APIHandler) string {
+	secs := 1.0
+	if rl, ok := h.(rateLimited); ok && rl.rateLimit().PerSecond > 0 {
+		secs = math.Ceil(1 / rl.rateLimit().PerSecond)
+	}
+	return strconv.FormatFloat(secs, 'f', 0, 64)
+}
+
+// Options with which the lockouts after failed logins are configured on a site. A threshold of 0
+// disables the lockout, and the durations are formatted like "90s" or "1h".
+const (
+	optLoginMaxFailures   = "login-max-failures"    // the consecutive failed logins to an account before it is locked out
+	optLoginIPMaxFailures = "login-ip-max-failures" // the consecutive failed logins from an IP address before it is locked out
+	optLoginLockout       = "login-lockout"         // the duration of the first lockout, doubled with each further failure
+	optLoginLockoutMax    = "login-lockout-max"     // the longest duration of a lockout
+)
+
+// loginFailuresTTL is how long after the last failed login the consecutive failures are forgotten.
+const loginFailuresTTL = time.Hour * 24
+
+// loginLockoutMsgKey is the key of the user messages recording the lockouts of accounts.
+const loginLockoutMsgKey = "login-lockout"
+
+// loginLockouts are the lockout policies of a site for accounts and for IP addresses.
+type loginLockouts struct {
+	user, ip rate_limit.Lockout
+}
+
+// defaultLoginLockouts are the lockout policies of sites that do not set the options.
+var defaultLoginLockouts = loginLockouts{
+	user: rate_limit.Lockout{Threshold: 5, Base: time.Minute, Max: time.Hour * 24},
+	ip:   rate_limit.Lockout{Threshold: 20, Base: time.Minute, Max: time.Hour * 24},
+}
+
+// siteLoginLockouts returns the lockout policies of the site, with the defaults for the options that
+// are not set or not valid.
+func siteLoginLockouts(r *http.Request, s *data.Site) loginLockouts {
+	l := defaultLoginLockouts
+	opts, err := data.Conn.OptionsKeyInMappedStr(s.Id,
+		[]string{optLoginMaxFailures, optLoginIPMaxFailures, optLoginLockout, optLoginLockoutMax})
+	if err != nil {
+		log.Err(r, "could not get the login lockout options", err)
+		return l
+	}
+	for k, v := range opts {
+		var err error
+		switch k {
+		case optLoginMaxFailures, optLoginIPMaxFailures:
+			var n uint64
+			if n, err = strconv.ParseUint(v, 10, 32); err == nil {
+				if k == optLoginMaxFailures {
+					l.user.Threshold = uint32(n)
+				} else {
+					l.ip.Threshold = uint32(n)
+				}
+			}
+		case optLoginLockout, optLoginLockoutMax:
+			var d time.Duration
+			if d, err = time.ParseDuration(v); err == nil {
+				if k == optLoginLockout {
+					l.user.Base, l.ip.Base = d, d
+				} else {
+					l.user.Max, l.ip.Max = d, d
+				}
+			}
+		}
+		if err != nil {
+			log.Err(r, fmt.Sprintf("invalid value of option %q: %q", k, v), err)
+		}
+	}
+	return l
+}
+
+// loginLockedOut returns an APIResponse with the 429 error if the logins on the site are locked out for
+// the throttle with the key k, or else nil. If the throttle cannot be checked, nil is returned.
+func loginLockedOut(r *http.Request, s *data.Site, k string) *APIResponse {
+	lt, err := data.Conn.LoginThrottle(s.Id, k)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Err(r, "could not get login throttle", err)
+		}
+		return nil
+	}
+	until, err := lt.LockedUntil.ToTime()
+	if err != nil {
+		log.Err(r, "invalid login throttle lockout time", err)
+		return nil
+	}
+	wait := time.Until(until)
+	if wait <= 0 {
+		return nil
+	}
+	return &APIResponse{
+		Status: http.StatusTooManyRequests,
+		err:    fmt.Sprintf("Too many failed logins. Try again in %d minutes.", int(math.Ceil(wait.Minutes()))),
+	}
+}
+
+// loginFailed counts a failed login on the site from the IP address of the request and, if userID is not
+// 0, to the account of the user, and locks out the logins as the lockout policies require. The lockouts
+// of accounts are recorded as messages to the users.
+func loginFailed(r *http.Request, s *data.Site, l loginLockouts, userID int64) {
+	countLoginFailure(r, s, rate_limit.IPKey(rate_limit.RemoteIP(r)), l.ip)
+	if userID == 0 {
+		return
+	}
+	if failures, d := countLoginFailure(r, s, rate_limit.UserKey(userID), l.user); d > 0 {
+		saveUserMessage(r, userID, loginLockoutMsgKey, fmt.Sprintf(
+			"After %d failed logins, logging in to %s was locked out for %v.", failures, s.Domain, d))
+	}
+}
+
+// countLoginFailure counts a failed login for the throttle with the key k and, if the lockout policy
+// requires, locks out the logins. Returned are the number of consecutive failures and the duration of
+// the lockout, which is 0 if the logins are not locked out.
+func countLoginFailure(r *http.Request, s *data.Site, k string, l rate_limit.Lockout) (int64, time.Duration) {
+	failures, err := data.Conn.LoginThrottleFail(s.Id, k, time.Now().Add(-loginFailuresTTL))
+	if err != nil {
+		log.Err(r, "could not count failed login", err)
+		return 0, 0
+	}
+	n := uint32(math.MaxUint32)
+	if failures < math.MaxUint32 {
+		n = uint32(failures)
+	}
+	d := l.Duration(n)
+	if d == 0 {
+		return failures, 0
+	}
+	if err = data.Conn.LoginThrottleLock(s.Id, k, time.Now().Add(d)); err != nil {
+		log.Err(r, "could not lock out logins", err)
+		return failures, 0
+	}
+	return failures, d
+}
+
+// loginSucceeded forgets the failed logins on the site to the account of the user. The failed logins
+// from the IP address of the request are kept until they expire, since otherwise whoever is guessing the
+// passwords of others could log in to an account of their own to be allowed to keep guessing.
+func loginSucceeded(r *http.Request, s *data.Site, userID int64) {
+	if err := data.Conn.LoginThrottleDelete(s.Id, rate_limit.UserKey(userID)); err != nil {
+		log.Err(r, "could not delete login throttle", err)
+	}
+}
+
+// purgeRateLimits deletes every hour the rate limit buckets and the login throttles that have not been
+// used for a day. Every instance runs this function.
+func purgeRateLimits() {
+	for range time.Tick(time.Hour) {
+		before := time.Now().Add(-loginFailuresTTL)
+		if _, err := data.Conn.LoginThrottlesPurge(before); err != nil {
+			log.Err(nil, "could not purge login throttles", err)
+		}
+		if _, err := data.Conn.RateLimitsPurge(before); err != nil {
+			log.Err(nil, "could not purge rate limits", err)
+		}
+	}
+}
@@ -39,7 +39,7 @@ func logoutRedirect(r *http.Request, s *data.Site, u *data.User) (redirectPath s
 }
 
 // registerRedirect returns a complete redirect path where the user should go to register under the current site.
-// TODO: offer redirection to a page for user to add themselves to the site (already a Mazewire user)
+// Users who already have an account join a site by accepting an invitation (see ReqInvitationAccept).
 func registerRedirect(r *http.Request, s *data.Site, u *data.User) (redirectPath string) {
 	rp, err := s.OptionV("register-page-slug")
 	if err != nil && err != sql.ErrNoRows {
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/route"
+)
+
+// siteRole returns the role of the user on the site with the ID given, which need not be the current
+// site. The roles of each user are loaded at most once during a request.
+func siteRole(r *http.Request, userID, siteID int64) (roles.Role, error) {
+	return roles.FromContext(r.Context()).SiteRole(userID, siteID)
+}
+
+// isSuper says if the user is a super user.
+func isSuper(r *http.Request, userID int64) bool {
+	return roles.FromContext(r.Context()).IsSuper(userID)
+}
+
+// memberships returns the roles of the user on all of the sites where the user has a role.
+func memberships(r *http.Request, userID int64) (*roles.Memberships, error) {
+	return roles.FromContext(r.Context()).Memberships(userID)
+}
+
+// can says if the user has the capability on the current site.
+func can(r *http.Request, s *data.Site, u *data.User, c roles.Capability) bool {
+	return u.Id != 0 && roleCan(r, s.Id, u.Role, c)
+}
+
+// roleCan says if a user with the role on the site with the ID given has the capability. If the
+// capabilities of a custom role cannot be retrieved, the error is logged and the role is taken not to
+// have the capability.
+func roleCan(r *http.Request, siteID int64, role roles.Role, c roles.Capability) bool {
+	ok, err := roles.Can(siteID, role, c)
+	if err != nil {
+		log.Err(r, "could not get capabilities of role", err)
+	}
+	return ok
+}
+
+// userCan checks if the user has the capability on the site with the ID given, which need not be the
+// current site. If the user does not, the error response to give is returned.
+func userCan(r *http.Request, userID, siteID int64, c roles.Capability) *APIResponse {
+	role, err := siteRole(r, userID, siteID)
+	if err != nil {
+		log.Err(r, "could not get logged in site role for user", err)
+		return errProcessing()
+	}
+	if !roleCan(r, siteID, role, c) {
+		return errLowPrivileges()
+	}
+	return nil
+}
+
+// canGrant checks if the user may give other users the role on the current site. If the user may not,
+// the error response to give is returned.
+func canGrant(r *http.Request, s *data.Site, u *data.User, role roles.Role) *APIResponse {
+	ok, err := roles.CanGrant(s.Id, u.Role, role)
+	if err != nil {
+		log.Err(r, "could not get capabilities of role", err)
+		return errProcessing()
+	}
+	if !ok {
+		return APIResponseErr("You may not give a role with capabilities that you do not have.")
+	}
+	return nil
+}
+
+// parseSiteRole returns the role of the current site with the name given, built-in or custom, which
+// a user may be given: neither NONE nor SUPER. If there is no such role, the error response to give is
+// returned.
+func parseSiteRole(r *http.Request, s *data.Site, name string) (roles.Role, *APIResponse) {
+	role, ok, err := roles.ParseSiteRole(s.Id, name)
+	if err != nil {
+		log.Err(r, "could not get custom roles", err)
+		return roles.Role_NONE, errProcessing()
+	}
+	if !ok || role == roles.Role_NONE || role == roles.Role_SUPER {
+		return roles.Role_NONE, APIResponseErr("The role \"" + name + "\" is not valid.")
+	}
+	return role, nil
+}
+
+// builtinSiteRoles are the built-in roles that members of a site may have, from the lowest.
+var builtinSiteRoles = []roles.Role{
+	roles.Role_SUBSCRIBER, roles.Role_AUTHOR, roles.Role_EDITOR, roles.Role_ADMIN, roles.Role_OWNER,
+}
+
+// maxRoleNameLen is the maximum length of the name of a custom role.
+const maxRoleNameLen = 50
+
+// roleInfo describes the role with its capabilities.
+func roleInfo(siteID int64, role roles.Role, name string) (*apiv1.RoleInfo, error) {
+	cs, err := roles.RoleCapabilities(siteID, role)
+	if err != nil {
+		return nil, err
+	}
+	info := &apiv1.RoleInfo{Id: int64(role), Name: name, Capabilities: make([]string, len(cs)),
+		Custom: roles.IsCustom(role)}
+	for i, c := range cs {
+		info.Capabilities[i] = string(c)
+	}
+	sort.Strings(info.Capabilities)
+	return info, nil
+}
+
+// ReqRoleList: GET site/roles
+// The roles that members of the site may have are listed with their capabilities, along with all of
+// the capabilities that custom roles may be given.
+type ReqRoleList struct{}
+
+// authorized returns true; the route requires the user to be able to manage users.
+func (*ReqRoleList) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqRoleList) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	crs, err := data.Conn.CustomRolesBySite(s.Id)
+	if err != nil {
+		log.Err(r, "could not get custom roles", err)
+		return errProcessing()
+	}
+	resp := &apiv1.ListRolesResponse{Roles: make([]*apiv1.RoleInfo, 0, len(builtinSiteRoles)+len(crs))}
+	for _, role := range builtinSiteRoles {
+		info, _ := roleInfo(s.Id, role, role.String()) // Built-in roles are resolved without the database.
+		resp.Roles = append(resp.Roles, info)
+	}
+	for i := range crs {
+		info, err := roleInfo(s.Id, roles.Role(crs[i].Id), crs[i].Name)
+		if err != nil {
+			log.Err(r, "could not get capabilities of role", err)
+			return errProcessing()
+		}
+		resp.Roles = append(resp.Roles, info)
+	}
+	for _, c := range roles.Capabilities() {
+		resp.Capabilities = append(resp.Capabilities, string(c))
+	}
+	return &APIResponse{Body: resp}
+}
+
+// customRoleFields checks the name and the capabilities given for a custom role of the site, other than
+// the role with the ID except (or 0 for a new role). The name must be unique on the site and not be
+// the name of a built-in role, and the user must have each capability. Returned are the trimmed name
+// and the capabilities in the form in which they are stored.
+func customRoleFields(r *http.Request, s *data.Site, u *data.User, name string, caps []string,
+	except int64) (string, string, *APIResponse) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxRoleNameLen {
+		return "", "", APIResponseErr(fmt.Sprintf("The name of a role must have 1 to %d characters.", maxRoleNameLen))
+	}
+	if _, ok := roles.ValidRoleStr(strings.ToUpper(name)); ok {
+		return "", "", APIResponseErr("The name of a role may not be the name of a built-in role.")
+	}
+	if _, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return "", "", APIResponseErr("The name of a role may not be a number.")
+	}
+	crs, err := data.Conn.CustomRolesBySite(s.Id)
+	if err != nil {
+		log.Err(r, "could not get custom roles", err)
+		return "", "", errProcessing()
+	}
+	for i := range crs {
+		if crs[i].Id != except && strings.EqualFold(crs[i].Name, name) {
+			return "", "", APIResponseErr(fmt.Sprintf("There is already a role named %q.", crs[i].Name))
+		}
+	}
+
+	// Every custom role can read, so the capability is not stored.
+	set := make(map[string]bool, len(caps))
+	for _, c := range caps {
+		c = strings.TrimSpace(c)
+		if c == "" || set[c] || roles.Capability(c) == roles.CapRead {
+			continue
+		}
+		if !roles.ValidCapability(roles.Capability(c)) {
+			return "", "", APIResponseErr(fmt.Sprintf("The capability %q is not valid.", c))
+		}
+		if !can(r, s, u, roles.Capability(c)) {
+			return "", "", APIResponseErr(fmt.Sprintf("You may not give a role the capability %q, which you do not have.", c))
+		}
+		set[c] = true
+	}
+	stored := make([]string, 0, len(set))
+	for c := range set {
+		stored = append(stored, c)
+	}
+	sort.Strings(stored)
+	return name, strings.Join(stored, " "), nil
+}
+
+// customRoleParam returns the custom role of the site identified in the path.
+func customRoleParam(r *http.Request, s *data.Site) (*data.CustomRole, *APIResponse) {
+	id, err := strconv.ParseInt(route.Param(r, "id"), 10, 64)
+	if err != nil || !roles.IsCustom(roles.Role(id)) {
+		return nil, errNotFound("The role does not exist.")
+	}
+	cr, err := data.Conn.CustomRole(s.Id, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errNotFound("The role does not exist.")
+		}
+		log.Err(r, "could not get custom role", err)
+		return nil, errProcessing()
+	}
+	return cr, nil
+}
+
+// ReqRoleCreate: POST site/roles
+// A custom role is created for the site with a name and capabilities. The response is the RoleInfo of
+// the new role.
+type ReqRoleCreate struct {
+	Name         string   `json:"name"`
+	Capabilities []string `json:"capabilities"` // such as "edit_pages"
+}
+
+// authorized returns true; the route requires the user to be able to manage roles.
+func (*ReqRoleCreate) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqRoleCreate) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	name, caps, errResp := customRoleFields(r, s, u, req.Name, req.Capabilities, 0)
+	if errResp != nil {
+		return errResp
+	}
+	id, err := data.Conn.CustomRoleInsert(&data.CustomRole{Site: s.Id, Name: name, Capabilities: caps})
+	if err != nil {
+		log.Err(r, "could not insert custom role", err)
+		return errProcessing()
+	}
+	info, err := roleInfo(s.Id, roles.Role(id), name)
+	if err != nil {
+		log.Err(r, "could not get capabilities of role", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: info}
+}
+
+// ReqRoleUpdate: PATCH site/roles/{id}
+// The name and the capabilities of a custom role are replaced. The members who have the role have the
+// new capabilities right away. The response is the RoleInfo of the role.
+type ReqRoleUpdate struct {
+	Name         string   `json:"name"`
+	Capabilities []string `json:"capabilities"` // such as "edit_pages"
+}
+
+// authorized returns true; the route requires the user to be able to manage roles.
+func (*ReqRoleUpdate) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqRoleUpdate) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	cr, errResp := customRoleParam(r, s)
+	if errResp != nil {
+		return errResp
+	}
+	if cr.Name, cr.Capabilities, errResp = customRoleFields(r, s, u, req.Name, req.Capabilities, cr.Id); errResp != nil {
+		return errResp
+	}
+	n, err := data.Conn.CustomRoleUpdate(cr)
+	if err != nil {
+		log.Err(r, "could not update custom role", err)
+		return errProcessing()
+	}
+	if n == 0 {
+		return errNotFound("The role does not exist.")
+	}
+	info, err := roleInfo(s.Id, roles.Role(cr.Id), cr.Name)
+	if err != nil {
+		log.Err(r, "could not get capabilities of role", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: info}
+}
+
+// ReqRoleDelete: DELETE site/roles/{id}
+// A custom role is deleted. A role cannot be deleted while members of the site have it.
+type ReqRoleDelete struct{}
+
+// authorized returns true; the route requires the user to be able to manage roles.
+func (*ReqRoleDelete) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqRoleDelete) handle(r *http.Request, s *data.Site, _ *data.User) *APIResponse {
+	cr, errResp := customRoleParam(r, s)
+	if errResp != nil {
+		return errResp
+	}
+	// The role is deleted only if no member has it, checked in the same statement so that no member can be
+	// given the role as it is deleted.
+	n, err := data.Conn.CustomRoleDelete(s.Id, cr.Id, roles.SiteRoleKey(s.Id), []byte(strconv.FormatInt(cr.Id, 10)))
+	if err != nil {
+		log.Err(r, "could not delete custom role", err)
+		return errProcessing()
+	}
+	if n == 0 {
+		members, err := roles.SiteRoleCount(s.Id, roles.Role(cr.Id))
+		if err != nil {
+			log.Err(r, "could not count members with role", err)
+			return errProcessing()
+		}
+		if members > 0 {
+			return APIResponseErr(fmt.Sprintf("The role cannot be deleted while %d members of the site have it.", members))
+		}
+	}
+	return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+	"github.com/dchenk/mazewire/pkg/route"
+	timeproto "github.com/dchenk/mazewire/pkg/types/time"
+)
+
+// Each login creates a session, stored in the database, which the login token identifies. A session
+// expires after sessionIdleTTL without being used, and each use extends it, but the token itself is
+// valid for at most loginTokenTTL. Deleting a session logs the user out of it. A session started by
+// single sign-on (see sso.go) has the session on the main site as its parent, and logging out of any of
+// the sessions of such a tree ends them all.
+const (
+	sessionIdleTTL       = time.Hour * 4
+	sessionTouchInterval = time.Minute // how often at most the last use of a session is recorded
+)
+
+// maxSessionUserAgent is how much of the user agent of a login is stored with the session.
+const maxSessionUserAgent = 256
+
+// newSessionID generates a random session ID.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// startSession creates a login session for the user u on the site s, which must be the user that just
+// logged in, and returns a login token identifying the session along with the time the token expires.
+func startSession(r *http.Request, s *data.Site, u *data.User) (string, time.Time, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return insertSession(r, s, u, id, "")
+}
+
+// errAccountClosed indicates that a session cannot be started for a user whose deletion is pending.
+var errAccountClosed = errors.New("the account of the user is closed")
+
+// insertSession creates the login session with the ID given, and with the parent session unless parent
+// is empty, like startSession.
+func insertSession(r *http.Request, s *data.Site, u *data.User, id, parent string) (string, time.Time, error) {
+	// Whatever way the user logged in, no session is started for an account being deleted.
+	if pending, err := deletionPending(u.Id); err != nil || pending {
+		if err == nil {
+			err = errAccountClosed
+		}
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expires, err := timeproto.TimeProto(now.Add(sessionIdleTTL))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	ua := r.UserAgent()
+	if len(ua) > maxSessionUserAgent {
+		ua = ua[:maxSessionUserAgent]
+	}
+	sess := &data.Session{
+		Id:        id,
+		UserId:    u.Id,
+		Site:      s.Id,
+		UserAgent: ua,
+		Ip:        rate_limit.RemoteIP(r),
+		Expires:   expires,
+		Parent:    parent,
+	}
+	if err = data.Conn.SessionInsert(sess); err != nil {
+		return "", time.Time{}, err
+	}
+	tokenExpires := now.Add(loginTokenTTL)
+	return createLoginToken(r, u.Id, s.Id, id, tokenExpires), tokenExpires, nil
+}
+
+// checkSession says if the session with the ID given is a live session of the user on the site. The
+// session is extended by sessionIdleTTL.
+func checkSession(r *http.Request, id string, userID, siteID int64) bool {
+	sess, err := data.Conn.Session(id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Err(r, "could not get session", err)
+		}
+		return false
+	}
+	if sess.UserId != userID || sess.Site != siteID {
+		return false
+	}
+	now := time.Now()
+	expires, err := sess.Expires.ToTime()
+	if err != nil {
+		log.Err(r, "invalid session expiration time", err)
+		return false
+	}
+	if !now.Before(expires) {
+		return false
+	}
+	if lastSeen, err := sess.LastSeen.ToTime(); err != nil || now.Sub(lastSeen) >= sessionTouchInterval {
+		if err = data.Conn.SessionTouch(id, now.Add(sessionIdleTTL)); err != nil {
+			log.Err(r, "could not extend session", err)
+		}
+	}
+	return true
+}
+
+// sessionIDKey is the context key of the ID of the session of a request.
+type sessionIDKey struct{}
+
+// withSessionID returns the request with the ID of the session of the logged in user.
+func withSessionID(r *http.Request, id string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), sessionIDKey{}, id))
+}
+
+// currentSessionID returns the ID of the session of the logged in user, or the empty string.
+func currentSessionID(r *http.Request) string {
+	id, _ := r.Context().Value(sessionIDKey{}).(string)
+	return id
+}
+
+// endSession deletes the session of the request, if there is one, to log out the user. If the session
+// belongs to a tree of sessions started by single sign-on, the user is logged out of every site of the
+// tree.
+func endSession(r *http.Request) {
+	id := currentSessionID(r)
+	if id == "" {
+		return
+	}
+	sess, err := data.Conn.Session(id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Err(r, "could not get session", err)
+		}
+		return
+	}
+	root := sess.Id
+	if sess.Parent != "" {
+		root = sess.Parent
+	}
+	if _, err = data.Conn.SessionsDeleteTree(root); err != nil {
+		log.Err(r, "could not delete sessions", err)
+	}
+}
+
+// purgeSessions deletes every hour the sessions that have expired. Every instance runs this function.
+func purgeSessions() {
+	for range time.Tick(time.Hour) {
+		if _, err := data.Conn.SessionsPurge(time.Now()); err != nil {
+			log.Err(nil, "could not purge sessions", err)
+		}
+	}
+}
+
+// ReqSessionList: GET sessions
+// The user's sessions on the site are listed.
+type ReqSessionList struct{}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqSessionList) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqSessionList) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	ss, err := data.Conn.SessionsByUser(u.Id, s.Id)
+	if err != nil {
+		log.Err(r, "could not list sessions", err)
+		return errProcessing()
+	}
+	resp := &apiv1.ListSessionsResponse{
+		Sessions: make([]*data.Session, len(ss)),
+		Current:  currentSessionID(r),
+	}
+	for i := range ss {
+		resp.Sessions[i] = &ss[i]
+	}
+	return &APIResponse{Body: resp}
+}
+
+// ReqSessionRevoke: DELETE sessions/{id}
+// One of the user's sessions on the site is ended, along with the sessions on other sites started from
+// it by single sign-on.
+type ReqSessionRevoke struct{}
+
+// authorized returns true; the handler accepts only the user's own sessions.
+func (*ReqSessionRevoke) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqSessionRevoke) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	const notFound = "The session was not found."
+	id := route.Param(r, "id")
+	sess, err := data.Conn.Session(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errNotFound(notFound)
+		}
+		log.Err(r, "could not get session", err)
+		return errProcessing()
+	}
+	if sess.UserId != u.Id || sess.Site != s.Id {
+		return errNotFound(notFound)
+	}
+	if _, err = data.Conn.SessionsDeleteTree(id); err != nil {
+		log.Err(r, "could not delete session", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: &wrappers.BoolValue{Value: true}}
+}
+
+// ReqSessionRevokeOthers: DELETE sessions
+// All of the user's sessions on the site other than the session of the request are ended. The
+// response gives the number of sessions ended.
+type ReqSessionRevokeOthers struct{}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqSessionRevokeOthers) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqSessionRevokeOthers) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	// The sessions are ended one at a time so that the sessions started from them by single sign-on
+	// are ended too.
+	ss, err := data.Conn.SessionsByUser(u.Id, s.Id)
+	if err != nil {
+		log.Err(r, "could not list sessions", err)
+		return errProcessing()
+	}
+	current := currentSessionID(r)
+	var n int64
+	for i := range ss {
+		if ss[i].Id == current {
+			continue
+		}
+		deleted, err := data.Conn.SessionsDeleteTree(ss[i].Id)
+		if err != nil {
+			log.Err(r, "could not delete sessions", err)
+			return errProcessing()
+		}
+		n += deleted
+	}
+	return &APIResponse{Body: &wrappers.Int64Value{Value: n}}
+}
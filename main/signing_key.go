@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/keyring"
+	"github.com/dchenk/mazewire/pkg/log"
+)
+
+// The tokens and cookies signed by the servers, such as the login cookies, are signed with the keys in the
+// signing_keys table (see package keyring): each token names the key that signed it, new tokens are
+// signed with the newest key, and a token is accepted until the key that signed it expires. The keys are
+// generated and retired with the "keys" command of the run tool. Each server loads the keys at startup
+// and reloads them every minute, or sooner when it sees a token naming a key it does not have.
+//
+// If there are no keys at startup, the TOKEN_KEY with which the cluster was set up is saved as the key
+// with the ID keyring.LegacyID, which verifies the tokens signed before keys had IDs, so the users stay
+// logged in until that key is retired.
+
+const (
+	// signingKeysRefresh is how often the keys are reloaded.
+	signingKeysRefresh = time.Minute
+
+	// signingKeysMinReload is how often at most the keys are reloaded for tokens naming unknown keys.
+	signingKeysMinReload = 10 * time.Second
+)
+
+var (
+	signingKeys atomic.Value // *keyring.Ring
+
+	// signingKeysMu guards signingKeysLoaded, the time the keys were last loaded.
+	signingKeysMu     sync.Mutex
+	signingKeysLoaded time.Time
+)
+
+// loadSigningKeys loads the keys from the database, saving the first key if there are none.
+func loadSigningKeys() error {
+	ks, err := data.Conn.SigningKeys()
+	if err != nil {
+		return err
+	}
+	if len(ks) == 0 {
+		first := &data.SigningKey{Id: keyring.LegacyID, Secret: TOKEN_KEY}
+		if len(first.Secret) == 0 {
+			k, err := keyring.GenerateKey()
+			if err != nil {
+				return err
+			}
+			first.Id, first.Secret = k.ID, k.Secret
+		}
+		if err = data.Conn.SigningKeyInsert(first); err != nil {
+			// Another server may have saved its own first key at the same time.
+			log.Err(nil, "could not save the first signing key", err)
+		}
+		if ks, err = data.Conn.SigningKeys(); err != nil {
+			return err
+		}
+	}
+
+	keys := make([]keyring.Key, len(ks))
+	for i := range ks {
+		k := &ks[i]
+		keys[i] = keyring.Key{ID: k.Id, Secret: k.Secret}
+		if keys[i].Created, err = k.Created.ToTime(); err != nil {
+			return err
+		}
+		if keys[i].Expires, err = k.Expires.ToTime(); err != nil {
+			return err
+		}
+	}
+	signingKeys.Store(keyring.New(keys))
+	return nil
+}
+
+// reloadSigningKeys reloads the keys unless they were loaded within the interval given.
+func reloadSigningKeys(interval time.Duration) {
+	signingKeysMu.Lock()
+	defer signingKeysMu.Unlock()
+	if time.Since(signingKeysLoaded) < interval {
+		return
+	}
+	if err := loadSigningKeys(); err != nil {
+		log.Err(nil, "could not load signing keys", err)
+		return
+	}
+	signingKeysLoaded = time.Now()
+}
+
+// refreshSigningKeys reloads the keys periodically.
+func refreshSigningKeys() {
+	for range time.Tick(signingKeysRefresh) {
+		reloadSigningKeys(signingKeysRefresh / 2)
+	}
+}
+
+func signingKeyring() *keyring.Ring {
+	return signingKeys.Load().(*keyring.Ring)
+}
+
+// signToken signs the base64-encoded body of a token for the purpose with the newest key.
+func signToken(purpose, bodyEncoded string) string {
+	token, err := signingKeyring().Sign(purpose, bodyEncoded)
+	if err != nil {
+		// The keys are loaded at startup, and there is always at least one.
+		panic(err)
+	}
+	return token
+}
+
+// verifyToken checks the signature of a token signed by signToken for the purpose and returns the
+// base64-encoded body of the token if the signature is valid.
+func verifyToken(purpose, token string) (bodyEncoded string, ok bool) {
+	ring := signingKeyring()
+	if !ring.Has(keyring.KeyID(token)) {
+		// The key may have been generated after the keys were last loaded.
+		reloadSigningKeys(signingKeysMinReload)
+		ring = signingKeyring()
+	}
+	return ring.Verify(purpose, token)
+}
+
+// The fields of the bodies of tokens are encoded with the functions below: an int64 as eight bytes in
+// big-endian order, and a string as its length, a uvarint, followed by its bytes.
+
+const (
+	tokenIntSize          = 8                     // the size of an encoded int64
+	tokenStringPrefixSize = binary.MaxVarintLen64 // the maximum size of the length of an encoded string
+)
+
+// errTokenBody indicates that the body of a token ends before the field being read.
+var errTokenBody = errors.New("token body is too short")
+
+func appendTokenInt(b []byte, v int64) []byte {
+	return binary.BigEndian.AppendUint64(b, uint64(v))
+}
+
+func appendTokenString(b []byte, s string) []byte {
+	return append(binary.AppendUvarint(b, uint64(len(s))), s...)
+}
+
+// readTokenInt reads an int64 encoded with appendTokenInt and returns the bytes remaining.
+func readTokenInt(b []byte) (int64, []byte, error) {
+	if len(b) < 8 {
+		return 0, b, errTokenBody
+	}
+	return int64(binary.BigEndian.Uint64(b)), b[8:], nil
+}
+
+// readTokenString reads a string encoded with appendTokenString and returns the bytes remaining.
+func readTokenString(b []byte) (string, []byte, error) {
+	n, k := binary.Uvarint(b)
+	if k <= 0 || n > uint64(len(b)-k) {
+		return "", b, errTokenBody
+	}
+	return string(b[k : k+int(n)]), b[k+int(n):], nil
+}
+
+// tokenKey returns the unexpired key that signed the token, or nil.
+func tokenKey(token string) *keyring.Key {
+	return signingKeyring().Key(keyring.KeyID(token))
+}
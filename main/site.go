@@ -4,12 +4,16 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
 	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/domain_verify"
 	"github.com/dchenk/mazewire/pkg/log"
 	"github.com/dchenk/mazewire/pkg/roles"
 	"github.com/dchenk/mazewire/pkg/room"
+	"github.com/dchenk/mazewire/pkg/route"
 	"github.com/dchenk/mazewire/pkg/util"
 )
 
@@ -59,8 +63,18 @@ func (sc *SiteCreate) handle(r *http.Request, s *data.Site, u *data.User) *APIRe
 		return APIResponseErr("A site with this domain already exists")
 	}
 
-	newID, err := data.Conn.InsertSite(sc.Domain, sc.Name)
+	// The site is pending until the user proves control over the domain.
+	token, err := domain_verify.NewToken()
 	if err != nil {
+		log.Err(r, "could not generate domain verification token", err)
+		return errProcessing()
+	}
+
+	newID, err := data.Conn.InsertSite(sc.Domain, sc.Name, token)
+	if err != nil {
+		if data.Conn.ErrIsDupKey(err) {
+			return APIResponseErr("A site with this domain already exists")
+		}
 		log.Err(r, "error inserting site", err)
 		return errProcessing()
 	}
@@ -75,25 +89,29 @@ func (sc *SiteCreate) handle(r *http.Request, s *data.Site, u *data.User) *APIRe
 		resp.warn("We could not create a homepage for you, but you can easily do that yourself.")
 	}
 
-	// respond with the ID of the new site
-	resp.Body = &RespSiteCreate{NewId: newID}
+	// respond with the ID of the new site and how to verify the domain
+	resp.Body = &apiv1.CreateSiteResponse{
+		NewId: newID,
+		Verify: newDomainVerificationStatus(&data.DomainVerification{
+			Domain: sc.Domain,
+			Site:   newID,
+			Token:  token,
+			Status: data.DomainPending,
+		}),
+	}
 	return &resp
 
 }
 
-type RespSiteCreate struct {
-	NewId int64 `msgp:"new_id"`
-}
-
 // SiteChangeHome: POST
 type SiteChangeHome struct {
-	Site        int64  `msgp:"site"`     // the site ID; defaults to current host
-	HomeNewID   uint32 `msgp:"new_id"`   // the ID of the new home page
-	OldHomeSlug string `msgp:"old_slug"` // the new slug of the old home page
+	Site        int64  `json:"site"`     // the site ID; defaults to current host
+	HomeNewID   uint32 `json:"new_id"`   // the ID of the new home page
+	OldHomeSlug string `json:"old_slug"` // the new slug of the old home page
 }
 
 func (req *SiteChangeHome) authorized(r *http.Request, s *data.Site, u *data.User) bool {
-	return roles.RoleAtLeast(u.Role, roles.Role_ADMIN)
+	return can(r, s, u, roles.CapManageSite)
 }
 
 func (req *SiteChangeHome) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
@@ -101,13 +119,8 @@ func (req *SiteChangeHome) handle(r *http.Request, s *data.Site, u *data.User) *
 	if req.Site == 0 {
 		req.Site = s.Id // Site defaults to the current host
 	} else {
-		role, err := roles.SiteRole(u.Id, req.Site)
-		if err != nil {
-			log.Err(r, "could not get logged in site role for user", err)
-			return errProcessing()
-		}
-		if !roles.RoleAtLeast(role, roles.Role_ADMIN) {
-			return errLowPrivileges()
+		if resp := userCan(r, u.Id, req.Site, roles.CapManageSite); resp != nil {
+			return resp
 		}
 	}
 
@@ -118,16 +131,16 @@ func (req *SiteChangeHome) handle(r *http.Request, s *data.Site, u *data.User) *
 }
 
 type RespSiteChangeHome struct {
-	Ok bool `msgp:"ok"`
+	Ok bool `json:"ok"`
 }
 
 // SiteDelete: DELETE site
 type SiteDelete struct {
-	SiteID int64 `msgp:"site_id"`
+	SiteID int64 `json:"site_id"`
 }
 
-func (*SiteDelete) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
-	return roles.RoleAtLeast(u.Role, roles.Role_OWNER)
+func (*SiteDelete) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapDeleteSite)
 }
 
 func (*SiteDelete) handle(*http.Request, *data.Site, *data.User) *APIResponse { // TODO: finish, require email confirmation
@@ -145,12 +158,12 @@ func siteLogoutRedir(r *http.Request, s *data.Site, u *data.User) *APIResponse {
 
 // SiteGetTheme: GET site/theme
 type SiteGetTheme struct {
-	Site int64 `msgp:"site"`
+	Site int64 `json:"site"`
 }
 
-// authorized checks just if the user has at least author role on the current site.
-func (*SiteGetTheme) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
-	return roles.RoleAtLeast(u.Role, roles.Role_AUTHOR)
+// authorized checks just if the user may edit pages on the current site.
+func (*SiteGetTheme) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapEditPages)
 }
 
 // handle returns the current theme for a site. This response body is a room.Tree.
@@ -158,13 +171,8 @@ func (req *SiteGetTheme) handle(r *http.Request, s *data.Site, u *data.User) *AP
 	if req.Site == 0 {
 		req.Site = s.Id // Site defaults to the current host.
 	} else {
-		role, err := roles.SiteRole(u.Id, req.Site)
-		if err != nil {
-			log.Err(r, "could not get logged in site role for user", err)
-			return errProcessing()
-		}
-		if !roles.RoleAtLeast(role, roles.Role_AUTHOR) {
-			return errLowPrivileges()
+		if resp := userCan(r, u.Id, req.Site, roles.CapEditPages); resp != nil {
+			return resp
 		}
 	}
 	theme, err := siteMainTheme(s)
@@ -267,3 +275,70 @@ func defaultPostTheme() room.CompiledTree {
 		},
 	}
 }
+
+// ReqSiteList: GET sites
+type ReqSiteList struct{}
+
+// authorized says if the user is logged in.
+func (*ReqSiteList) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
+	return u.Id != 0
+}
+
+// handle lists the sites on which the user has a role.
+func (*ReqSiteList) handle(r *http.Request, _ *data.Site, u *data.User) *APIResponse {
+	ms, err := memberships(r, u.Id)
+	if err != nil {
+		log.Err(r, "could not get a user's roles", err)
+		return errProcessing()
+	}
+	resp := &apiv1.ListSitesResponse{}
+	if len(ms.Sites) == 0 {
+		return &APIResponse{Body: resp}
+	}
+	sites, err := data.Conn.SitesByIDs(ms.SiteIDs())
+	if err != nil {
+		log.Err(r, "could not get sites by IDs", err)
+		return errProcessing()
+	}
+	resp.Sites = make([]*data.Site, len(sites))
+	for i := range sites {
+		resp.Sites[i] = &sites[i]
+	}
+	return &APIResponse{Body: resp}
+}
+
+// ReqSiteGet: GET sites/{id}
+type ReqSiteGet struct{}
+
+// authorized says if the user is logged in. The role on the site requested is checked by the handler.
+func (*ReqSiteGet) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
+	return u.Id != 0
+}
+
+// handle responds with the site identified in the path if the user has a role on it.
+func (*ReqSiteGet) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	id, err := strconv.ParseInt(route.Param(r, "id"), 10, 64)
+	if err != nil {
+		return APIResponseErr("The site ID is not valid.")
+	}
+	if id == s.Id && roles.RoleAtLeast(u.Role, roles.Role_SUBSCRIBER) {
+		return &APIResponse{Body: s}
+	}
+	role, err := siteRole(r, u.Id, id)
+	if err != nil {
+		log.Err(r, "could not get logged in site role for user", err)
+		return errProcessing()
+	}
+	if !roles.RoleAtLeast(role, roles.Role_SUBSCRIBER) {
+		return errLowPrivileges()
+	}
+	sites, err := data.Conn.SitesByIDs([]int64{id})
+	if err != nil {
+		log.Err(r, "could not get site", err)
+		return errProcessing()
+	}
+	if len(sites) == 0 {
+		return errNotFound("The site does not exist.")
+	}
+	return &APIResponse{Body: &sites[0]}
+}
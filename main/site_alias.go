@@ -0,0 +1,237 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/domain_verify"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/util"
+)
+
+// siteByAlias retrieves the alias with the domain given and the site to which it belongs. If there
+// is no such alias, the error is sql.ErrNoRows.
+func siteByAlias(domain string) (*data.Site, *data.SiteAlias, error) {
+	alias, err := data.Conn.SiteAlias(domain)
+	if err != nil {
+		return nil, nil, err
+	}
+	sites, err := data.Conn.SitesByIDs([]int64{alias.Site})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sites) == 0 {
+		return nil, nil, sql.ErrNoRows
+	}
+	return &sites[0], alias, nil
+}
+
+// siteRoot returns the scheme and the primary domain of the site, such as "https://example.com".
+func siteRoot(r *http.Request, s *data.Site) string {
+	// Without a request, as when emails are sent in the background, HTTPS is used if the site has it.
+	if r == nil && s.Tls != 0 || r != nil && r.TLS != nil || s.Tls == 2 {
+		return "https://" + s.Domain
+	}
+	return "http://" + s.Domain
+}
+
+// canonicalURL returns the URL of the request on the primary domain of the site.
+func canonicalURL(r *http.Request, s *data.Site) string {
+	return siteRoot(r, s) + r.RequestURI
+}
+
+// redirectToCanonical permanently redirects a request made to an alias to the primary domain of the site.
+func redirectToCanonical(w http.ResponseWriter, r *http.Request, s *data.Site) {
+	http.Redirect(w, r, canonicalURL(r, s), http.StatusMovedPermanently)
+}
+
+// setCanonicalLink sets the Link header to point search engines to the primary domain of the site for
+// a request made to an alias that serves the site directly.
+func setCanonicalLink(w http.ResponseWriter, r *http.Request, s *data.Site) {
+	w.Header().Set("Link", "<"+canonicalURL(r, s)+`>; rel="canonical"`)
+}
+
+// siteAliasOf retrieves the alias with the domain given, checking that it belongs to the site.
+func siteAliasOf(r *http.Request, site *data.Site, domain string) (*data.SiteAlias, *APIResponse) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	alias, err := data.Conn.SiteAlias(domain)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, APIResponseErr("The alias does not exist.")
+		}
+		log.Err(r, "could not get site alias "+domain, err)
+		return nil, errProcessing()
+	}
+	if alias.Site != site.Id {
+		return nil, APIResponseErr("The alias does not belong to the site.")
+	}
+	return alias, nil
+}
+
+// SiteAliasList: GET site/aliases
+// The query may have the site ID, which defaults to the current host.
+type SiteAliasList struct {
+	Site int64
+}
+
+func (req *SiteAliasList) decodeQuery(q url.Values) error {
+	if site := q.Get("site"); site != "" {
+		var err error
+		if req.Site, err = strconv.ParseInt(site, 10, 64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorized checks just if the user may manage the current site. The capability on other
+// sites is checked by the handler.
+func (*SiteAliasList) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapManageSite)
+}
+
+// handle lists the aliases of the site, with the instructions for verifying those that are pending.
+func (req *SiteAliasList) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	site, errResp := siteForAdmin(r, s, u, req.Site)
+	if errResp != nil {
+		return errResp
+	}
+	aliases, err := data.Conn.SiteAliases(site.Id)
+	if err != nil {
+		log.Err(r, "could not list site aliases", err)
+		return errProcessing()
+	}
+	dvs, err := data.Conn.DomainVerificationsBySite(site.Id)
+	if err != nil {
+		log.Err(r, "could not list domain verifications", err)
+		return errProcessing()
+	}
+	byDomain := make(map[string]*data.DomainVerification, len(dvs))
+	for i := range dvs {
+		byDomain[dvs[i].Domain] = &dvs[i]
+	}
+	resp := &apiv1.ListSiteAliasesResponse{Aliases: make([]*apiv1.SiteAlias, len(aliases))}
+	for i, a := range aliases {
+		resp.Aliases[i] = &apiv1.SiteAlias{Domain: a.Domain, Redirect: a.Redirect}
+		if dv := byDomain[a.Domain]; dv != nil {
+			resp.Aliases[i].Verify = newDomainVerificationStatus(dv)
+		}
+	}
+	return &APIResponse{Body: resp}
+}
+
+// SiteAliasAdd: POST site/aliases
+type SiteAliasAdd struct {
+	Site     int64  `json:"site"`     // the site ID; defaults to current host
+	Domain   string `json:"domain"`   // the domain of the new alias
+	Redirect bool   `json:"redirect"` // whether to redirect to the primary domain rather than serve the site directly
+}
+
+// authorized checks just if the user may manage the current site. The capability on other
+// sites is checked by the handler.
+func (*SiteAliasAdd) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapManageSite)
+}
+
+// handle adds an alias to the site. The alias is pending until its domain is verified.
+func (req *SiteAliasAdd) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	site, errResp := siteForAdmin(r, s, u, req.Site)
+	if errResp != nil {
+		return errResp
+	}
+
+	domain, err := util.ExtractDomain(req.Domain)
+	if err != nil {
+		return APIResponseErr("It looks like the domain name you provided is not valid.")
+	}
+
+	token, err := domain_verify.NewToken()
+	if err != nil {
+		log.Err(r, "could not generate domain verification token", err)
+		return errProcessing()
+	}
+
+	if err = data.Conn.SiteAliasInsert(site.Id, domain, req.Redirect, token); err != nil {
+		if err == data.ErrDomainTaken || data.Conn.ErrIsDupKey(err) {
+			return APIResponseErr("The domain is already in use.")
+		}
+		log.Err(r, "could not insert site alias", err)
+		return errProcessing()
+	}
+
+	return &APIResponse{Body: &apiv1.SiteAlias{
+		Domain:   domain,
+		Redirect: req.Redirect,
+		Verify: newDomainVerificationStatus(&data.DomainVerification{
+			Domain: domain,
+			Site:   site.Id,
+			Token:  token,
+			Status: data.DomainPending,
+		}),
+	}}
+}
+
+// SiteAliasEdit: PATCH site/aliases
+type SiteAliasEdit struct {
+	Site     int64  `json:"site"`     // the site ID; defaults to current host
+	Domain   string `json:"domain"`   // the domain of the alias
+	Redirect bool   `json:"redirect"` // whether to redirect to the primary domain rather than serve the site directly
+}
+
+// authorized checks just if the user may manage the current site. The capability on other
+// sites is checked by the handler.
+func (*SiteAliasEdit) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapManageSite)
+}
+
+// handle sets whether the alias redirects to the primary domain of the site.
+func (req *SiteAliasEdit) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	site, errResp := siteForAdmin(r, s, u, req.Site)
+	if errResp != nil {
+		return errResp
+	}
+	alias, errResp := siteAliasOf(r, site, req.Domain)
+	if errResp != nil {
+		return errResp
+	}
+	if err := data.Conn.SiteAliasSetRedirect(alias.Domain, req.Redirect); err != nil {
+		log.Err(r, "could not update site alias "+alias.Domain, err)
+		return errProcessing()
+	}
+	return &APIResponse{}
+}
+
+// SiteAliasRemove: DELETE site/aliases
+type SiteAliasRemove struct {
+	Site   int64  `json:"site"`   // the site ID; defaults to current host
+	Domain string `json:"domain"` // the domain of the alias
+}
+
+// authorized checks just if the user may manage the current site. The capability on other
+// sites is checked by the handler.
+func (*SiteAliasRemove) authorized(r *http.Request, s *data.Site, u *data.User) bool {
+	return can(r, s, u, roles.CapManageSite)
+}
+
+// handle removes an alias from the site.
+func (req *SiteAliasRemove) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	site, errResp := siteForAdmin(r, s, u, req.Site)
+	if errResp != nil {
+		return errResp
+	}
+	alias, errResp := siteAliasOf(r, site, req.Domain)
+	if errResp != nil {
+		return errResp
+	}
+	if err := data.Conn.SiteAliasDelete(alias.Domain); err != nil {
+		log.Err(r, "could not delete site alias "+alias.Domain, err)
+		return errProcessing()
+	}
+	return &APIResponse{}
+}
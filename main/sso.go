@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/env"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+	"github.com/dchenk/mazewire/pkg/roles"
+)
+
+// Users log in once, on the main site, for all of the sites of the network on which they have a role.
+// To log in to another site, the browser goes to auth/sso on that site, which sends it on to
+// auth/sso/authorize on the main site. If the user is logged in there (or after the user logs in), the
+// main site sends the browser back to auth/sso/callback with a ticket: a short-lived token signed with
+// signToken that names the user, the site, the session on the main site, and the ID of the session to
+// be started on the site. The site starts that session with the session on the main site as its parent
+// and sets its own login cookie, so each login cookie still belongs to one site. A session with the ID
+// can be started only once, so each ticket is used only once.
+//
+// The flow is bound to the browser that started it by a nonce in a cookie of the site that must come
+// back in the ticket, so that nobody can log a user in to another account with a ticket of their own.
+const (
+	ssoCookieName = "mwsso"
+	ssoCookiePath = "/api/auth/sso"
+	ssoStateTTL   = time.Minute * 10 // how long the user has to log in on the main site
+	ssoTicketTTL  = time.Minute
+)
+
+const (
+	errSSOExpired = "Your sign-in has expired. Please try again."
+	errSSO2FA     = "This site requires two-factor authentication. Set it up, or log in on this site directly."
+	errSSONoRole  = "You are not a member of the site you are logging in to."
+)
+
+// ssoState is the state of a single sign-on in progress, kept in a cookie of the site being logged in to.
+type ssoState struct {
+	nonce    string
+	returnTo string // the path on the site to which the user is sent after logging in
+}
+
+// createSSOCookie creates the cookie holding the state. The value is the base64-encoded body signed with
+// signToken; the body consists of the expiration time (Unix seconds) followed by the fields of the
+// state in order, encoded with appendTokenInt and appendTokenString.
+func createSSOCookie(st *ssoState, expires time.Time) string {
+	body := make([]byte, 0, tokenIntSize+tokenStringPrefixSize*2+len(st.nonce)+len(st.returnTo))
+	body = appendTokenInt(body, expires.Unix())
+	body = appendTokenString(body, st.nonce)
+	body = appendTokenString(body, st.returnTo)
+	token := signToken("ssostate.", base64.RawURLEncoding.EncodeToString(body))
+	return ssoCookie(token, int(ssoStateTTL/time.Second))
+}
+
+// ssoCookie returns the cookie with the value given. A negative maxAge deletes the cookie.
+func ssoCookie(value string, maxAge int) string {
+	c := fmt.Sprintf("%s=%s; HttpOnly; SameSite=Lax; Path=%s; Max-Age=%d", ssoCookieName, value, ssoCookiePath, maxAge)
+	if env.Prod() {
+		c += "; Secure"
+	}
+	return c
+}
+
+// readSSOState returns the state in the cookie of the request if the cookie is valid and has not expired.
+func readSSOState(r *http.Request) (*ssoState, bool) {
+	cookie, err := r.Cookie(ssoCookieName)
+	if err != nil {
+		return nil, false
+	}
+	bodyEncoded, ok := verifyToken("ssostate.", cookie.Value)
+	if !ok {
+		return nil, false
+	}
+	body, err := base64.RawURLEncoding.DecodeString(bodyEncoded)
+	if err != nil {
+		return nil, false
+	}
+	var expires int64
+	if expires, body, err = readTokenInt(body); err != nil || time.Now().Unix() > expires {
+		return nil, false
+	}
+	st := new(ssoState)
+	if st.nonce, body, err = readTokenString(body); err != nil {
+		return nil, false
+	}
+	if st.returnTo, _, err = readTokenString(body); err != nil {
+		return nil, false
+	}
+	return st, true
+}
+
+// ssoTicket is what a ticket says: the user may start the session with the ID on the site.
+type ssoTicket struct {
+	userID  int64
+	siteID  int64
+	parent  string // the ID of the session on the main site
+	session string // the ID of the session to be started on the site
+	nonce   string
+}
+
+// createSSOTicket creates a ticket. Like a login token, it is bound to the user agent and consists of the
+// base64-encoded body and its signature. The body consists of the encoded expiration time
+// (Unix seconds), the hash of the user agent token, and the encoded fields of the ticket in order.
+func createSSOTicket(r *http.Request, t *ssoTicket, expires time.Time) string {
+	body := make([]byte, 0, tokenIntSize*3+md5.Size+tokenStringPrefixSize*3+len(t.parent)+len(t.session)+
+		len(t.nonce))
+	body = appendTokenInt(body, expires.Unix())
+	body = append(body, userAgentToken(r.UserAgent())...)
+	body = appendTokenInt(body, t.userID)
+	body = appendTokenInt(body, t.siteID)
+	body = appendTokenString(body, t.parent)
+	body = appendTokenString(body, t.session)
+	body = appendTokenString(body, t.nonce)
+	return signToken("sso.", base64.RawURLEncoding.EncodeToString(body))
+}
+
+// parseSSOTicket returns the ticket if the token is a valid ticket made for the user agent of the request
+// that has not expired.
+func parseSSOTicket(r *http.Request, token string) (*ssoTicket, bool) {
+	bodyEncoded, ok := verifyToken("sso.", token)
+	if !ok {
+		return nil, false
+	}
+	body, err := base64.RawURLEncoding.DecodeString(bodyEncoded)
+	if err != nil {
+		return nil, false
+	}
+	var expires int64
+	if expires, body, err = readTokenInt(body); err != nil || time.Now().Unix() > expires {
+		return nil, false
+	}
+	if len(body) < md5.Size || !bytes.Equal(body[:md5.Size], userAgentToken(r.UserAgent())) {
+		return nil, false
+	}
+	t := new(ssoTicket)
+	if t.userID, body, err = readTokenInt(body[md5.Size:]); err != nil {
+		return nil, false
+	}
+	if t.siteID, body, err = readTokenInt(body); err != nil {
+		return nil, false
+	}
+	for _, f := range [...]*string{&t.parent, &t.session, &t.nonce} {
+		if *f, body, err = readTokenString(body); err != nil {
+			return nil, false
+		}
+	}
+	return t, true
+}
+
+// localPath says if p is a path on the same site, to which the user may be redirected.
+func localPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "/\\")
+}
+
+// siteByID retrieves the site with the ID given. If there is none, the error is sql.ErrNoRows.
+func siteByID(id int64) (*data.Site, error) {
+	sites, err := data.Conn.SitesByIDs([]int64{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(sites) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &sites[0], nil
+}
+
+// ReqSSOStart: GET auth/sso
+// The browser is sent to the main site to log in to the site with the account with which the user is
+// logged in there. The "return" query parameter may give the path on the site to which the user is sent
+// after logging in.
+type ReqSSOStart struct {
+	returnTo string
+
+	setCookies []string
+	redirectTo string
+}
+
+func (req *ReqSSOStart) decodeQuery(q url.Values) error {
+	req.returnTo = q.Get("return")
+	return nil
+}
+
+// authorized returns true; the flow logs the user in.
+func (*ReqSSOStart) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqSSOStart) cookies() []string { return req.setCookies }
+func (req *ReqSSOStart) location() string  { return req.redirectTo }
+
+func (req *ReqSSOStart) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	if req.returnTo != "" && !localPath(req.returnTo) {
+		return APIResponseErr("The return path is not valid.")
+	}
+	if u.Id != 0 {
+		return APIResponseErr("You are already logged in as user: " + u.Uname)
+	}
+	if s.Id == mainSite().Id {
+		return APIResponseErr("Log in with your username and password on this site.")
+	}
+	ms, err := siteByID(mainSite().Id)
+	if err != nil {
+		log.Err(r, "could not get main site", err)
+		return errProcessing()
+	}
+	nonce, err := newSessionID()
+	if err != nil {
+		log.Err(r, "could not generate single sign-on nonce", err)
+		return errProcessing()
+	}
+	st := &ssoState{nonce: nonce, returnTo: req.returnTo}
+	req.setCookies = []string{createSSOCookie(st, time.Now().Add(ssoStateTTL))}
+	q := url.Values{"site": {strconv.FormatInt(s.Id, 10)}, "nonce": {nonce}}
+	req.redirectTo = siteRoot(r, ms) + "/api/auth/sso/authorize?" + q.Encode()
+	return &APIResponse{Body: &wrappers.StringValue{Value: req.redirectTo}}
+}
+
+// ReqSSOAuthorize: GET auth/sso/authorize
+// On the main site, the browser of a user logged in there is sent back to the site being logged in to
+// with a ticket. A user who is not logged in is first sent to the login page, which returns here.
+type ReqSSOAuthorize struct {
+	site  int64
+	nonce string
+
+	redirectTo string
+}
+
+func (req *ReqSSOAuthorize) decodeQuery(q url.Values) error {
+	req.site, _ = strconv.ParseInt(q.Get("site"), 10, 64)
+	req.nonce = q.Get("nonce")
+	return nil
+}
+
+// authorized returns true; a user who is not logged in is sent to log in.
+func (*ReqSSOAuthorize) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqSSOAuthorize) cookies() []string    { return nil }
+func (req *ReqSSOAuthorize) location() string { return req.redirectTo }
+
+func (req *ReqSSOAuthorize) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	if s.Id != mainSite().Id {
+		return errNotFound("Single sign-on is authorized only on the main site.")
+	}
+	if req.site == 0 || req.site == s.Id || req.nonce == "" {
+		return APIResponseErr(errSSOExpired)
+	}
+	parent := currentSessionID(r)
+	if u.Id == 0 || parent == "" {
+		req.redirectTo = "/login?" + url.Values{"redir": {r.URL.RequestURI()}}.Encode()
+		return &APIResponse{Body: &wrappers.StringValue{Value: req.redirectTo}}
+	}
+
+	target, err := siteByID(req.site)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errNotFound("The site does not exist.")
+		}
+		log.Err(r, "could not get site", err)
+		return errProcessing()
+	}
+	role, err := siteRole(r, u.Id, target.Id)
+	if err != nil {
+		log.Err(r, "could not get site role for user", err)
+		return errProcessing()
+	}
+	if role == roles.Role_NONE {
+		return APIResponseErr(errSSONoRole)
+	}
+
+	// Users who enabled two-factor authentication gave a second factor when logging in on the main site,
+	// but a site may require a second factor of users who have not enabled it.
+	member := *u
+	member.Role = role
+	if requires2FA(r, target, &member) {
+		secret, err := data.Conn.UserMetaV(u.Id, totpSecretKey)
+		if err != nil && err != sql.ErrNoRows {
+			log.Err(r, "could not check if two-factor authentication is enabled", err)
+			return errProcessing()
+		}
+		if len(secret) == 0 {
+			return APIResponseErr(errSSO2FA)
+		}
+	}
+
+	session, err := newSessionID()
+	if err != nil {
+		log.Err(r, "could not generate session ID", err)
+		return errProcessing()
+	}
+	ticket := createSSOTicket(r, &ssoTicket{
+		userID:  u.Id,
+		siteID:  target.Id,
+		parent:  parent,
+		session: session,
+		nonce:   req.nonce,
+	}, time.Now().Add(ssoTicketTTL))
+	req.redirectTo = siteRoot(r, target) + "/api/auth/sso/callback?ticket=" + url.QueryEscape(ticket)
+	return &APIResponse{Body: &wrappers.StringValue{Value: req.redirectTo}}
+}
+
+// ReqSSOCallback: GET auth/sso/callback
+// The ticket from the main site is exchanged for a session on the site, and the browser is sent to the
+// path given when the flow was started or else as after a login.
+type ReqSSOCallback struct {
+	ticket string
+
+	setCookies []string
+	redirectTo string
+}
+
+func (req *ReqSSOCallback) decodeQuery(q url.Values) error {
+	req.ticket = q.Get("ticket")
+	return nil
+}
+
+// authorized returns true; the ticket identifies the user.
+func (*ReqSSOCallback) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+// rateLimit limits how often each IP address may try to log in.
+func (*ReqSSOCallback) rateLimit() rate_limit.Bucket {
+	return loginRateLimit
+}
+
+func (req *ReqSSOCallback) cookies() []string { return req.setCookies }
+func (req *ReqSSOCallback) location() string  { return req.redirectTo }
+
+func (req *ReqSSOCallback) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	// The state is used only once.
+	req.setCookies = []string{ssoCookie("none", -1)}
+
+	st, ok := readSSOState(r)
+	if !ok {
+		return APIResponseErr(errSSOExpired)
+	}
+	t, ok := parseSSOTicket(r, req.ticket)
+	if !ok || t.siteID != s.Id || !hmac.Equal([]byte(t.nonce), []byte(st.nonce)) {
+		return APIResponseErr(errSSOExpired)
+	}
+	if u.Id != 0 {
+		return APIResponseErr("You are already logged in as user: " + u.Uname)
+	}
+
+	// The user must still be logged in on the main site, and the ticket must not have been used.
+	if !checkSession(r, t.parent, t.userID, mainSite().Id) {
+		return APIResponseErr(errSSOExpired)
+	}
+	if _, err := data.Conn.Session(t.session); err != sql.ErrNoRows {
+		if err != nil {
+			log.Err(r, "could not get session", err)
+			return errProcessing()
+		}
+		return APIResponseErr(errSSOExpired)
+	}
+
+	if resp := loginLockedOut(r, s, rate_limit.UserKey(t.userID)); resp != nil {
+		return resp
+	}
+	tempU, err := data.Conn.UserSiteInfoByID(s.Id, t.userID)
+	if err != nil {
+		log.Err(r, "could not get user of single sign-on", err)
+		return errProcessing()
+	}
+	if tempU.Role == roles.Role_NONE {
+		return APIResponseErr(errNoMembership)
+	}
+
+	token, _, err := insertSession(r, s, tempU, t.session, t.parent)
+	if err != nil {
+		log.Err(r, "could not start session", err)
+		return errProcessing()
+	}
+	loginSucceeded(r, s, tempU.Id)
+	req.setCookies = append(req.setCookies, createLoginCookie(token), createCSRFCookie(token))
+	req.redirectTo = st.returnTo
+	if req.redirectTo == "" {
+		req.redirectTo = loginRedirect(r, s, tempU)
+	}
+	return &APIResponse{Body: &wrappers.StringValue{Value: RespUserLogin}}
+}
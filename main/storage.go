@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+
+	"github.com/dchenk/mazewire/pkg/env"
+)
+
+// The Cloud Storage buckets in which the media objects of the sites and the archives of exported
+// personal data are stored. Each is nil if its variable is not set.
+var contentBucket, exportsBucket *storage.BucketHandle
+
+// initBuckets sets up the buckets configured with the environment.
+func initBuckets(ctx context.Context) error {
+	vars := env.Vars()
+	if vars[env.VarContentBucket] == "" && vars[env.VarExportsBucket] == "" {
+		return nil
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	if name := vars[env.VarContentBucket]; name != "" {
+		contentBucket = client.Bucket(name)
+	}
+	if name := vars[env.VarExportsBucket]; name != "" {
+		exportsBucket = client.Bucket(name)
+	}
+	return nil
+}
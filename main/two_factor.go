@@ -0,0 +1,521 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/env"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/totp"
+)
+
+// The user meta keys with which the two-factor authentication of a user is stored.
+const (
+	totpSecretKey   = "totp-secret"   // the base32-encoded secret key, once two-factor authentication is enabled
+	totpPendingKey  = "totp-pending"  // the secret key being set up, until a password generated with it is verified
+	totpRecoveryKey = "totp-recovery" // the hashes of the unused recovery codes, one per line
+	totpLastStepKey = "totp-last"     // the time step of the last password used, so no password is used twice
+)
+
+// opt2FARequiredRole is the option with which a site owner requires two-factor authentication for the
+// users with at least the role named, which must be at least EDITOR.
+const opt2FARequiredRole = "2fa-required-role"
+
+const (
+	twoFactorChallengeTTL = time.Minute * 5
+	twoFactorSkew         = 1  // the number of time steps before and after the current one accepted
+	recoveryCodesCount    = 10 // the number of recovery codes generated
+)
+
+const (
+	errInvalid2FACode = "The code you entered is incorrect."
+	err2FAExpired     = "Your login has expired. Please log in again."
+	err2FANotEnabled  = "Two-factor authentication is not enabled."
+)
+
+// requires2FA says if the site requires the user to log in with a second factor. If the option cannot be
+// read, the second factor is required only if the user enabled it.
+func requires2FA(r *http.Request, s *data.Site, u *data.User) bool {
+	opts, err := data.Conn.OptionsKeyInMappedStr(s.Id, []string{opt2FARequiredRole})
+	if err != nil {
+		log.Err(r, "could not get the two-factor authentication option", err)
+		return false
+	}
+	v, ok := opts[opt2FARequiredRole]
+	if !ok || v == "" {
+		return false
+	}
+	minRole, ok := roles.ValidRoleStr(v)
+	if !ok || !roles.RoleAtLeast(minRole, roles.Role_EDITOR) {
+		log.Err(r, fmt.Sprintf("invalid value of option %q: %q", opt2FARequiredRole, v),
+			errors.New("the role must be at least EDITOR"))
+		return false
+	}
+	return roles.RoleAtLeast(u.Role, minRole)
+}
+
+// challengeTwoFactor is called after the password of the user u, filled in by checkLoginCreds, is
+// verified. If the user has two-factor authentication enabled or the site requires it, the login moves
+// into the challenge state: u is reset so that the user is not logged in, and the response gives the
+// challenge. Otherwise the login succeeds, forgetting the failed logins, and resp is returned. The failed
+// logins are kept while a challenge is pending so that guessing the second factor counts toward the
+// lockouts even when the password is known.
+func challengeTwoFactor(r *http.Request, s *data.Site, u *data.User, resp *APIResponse) *APIResponse {
+	meta, err := data.Conn.UserMetaByIdMapped(u.Id)
+	if err != nil {
+		log.Err(r, "could not get user meta for two-factor authentication", err)
+		*u = data.User{}
+		return errProcessing()
+	}
+	enabled := len(meta[totpSecretKey]) > 0
+	if !enabled && !requires2FA(r, s, u) {
+		loginSucceeded(r, s, u.Id)
+		return resp
+	}
+
+	c := &apiv1.TwoFactorChallenge{
+		Challenge: createChallengeToken(r, u.Id, s.Id, time.Now().Add(twoFactorChallengeTTL)),
+	}
+	if !enabled {
+		// The user must set up two-factor authentication to log in. If the setup was already started, the
+		// same secret is given again so that an app that was set up with it keeps working.
+		secret, err := pendingSecret(meta)
+		if err == errNoPendingSecret {
+			secret, err = startEnrollment(u.Id)
+		}
+		if err != nil {
+			log.Err(r, "could not generate a TOTP secret", err)
+			*u = data.User{}
+			return errProcessing()
+		}
+		c.Enroll = true
+		c.Secret = totp.EncodeSecret(secret)
+		c.Uri = totp.URI(s.Domain, u.Uname, secret)
+	}
+	*u = data.User{}
+	return &APIResponse{Status: http.StatusAccepted, Body: c}
+}
+
+// enrollKeyCookieName is the name of the cookie that gives the login page the secret key with which to
+// set up an authenticator app when logging in with a form.
+const enrollKeyCookieName = "mw2fakey"
+
+// enrollKeyCookie returns the cookie with the secret key for the login form at the path, which keeps the
+// key out of the URL to which the user is redirected, and so out of access logs, the browser history,
+// and Referer headers. The page reads the cookie with a script, so it is not HttpOnly, but it is sent to
+// no other path and expires with the challenge. A negative maxAge deletes the cookie.
+func enrollKeyCookie(path, secret string, maxAge int) string {
+	c := fmt.Sprintf("%s=%s; SameSite=Strict; Path=%s; Max-Age=%d", enrollKeyCookieName, secret, path, maxAge)
+	if env.Prod() {
+		c += "; Secure"
+	}
+	return c
+}
+
+// pendingSecret returns the secret key of the setup in progress in the user meta. If there is none, the
+// error is errNoPendingSecret.
+func pendingSecret(meta map[string][]byte) ([]byte, error) {
+	if v := meta[totpPendingKey]; len(v) > 0 {
+		if secret, err := totp.DecodeSecret(string(v)); err == nil {
+			return secret, nil
+		}
+	}
+	return nil, errNoPendingSecret
+}
+
+var errNoPendingSecret = errors.New("no pending TOTP secret")
+
+// startEnrollment stores a new secret key being set up for the user and returns it.
+func startEnrollment(userID int64) ([]byte, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = data.Conn.UserMetaUpdate(userID, totpPendingKey, []byte(totp.EncodeSecret(secret))); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// createChallengeToken creates a token identifying a login of the user on the site that awaits a second
+// factor. Like a login token, it is bound to the user agent and consists of the base64-encoded body and
+// its signature; the signature covers a prefix as well so that neither kind of token can pass for the
+// other. The body consists of the encoded expiration time (Unix seconds), the hash of the user agent
+// token, and the encoded user ID and site ID.
+func createChallengeToken(r *http.Request, userID, siteID int64, expires time.Time) string {
+	body := make([]byte, 0, tokenIntSize*3+md5.Size)
+	body = appendTokenInt(body, expires.Unix())
+	body = append(body, userAgentToken(r.UserAgent())...)
+	body = appendTokenInt(body, userID)
+	body = appendTokenInt(body, siteID)
+	return signToken("2fa.", base64.RawURLEncoding.EncodeToString(body))
+}
+
+// parseChallengeToken returns the user ID and the site ID of a valid challenge token made by the user
+// agent of the request that has not expired.
+func parseChallengeToken(r *http.Request, token string) (userID, siteID int64, ok bool) {
+	bodyEncoded, ok := verifyToken("2fa.", token)
+	if !ok {
+		return 0, 0, false
+	}
+	body, err := base64.RawURLEncoding.DecodeString(bodyEncoded)
+	if err != nil {
+		return 0, 0, false
+	}
+	var expires int64
+	if expires, body, err = readTokenInt(body); err != nil || time.Now().Unix() > expires {
+		return 0, 0, false
+	}
+	if len(body) < md5.Size || !bytes.Equal(body[:md5.Size], userAgentToken(r.UserAgent())) {
+		return 0, 0, false
+	}
+	if userID, body, err = readTokenInt(body[md5.Size:]); err != nil {
+		return 0, 0, false
+	}
+	if siteID, _, err = readTokenInt(body); err != nil {
+		return 0, 0, false
+	}
+	return userID, siteID, true
+}
+
+// verifyTOTP checks a one-time password of the user generated with the secret, rejecting the passwords
+// of time steps up to the last one used, and records the time step of the password.
+func verifyTOTP(r *http.Request, userID int64, meta map[string][]byte, secret []byte, code string) bool {
+	step, ok := totp.Verify(secret, code, time.Now(), twoFactorSkew)
+	if !ok {
+		return false
+	}
+	if last, err := strconv.ParseUint(string(meta[totpLastStepKey]), 10, 64); err == nil && step <= last {
+		return false
+	}
+	if _, err := data.Conn.UserMetaUpdate(userID, totpLastStepKey, []byte(strconv.FormatUint(step, 10))); err != nil {
+		log.Err(r, "could not save the last TOTP time step", err)
+	}
+	return true
+}
+
+// useRecoveryCode checks a recovery code of the user and, if it is valid, removes it so that it cannot
+// be used again.
+func useRecoveryCode(r *http.Request, userID int64, meta map[string][]byte, code string) bool {
+	hash := totp.HashRecoveryCode(code)
+	hashes := strings.Fields(string(meta[totpRecoveryKey]))
+	for i, h := range hashes {
+		if !hmac.Equal([]byte(h), []byte(hash)) {
+			continue
+		}
+		hashes = append(hashes[:i], hashes[i+1:]...)
+		if _, err := data.Conn.UserMetaUpdate(userID, totpRecoveryKey, []byte(strings.Join(hashes, "\n"))); err != nil {
+			log.Err(r, "could not remove a used recovery code", err)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// newRecoveryCodes generates recovery codes for the user, replacing any earlier ones, and returns them.
+func newRecoveryCodes(userID int64) ([]string, error) {
+	codes, err := totp.NewRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = totp.HashRecoveryCode(c)
+	}
+	if _, err = data.Conn.UserMetaUpdate(userID, totpRecoveryKey, []byte(strings.Join(hashes, "\n"))); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// enable2FA verifies a one-time password generated with the pending secret of the user and, if it is
+// valid, enables two-factor authentication with the secret. If recoveryCodes is true, the new recovery
+// codes are returned; otherwise the returned slice is empty but not nil, and the user has no recovery
+// codes until they are generated with ReqTwoFactorRecoveryCodes. If the code is not valid, both
+// returned values are nil.
+func enable2FA(r *http.Request, userID int64, meta map[string][]byte, code string, recoveryCodes bool) ([]string, error) {
+	secret, err := pendingSecret(meta)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyTOTP(r, userID, meta, secret, code) {
+		return nil, nil
+	}
+	if _, err = data.Conn.UserMetaUpdate(userID, totpSecretKey, []byte(totp.EncodeSecret(secret))); err != nil {
+		return nil, err
+	}
+	if _, err = data.Conn.UserMetaDelete(userID, totpPendingKey); err != nil {
+		log.Err(r, "could not delete the pending TOTP secret", err)
+	}
+	if !recoveryCodes {
+		if _, err = data.Conn.UserMetaDelete(userID, totpRecoveryKey); err != nil {
+			log.Err(r, "could not delete old recovery codes", err)
+		}
+		return []string{}, nil
+	}
+	return newRecoveryCodes(userID)
+}
+
+// check2FA verifies a one-time password or a recovery code of a user who has two-factor authentication
+// enabled.
+func check2FA(r *http.Request, userID int64, meta map[string][]byte, code string) bool {
+	secret, err := totp.DecodeSecret(string(meta[totpSecretKey]))
+	if err != nil {
+		log.Err(r, "invalid TOTP secret", err)
+		return false
+	}
+	if verifyTOTP(r, userID, meta, secret, code) {
+		return true
+	}
+	return useRecoveryCode(r, userID, meta, code)
+}
+
+// AuthTwoFactor: POST auth/2fa
+// A login awaiting a second factor is completed with a one-time password or a recovery code. If the
+// user is setting up two-factor authentication, the code must be a one-time password, and the response
+// gives the new recovery codes.
+type AuthTwoFactor struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+// authorized returns true for login requests.
+func (*AuthTwoFactor) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+// rateLimit limits how often each IP address may try to log in.
+func (*AuthTwoFactor) rateLimit() rate_limit.Bucket {
+	return loginRateLimit
+}
+
+func (req *AuthTwoFactor) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	return checkTwoFactor(r, s, u, req.Challenge, req.Code, true)
+}
+
+// authTwoFactorForm: POST auth/2fa/form
+// This endpoint completes logins via a form, like AuthTwoFactor. Since the user is redirected after
+// logging in, no recovery codes are generated when two-factor authentication is set up this way; the
+// user generates them with ReqTwoFactorRecoveryCodes.
+type authTwoFactorForm struct {
+	AuthTwoFactor
+}
+
+func (req *authTwoFactorForm) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	return checkTwoFactor(r, s, u, req.Challenge, req.Code, false)
+}
+
+func (req *authTwoFactorForm) decodeFormURL(r *http.Request) error {
+	req.Challenge = r.PostFormValue("challenge")
+	req.Code = r.PostFormValue("code")
+	return nil
+}
+
+// checkTwoFactor completes a login awaiting a second factor, filling in u if the code is valid, like
+// checkLoginCreds. Failures are counted toward the lockouts of the account and the IP address. If the
+// user is setting up two-factor authentication, recoveryCodes says if the recovery codes are generated
+// and given in the response.
+func checkTwoFactor(r *http.Request, s *data.Site, u *data.User, challenge, code string, recoveryCodes bool) *APIResponse {
+	if u.Id != 0 {
+		return APIResponseErr("You are already logged in as user: " + u.Uname)
+	}
+	userID, siteID, ok := parseChallengeToken(r, challenge)
+	if !ok || siteID != s.Id {
+		return APIResponseErr(err2FAExpired)
+	}
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return APIResponseErr(errIncompleteForm)
+	}
+	for _, k := range [2]string{rate_limit.IPKey(rate_limit.RemoteIP(r)), rate_limit.UserKey(userID)} {
+		if resp := loginLockedOut(r, s, k); resp != nil {
+			return resp
+		}
+	}
+
+	tempU, err := data.Conn.UserSiteInfoByID(s.Id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return APIResponseErr(err2FAExpired)
+		}
+		log.Err(r, "could not get user for two-factor authentication", err)
+		return errProcessing()
+	}
+	if tempU.Role == roles.Role_NONE {
+		return APIResponseErr(errNoMembership)
+	}
+	meta, err := data.Conn.UserMetaByIdMapped(userID)
+	if err != nil {
+		log.Err(r, "could not get user meta for two-factor authentication", err)
+		return errProcessing()
+	}
+
+	body := new(apiv1.RecoveryCodes)
+	if len(meta[totpSecretKey]) > 0 {
+		ok = check2FA(r, userID, meta, code)
+	} else {
+		body.Codes, err = enable2FA(r, userID, meta, code, recoveryCodes)
+		if err != nil {
+			if err == errNoPendingSecret {
+				return APIResponseErr(err2FAExpired)
+			}
+			log.Err(r, "could not enable two-factor authentication", err)
+			return errProcessing()
+		}
+		ok = body.Codes != nil
+	}
+	if !ok {
+		loginFailed(r, s, siteLoginLockouts(r, s), userID)
+		return APIResponseErr(errInvalid2FACode)
+	}
+
+	loginSucceeded(r, s, userID)
+	*u = *tempU
+	return &APIResponse{Body: body}
+}
+
+// ReqTwoFactorStart: POST user/2fa
+// The user starts setting up two-factor authentication, getting the secret key for an authenticator app.
+type ReqTwoFactorStart struct{}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqTwoFactorStart) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (*ReqTwoFactorStart) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	enabled, err := data.Conn.UserMetaV(u.Id, totpSecretKey)
+	if err != nil && err != sql.ErrNoRows {
+		log.Err(r, "could not get user meta for two-factor authentication", err)
+		return errProcessing()
+	}
+	if len(enabled) > 0 {
+		return APIResponseErr("Two-factor authentication is already enabled. Disable it first to set it up again.")
+	}
+	secret, err := startEnrollment(u.Id)
+	if err != nil {
+		log.Err(r, "could not start setting up two-factor authentication", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: &apiv1.TwoFactorEnrollment{
+		Secret: totp.EncodeSecret(secret),
+		Uri:    totp.URI(s.Domain, u.Uname, secret),
+	}}
+}
+
+// ReqTwoFactorConfirm: PUT user/2fa
+// The user confirms the setup of two-factor authentication with a one-time password generated with the
+// new secret key, which enables it. The response gives the new recovery codes.
+type ReqTwoFactorConfirm struct {
+	Code string `json:"code"`
+}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqTwoFactorConfirm) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqTwoFactorConfirm) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	meta, err := data.Conn.UserMetaByIdMapped(u.Id)
+	if err != nil {
+		log.Err(r, "could not get user meta for two-factor authentication", err)
+		return errProcessing()
+	}
+	codes, err := enable2FA(r, u.Id, meta, req.Code, true)
+	if err != nil {
+		if err == errNoPendingSecret {
+			return APIResponseErr("Two-factor authentication is not being set up.")
+		}
+		log.Err(r, "could not enable two-factor authentication", err)
+		return errProcessing()
+	}
+	if codes == nil {
+		return APIResponseErr(errInvalid2FACode)
+	}
+	return &APIResponse{Body: &apiv1.RecoveryCodes{Codes: codes}}
+}
+
+// ReqTwoFactorDisable: DELETE user/2fa
+// The user disables two-factor authentication, confirming with a one-time password or a recovery code.
+// If the site requires a second factor for the role of the user, it must be set up again at the next login.
+type ReqTwoFactorDisable struct {
+	Code string `json:"code"`
+}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqTwoFactorDisable) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqTwoFactorDisable) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	meta, resp := twoFactorMeta(r, u)
+	if resp != nil {
+		return resp
+	}
+	if !check2FA(r, u.Id, meta, req.Code) {
+		return APIResponseErr(errInvalid2FACode)
+	}
+	for _, k := range [...]string{totpSecretKey, totpPendingKey, totpRecoveryKey, totpLastStepKey} {
+		if _, err := data.Conn.UserMetaDelete(u.Id, k); err != nil {
+			log.Err(r, "could not disable two-factor authentication", err)
+			return errProcessing()
+		}
+	}
+	return &APIResponse{Body: new(apiv1.RecoveryCodes)}
+}
+
+// ReqTwoFactorRecoveryCodes: POST user/2fa/recovery-codes
+// The user generates new recovery codes, which replace the earlier ones, confirming with a one-time
+// password or a recovery code.
+type ReqTwoFactorRecoveryCodes struct {
+	Code string `json:"code"`
+}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqTwoFactorRecoveryCodes) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqTwoFactorRecoveryCodes) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	meta, resp := twoFactorMeta(r, u)
+	if resp != nil {
+		return resp
+	}
+	if !check2FA(r, u.Id, meta, req.Code) {
+		return APIResponseErr(errInvalid2FACode)
+	}
+	codes, err := newRecoveryCodes(u.Id)
+	if err != nil {
+		log.Err(r, "could not generate recovery codes", err)
+		return errProcessing()
+	}
+	return &APIResponse{Body: &apiv1.RecoveryCodes{Codes: codes}}
+}
+
+// twoFactorMeta returns the user meta of a user who has two-factor authentication enabled, or else an
+// error response.
+func twoFactorMeta(r *http.Request, u *data.User) (map[string][]byte, *APIResponse) {
+	meta, err := data.Conn.UserMetaByIdMapped(u.Id)
+	if err != nil {
+		log.Err(r, "could not get user meta for two-factor authentication", err)
+		return nil, errProcessing()
+	}
+	if len(meta[totpSecretKey]) == 0 {
+		return nil, APIResponseErr(err2FANotEnabled)
+	}
+	return meta, nil
+}
@@ -2,9 +2,8 @@ package main
 
 import (
 	"bytes"
-	"crypto/hmac"
 	"crypto/md5"
-	"crypto/sha256"
+	"database/sql"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -13,16 +12,20 @@ import (
 	"strings"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/golang/protobuf/ptypes/wrappers"
 
 	"github.com/dchenk/mazewire/pkg/data"
 	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/password"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/route"
 	"github.com/dchenk/mazewire/pkg/util"
 )
 
 // getCurrentUser sends a non-nil *data.User to chan c. If a real logged in user is behind the
 // request, then the User struct  will be filled in with the basic info of the user and their role
-// for the current site.
+// for the current site. The user is identified by the login token given either as a bearer token in
+// the Authorization header or in the login cookie.
 func getCurrentUser(r *http.Request, c chan *userSite) {
 
 	us := &userSite{u: new(data.User)}
@@ -30,40 +33,43 @@ func getCurrentUser(r *http.Request, c chan *userSite) {
 		c <- us
 	}()
 
-	cookie, err := r.Cookie("mwuser")
-	if err == http.ErrNoCookie || len(cookie.Value) < 40 {
-		// The base64-encoded cookie value includes a hashed signature, so in all the whole thing
-		// must be at least 40 bytes long.
+	token := bearerToken(r)
+	if strings.HasPrefix(token, apiTokenPrefix) {
+		if u, t := apiTokenUser(r, token); u != nil {
+			us.u, us.siteID, us.token = u, t.Site, t
+		}
 		return
 	}
-
-	splitCookie := strings.Split(cookie.Value, ".")
-
-	if len(splitCookie) != 2 {
-		log.Err(r, userCookieErr(cookie.Value), errors.New("cookie does not have two parts"))
+	if token == "" {
+		cookie, err := r.Cookie("mwuser")
+		if err == http.ErrNoCookie {
+			return
+		}
+		token = cookie.Value
+	}
+	if len(token) < 40 {
+		// The base64-encoded token includes a hashed signature, so in all the whole thing
+		// must be at least 40 bytes long.
 		return
 	}
 
-	// Hash the cookie body to verify that it was not tampered with
-	sig := hmac.New(sha256.New, TOKEN_KEY)
-	sig.Write([]byte(splitCookie[0]))
-
-	// Compare the cookie's hashed body with the signature that came with it.
-	if base64.RawURLEncoding.EncodeToString(sig.Sum(nil)) != splitCookie[1] {
-		log.Err(r, userCookieErr(cookie.Value), errors.New("cookie signature is not correct"))
+	// Verify the signature so that a token that was tampered with is not accepted.
+	bodyEncoded, ok := verifyToken("", token)
+	if !ok {
+		log.Err(r, userTokenErr(token), errors.New("token signature is not correct"))
 		return
 	}
 
-	body, err := base64.RawURLEncoding.DecodeString(splitCookie[0])
+	body, err := base64.RawURLEncoding.DecodeString(bodyEncoded)
 	if err != nil {
-		log.Err(r, userCookieErr(cookie.Value), err)
+		log.Err(r, userTokenErr(token), err)
 		return
 	}
 
 	var expiration int64
-	expiration, body, err = msgp.ReadInt64Bytes(body)
+	expiration, body, err = readTokenInt(body)
 	if err != nil {
-		log.Err(r, userCookieErr(cookie.Value), err)
+		log.Err(r, userTokenErr(token), err)
 		return
 	}
 
@@ -76,15 +82,22 @@ func getCurrentUser(r *http.Request, c chan *userSite) {
 	}
 
 	var userID int64
-	userID, body, err = msgp.ReadInt64Bytes(body[md5.Size:])
+	userID, body, err = readTokenInt(body[md5.Size:])
 	if err != nil {
-		log.Err(r, userCookieErr(cookie.Value), err)
+		log.Err(r, userTokenErr(token), err)
 		return
 	}
 
-	us.siteID, body, err = msgp.ReadInt64Bytes(body)
+	us.siteID, body, err = readTokenInt(body)
 	if err != nil {
-		log.Err(r, userCookieErr(cookie.Value), err)
+		log.Err(r, userTokenErr(token), err)
+		return
+	}
+
+	var sessionID string
+	sessionID, body, err = readTokenString(body)
+	if err != nil {
+		log.Err(r, userTokenErr(token), err)
 		return
 	}
 
@@ -95,16 +108,23 @@ func getCurrentUser(r *http.Request, c chan *userSite) {
 		return
 	}
 
-	if !bytes.Equal(userGot.Pass[10:31], body) {
+	if len(body) != 0 {
+		log.Err(r, userTokenErr(token), errors.New("token has trailing bytes"))
+		return
+	}
+
+	// The session must not have been ended by logging out or revoked.
+	if !checkSession(r, sessionID, userID, us.siteID) {
 		return
 	}
 
-	// At this point, we know that everything with the cookie is good, and the user is authenticated.
+	// At this point, we know that everything with the token is good, and the user is authenticated.
 	us.u = userGot
+	us.sessionID = sessionID
 }
 
-func userCookieErr(cookieVal string) string {
-	return fmt.Sprintf("user cookie error; cookie value: %q", cookieVal)
+func userTokenErr(token string) string {
+	return fmt.Sprintf("user token error; token: %q", token)
 }
 
 // ReqUserCreate: POST user
@@ -130,25 +150,14 @@ type ReqUserCreate struct {
 }
 
 // handle creates a user on the site s. This API endpoint accepts the Protocol Buffers, JSON, and
-// x-www-form-urlencoded content types.
-// TODO: If a user exists, assign to site.
+// x-www-form-urlencoded content types. Existing users join a site by accepting an invitation (see
+// ReqInvitationAccept).
 func (req *ReqUserCreate) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
 	// All values must be provided.
 	if util.IsAnyStringBlank(req.Uname, req.Fname, req.Lname, req.Email, req.Pass, req.Role) {
 		return APIResponseErr(errIncompleteForm)
 	}
 
-	if !util.ValidEmail(req.Email) {
-		return APIResponseErr("The email you entered seems to be invalid.")
-	}
-
-	// Set all capital letters in the chosen username to lowercase.
-	req.Uname = strings.ToLower(req.Uname)
-
-	if !util.ValidUsername(req.Uname) {
-		return APIResponseErr("The username you selected is not valid. It must begin with a letter and be at least 3 characters long; the special characters allowed are '$' and '_'.")
-	}
-
 	// If the user is registering not on the main site, check if registrations are open for the site.
 	if s.Id != 1 {
 		// TODO: What site is the user being added to? What's the role? Is it allowed?
@@ -167,45 +176,21 @@ func (req *ReqUserCreate) handle(r *http.Request, s *data.Site, u *data.User) *A
 		}
 	}
 
-	// TODO: Validate the password as sufficiently strong.
-	if len(req.Pass) < 6 {
-		return APIResponseErr("The password you chose is too short.")
+	newID, errResp := insertUser(r, req.Uname, req.Email, req.Pass, req.Fname, req.Lname)
+	if errResp != nil {
+		return errResp
 	}
+	req.Uname = strings.ToLower(req.Uname)
 
-	passHash, err := hashPassword(req.Pass)
-	if err != nil {
-		log.Err(r, "error hashing user password", err)
+	// The user cannot log in until the email address is verified.
+	if _, err := data.Conn.UserMetaUpdate(newID, emailUnverifiedKey, []byte("1")); err != nil {
+		log.Err(r, "could not mark new user's email address unverified", err)
 		return errProcessing()
 	}
-
-	// Check if the email is already taken. Although we are not doing this in a transaction and someone could
-	// take the username within the next couple microseconds until the call to create the user, the database contains
-	// a uniqueness constraint on the username column, so the worst thing that can happen is this request will fail.
-	unameCount, emailCount, err := data.Conn.UserCountByUnameEmail(req.Uname, req.Email)
-	if err != nil {
-		log.Err(r, "error checking if user exists with Uname: "+req.Uname, err)
-		return errProcessing()
-	}
-
-	if emailCount > 0 {
-		// TODO: offer a link to sign in with the username retrieved... "Try signing in with your Mazewire username <b>"+existsUsername+"</b>"
-		// TODO: <br><a href="/existing-accounts" target="_blank">LEARN MORE</a>
-		e := "The email address you entered is already taken by a user. Perhaps you have already signed up for an account " +
-			"on a website built with Mazewire and need to log in?"
-		return APIResponseErr(e)
-	}
-
-	if unameCount > 0 {
-		e := "The username you chose is already taken by a user. Perhaps you have already signed up for an account " +
-			"on a website built with Mazewire and need to log in?"
-		return APIResponseErr(e)
-	}
-
-	// TODO: do this in a transaction
-	newID, err := data.Conn.UserInsert(req.Uname, req.Email, passHash, u.Fname, u.Lname)
-	if err != nil || newID == 0 { // make sure no rows were inserted
-		log.Err(r, "could not create user", err)
-		return errProcessing()
+	newUser := &data.User{Id: newID, Uname: req.Uname, Email: req.Email, Fname: req.Fname, Lname: req.Lname}
+	if err := sendVerificationEmail(r, s, newUser); err != nil {
+		// The user can ask for the email again.
+		log.Err(r, "could not send verification email to new user", err)
 	}
 
 	resp := APIResponse{
@@ -238,6 +223,63 @@ func (req *ReqUserCreate) handle(r *http.Request, s *data.Site, u *data.User) *A
 	return &resp
 }
 
+// insertUser validates the account details of a new user and creates the user, returning the ID of
+// the user. If the user cannot be created, the returned APIResponse gives the error.
+func insertUser(r *http.Request, uname, email, pass, fname, lname string) (int64, *APIResponse) {
+	if !util.ValidEmail(email) {
+		return 0, APIResponseErr("The email you entered seems to be invalid.")
+	}
+
+	// Set all capital letters in the chosen username to lowercase.
+	uname = strings.ToLower(uname)
+
+	if !util.ValidUsername(uname) {
+		return 0, APIResponseErr("The username you selected is not valid. It must begin with a letter and be at least 3 characters long; the special characters allowed are '$' and '_'.")
+	}
+
+	// TODO: Validate the password as sufficiently strong.
+	if len(pass) < minPasswordLength {
+		return 0, APIResponseErr(errPasswordTooShort)
+	}
+
+	passHash, err := hashPassword(pass)
+	if err != nil {
+		log.Err(r, "error hashing user password", err)
+		return 0, errProcessing()
+	}
+
+	// Check if the email is already taken. Although we are not doing this in a transaction and someone could
+	// take the username within the next couple microseconds until the call to create the user, the database contains
+	// a uniqueness constraint on the username column, so the worst thing that can happen is this request will fail.
+	unameCount, emailCount, err := data.Conn.UserCountByUnameEmail(uname, email)
+	if err != nil {
+		log.Err(r, "error checking if user exists with Uname: "+uname, err)
+		return 0, errProcessing()
+	}
+
+	if emailCount > 0 {
+		// TODO: offer a link to sign in with the username retrieved... "Try signing in with your Mazewire username <b>"+existsUsername+"</b>"
+		// TODO: <br><a href="/existing-accounts" target="_blank">LEARN MORE</a>
+		e := "The email address you entered is already taken by a user. Perhaps you have already signed up for an account " +
+			"on a website built with Mazewire and need to log in?"
+		return 0, APIResponseErr(e)
+	}
+
+	if unameCount > 0 {
+		e := "The username you chose is already taken by a user. Perhaps you have already signed up for an account " +
+			"on a website built with Mazewire and need to log in?"
+		return 0, APIResponseErr(e)
+	}
+
+	// TODO: do this in a transaction
+	newID, err := data.Conn.UserInsert(uname, email, passHash, fname, lname)
+	if err != nil || newID == 0 { // make sure no rows were inserted
+		log.Err(r, "could not create user", err)
+		return 0, errProcessing()
+	}
+	return newID, nil
+}
+
 // authorized returns true for user creation requests.
 func (*ReqUserCreate) authorized(*http.Request, *data.Site, *data.User) bool {
 	return true
@@ -255,8 +297,8 @@ func (uc *ReqUserCreate) decodeFormURL(r *http.Request) error {
 }
 
 type RespUserCreate struct {
-	Username string `msgp:"uname" json:"uname"`
-	Id       int64  `msgp:"id" json:"id"`
+	Username string `json:"uname"`
+	Id       int64  `json:"id"`
 }
 
 // reqUserCheckCurrent: GET: user
@@ -274,13 +316,13 @@ func (reqUserCheckCurrent) handle(_ *http.Request, _ *data.Site, u *data.User) *
 	} else {
 		uname = u.Uname
 	}
-	return &APIResponse{Body: msgp.Raw(msgp.AppendString(nil, uname))}
+	return &APIResponse{Body: &wrappers.StringValue{Value: uname}}
 }
 
 // ReqUserEdit: PATCH user
 type ReqUserEdit struct {
 	// TODO
-	UserID int64 `msgp:"user_id"` // the user to edit
+	UserID int64 `json:"user_id"` // the user to edit
 }
 
 func (ue *ReqUserEdit) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
@@ -295,15 +337,6 @@ func (*ReqUserEdit) handle(r *http.Request, _ *data.Site, _ *data.User) *APIResp
 	return APIResponseErr("this API endpoint is not ready")
 }
 
-// delete a user and all of the usermeta associated with the user
-// API params:
-// - user (username)
-// - pass (password)
-func ReqUserDelete(r *http.Request, _ *data.Site, _ *data.User) *APIResponse {
-	// TODO
-	return APIResponseErr("this API endpoint is not ready")
-}
-
 // userSitesList: GET user/sites
 // Retrieve the sites with which the user is associated
 type userSitesList struct{}
@@ -313,29 +346,18 @@ func (userSitesList) authorized(_ *http.Request, _ *data.Site, u *data.User) boo
 }
 
 func (userSitesList) handle(r *http.Request, _ *data.Site, u *data.User) *APIResponse {
-	metas, err := data.Conn.UserMetaByIdLikeKey(u.Id, "role%")
+	ms, err := memberships(r, u.Id)
 	if err != nil {
 		log.Err(r, "could not get a user's associated sites", err)
 		return errProcessing()
 	}
-	// Extract the site IDs.
-	ids := make([]int64, 0, len(metas))
-	for i := range metas {
-		k := metas[i].K
-		id, err := strconv.ParseInt(k[len("role"):], 10, 64)
-		if err != nil {
-			log.Err(r, fmt.Sprintf("could not parse site ID from the role key %q", k), err)
-			return errProcessing()
-		}
-		ids = append(ids, id)
-	}
-	if len(ids) == 0 {
+	if len(ms.Sites) == 0 {
 		// This should never happen because the user is logged in.
 		log.Err(r, "got zero ids of associated sites", errors.New("unexpected empty list of associated sites"))
 		return errProcessing()
 	}
 	// Retrieve info for the sites.
-	sites, err := data.Conn.SitesByIDs(ids)
+	sites, err := data.Conn.SitesByIDs(ms.SiteIDs())
 	if err != nil {
 		log.Err(r, "could not get sites by IDs", err)
 		return errProcessing()
@@ -347,12 +369,7 @@ func (userSitesList) handle(r *http.Request, _ *data.Site, u *data.User) *APIRes
 		list[i].Domain = sites[i].Domain
 		list[i].Name = sites[i].Name
 		list[i].Logo = sites[i].Logo
-		for _, m := range metas {
-			if m.K == users.SiteRoleKey(sites[i].Id) {
-				list[i].Role = string(metas[i].V)
-				break
-			}
-		}
+		list[i].Role = strconv.FormatInt(int64(ms.Role(sites[i].Id)), 10)
 	}
 	return &APIResponse{Body: list}
 }
@@ -368,6 +385,88 @@ type UserSitesListItem struct {
 	Role    string
 }
 
+// ReqUserGet: GET users/{id}
+type ReqUserGet struct{}
+
+// authorized says if the user is logged in. Whether the user may view the user identified in the path
+// is checked by the handler.
+func (*ReqUserGet) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
+	return u.Id != 0
+}
+
+// handle responds with the user identified in the path, which is either a user ID or "self" for the
+// current user. Super users may view any user, and admins may view the users who have a role on the
+// current site.
+func (*ReqUserGet) handle(r *http.Request, s *data.Site, u *data.User) *APIResponse {
+	id := u.Id
+	if p := route.Param(r, "id"); p != "self" {
+		var err error
+		if id, err = strconv.ParseInt(p, 10, 64); err != nil {
+			return APIResponseErr("The user ID is not valid.")
+		}
+	}
+	if id != u.Id && !isSuper(r, u.Id) {
+		if !can(r, s, u, roles.CapManageUsers) {
+			return errLowPrivileges()
+		}
+		role, err := siteRole(r, id, s.Id)
+		if err != nil {
+			log.Err(r, "could not get site role for user", err)
+			return errProcessing()
+		}
+		if role == roles.Role_NONE {
+			return errNotFound("The user does not exist.")
+		}
+	}
+	user, err := data.Conn.UserById(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errNotFound("The user does not exist.")
+		}
+		log.Err(r, "could not get user by ID", err)
+		return errProcessing()
+	}
+	user.Pass = nil
+	return &APIResponse{Body: user}
+}
+
+// ReqUserRoles: GET users/{id}/roles
+type ReqUserRoles struct{}
+
+// authorized says if the user is logged in. Only super users may view the roles of other users,
+// which is checked by the handler.
+func (*ReqUserRoles) authorized(_ *http.Request, _ *data.Site, u *data.User) bool {
+	return u.Id != 0
+}
+
+// handle responds with the roles of the user identified in the path on all the sites where the user
+// has a role.
+func (*ReqUserRoles) handle(r *http.Request, _ *data.Site, u *data.User) *APIResponse {
+	id, err := strconv.ParseInt(route.Param(r, "id"), 10, 64)
+	if err != nil {
+		return APIResponseErr("The user ID is not valid.")
+	}
+	if id != u.Id && !isSuper(r, u.Id) {
+		return errLowPrivileges()
+	}
+	ms, err := memberships(r, id)
+	if err != nil {
+		log.Err(r, "could not get a user's roles", err)
+		return errProcessing()
+	}
+	resp := make(RespUserRoles, len(ms.Sites))
+	for _, m := range ms.Sites {
+		if resp[m.Site], err = roles.SiteRoleName(m.Site, m.Role); err != nil {
+			log.Err(r, "could not get name of role", err)
+			return errProcessing()
+		}
+	}
+	return &APIResponse{Body: resp}
+}
+
+// RespUserRoles maps site IDs to the user's role on the site.
+type RespUserRoles map[int64]string
+
 // userAgentToken returns the (salted) user agent token to use in a cookie.
 // The returned slice is a 32-byte long hash made out of the concatenation
 // of the user agent of the client and the global TOKEN_SALT.
@@ -376,7 +475,16 @@ func userAgentToken(userAgent string) []byte {
 	return h[:]
 }
 
-// hashPassword uses bcrypt to hash the password string and possibly return an error.
+// minPasswordLength is the minimum length of a password.
+const minPasswordLength = 6
+
+const errPasswordTooShort = "The password you chose is too short."
+
+// passwordParams are the cost parameters of new password hashes, set with the PASSWORD_HASH_PARAMS
+// variable.
+var passwordParams = password.DefaultParams
+
+// hashPassword hashes the password string with the current scheme and parameters.
 func hashPassword(p string) ([]byte, error) {
-	return bcrypt.GenerateFromPassword([]byte(p), 14)
+	return password.Hash(p, passwordParams)
 }
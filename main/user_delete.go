@@ -0,0 +1,354 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "github.com/dchenk/mazewire/pkg/api/v1"
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/hooks"
+	"github.com/dchenk/mazewire/pkg/hooks/payloads"
+	"github.com/dchenk/mazewire/pkg/log"
+	"github.com/dchenk/mazewire/pkg/password"
+	"github.com/dchenk/mazewire/pkg/plugins"
+	"github.com/dchenk/mazewire/pkg/roles"
+	"github.com/dchenk/mazewire/pkg/route"
+)
+
+// When the deletion of a user is requested, the account is closed right away: the meta of the user,
+// including the roles, the sessions, and the API tokens are deleted. The content of the user on each
+// site is then given to a member of the site chosen in the request or, if none is chosen, moved to the
+// trash and given to an owner of the site. The content is reassigned in batches, within the request for
+// users with little content and otherwise in the background, and the user is deleted last, calling the
+// user_deleted hook. The last owner of a site cannot be deleted.
+const (
+	userDeletionInlineMax = 200              // the most content items of a user reassigned within the request
+	userDeletionBatch     = 500              // how many content items are reassigned at a time
+	userDeletionLease     = time.Minute * 10 // how long a claimed deletion is left to the server processing it
+)
+
+// ReqUserDelete: DELETE user
+// The user deletes the account, confirming with the password. The reassign map gives, for any of the
+// sites, the ID of the member to whom the content of the user on the site is given.
+type ReqUserDelete struct {
+	Password string          `json:"password"`
+	Reassign map[int64]int64 `json:"reassign"`
+}
+
+// authorized returns true; the route requires the user to be logged in.
+func (*ReqUserDelete) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqUserDelete) handle(r *http.Request, _ *data.Site, u *data.User) *APIResponse {
+	hash, err := data.Conn.UserPassword(u.Id)
+	if err != nil {
+		log.Err(r, "could not get password of user", err)
+		return errProcessing()
+	}
+	ok, err := password.Verify(hash, req.Password)
+	if err != nil {
+		log.Err(r, "could not verify password", err)
+	}
+	if !ok {
+		return APIResponseErr("The password is not correct.")
+	}
+	return requestUserDeletion(r, u.Id, u.Id, req.Reassign)
+}
+
+// ReqUserDeleteOther: DELETE users/{id}
+// A super user deletes another user. The reassign map gives, for any of the sites, the ID of the member
+// to whom the content of the user on the site is given.
+type ReqUserDeleteOther struct {
+	Reassign map[int64]int64 `json:"reassign"`
+}
+
+// authorized returns true; the route requires a super user.
+func (*ReqUserDeleteOther) authorized(*http.Request, *data.Site, *data.User) bool {
+	return true
+}
+
+func (req *ReqUserDeleteOther) handle(r *http.Request, _ *data.Site, u *data.User) *APIResponse {
+	id, err := strconv.ParseInt(route.Param(r, "id"), 10, 64)
+	if err != nil {
+		return APIResponseErr("The user ID is not valid.")
+	}
+	if _, err = data.Conn.UserById(id); err != nil {
+		if err == sql.ErrNoRows {
+			return errNotFound("The user does not exist.")
+		}
+		log.Err(r, "could not get user by ID", err)
+		return errProcessing()
+	}
+	return requestUserDeletion(r, u.Id, id, req.Reassign)
+}
+
+// requestUserDeletion checks that the user with the ID id can be deleted with the content reassigned as
+// given, closes the account, and deletes the user within the request if the user has little content.
+func requestUserDeletion(r *http.Request, requestedBy, id int64, reassign map[int64]int64) *APIResponse {
+	ms, err := memberships(r, id)
+	if err != nil {
+		log.Err(r, "could not get site roles of user", err)
+		return errProcessing()
+	}
+	for siteID, to := range reassign {
+		if to == id {
+			return APIResponseErr("The content of the user cannot be given to the user being deleted.")
+		}
+		role, err := siteRole(r, to, siteID)
+		if err != nil {
+			log.Err(r, "could not get site role for user", err)
+			return errProcessing()
+		}
+		if !roleCan(r, siteID, role, roles.CapEditPages) {
+			return APIResponseErr(fmt.Sprintf("The user with ID %d cannot have content on the site with ID %d.",
+				to, siteID))
+		}
+	}
+
+	counts, err := data.Conn.ContentCountsByAuthor(id)
+	if err != nil {
+		log.Err(r, "could not count content of user", err)
+		return errProcessing()
+	}
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+
+	released, errResp := releaseOwnerships(r, id, ms)
+	if errResp != nil {
+		return errResp
+	}
+	del := &data.UserDeletion{UserId: id, RequestedBy: requestedBy, Reassign: formatReassign(reassign)}
+	if err = data.Conn.UserDeletionInsert(del); err != nil {
+		log.Err(r, "could not insert user deletion", err)
+		restoreOwnerships(r, id, released)
+		return errProcessing()
+	}
+	if err = closeAccount(id); err != nil {
+		log.Err(r, "could not close account of user", err)
+		return errProcessing()
+	}
+
+	resp := &apiv1.DeleteUserResponse{Queued: true, Content: total}
+	if total <= userDeletionInlineMax {
+		// If the deletion fails or is claimed by the background processing, it is left to be retried.
+		if ok, err := data.Conn.UserDeletionClaim(id, time.Now().Add(-userDeletionLease)); err != nil {
+			log.Err(r, "could not claim user deletion", err)
+		} else if ok {
+			if err = processUserDeletion(del); err != nil {
+				log.Err(r, "could not delete user", err)
+				failUserDeletion(r, id, err)
+			} else {
+				resp.Queued = false
+			}
+		}
+	}
+	return &APIResponse{Body: resp}
+}
+
+// releaseOwnerships takes away the owner roles of the user being deleted on the sites in ms and returns
+// the IDs of the sites where the user was an owner. Each role is taken only if the site has another owner,
+// which is checked as the role is removed, so that owners deleting themselves at once cannot leave a site
+// without an owner. If the user is the last owner of a site, the roles already taken are given back and
+// the error response to give is returned.
+func releaseOwnerships(r *http.Request, id int64, ms *roles.Memberships) ([]int64, *APIResponse) {
+	var released []int64
+	for _, m := range ms.Sites {
+		if m.Role != roles.Role_OWNER {
+			continue
+		}
+		err := roles.RemoveSiteRoleKeepOwner(id, m.Site)
+		if err == nil {
+			released = append(released, m.Site)
+			continue
+		}
+		if err == sql.ErrNoRows { // The user left the site since.
+			continue
+		}
+		restoreOwnerships(r, id, released)
+		if err == roles.ErrLastOwner {
+			return nil, APIResponseErr(fmt.Sprintf("The user is the last owner of the site with ID %d, which "+
+				"must have another owner first.", m.Site))
+		}
+		log.Err(r, "could not remove site role of user", err)
+		return nil, errProcessing()
+	}
+	return released, nil
+}
+
+// restoreOwnerships gives the user back the owner roles on the sites with the IDs given after the
+// deletion of the user failed.
+func restoreOwnerships(r *http.Request, id int64, sites []int64) {
+	for _, siteID := range sites {
+		if err := roles.SetSiteRole(id, siteID, int64(roles.Role_OWNER)); err != nil {
+			log.Err(r, fmt.Sprintf("could not give back the owner role on site %d", siteID), err)
+		}
+	}
+}
+
+// closeAccount clears the password and deletes the meta, including the site roles, the sessions, and the
+// API tokens of the user, so that the user can no longer make requests. The user remains until the
+// deletion is processed, which may be long after, so logins are also refused while the deletion is
+// pending (see deletionPending).
+func closeAccount(userID int64) error {
+	if err := data.Conn.UserPasswordUpdate(userID, []byte{}); err != nil {
+		return err
+	}
+	_, err := data.Conn.UserMetaDeleteAll(userID)
+	roles.Invalidate(userID)
+	if err != nil {
+		return err
+	}
+	if _, err = data.Conn.SessionsDeleteByUser(userID, 0, ""); err != nil {
+		return err
+	}
+	_, err = data.Conn.APITokensDeleteByUser(userID)
+	return err
+}
+
+// deletionPending says if the deletion of the user was requested and the account is closed.
+func deletionPending(userID int64) (bool, error) {
+	_, err := data.Conn.UserDeletion(userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// processUserDeletion reassigns the content of the user being deleted and deletes the user.
+func processUserDeletion(del *data.UserDeletion) error {
+	reassign, err := parseReassign(del.Reassign)
+	if err != nil {
+		return err
+	}
+	counts, err := data.Conn.ContentCountsByAuthor(del.UserId)
+	if err != nil {
+		return err
+	}
+	for siteID := range counts {
+		to, trash := reassign[siteID], false
+		if to == 0 {
+			if to, err = siteOwner(siteID, del.UserId); err != nil {
+				return err
+			}
+			trash = true
+		}
+		for {
+			n, err := data.Conn.ContentReassign(siteID, del.UserId, to, trash, userDeletionBatch)
+			if err != nil {
+				return err
+			}
+			if n < userDeletionBatch {
+				break
+			}
+		}
+	}
+
+	u, err := data.Conn.UserById(del.UserId)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		if err = data.Conn.UserDelete(del.UserId); err != nil {
+			return err
+		}
+		payload := &payloads.UserDeleted{UserId: u.Id, Username: u.Uname, Email: u.Email,
+			RequestedBy: del.RequestedBy}
+		if _, err = plugins.DoHook(hooks.UserDeleted, payload); err != nil {
+			log.Err(nil, "could not call the user_deleted hook handlers", err)
+		}
+	}
+	return data.Conn.UserDeletionDelete(del.UserId)
+}
+
+// failUserDeletion records the error with which the deletion of the user failed.
+func failUserDeletion(r *http.Request, userID int64, err error) {
+	if err = data.Conn.UserDeletionFail(userID, err.Error()); err != nil {
+		log.Err(r, "could not record failed user deletion", err)
+	}
+}
+
+// siteOwner returns the ID of the owner of the site who is given the trashed content of the user being
+// deleted, except: the owner with the lowest ID.
+func siteOwner(siteID, except int64) (int64, error) {
+	metas, err := data.Conn.UserMetaByKey(roles.SiteRoleKey(siteID))
+	if err != nil {
+		return 0, err
+	}
+	owner := strconv.FormatInt(int64(roles.Role_OWNER), 10)
+	var id int64
+	for i := range metas {
+		if string(metas[i].V) == owner && metas[i].UserId != except && (id == 0 || metas[i].UserId < id) {
+			id = metas[i].UserId
+		}
+	}
+	if id == 0 {
+		return 0, fmt.Errorf("site %d has no owner to be given trashed content", siteID)
+	}
+	return id, nil
+}
+
+// formatReassign formats the map of site IDs to the IDs of the users to whom content is given in the way
+// in which it is stored in a UserDeletion, like "3:17 5:2", in the order of the site IDs.
+func formatReassign(reassign map[int64]int64) string {
+	pairs := make([]string, 0, len(reassign))
+	for siteID, to := range reassign {
+		pairs = append(pairs, strconv.FormatInt(siteID, 10)+":"+strconv.FormatInt(to, 10))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// parseReassign parses the reassignments of content stored in a UserDeletion.
+func parseReassign(s string) (map[int64]int64, error) {
+	reassign := make(map[int64]int64)
+	for _, pair := range strings.Fields(s) {
+		i := strings.IndexByte(pair, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("malformed content reassignment %q", pair)
+		}
+		siteID, err := strconv.ParseInt(pair[:i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed content reassignment %q", pair)
+		}
+		to, err := strconv.ParseInt(pair[i+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed content reassignment %q", pair)
+		}
+		reassign[siteID] = to
+	}
+	return reassign, nil
+}
+
+// processUserDeletions processes in the background the deletions of users that are not claimed by a
+// server, which are the deletions of users with a lot of content and those that failed.
+func processUserDeletions() {
+	for range time.Tick(time.Minute) {
+		dels, err := data.Conn.UserDeletionsUnclaimed(time.Now().Add(-userDeletionLease), 10)
+		if err != nil {
+			log.Err(nil, "could not get user deletions", err)
+			continue
+		}
+		for i := range dels {
+			ok, err := data.Conn.UserDeletionClaim(dels[i].UserId, time.Now().Add(-userDeletionLease))
+			if err != nil {
+				log.Err(nil, "could not claim user deletion", err)
+				continue
+			}
+			if !ok {
+				continue // Another server claimed it.
+			}
+			if err = processUserDeletion(&dels[i]); err != nil {
+				log.Err(nil, fmt.Sprintf("could not delete user %d", dels[i].UserId), err)
+				failUserDeletion(nil, dels[i].UserId, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+// The purposes of the tokens sent to users by email. Each user has at most one live token of each
+// purpose: creating a token invalidates the one created before it.
+const (
+	tokenPasswordReset = "password-reset"
+	tokenVerifyEmail   = "verify-email"
+)
+
+// userTokenNonceSize is the number of random bytes that make each token unique.
+const userTokenNonceSize = 16
+
+// userTokenMetaKey returns the user meta key under which the hash of the nonce of the live token of
+// the purpose is stored.
+func userTokenMetaKey(purpose string) string {
+	return purpose + "-token"
+}
+
+// createUserToken creates a signed token for the user on the site that can be used once for the
+// purpose given until it expires. The body of the token consists of the encoded expiration time (Unix
+// seconds), user ID, and site ID followed by a random nonce, the hash of which is stored in
+// the user meta until the token is used. The signature covers the purpose as well so that a token cannot
+// be used for another purpose.
+func createUserToken(purpose string, userID, siteID int64, ttl time.Duration) (string, error) {
+	nonce := make([]byte, userTokenNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	nonceHash := sha256.Sum256(nonce)
+	if _, err := data.Conn.UserMetaUpdate(userID, userTokenMetaKey(purpose), []byte(hex.EncodeToString(nonceHash[:]))); err != nil {
+		return "", err
+	}
+	body := make([]byte, 0, tokenIntSize*3+userTokenNonceSize)
+	body = appendTokenInt(body, time.Now().Add(ttl).Unix())
+	body = appendTokenInt(body, userID)
+	body = appendTokenInt(body, siteID)
+	body = append(body, nonce...)
+	return signToken(purpose+".", base64.RawURLEncoding.EncodeToString(body)), nil
+}
+
+// consumeUserToken checks a token created by createUserToken for the purpose and, if it is valid, uses
+// it up so that it cannot be used again. The user ID and the site ID of a valid token are returned.
+// The error is not nil only if the database could not be reached.
+func consumeUserToken(purpose, token string) (userID, siteID int64, ok bool, err error) {
+	bodyEncoded, ok := verifyToken(purpose+".", token)
+	if !ok {
+		return 0, 0, false, nil
+	}
+	body, err := base64.RawURLEncoding.DecodeString(bodyEncoded)
+	if err != nil {
+		return 0, 0, false, nil
+	}
+	var expires int64
+	if expires, body, err = readTokenInt(body); err != nil || time.Now().Unix() > expires {
+		return 0, 0, false, nil
+	}
+	if userID, body, err = readTokenInt(body); err != nil {
+		return 0, 0, false, nil
+	}
+	if siteID, body, err = readTokenInt(body); err != nil || len(body) != userTokenNonceSize {
+		return 0, 0, false, nil
+	}
+
+	k := userTokenMetaKey(purpose)
+	stored, err := data.Conn.UserMetaV(userID, k)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, false, nil
+		}
+		return 0, 0, false, err
+	}
+	nonceHash := sha256.Sum256(body)
+	if !hmac.Equal(stored, []byte(hex.EncodeToString(nonceHash[:]))) {
+		return 0, 0, false, nil
+	}
+	// Of concurrent requests with the same token, only the one that deletes it may use it.
+	n, err := data.Conn.UserMetaDelete(userID, k)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return userID, siteID, n > 0, nil
+}
+
+// revokeUserToken invalidates the live token of the purpose of the user, if there is one.
+func revokeUserToken(purpose string, userID int64) error {
+	_, err := data.Conn.UserMetaDelete(userID, userTokenMetaKey(purpose))
+	return err
+}
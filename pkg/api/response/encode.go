@@ -0,0 +1,189 @@
+// Package response encodes the responses of the API in the format requested by the client.
+//
+// Every response is sent in the envelope defined by the Response message, holding either the body
+// of a successful response along with any warnings, or the error message of a failed request. The
+// envelope can be encoded as plain JSON, in the Protocol Buffers binary format, or in the JSON
+// mapping of Protocol Buffers (protojson). Plain JSON is the default.
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/dchenk/mazewire/pkg/util"
+)
+
+// A Format is an encoding of the API responses.
+type Format int
+
+const (
+	JSON      Format = iota // plain JSON, with the body encoded using its JSON struct tags
+	Protobuf                // the Protocol Buffers binary format
+	ProtoJSON               // the Protocol Buffers JSON mapping, with the body as a google.protobuf.Any
+)
+
+// formats lists the formats in the order of preference when the client accepts several equally.
+var formats = [...]Format{JSON, Protobuf, ProtoJSON}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case Protobuf:
+		return util.ContentTypeProtobuf
+	case ProtoJSON:
+		return util.ContentTypeProtoJSON
+	default:
+		return util.ContentTypeJSON
+	}
+}
+
+// Negotiate chooses the format of the response given the value of the Accept header of a request.
+// If the header is empty, the format is JSON. If none of the formats is acceptable, ok is false.
+func Negotiate(accept string) (f Format, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return JSON, true
+	}
+	ranges := parseAccept(accept)
+	bestQ := 0.0
+	for _, format := range formats {
+		if q := format.quality(ranges); q > bestQ {
+			f, bestQ = format, q
+		}
+	}
+	return f, bestQ > 0
+}
+
+// A mediaRange is a media range from an Accept header with its quality value.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept parses the media ranges of an Accept header, skipping those that are malformed.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		slash := strings.IndexByte(mt, '/')
+		if slash < 0 {
+			continue
+		}
+		mr := mediaRange{typ: mt[:slash], subtype: mt[slash+1:], q: 1}
+		if qs, ok := params["q"]; ok {
+			q, err := strconv.ParseFloat(qs, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+			mr.q = q
+		}
+		ranges = append(ranges, mr)
+	}
+	return ranges
+}
+
+// quality returns the quality value given to the format by the most specific of the media ranges
+// that match it, or 0 if none match.
+func (f Format) quality(ranges []mediaRange) float64 {
+	var names []string // the media types by which the format is known
+	switch f {
+	case Protobuf:
+		names = []string{util.ContentTypeProtobuf, "application/x-protobuf"}
+	default:
+		names = []string{f.ContentType()}
+	}
+	q, specificity := 0.0, -1
+	for _, name := range names {
+		slash := strings.IndexByte(name, '/')
+		typ, subtype := name[:slash], name[slash+1:]
+		for _, mr := range ranges {
+			var s int
+			switch {
+			case mr.typ == typ && mr.subtype == subtype:
+				s = 2
+			case mr.typ == typ && mr.subtype == "*":
+				s = 1
+			case mr.typ == "*" && mr.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity || (s == specificity && mr.q > q) {
+				q, specificity = mr.q, s
+			}
+		}
+	}
+	return q
+}
+
+// jsonEnvelope is the envelope in the plain JSON format.
+type jsonEnvelope struct {
+	Body     interface{} `json:"body,omitempty"`
+	Warnings []string    `json:"warnings,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// Marshal encodes the body and the warnings of a successful response in the format f. The body may
+// be nil. In the Protocol Buffers formats, the type of the body must be registered with the proto
+// package so that it can be identified within a google.protobuf.Any.
+func Marshal(f Format, body proto.Message, warnings []string) ([]byte, error) {
+	if f == JSON {
+		env := jsonEnvelope{Warnings: warnings}
+		if body != nil {
+			env.Body = body
+		}
+		return json.Marshal(&env)
+	}
+	env := &Response{Warnings: warnings}
+	if body != nil {
+		if proto.MessageName(body) == "" {
+			return nil, fmt.Errorf("response: type %T is not a registered message", body)
+		}
+		var err error
+		if env.Body, err = ptypes.MarshalAny(body); err != nil {
+			return nil, err
+		}
+	}
+	return marshalProto(f, env)
+}
+
+// MarshalError encodes the error message of a failed request in the format f.
+func MarshalError(f Format, msg string) ([]byte, error) {
+	if f == JSON {
+		return json.Marshal(&jsonEnvelope{Error: msg})
+	}
+	return marshalProto(f, &Response{Error: msg})
+}
+
+func marshalProto(f Format, env *Response) ([]byte, error) {
+	if f == Protobuf {
+		return proto.Marshal(env)
+	}
+	var buf bytes.Buffer
+	if err := new(jsonpb.Marshaler).Marshal(&buf, env); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write writes the encoded response b in the format f with the HTTP status given.
+func Write(w http.ResponseWriter, f Format, status int, b []byte) error {
+	h := w.Header()
+	h.Set("Content-Type", f.ContentType())
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Add("Vary", "Accept")
+	w.WriteHeader(status)
+	_, err := w.Write(b)
+	return err
+}
@@ -0,0 +1,163 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		accept string
+		f      Format
+		ok     bool
+	}{
+		{"", JSON, true},
+		{"*/*", JSON, true},
+		{"application/json", JSON, true},
+		{"application/*", JSON, true},
+		{"application/protobuf", Protobuf, true},
+		{"application/x-protobuf", Protobuf, true},
+		{"application/protobuf+json", ProtoJSON, true},
+		{"application/json;q=0.5, application/protobuf", Protobuf, true},
+		{"application/protobuf;q=0.9, application/protobuf+json", ProtoJSON, true},
+		{"application/*;q=0.2, application/protobuf;q=0.8, */*;q=0.1", Protobuf, true},
+		{"application/json;q=0, */*", Protobuf, true},
+		{"text/html, application/xhtml+xml, */*;q=0.8", JSON, true},
+		{"text/html", JSON, false},
+		{"application/json;q=0", JSON, false},
+		{"application/json;q=x", JSON, false},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			f, ok := Negotiate(tc.accept)
+			if ok != tc.ok || (ok && f != tc.f) {
+				t.Errorf("got (%v, %v) but expected (%v, %v)", f, ok, tc.f, tc.ok)
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	body := &wrappers.StringValue{Value: "content"}
+	warnings := []string{"partly done"}
+
+	t.Run("json", func(t *testing.T) {
+		b, err := Marshal(JSON, body, warnings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(b), `{"body":{"value":"content"},"warnings":["partly done"]}`; got != want {
+			t.Errorf("got %s but expected %s", got, want)
+		}
+		b, err = Marshal(JSON, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "{}" {
+			t.Errorf("got %s for empty response", b)
+		}
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		b, err := Marshal(Protobuf, body, warnings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp Response
+		if err := proto.Unmarshal(b, &resp); err != nil {
+			t.Fatal(err)
+		}
+		checkResponse(t, &resp, body, warnings)
+	})
+
+	t.Run("protojson", func(t *testing.T) {
+		b, err := Marshal(ProtoJSON, body, warnings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), `"@type":"type.googleapis.com/google.protobuf.StringValue"`) {
+			t.Errorf("body type not identified in %s", b)
+		}
+		var resp Response
+		if err := jsonpb.UnmarshalString(string(b), &resp); err != nil {
+			t.Fatal(err)
+		}
+		checkResponse(t, &resp, body, warnings)
+	})
+}
+
+func checkResponse(t *testing.T, resp *Response, body proto.Message, warnings []string) {
+	t.Helper()
+	var got wrappers.StringValue
+	if err := ptypes.UnmarshalAny(resp.Body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&got, body) {
+		t.Errorf("got body %v but expected %v", &got, body)
+	}
+	if !reflect.DeepEqual(resp.Warnings, warnings) {
+		t.Errorf("got warnings %v but expected %v", resp.Warnings, warnings)
+	}
+}
+
+// unregistered is a message type not registered with the proto package.
+type unregistered struct{}
+
+func (*unregistered) Reset()         {}
+func (*unregistered) String() string { return "" }
+func (*unregistered) ProtoMessage()  {}
+
+func TestMarshalUnregistered(t *testing.T) {
+	if _, err := Marshal(Protobuf, new(unregistered), nil); err == nil {
+		t.Error("no error for unregistered message type")
+	}
+	if _, err := Marshal(JSON, new(unregistered), nil); err != nil {
+		t.Errorf("got error for plain JSON: %v", err)
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	for _, f := range formats {
+		b, err := MarshalError(f, "failed")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp Response
+		switch f {
+		case JSON:
+			err = json.Unmarshal(b, &resp)
+		case Protobuf:
+			err = proto.Unmarshal(b, &resp)
+		case ProtoJSON:
+			err = jsonpb.UnmarshalString(string(b), &resp)
+		}
+		if err != nil {
+			t.Fatalf("format %d: %v", f, err)
+		}
+		if resp.Error != "failed" || resp.Body != nil || resp.Warnings != nil {
+			t.Errorf("format %d: got %v", f, &resp)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := Write(w, Protobuf, 400, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 400 || w.Header().Get("Content-Type") != "application/protobuf" || w.Body.String() != "x" {
+		t.Errorf("got %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+	if w.Header().Get("Vary") != "Accept" {
+		t.Error("Vary header not set")
+	}
+}
@@ -0,0 +1,103 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: api/response/response.proto
+
+package response
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	any "github.com/golang/protobuf/ptypes/any"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// A Response is the envelope in which every response of the API is sent.
+type Response struct {
+	// The body of a successful response; the type depends on the endpoint.
+	Body *any.Any `protobuf:"bytes,1,opt,name=body,proto3" json:"body,omitempty"`
+	// Warnings are added when non-critical parts of a request fail.
+	Warnings []string `protobuf:"bytes,2,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	// The error message of a failed request. If set, the other fields are empty.
+	Error                string   `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Response) Reset()         { *m = Response{} }
+func (m *Response) String() string { return proto.CompactTextString(m) }
+func (*Response) ProtoMessage()    {}
+func (*Response) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2fc113328d150d9c, []int{0}
+}
+
+func (m *Response) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Response.Unmarshal(m, b)
+}
+func (m *Response) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Response.Marshal(b, m, deterministic)
+}
+func (m *Response) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Response.Merge(m, src)
+}
+func (m *Response) XXX_Size() int {
+	return xxx_messageInfo_Response.Size(m)
+}
+func (m *Response) XXX_DiscardUnknown() {
+	xxx_messageInfo_Response.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Response proto.InternalMessageInfo
+
+func (m *Response) GetBody() *any.Any {
+	if m != nil {
+		return m.Body
+	}
+	return nil
+}
+
+func (m *Response) GetWarnings() []string {
+	if m != nil {
+		return m.Warnings
+	}
+	return nil
+}
+
+func (m *Response) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*Response)(nil), "response.Response")
+}
+
+func init() { proto.RegisterFile("api/response/response.proto", fileDescriptor_2fc113328d150d9c) }
+
+var fileDescriptor_2fc113328d150d9c = []byte{
+	// 182 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x8f, 0xc1, 0x8a, 0x83, 0x30,
+	0x10, 0x40, 0x71, 0xdd, 0x5d, 0x34, 0xbd, 0x05, 0x0f, 0xd6, 0x5e, 0xa4, 0xa7, 0x40, 0x69, 0x02,
+	0xed, 0x17, 0xb4, 0x9f, 0x90, 0x63, 0x6f, 0x89, 0xc6, 0x18, 0xac, 0x99, 0x10, 0x15, 0xb1, 0x5f,
+	0x5f, 0xd0, 0x2a, 0xbd, 0xcd, 0x1b, 0xde, 0x63, 0x18, 0x74, 0x10, 0xce, 0x30, 0xaf, 0x3a, 0x07,
+	0xb6, 0x53, 0xdb, 0x40, 0x9d, 0x87, 0x1e, 0x70, 0xb4, 0x72, 0xb6, 0xd7, 0x00, 0xfa, 0xa9, 0xd8,
+	0xbc, 0x97, 0x43, 0xc5, 0x84, 0x9d, 0x16, 0xe9, 0x58, 0xa1, 0x88, 0x7f, 0x34, 0x4c, 0xd0, 0xaf,
+	0x84, 0x72, 0x4a, 0x83, 0x3c, 0x20, 0xbb, 0x4b, 0x42, 0x97, 0x8a, 0xae, 0x15, 0xbd, 0xd9, 0x89,
+	0xcf, 0x06, 0xce, 0x50, 0x34, 0x0a, 0x6f, 0x8d, 0xd5, 0x5d, 0xfa, 0x93, 0x87, 0x24, 0xe6, 0x1b,
+	0xe3, 0x04, 0xfd, 0x29, 0xef, 0xc1, 0xa7, 0x61, 0x1e, 0x90, 0x98, 0x2f, 0x70, 0x3f, 0x3f, 0x4e,
+	0xda, 0xf4, 0xf5, 0x20, 0x69, 0x01, 0x2d, 0x2b, 0x8b, 0x5a, 0xd9, 0x86, 0xb5, 0xe2, 0xa5, 0x46,
+	0xe3, 0x15, 0x73, 0x8d, 0x66, 0xdf, 0xaf, 0xc8, 0xff, 0xf9, 0xe8, 0xf5, 0x3d, 0x00, 0x75, 0xdf,
+	0xb0, 0x83, 0xe1, 0x00, 0x00, 0x00,
+}
@@ -0,0 +1,2857 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: api/v1/mazewire.proto
+
+package v1
+
+import (
+	context "context"
+	fmt "fmt"
+	data "github.com/dchenk/mazewire/pkg/data"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type LoginRequest struct {
+	// Either the username or the email address.
+	User string `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	// The password.
+	Pass                 string   `protobuf:"bytes,2,opt,name=pass,proto3" json:"pass,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LoginRequest) Reset()         { *m = LoginRequest{} }
+func (m *LoginRequest) String() string { return proto.CompactTextString(m) }
+func (*LoginRequest) ProtoMessage()    {}
+func (*LoginRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{0}
+}
+
+func (m *LoginRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoginRequest.Unmarshal(m, b)
+}
+func (m *LoginRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoginRequest.Marshal(b, m, deterministic)
+}
+func (m *LoginRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoginRequest.Merge(m, src)
+}
+func (m *LoginRequest) XXX_Size() int {
+	return xxx_messageInfo_LoginRequest.Size(m)
+}
+func (m *LoginRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoginRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LoginRequest proto.InternalMessageInfo
+
+func (m *LoginRequest) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
+}
+
+func (m *LoginRequest) GetPass() string {
+	if m != nil {
+		return m.Pass
+	}
+	return ""
+}
+
+type LoginResponse struct {
+	// The token to send in the "authorization" metadata. The token is valid only for the site and
+	// user agent of the login request.
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// When the token expires, as a Unix timestamp in seconds. The login session identified by the token
+	// ends sooner if it is not used for a while.
+	Expires int64 `protobuf:"varint,2,opt,name=expires,proto3" json:"expires,omitempty"`
+	// If the user must complete the login with a second factor, there is no token but the challenge
+	// to answer with VerifyTwoFactor.
+	TwoFactor *TwoFactorChallenge `protobuf:"bytes,3,opt,name=two_factor,json=twoFactor,proto3" json:"two_factor,omitempty"`
+	// The recovery codes of the user, given only when two-factor authentication is set up while
+	// logging in.
+	RecoveryCodes        []string `protobuf:"bytes,4,rep,name=recovery_codes,json=recoveryCodes,proto3" json:"recovery_codes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LoginResponse) Reset()         { *m = LoginResponse{} }
+func (m *LoginResponse) String() string { return proto.CompactTextString(m) }
+func (*LoginResponse) ProtoMessage()    {}
+func (*LoginResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{1}
+}
+
+func (m *LoginResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoginResponse.Unmarshal(m, b)
+}
+func (m *LoginResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoginResponse.Marshal(b, m, deterministic)
+}
+func (m *LoginResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoginResponse.Merge(m, src)
+}
+func (m *LoginResponse) XXX_Size() int {
+	return xxx_messageInfo_LoginResponse.Size(m)
+}
+func (m *LoginResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoginResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LoginResponse proto.InternalMessageInfo
+
+func (m *LoginResponse) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+func (m *LoginResponse) GetTwoFactor() *TwoFactorChallenge {
+	if m != nil {
+		return m.TwoFactor
+	}
+	return nil
+}
+
+func (m *LoginResponse) GetRecoveryCodes() []string {
+	if m != nil {
+		return m.RecoveryCodes
+	}
+	return nil
+}
+
+// A TwoFactorChallenge is the state of a login that requires a second factor.
+type TwoFactorChallenge struct {
+	// The signed token identifying the login, valid for a few minutes.
+	Challenge string `protobuf:"bytes,1,opt,name=challenge,proto3" json:"challenge,omitempty"`
+	// Whether the user must set up two-factor authentication, which the site requires for their role,
+	// before completing the login. The authenticator app is set up with the secret or the URI.
+	Enroll bool `protobuf:"varint,2,opt,name=enroll,proto3" json:"enroll,omitempty"`
+	// The secret key, encoded in base32, if enroll is true.
+	Secret string `protobuf:"bytes,3,opt,name=secret,proto3" json:"secret,omitempty"`
+	// The otpauth URI, to be shown as a QR code, if enroll is true.
+	Uri                  string   `protobuf:"bytes,4,opt,name=uri,proto3" json:"uri,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TwoFactorChallenge) Reset()         { *m = TwoFactorChallenge{} }
+func (m *TwoFactorChallenge) String() string { return proto.CompactTextString(m) }
+func (*TwoFactorChallenge) ProtoMessage()    {}
+func (*TwoFactorChallenge) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{2}
+}
+
+func (m *TwoFactorChallenge) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TwoFactorChallenge.Unmarshal(m, b)
+}
+func (m *TwoFactorChallenge) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TwoFactorChallenge.Marshal(b, m, deterministic)
+}
+func (m *TwoFactorChallenge) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TwoFactorChallenge.Merge(m, src)
+}
+func (m *TwoFactorChallenge) XXX_Size() int {
+	return xxx_messageInfo_TwoFactorChallenge.Size(m)
+}
+func (m *TwoFactorChallenge) XXX_DiscardUnknown() {
+	xxx_messageInfo_TwoFactorChallenge.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TwoFactorChallenge proto.InternalMessageInfo
+
+func (m *TwoFactorChallenge) GetChallenge() string {
+	if m != nil {
+		return m.Challenge
+	}
+	return ""
+}
+
+func (m *TwoFactorChallenge) GetEnroll() bool {
+	if m != nil {
+		return m.Enroll
+	}
+	return false
+}
+
+func (m *TwoFactorChallenge) GetSecret() string {
+	if m != nil {
+		return m.Secret
+	}
+	return ""
+}
+
+func (m *TwoFactorChallenge) GetUri() string {
+	if m != nil {
+		return m.Uri
+	}
+	return ""
+}
+
+type VerifyTwoFactorRequest struct {
+	// The challenge given by Login.
+	Challenge string `protobuf:"bytes,1,opt,name=challenge,proto3" json:"challenge,omitempty"`
+	// A one-time password or a recovery code.
+	Code                 string   `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VerifyTwoFactorRequest) Reset()         { *m = VerifyTwoFactorRequest{} }
+func (m *VerifyTwoFactorRequest) String() string { return proto.CompactTextString(m) }
+func (*VerifyTwoFactorRequest) ProtoMessage()    {}
+func (*VerifyTwoFactorRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{3}
+}
+
+func (m *VerifyTwoFactorRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VerifyTwoFactorRequest.Unmarshal(m, b)
+}
+func (m *VerifyTwoFactorRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VerifyTwoFactorRequest.Marshal(b, m, deterministic)
+}
+func (m *VerifyTwoFactorRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VerifyTwoFactorRequest.Merge(m, src)
+}
+func (m *VerifyTwoFactorRequest) XXX_Size() int {
+	return xxx_messageInfo_VerifyTwoFactorRequest.Size(m)
+}
+func (m *VerifyTwoFactorRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VerifyTwoFactorRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VerifyTwoFactorRequest proto.InternalMessageInfo
+
+func (m *VerifyTwoFactorRequest) GetChallenge() string {
+	if m != nil {
+		return m.Challenge
+	}
+	return ""
+}
+
+func (m *VerifyTwoFactorRequest) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+// A TwoFactorEnrollment is the secret key of an authenticator app being set up.
+type TwoFactorEnrollment struct {
+	// The secret key, encoded in base32.
+	Secret string `protobuf:"bytes,1,opt,name=secret,proto3" json:"secret,omitempty"`
+	// The otpauth URI, to be shown as a QR code.
+	Uri                  string   `protobuf:"bytes,2,opt,name=uri,proto3" json:"uri,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TwoFactorEnrollment) Reset()         { *m = TwoFactorEnrollment{} }
+func (m *TwoFactorEnrollment) String() string { return proto.CompactTextString(m) }
+func (*TwoFactorEnrollment) ProtoMessage()    {}
+func (*TwoFactorEnrollment) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{4}
+}
+
+func (m *TwoFactorEnrollment) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TwoFactorEnrollment.Unmarshal(m, b)
+}
+func (m *TwoFactorEnrollment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TwoFactorEnrollment.Marshal(b, m, deterministic)
+}
+func (m *TwoFactorEnrollment) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TwoFactorEnrollment.Merge(m, src)
+}
+func (m *TwoFactorEnrollment) XXX_Size() int {
+	return xxx_messageInfo_TwoFactorEnrollment.Size(m)
+}
+func (m *TwoFactorEnrollment) XXX_DiscardUnknown() {
+	xxx_messageInfo_TwoFactorEnrollment.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TwoFactorEnrollment proto.InternalMessageInfo
+
+func (m *TwoFactorEnrollment) GetSecret() string {
+	if m != nil {
+		return m.Secret
+	}
+	return ""
+}
+
+func (m *TwoFactorEnrollment) GetUri() string {
+	if m != nil {
+		return m.Uri
+	}
+	return ""
+}
+
+// RecoveryCodes are the single-use codes that a user may use instead of one-time passwords. They are
+// shown only once, when generated.
+type RecoveryCodes struct {
+	Codes                []string `protobuf:"bytes,1,rep,name=codes,proto3" json:"codes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RecoveryCodes) Reset()         { *m = RecoveryCodes{} }
+func (m *RecoveryCodes) String() string { return proto.CompactTextString(m) }
+func (*RecoveryCodes) ProtoMessage()    {}
+func (*RecoveryCodes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{5}
+}
+
+func (m *RecoveryCodes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RecoveryCodes.Unmarshal(m, b)
+}
+func (m *RecoveryCodes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RecoveryCodes.Marshal(b, m, deterministic)
+}
+func (m *RecoveryCodes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecoveryCodes.Merge(m, src)
+}
+func (m *RecoveryCodes) XXX_Size() int {
+	return xxx_messageInfo_RecoveryCodes.Size(m)
+}
+func (m *RecoveryCodes) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecoveryCodes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecoveryCodes proto.InternalMessageInfo
+
+func (m *RecoveryCodes) GetCodes() []string {
+	if m != nil {
+		return m.Codes
+	}
+	return nil
+}
+
+// An OIDCProvider is an external OpenID Connect identity provider with which users may log in to the site.
+type OIDCProvider struct {
+	// The name identifying the provider in the paths of the login routes.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// The name of the provider shown to users.
+	Label                string   `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OIDCProvider) Reset()         { *m = OIDCProvider{} }
+func (m *OIDCProvider) String() string { return proto.CompactTextString(m) }
+func (*OIDCProvider) ProtoMessage()    {}
+func (*OIDCProvider) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{6}
+}
+
+func (m *OIDCProvider) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OIDCProvider.Unmarshal(m, b)
+}
+func (m *OIDCProvider) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OIDCProvider.Marshal(b, m, deterministic)
+}
+func (m *OIDCProvider) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OIDCProvider.Merge(m, src)
+}
+func (m *OIDCProvider) XXX_Size() int {
+	return xxx_messageInfo_OIDCProvider.Size(m)
+}
+func (m *OIDCProvider) XXX_DiscardUnknown() {
+	xxx_messageInfo_OIDCProvider.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OIDCProvider proto.InternalMessageInfo
+
+func (m *OIDCProvider) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *OIDCProvider) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+type ListOIDCProvidersResponse struct {
+	Providers            []*OIDCProvider `protobuf:"bytes,1,rep,name=providers,proto3" json:"providers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ListOIDCProvidersResponse) Reset()         { *m = ListOIDCProvidersResponse{} }
+func (m *ListOIDCProvidersResponse) String() string { return proto.CompactTextString(m) }
+func (*ListOIDCProvidersResponse) ProtoMessage()    {}
+func (*ListOIDCProvidersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{7}
+}
+
+func (m *ListOIDCProvidersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListOIDCProvidersResponse.Unmarshal(m, b)
+}
+func (m *ListOIDCProvidersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListOIDCProvidersResponse.Marshal(b, m, deterministic)
+}
+func (m *ListOIDCProvidersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListOIDCProvidersResponse.Merge(m, src)
+}
+func (m *ListOIDCProvidersResponse) XXX_Size() int {
+	return xxx_messageInfo_ListOIDCProvidersResponse.Size(m)
+}
+func (m *ListOIDCProvidersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListOIDCProvidersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListOIDCProvidersResponse proto.InternalMessageInfo
+
+func (m *ListOIDCProvidersResponse) GetProviders() []*OIDCProvider {
+	if m != nil {
+		return m.Providers
+	}
+	return nil
+}
+
+// An Identity is an account of a user at an external identity provider linked to the user's account.
+type Identity struct {
+	// The issuer URL of the provider.
+	Issuer string `protobuf:"bytes,1,opt,name=issuer,proto3" json:"issuer,omitempty"`
+	// The ID of the account at the provider.
+	Subject string `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	// The name of the provider on the site, if the site is configured with the provider.
+	Provider string `protobuf:"bytes,3,opt,name=provider,proto3" json:"provider,omitempty"`
+	// The email address of the account at the provider when it was linked.
+	Email                string   `protobuf:"bytes,4,opt,name=email,proto3" json:"email,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Identity) Reset()         { *m = Identity{} }
+func (m *Identity) String() string { return proto.CompactTextString(m) }
+func (*Identity) ProtoMessage()    {}
+func (*Identity) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{8}
+}
+
+func (m *Identity) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Identity.Unmarshal(m, b)
+}
+func (m *Identity) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Identity.Marshal(b, m, deterministic)
+}
+func (m *Identity) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Identity.Merge(m, src)
+}
+func (m *Identity) XXX_Size() int {
+	return xxx_messageInfo_Identity.Size(m)
+}
+func (m *Identity) XXX_DiscardUnknown() {
+	xxx_messageInfo_Identity.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Identity proto.InternalMessageInfo
+
+func (m *Identity) GetIssuer() string {
+	if m != nil {
+		return m.Issuer
+	}
+	return ""
+}
+
+func (m *Identity) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *Identity) GetProvider() string {
+	if m != nil {
+		return m.Provider
+	}
+	return ""
+}
+
+func (m *Identity) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+type ListIdentitiesResponse struct {
+	Identities           []*Identity `protobuf:"bytes,1,rep,name=identities,proto3" json:"identities,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *ListIdentitiesResponse) Reset()         { *m = ListIdentitiesResponse{} }
+func (m *ListIdentitiesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListIdentitiesResponse) ProtoMessage()    {}
+func (*ListIdentitiesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{9}
+}
+
+func (m *ListIdentitiesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListIdentitiesResponse.Unmarshal(m, b)
+}
+func (m *ListIdentitiesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListIdentitiesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListIdentitiesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListIdentitiesResponse.Merge(m, src)
+}
+func (m *ListIdentitiesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListIdentitiesResponse.Size(m)
+}
+func (m *ListIdentitiesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListIdentitiesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListIdentitiesResponse proto.InternalMessageInfo
+
+func (m *ListIdentitiesResponse) GetIdentities() []*Identity {
+	if m != nil {
+		return m.Identities
+	}
+	return nil
+}
+
+type ListAPITokensResponse struct {
+	// The tokens, without their hashes.
+	Tokens               []*data.APIToken `protobuf:"bytes,1,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *ListAPITokensResponse) Reset()         { *m = ListAPITokensResponse{} }
+func (m *ListAPITokensResponse) String() string { return proto.CompactTextString(m) }
+func (*ListAPITokensResponse) ProtoMessage()    {}
+func (*ListAPITokensResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{10}
+}
+
+func (m *ListAPITokensResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAPITokensResponse.Unmarshal(m, b)
+}
+func (m *ListAPITokensResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAPITokensResponse.Marshal(b, m, deterministic)
+}
+func (m *ListAPITokensResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAPITokensResponse.Merge(m, src)
+}
+func (m *ListAPITokensResponse) XXX_Size() int {
+	return xxx_messageInfo_ListAPITokensResponse.Size(m)
+}
+func (m *ListAPITokensResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAPITokensResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAPITokensResponse proto.InternalMessageInfo
+
+func (m *ListAPITokensResponse) GetTokens() []*data.APIToken {
+	if m != nil {
+		return m.Tokens
+	}
+	return nil
+}
+
+// A CreatedAPIToken is an API token just created along with the complete token to be sent in the
+// Authorization header, which is shown only once.
+type CreatedAPIToken struct {
+	Token                *data.APIToken `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Secret               string         `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *CreatedAPIToken) Reset()         { *m = CreatedAPIToken{} }
+func (m *CreatedAPIToken) String() string { return proto.CompactTextString(m) }
+func (*CreatedAPIToken) ProtoMessage()    {}
+func (*CreatedAPIToken) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{11}
+}
+
+func (m *CreatedAPIToken) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreatedAPIToken.Unmarshal(m, b)
+}
+func (m *CreatedAPIToken) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreatedAPIToken.Marshal(b, m, deterministic)
+}
+func (m *CreatedAPIToken) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreatedAPIToken.Merge(m, src)
+}
+func (m *CreatedAPIToken) XXX_Size() int {
+	return xxx_messageInfo_CreatedAPIToken.Size(m)
+}
+func (m *CreatedAPIToken) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreatedAPIToken.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreatedAPIToken proto.InternalMessageInfo
+
+func (m *CreatedAPIToken) GetToken() *data.APIToken {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+func (m *CreatedAPIToken) GetSecret() string {
+	if m != nil {
+		return m.Secret
+	}
+	return ""
+}
+
+type ListInvitationsResponse struct {
+	// The invitations, without their hashes.
+	Invitations          []*data.Invitation `protobuf:"bytes,1,rep,name=invitations,proto3" json:"invitations,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *ListInvitationsResponse) Reset()         { *m = ListInvitationsResponse{} }
+func (m *ListInvitationsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListInvitationsResponse) ProtoMessage()    {}
+func (*ListInvitationsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{12}
+}
+
+func (m *ListInvitationsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListInvitationsResponse.Unmarshal(m, b)
+}
+func (m *ListInvitationsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListInvitationsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListInvitationsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListInvitationsResponse.Merge(m, src)
+}
+func (m *ListInvitationsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListInvitationsResponse.Size(m)
+}
+func (m *ListInvitationsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListInvitationsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListInvitationsResponse proto.InternalMessageInfo
+
+func (m *ListInvitationsResponse) GetInvitations() []*data.Invitation {
+	if m != nil {
+		return m.Invitations
+	}
+	return nil
+}
+
+// A RoleInfo describes a role that members of a site may have: a built-in role or a custom role of
+// the site.
+type RoleInfo struct {
+	// The number of the role; custom roles are numbered starting with 100.
+	Id   int64  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// The capabilities of the role, in alphabetical order.
+	Capabilities         []string `protobuf:"bytes,3,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	Custom               bool     `protobuf:"varint,4,opt,name=custom,proto3" json:"custom,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RoleInfo) Reset()         { *m = RoleInfo{} }
+func (m *RoleInfo) String() string { return proto.CompactTextString(m) }
+func (*RoleInfo) ProtoMessage()    {}
+func (*RoleInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{13}
+}
+
+func (m *RoleInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RoleInfo.Unmarshal(m, b)
+}
+func (m *RoleInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RoleInfo.Marshal(b, m, deterministic)
+}
+func (m *RoleInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RoleInfo.Merge(m, src)
+}
+func (m *RoleInfo) XXX_Size() int {
+	return xxx_messageInfo_RoleInfo.Size(m)
+}
+func (m *RoleInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_RoleInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RoleInfo proto.InternalMessageInfo
+
+func (m *RoleInfo) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *RoleInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RoleInfo) GetCapabilities() []string {
+	if m != nil {
+		return m.Capabilities
+	}
+	return nil
+}
+
+func (m *RoleInfo) GetCustom() bool {
+	if m != nil {
+		return m.Custom
+	}
+	return false
+}
+
+type ListRolesResponse struct {
+	// The built-in roles from the lowest, followed by the custom roles of the site.
+	Roles []*RoleInfo `protobuf:"bytes,1,rep,name=roles,proto3" json:"roles,omitempty"`
+	// All of the capabilities that roles may have, including those registered by plugins.
+	Capabilities         []string `protobuf:"bytes,2,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRolesResponse) Reset()         { *m = ListRolesResponse{} }
+func (m *ListRolesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListRolesResponse) ProtoMessage()    {}
+func (*ListRolesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{14}
+}
+
+func (m *ListRolesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRolesResponse.Unmarshal(m, b)
+}
+func (m *ListRolesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRolesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListRolesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRolesResponse.Merge(m, src)
+}
+func (m *ListRolesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListRolesResponse.Size(m)
+}
+func (m *ListRolesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRolesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRolesResponse proto.InternalMessageInfo
+
+func (m *ListRolesResponse) GetRoles() []*RoleInfo {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+func (m *ListRolesResponse) GetCapabilities() []string {
+	if m != nil {
+		return m.Capabilities
+	}
+	return nil
+}
+
+// A SiteMember is a user who has a role on a site.
+type SiteMember struct {
+	// The user, without the password hash.
+	User *data.User `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	// The number of the user's role on the site.
+	Role int64 `protobuf:"varint,2,opt,name=role,proto3" json:"role,omitempty"`
+	// The name of the user's role on the site.
+	RoleName             string   `protobuf:"bytes,3,opt,name=role_name,json=roleName,proto3" json:"role_name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SiteMember) Reset()         { *m = SiteMember{} }
+func (m *SiteMember) String() string { return proto.CompactTextString(m) }
+func (*SiteMember) ProtoMessage()    {}
+func (*SiteMember) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{15}
+}
+
+func (m *SiteMember) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SiteMember.Unmarshal(m, b)
+}
+func (m *SiteMember) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SiteMember.Marshal(b, m, deterministic)
+}
+func (m *SiteMember) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SiteMember.Merge(m, src)
+}
+func (m *SiteMember) XXX_Size() int {
+	return xxx_messageInfo_SiteMember.Size(m)
+}
+func (m *SiteMember) XXX_DiscardUnknown() {
+	xxx_messageInfo_SiteMember.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SiteMember proto.InternalMessageInfo
+
+func (m *SiteMember) GetUser() *data.User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+func (m *SiteMember) GetRole() int64 {
+	if m != nil {
+		return m.Role
+	}
+	return 0
+}
+
+func (m *SiteMember) GetRoleName() string {
+	if m != nil {
+		return m.RoleName
+	}
+	return ""
+}
+
+type ListSiteMembersResponse struct {
+	// The members in the order of their usernames.
+	Members              []*SiteMember `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ListSiteMembersResponse) Reset()         { *m = ListSiteMembersResponse{} }
+func (m *ListSiteMembersResponse) String() string { return proto.CompactTextString(m) }
+func (*ListSiteMembersResponse) ProtoMessage()    {}
+func (*ListSiteMembersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{16}
+}
+
+func (m *ListSiteMembersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSiteMembersResponse.Unmarshal(m, b)
+}
+func (m *ListSiteMembersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSiteMembersResponse.Marshal(b, m, deterministic)
+}
+func (m *ListSiteMembersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSiteMembersResponse.Merge(m, src)
+}
+func (m *ListSiteMembersResponse) XXX_Size() int {
+	return xxx_messageInfo_ListSiteMembersResponse.Size(m)
+}
+func (m *ListSiteMembersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSiteMembersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSiteMembersResponse proto.InternalMessageInfo
+
+func (m *ListSiteMembersResponse) GetMembers() []*SiteMember {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+type DeleteUserResponse struct {
+	// Whether the deletion continues in the background; if not, the user is deleted.
+	Queued bool `protobuf:"varint,1,opt,name=queued,proto3" json:"queued,omitempty"`
+	// The number of content items of the user, which are given to other users or trashed.
+	Content              int64    `protobuf:"varint,2,opt,name=content,proto3" json:"content,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteUserResponse) Reset()         { *m = DeleteUserResponse{} }
+func (m *DeleteUserResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteUserResponse) ProtoMessage()    {}
+func (*DeleteUserResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{17}
+}
+
+func (m *DeleteUserResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteUserResponse.Unmarshal(m, b)
+}
+func (m *DeleteUserResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteUserResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteUserResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteUserResponse.Merge(m, src)
+}
+func (m *DeleteUserResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteUserResponse.Size(m)
+}
+func (m *DeleteUserResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteUserResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteUserResponse proto.InternalMessageInfo
+
+func (m *DeleteUserResponse) GetQueued() bool {
+	if m != nil {
+		return m.Queued
+	}
+	return false
+}
+
+func (m *DeleteUserResponse) GetContent() int64 {
+	if m != nil {
+		return m.Content
+	}
+	return 0
+}
+
+// A DataExportInfo describes an export of the personal data of the user.
+type DataExportInfo struct {
+	Export *data.DataExport `protobuf:"bytes,1,opt,name=export,proto3" json:"export,omitempty"`
+	// The link at which the archive is downloaded until it expires, if it is ready.
+	DownloadUrl          string   `protobuf:"bytes,2,opt,name=download_url,json=downloadUrl,proto3" json:"download_url,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DataExportInfo) Reset()         { *m = DataExportInfo{} }
+func (m *DataExportInfo) String() string { return proto.CompactTextString(m) }
+func (*DataExportInfo) ProtoMessage()    {}
+func (*DataExportInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{18}
+}
+
+func (m *DataExportInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DataExportInfo.Unmarshal(m, b)
+}
+func (m *DataExportInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DataExportInfo.Marshal(b, m, deterministic)
+}
+func (m *DataExportInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DataExportInfo.Merge(m, src)
+}
+func (m *DataExportInfo) XXX_Size() int {
+	return xxx_messageInfo_DataExportInfo.Size(m)
+}
+func (m *DataExportInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_DataExportInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DataExportInfo proto.InternalMessageInfo
+
+func (m *DataExportInfo) GetExport() *data.DataExport {
+	if m != nil {
+		return m.Export
+	}
+	return nil
+}
+
+func (m *DataExportInfo) GetDownloadUrl() string {
+	if m != nil {
+		return m.DownloadUrl
+	}
+	return ""
+}
+
+type ListDataExportsResponse struct {
+	Exports              []*DataExportInfo `protobuf:"bytes,1,rep,name=exports,proto3" json:"exports,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ListDataExportsResponse) Reset()         { *m = ListDataExportsResponse{} }
+func (m *ListDataExportsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListDataExportsResponse) ProtoMessage()    {}
+func (*ListDataExportsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{19}
+}
+
+func (m *ListDataExportsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListDataExportsResponse.Unmarshal(m, b)
+}
+func (m *ListDataExportsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListDataExportsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListDataExportsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListDataExportsResponse.Merge(m, src)
+}
+func (m *ListDataExportsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListDataExportsResponse.Size(m)
+}
+func (m *ListDataExportsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListDataExportsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListDataExportsResponse proto.InternalMessageInfo
+
+func (m *ListDataExportsResponse) GetExports() []*DataExportInfo {
+	if m != nil {
+		return m.Exports
+	}
+	return nil
+}
+
+// A DnsZone is a DNS zone managed by the DNS provider.
+type DnsZone struct {
+	// The name by which the provider identifies the zone, which for some providers is the dns_name.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// The fully qualified domain name of the zone apex, such as "example.com.".
+	DnsName string `protobuf:"bytes,2,opt,name=dns_name,json=dnsName,proto3" json:"dns_name,omitempty"`
+	// The authoritative name servers for the zone, if known.
+	NameServers          []string `protobuf:"bytes,3,rep,name=name_servers,json=nameServers,proto3" json:"name_servers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DnsZone) Reset()         { *m = DnsZone{} }
+func (m *DnsZone) String() string { return proto.CompactTextString(m) }
+func (*DnsZone) ProtoMessage()    {}
+func (*DnsZone) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{20}
+}
+
+func (m *DnsZone) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DnsZone.Unmarshal(m, b)
+}
+func (m *DnsZone) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DnsZone.Marshal(b, m, deterministic)
+}
+func (m *DnsZone) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DnsZone.Merge(m, src)
+}
+func (m *DnsZone) XXX_Size() int {
+	return xxx_messageInfo_DnsZone.Size(m)
+}
+func (m *DnsZone) XXX_DiscardUnknown() {
+	xxx_messageInfo_DnsZone.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DnsZone proto.InternalMessageInfo
+
+func (m *DnsZone) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DnsZone) GetDnsName() string {
+	if m != nil {
+		return m.DnsName
+	}
+	return ""
+}
+
+func (m *DnsZone) GetNameServers() []string {
+	if m != nil {
+		return m.NameServers
+	}
+	return nil
+}
+
+type ListDnsZonesResponse struct {
+	Zones                []*DnsZone `protobuf:"bytes,1,rep,name=zones,proto3" json:"zones,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ListDnsZonesResponse) Reset()         { *m = ListDnsZonesResponse{} }
+func (m *ListDnsZonesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListDnsZonesResponse) ProtoMessage()    {}
+func (*ListDnsZonesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{21}
+}
+
+func (m *ListDnsZonesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListDnsZonesResponse.Unmarshal(m, b)
+}
+func (m *ListDnsZonesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListDnsZonesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListDnsZonesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListDnsZonesResponse.Merge(m, src)
+}
+func (m *ListDnsZonesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListDnsZonesResponse.Size(m)
+}
+func (m *ListDnsZonesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListDnsZonesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListDnsZonesResponse proto.InternalMessageInfo
+
+func (m *ListDnsZonesResponse) GetZones() []*DnsZone {
+	if m != nil {
+		return m.Zones
+	}
+	return nil
+}
+
+// A DnsRecordSet is the collection of records in a zone that have the same name and type.
+type DnsRecordSet struct {
+	// The fully qualified name of the records, such as "www.example.com.".
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// The record type, such as "A", "TXT", or "MX".
+	Type string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	// The number of seconds that the records may be cached by resolvers.
+	Ttl int64 `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// The data of each record in the presentation format defined in RFC 1035 (section 5).
+	Data                 []string `protobuf:"bytes,4,rep,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DnsRecordSet) Reset()         { *m = DnsRecordSet{} }
+func (m *DnsRecordSet) String() string { return proto.CompactTextString(m) }
+func (*DnsRecordSet) ProtoMessage()    {}
+func (*DnsRecordSet) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{22}
+}
+
+func (m *DnsRecordSet) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DnsRecordSet.Unmarshal(m, b)
+}
+func (m *DnsRecordSet) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DnsRecordSet.Marshal(b, m, deterministic)
+}
+func (m *DnsRecordSet) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DnsRecordSet.Merge(m, src)
+}
+func (m *DnsRecordSet) XXX_Size() int {
+	return xxx_messageInfo_DnsRecordSet.Size(m)
+}
+func (m *DnsRecordSet) XXX_DiscardUnknown() {
+	xxx_messageInfo_DnsRecordSet.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DnsRecordSet proto.InternalMessageInfo
+
+func (m *DnsRecordSet) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DnsRecordSet) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *DnsRecordSet) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+func (m *DnsRecordSet) GetData() []string {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+type ListDnsRecordSetsResponse struct {
+	RecordSets           []*DnsRecordSet `protobuf:"bytes,1,rep,name=record_sets,json=recordSets,proto3" json:"record_sets,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ListDnsRecordSetsResponse) Reset()         { *m = ListDnsRecordSetsResponse{} }
+func (m *ListDnsRecordSetsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListDnsRecordSetsResponse) ProtoMessage()    {}
+func (*ListDnsRecordSetsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{23}
+}
+
+func (m *ListDnsRecordSetsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListDnsRecordSetsResponse.Unmarshal(m, b)
+}
+func (m *ListDnsRecordSetsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListDnsRecordSetsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListDnsRecordSetsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListDnsRecordSetsResponse.Merge(m, src)
+}
+func (m *ListDnsRecordSetsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListDnsRecordSetsResponse.Size(m)
+}
+func (m *ListDnsRecordSetsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListDnsRecordSetsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListDnsRecordSetsResponse proto.InternalMessageInfo
+
+func (m *ListDnsRecordSetsResponse) GetRecordSets() []*DnsRecordSet {
+	if m != nil {
+		return m.RecordSets
+	}
+	return nil
+}
+
+// A DomainVerificationStatus describes the status of the verification of a domain of a site and how to
+// complete it. A site or an alias is not used until its domain is verified.
+type DomainVerificationStatus struct {
+	Domain   string `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Verified bool   `protobuf:"varint,2,opt,name=verified,proto3" json:"verified,omitempty"`
+	// How the domain was verified, if it is verified.
+	Method string `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
+	// The name and the value of the TXT record to create to verify the domain.
+	TxtName  string `protobuf:"bytes,4,opt,name=txt_name,json=txtName,proto3" json:"txt_name,omitempty"`
+	TxtValue string `protobuf:"bytes,5,opt,name=txt_value,json=txtValue,proto3" json:"txt_value,omitempty"`
+	// Alternatively, the path at which to serve the token on the domain.
+	HttpPath string `protobuf:"bytes,6,opt,name=http_path,json=httpPath,proto3" json:"http_path,omitempty"`
+	// The token to serve at http_path.
+	Token                string   `protobuf:"bytes,7,opt,name=token,proto3" json:"token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DomainVerificationStatus) Reset()         { *m = DomainVerificationStatus{} }
+func (m *DomainVerificationStatus) String() string { return proto.CompactTextString(m) }
+func (*DomainVerificationStatus) ProtoMessage()    {}
+func (*DomainVerificationStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{24}
+}
+
+func (m *DomainVerificationStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DomainVerificationStatus.Unmarshal(m, b)
+}
+func (m *DomainVerificationStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DomainVerificationStatus.Marshal(b, m, deterministic)
+}
+func (m *DomainVerificationStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DomainVerificationStatus.Merge(m, src)
+}
+func (m *DomainVerificationStatus) XXX_Size() int {
+	return xxx_messageInfo_DomainVerificationStatus.Size(m)
+}
+func (m *DomainVerificationStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_DomainVerificationStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DomainVerificationStatus proto.InternalMessageInfo
+
+func (m *DomainVerificationStatus) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *DomainVerificationStatus) GetVerified() bool {
+	if m != nil {
+		return m.Verified
+	}
+	return false
+}
+
+func (m *DomainVerificationStatus) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *DomainVerificationStatus) GetTxtName() string {
+	if m != nil {
+		return m.TxtName
+	}
+	return ""
+}
+
+func (m *DomainVerificationStatus) GetTxtValue() string {
+	if m != nil {
+		return m.TxtValue
+	}
+	return ""
+}
+
+func (m *DomainVerificationStatus) GetHttpPath() string {
+	if m != nil {
+		return m.HttpPath
+	}
+	return ""
+}
+
+func (m *DomainVerificationStatus) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+type CreateSiteResponse struct {
+	// The ID of the new site.
+	NewId int64 `protobuf:"varint,1,opt,name=new_id,json=newId,proto3" json:"new_id,omitempty"`
+	// The site is pending until its domain is verified.
+	Verify               *DomainVerificationStatus `protobuf:"bytes,2,opt,name=verify,proto3" json:"verify,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                  `json:"-"`
+	XXX_unrecognized     []byte                    `json:"-"`
+	XXX_sizecache        int32                     `json:"-"`
+}
+
+func (m *CreateSiteResponse) Reset()         { *m = CreateSiteResponse{} }
+func (m *CreateSiteResponse) String() string { return proto.CompactTextString(m) }
+func (*CreateSiteResponse) ProtoMessage()    {}
+func (*CreateSiteResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{25}
+}
+
+func (m *CreateSiteResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateSiteResponse.Unmarshal(m, b)
+}
+func (m *CreateSiteResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateSiteResponse.Marshal(b, m, deterministic)
+}
+func (m *CreateSiteResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateSiteResponse.Merge(m, src)
+}
+func (m *CreateSiteResponse) XXX_Size() int {
+	return xxx_messageInfo_CreateSiteResponse.Size(m)
+}
+func (m *CreateSiteResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateSiteResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateSiteResponse proto.InternalMessageInfo
+
+func (m *CreateSiteResponse) GetNewId() int64 {
+	if m != nil {
+		return m.NewId
+	}
+	return 0
+}
+
+func (m *CreateSiteResponse) GetVerify() *DomainVerificationStatus {
+	if m != nil {
+		return m.Verify
+	}
+	return nil
+}
+
+// A SiteAlias is a domain, other than the primary domain, at which a site is served.
+type SiteAlias struct {
+	Domain string `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	// Whether the alias redirects to the primary domain rather than serving the site directly.
+	Redirect bool `protobuf:"varint,2,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	// The alias is not used until its domain is verified.
+	Verify               *DomainVerificationStatus `protobuf:"bytes,3,opt,name=verify,proto3" json:"verify,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                  `json:"-"`
+	XXX_unrecognized     []byte                    `json:"-"`
+	XXX_sizecache        int32                     `json:"-"`
+}
+
+func (m *SiteAlias) Reset()         { *m = SiteAlias{} }
+func (m *SiteAlias) String() string { return proto.CompactTextString(m) }
+func (*SiteAlias) ProtoMessage()    {}
+func (*SiteAlias) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{26}
+}
+
+func (m *SiteAlias) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SiteAlias.Unmarshal(m, b)
+}
+func (m *SiteAlias) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SiteAlias.Marshal(b, m, deterministic)
+}
+func (m *SiteAlias) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SiteAlias.Merge(m, src)
+}
+func (m *SiteAlias) XXX_Size() int {
+	return xxx_messageInfo_SiteAlias.Size(m)
+}
+func (m *SiteAlias) XXX_DiscardUnknown() {
+	xxx_messageInfo_SiteAlias.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SiteAlias proto.InternalMessageInfo
+
+func (m *SiteAlias) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *SiteAlias) GetRedirect() bool {
+	if m != nil {
+		return m.Redirect
+	}
+	return false
+}
+
+func (m *SiteAlias) GetVerify() *DomainVerificationStatus {
+	if m != nil {
+		return m.Verify
+	}
+	return nil
+}
+
+type ListSiteAliasesResponse struct {
+	Aliases              []*SiteAlias `protobuf:"bytes,1,rep,name=aliases,proto3" json:"aliases,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *ListSiteAliasesResponse) Reset()         { *m = ListSiteAliasesResponse{} }
+func (m *ListSiteAliasesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListSiteAliasesResponse) ProtoMessage()    {}
+func (*ListSiteAliasesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{27}
+}
+
+func (m *ListSiteAliasesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSiteAliasesResponse.Unmarshal(m, b)
+}
+func (m *ListSiteAliasesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSiteAliasesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListSiteAliasesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSiteAliasesResponse.Merge(m, src)
+}
+func (m *ListSiteAliasesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListSiteAliasesResponse.Size(m)
+}
+func (m *ListSiteAliasesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSiteAliasesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSiteAliasesResponse proto.InternalMessageInfo
+
+func (m *ListSiteAliasesResponse) GetAliases() []*SiteAlias {
+	if m != nil {
+		return m.Aliases
+	}
+	return nil
+}
+
+type GetSiteRequest struct {
+	// The site ID.
+	Id                   int64    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSiteRequest) Reset()         { *m = GetSiteRequest{} }
+func (m *GetSiteRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSiteRequest) ProtoMessage()    {}
+func (*GetSiteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{28}
+}
+
+func (m *GetSiteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSiteRequest.Unmarshal(m, b)
+}
+func (m *GetSiteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSiteRequest.Marshal(b, m, deterministic)
+}
+func (m *GetSiteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSiteRequest.Merge(m, src)
+}
+func (m *GetSiteRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSiteRequest.Size(m)
+}
+func (m *GetSiteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSiteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSiteRequest proto.InternalMessageInfo
+
+func (m *GetSiteRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type ListSitesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListSitesRequest) Reset()         { *m = ListSitesRequest{} }
+func (m *ListSitesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListSitesRequest) ProtoMessage()    {}
+func (*ListSitesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{29}
+}
+
+func (m *ListSitesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSitesRequest.Unmarshal(m, b)
+}
+func (m *ListSitesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSitesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListSitesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSitesRequest.Merge(m, src)
+}
+func (m *ListSitesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListSitesRequest.Size(m)
+}
+func (m *ListSitesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSitesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSitesRequest proto.InternalMessageInfo
+
+type ListSitesResponse struct {
+	Sites                []*data.Site `protobuf:"bytes,1,rep,name=sites,proto3" json:"sites,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *ListSitesResponse) Reset()         { *m = ListSitesResponse{} }
+func (m *ListSitesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListSitesResponse) ProtoMessage()    {}
+func (*ListSitesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{30}
+}
+
+func (m *ListSitesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSitesResponse.Unmarshal(m, b)
+}
+func (m *ListSitesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSitesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListSitesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSitesResponse.Merge(m, src)
+}
+func (m *ListSitesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListSitesResponse.Size(m)
+}
+func (m *ListSitesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSitesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSitesResponse proto.InternalMessageInfo
+
+func (m *ListSitesResponse) GetSites() []*data.Site {
+	if m != nil {
+		return m.Sites
+	}
+	return nil
+}
+
+type GetContentRequest struct {
+	// The content ID.
+	Id                   int64    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetContentRequest) Reset()         { *m = GetContentRequest{} }
+func (m *GetContentRequest) String() string { return proto.CompactTextString(m) }
+func (*GetContentRequest) ProtoMessage()    {}
+func (*GetContentRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{31}
+}
+
+func (m *GetContentRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetContentRequest.Unmarshal(m, b)
+}
+func (m *GetContentRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetContentRequest.Marshal(b, m, deterministic)
+}
+func (m *GetContentRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetContentRequest.Merge(m, src)
+}
+func (m *GetContentRequest) XXX_Size() int {
+	return xxx_messageInfo_GetContentRequest.Size(m)
+}
+func (m *GetContentRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetContentRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetContentRequest proto.InternalMessageInfo
+
+func (m *GetContentRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type ListContentRequest struct {
+	// The type of the content, such as "page" or "post".
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// If not empty, only the content with one of these statuses is listed.
+	Statuses []string `protobuf:"bytes,2,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	// If not empty, only the content with one of these parents is listed.
+	Parents []int64 `protobuf:"varint,3,rep,packed,name=parents,proto3" json:"parents,omitempty"`
+	// The number of items to skip.
+	Offset               uint64   `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListContentRequest) Reset()         { *m = ListContentRequest{} }
+func (m *ListContentRequest) String() string { return proto.CompactTextString(m) }
+func (*ListContentRequest) ProtoMessage()    {}
+func (*ListContentRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{32}
+}
+
+func (m *ListContentRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListContentRequest.Unmarshal(m, b)
+}
+func (m *ListContentRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListContentRequest.Marshal(b, m, deterministic)
+}
+func (m *ListContentRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListContentRequest.Merge(m, src)
+}
+func (m *ListContentRequest) XXX_Size() int {
+	return xxx_messageInfo_ListContentRequest.Size(m)
+}
+func (m *ListContentRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListContentRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListContentRequest proto.InternalMessageInfo
+
+func (m *ListContentRequest) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *ListContentRequest) GetStatuses() []string {
+	if m != nil {
+		return m.Statuses
+	}
+	return nil
+}
+
+func (m *ListContentRequest) GetParents() []int64 {
+	if m != nil {
+		return m.Parents
+	}
+	return nil
+}
+
+func (m *ListContentRequest) GetOffset() uint64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+type ListContentResponse struct {
+	Content              []*data.Content `protobuf:"bytes,1,rep,name=content,proto3" json:"content,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ListContentResponse) Reset()         { *m = ListContentResponse{} }
+func (m *ListContentResponse) String() string { return proto.CompactTextString(m) }
+func (*ListContentResponse) ProtoMessage()    {}
+func (*ListContentResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{33}
+}
+
+func (m *ListContentResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListContentResponse.Unmarshal(m, b)
+}
+func (m *ListContentResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListContentResponse.Marshal(b, m, deterministic)
+}
+func (m *ListContentResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListContentResponse.Merge(m, src)
+}
+func (m *ListContentResponse) XXX_Size() int {
+	return xxx_messageInfo_ListContentResponse.Size(m)
+}
+func (m *ListContentResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListContentResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListContentResponse proto.InternalMessageInfo
+
+func (m *ListContentResponse) GetContent() []*data.Content {
+	if m != nil {
+		return m.Content
+	}
+	return nil
+}
+
+type GetUserRequest struct {
+	// The user ID, or 0 for the current user.
+	Id                   int64    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetUserRequest) Reset()         { *m = GetUserRequest{} }
+func (m *GetUserRequest) String() string { return proto.CompactTextString(m) }
+func (*GetUserRequest) ProtoMessage()    {}
+func (*GetUserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{34}
+}
+
+func (m *GetUserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetUserRequest.Unmarshal(m, b)
+}
+func (m *GetUserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetUserRequest.Marshal(b, m, deterministic)
+}
+func (m *GetUserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetUserRequest.Merge(m, src)
+}
+func (m *GetUserRequest) XXX_Size() int {
+	return xxx_messageInfo_GetUserRequest.Size(m)
+}
+func (m *GetUserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetUserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetUserRequest proto.InternalMessageInfo
+
+func (m *GetUserRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type GetOptionsRequest struct {
+	// The keys of the options.
+	Keys                 []string `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetOptionsRequest) Reset()         { *m = GetOptionsRequest{} }
+func (m *GetOptionsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetOptionsRequest) ProtoMessage()    {}
+func (*GetOptionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{35}
+}
+
+func (m *GetOptionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetOptionsRequest.Unmarshal(m, b)
+}
+func (m *GetOptionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetOptionsRequest.Marshal(b, m, deterministic)
+}
+func (m *GetOptionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetOptionsRequest.Merge(m, src)
+}
+func (m *GetOptionsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetOptionsRequest.Size(m)
+}
+func (m *GetOptionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetOptionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetOptionsRequest proto.InternalMessageInfo
+
+func (m *GetOptionsRequest) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+type GetOptionsResponse struct {
+	Options              []*data.Option `protobuf:"bytes,1,rep,name=options,proto3" json:"options,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *GetOptionsResponse) Reset()         { *m = GetOptionsResponse{} }
+func (m *GetOptionsResponse) String() string { return proto.CompactTextString(m) }
+func (*GetOptionsResponse) ProtoMessage()    {}
+func (*GetOptionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{36}
+}
+
+func (m *GetOptionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetOptionsResponse.Unmarshal(m, b)
+}
+func (m *GetOptionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetOptionsResponse.Marshal(b, m, deterministic)
+}
+func (m *GetOptionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetOptionsResponse.Merge(m, src)
+}
+func (m *GetOptionsResponse) XXX_Size() int {
+	return xxx_messageInfo_GetOptionsResponse.Size(m)
+}
+func (m *GetOptionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetOptionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetOptionsResponse proto.InternalMessageInfo
+
+func (m *GetOptionsResponse) GetOptions() []*data.Option {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+type SetOptionsRequest struct {
+	// The values of the options by key.
+	Options              map[string]string `protobuf:"bytes,1,rep,name=options,proto3" json:"options,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *SetOptionsRequest) Reset()         { *m = SetOptionsRequest{} }
+func (m *SetOptionsRequest) String() string { return proto.CompactTextString(m) }
+func (*SetOptionsRequest) ProtoMessage()    {}
+func (*SetOptionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{37}
+}
+
+func (m *SetOptionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetOptionsRequest.Unmarshal(m, b)
+}
+func (m *SetOptionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetOptionsRequest.Marshal(b, m, deterministic)
+}
+func (m *SetOptionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetOptionsRequest.Merge(m, src)
+}
+func (m *SetOptionsRequest) XXX_Size() int {
+	return xxx_messageInfo_SetOptionsRequest.Size(m)
+}
+func (m *SetOptionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetOptionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetOptionsRequest proto.InternalMessageInfo
+
+func (m *SetOptionsRequest) GetOptions() map[string]string {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+type SetOptionsResponse struct {
+	// The number of options created or updated.
+	Updated              int64    `protobuf:"varint,1,opt,name=updated,proto3" json:"updated,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetOptionsResponse) Reset()         { *m = SetOptionsResponse{} }
+func (m *SetOptionsResponse) String() string { return proto.CompactTextString(m) }
+func (*SetOptionsResponse) ProtoMessage()    {}
+func (*SetOptionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{38}
+}
+
+func (m *SetOptionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetOptionsResponse.Unmarshal(m, b)
+}
+func (m *SetOptionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetOptionsResponse.Marshal(b, m, deterministic)
+}
+func (m *SetOptionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetOptionsResponse.Merge(m, src)
+}
+func (m *SetOptionsResponse) XXX_Size() int {
+	return xxx_messageInfo_SetOptionsResponse.Size(m)
+}
+func (m *SetOptionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetOptionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetOptionsResponse proto.InternalMessageInfo
+
+func (m *SetOptionsResponse) GetUpdated() int64 {
+	if m != nil {
+		return m.Updated
+	}
+	return 0
+}
+
+type GetMediaRequest struct {
+	// The media object ID.
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetMediaRequest) Reset()         { *m = GetMediaRequest{} }
+func (m *GetMediaRequest) String() string { return proto.CompactTextString(m) }
+func (*GetMediaRequest) ProtoMessage()    {}
+func (*GetMediaRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{39}
+}
+
+func (m *GetMediaRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetMediaRequest.Unmarshal(m, b)
+}
+func (m *GetMediaRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetMediaRequest.Marshal(b, m, deterministic)
+}
+func (m *GetMediaRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetMediaRequest.Merge(m, src)
+}
+func (m *GetMediaRequest) XXX_Size() int {
+	return xxx_messageInfo_GetMediaRequest.Size(m)
+}
+func (m *GetMediaRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetMediaRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetMediaRequest proto.InternalMessageInfo
+
+func (m *GetMediaRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type ListMediaRequest struct {
+	// The number of items to skip.
+	Offset               uint64   `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListMediaRequest) Reset()         { *m = ListMediaRequest{} }
+func (m *ListMediaRequest) String() string { return proto.CompactTextString(m) }
+func (*ListMediaRequest) ProtoMessage()    {}
+func (*ListMediaRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{40}
+}
+
+func (m *ListMediaRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListMediaRequest.Unmarshal(m, b)
+}
+func (m *ListMediaRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListMediaRequest.Marshal(b, m, deterministic)
+}
+func (m *ListMediaRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListMediaRequest.Merge(m, src)
+}
+func (m *ListMediaRequest) XXX_Size() int {
+	return xxx_messageInfo_ListMediaRequest.Size(m)
+}
+func (m *ListMediaRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListMediaRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListMediaRequest proto.InternalMessageInfo
+
+func (m *ListMediaRequest) GetOffset() uint64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+type ListMediaResponse struct {
+	Media                []*data.Media `protobuf:"bytes,1,rep,name=media,proto3" json:"media,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ListMediaResponse) Reset()         { *m = ListMediaResponse{} }
+func (m *ListMediaResponse) String() string { return proto.CompactTextString(m) }
+func (*ListMediaResponse) ProtoMessage()    {}
+func (*ListMediaResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{41}
+}
+
+func (m *ListMediaResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListMediaResponse.Unmarshal(m, b)
+}
+func (m *ListMediaResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListMediaResponse.Marshal(b, m, deterministic)
+}
+func (m *ListMediaResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListMediaResponse.Merge(m, src)
+}
+func (m *ListMediaResponse) XXX_Size() int {
+	return xxx_messageInfo_ListMediaResponse.Size(m)
+}
+func (m *ListMediaResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListMediaResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListMediaResponse proto.InternalMessageInfo
+
+func (m *ListMediaResponse) GetMedia() []*data.Media {
+	if m != nil {
+		return m.Media
+	}
+	return nil
+}
+
+type ListSessionsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListSessionsRequest) Reset()         { *m = ListSessionsRequest{} }
+func (m *ListSessionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListSessionsRequest) ProtoMessage()    {}
+func (*ListSessionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{42}
+}
+
+func (m *ListSessionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSessionsRequest.Unmarshal(m, b)
+}
+func (m *ListSessionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSessionsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListSessionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSessionsRequest.Merge(m, src)
+}
+func (m *ListSessionsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListSessionsRequest.Size(m)
+}
+func (m *ListSessionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSessionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSessionsRequest proto.InternalMessageInfo
+
+type ListSessionsResponse struct {
+	Sessions []*data.Session `protobuf:"bytes,1,rep,name=sessions,proto3" json:"sessions,omitempty"`
+	// The ID of the session of the request, if it is among the sessions.
+	Current              string   `protobuf:"bytes,2,opt,name=current,proto3" json:"current,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListSessionsResponse) Reset()         { *m = ListSessionsResponse{} }
+func (m *ListSessionsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListSessionsResponse) ProtoMessage()    {}
+func (*ListSessionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{43}
+}
+
+func (m *ListSessionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSessionsResponse.Unmarshal(m, b)
+}
+func (m *ListSessionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSessionsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListSessionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSessionsResponse.Merge(m, src)
+}
+func (m *ListSessionsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListSessionsResponse.Size(m)
+}
+func (m *ListSessionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSessionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSessionsResponse proto.InternalMessageInfo
+
+func (m *ListSessionsResponse) GetSessions() []*data.Session {
+	if m != nil {
+		return m.Sessions
+	}
+	return nil
+}
+
+func (m *ListSessionsResponse) GetCurrent() string {
+	if m != nil {
+		return m.Current
+	}
+	return ""
+}
+
+type RevokeSessionRequest struct {
+	// The session ID.
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeSessionRequest) Reset()         { *m = RevokeSessionRequest{} }
+func (m *RevokeSessionRequest) String() string { return proto.CompactTextString(m) }
+func (*RevokeSessionRequest) ProtoMessage()    {}
+func (*RevokeSessionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{44}
+}
+
+func (m *RevokeSessionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeSessionRequest.Unmarshal(m, b)
+}
+func (m *RevokeSessionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeSessionRequest.Marshal(b, m, deterministic)
+}
+func (m *RevokeSessionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeSessionRequest.Merge(m, src)
+}
+func (m *RevokeSessionRequest) XXX_Size() int {
+	return xxx_messageInfo_RevokeSessionRequest.Size(m)
+}
+func (m *RevokeSessionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeSessionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeSessionRequest proto.InternalMessageInfo
+
+func (m *RevokeSessionRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type RevokeSessionResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeSessionResponse) Reset()         { *m = RevokeSessionResponse{} }
+func (m *RevokeSessionResponse) String() string { return proto.CompactTextString(m) }
+func (*RevokeSessionResponse) ProtoMessage()    {}
+func (*RevokeSessionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a923d03cd8175fe2, []int{45}
+}
+
+func (m *RevokeSessionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeSessionResponse.Unmarshal(m, b)
+}
+func (m *RevokeSessionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeSessionResponse.Marshal(b, m, deterministic)
+}
+func (m *RevokeSessionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeSessionResponse.Merge(m, src)
+}
+func (m *RevokeSessionResponse) XXX_Size() int {
+	return xxx_messageInfo_RevokeSessionResponse.Size(m)
+}
+func (m *RevokeSessionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeSessionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeSessionResponse proto.InternalMessageInfo
+
+func init() {
+	proto.RegisterType((*LoginRequest)(nil), "mazewire.v1.LoginRequest")
+	proto.RegisterType((*LoginResponse)(nil), "mazewire.v1.LoginResponse")
+	proto.RegisterType((*TwoFactorChallenge)(nil), "mazewire.v1.TwoFactorChallenge")
+	proto.RegisterType((*VerifyTwoFactorRequest)(nil), "mazewire.v1.VerifyTwoFactorRequest")
+	proto.RegisterType((*TwoFactorEnrollment)(nil), "mazewire.v1.TwoFactorEnrollment")
+	proto.RegisterType((*RecoveryCodes)(nil), "mazewire.v1.RecoveryCodes")
+	proto.RegisterType((*OIDCProvider)(nil), "mazewire.v1.OIDCProvider")
+	proto.RegisterType((*ListOIDCProvidersResponse)(nil), "mazewire.v1.ListOIDCProvidersResponse")
+	proto.RegisterType((*Identity)(nil), "mazewire.v1.Identity")
+	proto.RegisterType((*ListIdentitiesResponse)(nil), "mazewire.v1.ListIdentitiesResponse")
+	proto.RegisterType((*ListAPITokensResponse)(nil), "mazewire.v1.ListAPITokensResponse")
+	proto.RegisterType((*CreatedAPIToken)(nil), "mazewire.v1.CreatedAPIToken")
+	proto.RegisterType((*ListInvitationsResponse)(nil), "mazewire.v1.ListInvitationsResponse")
+	proto.RegisterType((*RoleInfo)(nil), "mazewire.v1.RoleInfo")
+	proto.RegisterType((*ListRolesResponse)(nil), "mazewire.v1.ListRolesResponse")
+	proto.RegisterType((*SiteMember)(nil), "mazewire.v1.SiteMember")
+	proto.RegisterType((*ListSiteMembersResponse)(nil), "mazewire.v1.ListSiteMembersResponse")
+	proto.RegisterType((*DeleteUserResponse)(nil), "mazewire.v1.DeleteUserResponse")
+	proto.RegisterType((*DataExportInfo)(nil), "mazewire.v1.DataExportInfo")
+	proto.RegisterType((*ListDataExportsResponse)(nil), "mazewire.v1.ListDataExportsResponse")
+	proto.RegisterType((*DnsZone)(nil), "mazewire.v1.DnsZone")
+	proto.RegisterType((*ListDnsZonesResponse)(nil), "mazewire.v1.ListDnsZonesResponse")
+	proto.RegisterType((*DnsRecordSet)(nil), "mazewire.v1.DnsRecordSet")
+	proto.RegisterType((*ListDnsRecordSetsResponse)(nil), "mazewire.v1.ListDnsRecordSetsResponse")
+	proto.RegisterType((*DomainVerificationStatus)(nil), "mazewire.v1.DomainVerificationStatus")
+	proto.RegisterType((*CreateSiteResponse)(nil), "mazewire.v1.CreateSiteResponse")
+	proto.RegisterType((*SiteAlias)(nil), "mazewire.v1.SiteAlias")
+	proto.RegisterType((*ListSiteAliasesResponse)(nil), "mazewire.v1.ListSiteAliasesResponse")
+	proto.RegisterType((*GetSiteRequest)(nil), "mazewire.v1.GetSiteRequest")
+	proto.RegisterType((*ListSitesRequest)(nil), "mazewire.v1.ListSitesRequest")
+	proto.RegisterType((*ListSitesResponse)(nil), "mazewire.v1.ListSitesResponse")
+	proto.RegisterType((*GetContentRequest)(nil), "mazewire.v1.GetContentRequest")
+	proto.RegisterType((*ListContentRequest)(nil), "mazewire.v1.ListContentRequest")
+	proto.RegisterType((*ListContentResponse)(nil), "mazewire.v1.ListContentResponse")
+	proto.RegisterType((*GetUserRequest)(nil), "mazewire.v1.GetUserRequest")
+	proto.RegisterType((*GetOptionsRequest)(nil), "mazewire.v1.GetOptionsRequest")
+	proto.RegisterType((*GetOptionsResponse)(nil), "mazewire.v1.GetOptionsResponse")
+	proto.RegisterType((*SetOptionsRequest)(nil), "mazewire.v1.SetOptionsRequest")
+	proto.RegisterMapType((map[string]string)(nil), "mazewire.v1.SetOptionsRequest.OptionsEntry")
+	proto.RegisterType((*SetOptionsResponse)(nil), "mazewire.v1.SetOptionsResponse")
+	proto.RegisterType((*GetMediaRequest)(nil), "mazewire.v1.GetMediaRequest")
+	proto.RegisterType((*ListMediaRequest)(nil), "mazewire.v1.ListMediaRequest")
+	proto.RegisterType((*ListMediaResponse)(nil), "mazewire.v1.ListMediaResponse")
+	proto.RegisterType((*ListSessionsRequest)(nil), "mazewire.v1.ListSessionsRequest")
+	proto.RegisterType((*ListSessionsResponse)(nil), "mazewire.v1.ListSessionsResponse")
+	proto.RegisterType((*RevokeSessionRequest)(nil), "mazewire.v1.RevokeSessionRequest")
+	proto.RegisterType((*RevokeSessionResponse)(nil), "mazewire.v1.RevokeSessionResponse")
+}
+
+func init() { proto.RegisterFile("api/v1/mazewire.proto", fileDescriptor_a923d03cd8175fe2) }
+
+var fileDescriptor_a923d03cd8175fe2 = []byte{
+	// 1666 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x18, 0xeb, 0x6e, 0x13, 0xcd,
+	0x55, 0xb6, 0xe3, 0xdb, 0xb1, 0x13, 0xc8, 0x7c, 0x49, 0x30, 0x86, 0x86, 0x64, 0x68, 0x20, 0x05,
+	0x29, 0x29, 0xa9, 0xa0, 0x88, 0xb6, 0x20, 0x48, 0x02, 0x0a, 0x25, 0x4d, 0x34, 0x06, 0x2a, 0x41,
+	0x91, 0xb5, 0xf1, 0x9e, 0x24, 0x4b, 0xd6, 0xbb, 0xcb, 0xee, 0xd8, 0x89, 0xf9, 0xd1, 0xc7, 0xe8,
+	0x23, 0xf4, 0x6f, 0x9f, 0xa5, 0x6f, 0x54, 0xcd, 0x6d, 0x77, 0xd6, 0x97, 0x54, 0xfa, 0x7e, 0x79,
+	0xce, 0xfd, 0x7e, 0x66, 0xd6, 0xb0, 0xec, 0x44, 0xde, 0xf6, 0xf0, 0xc9, 0x76, 0xdf, 0xf9, 0x89,
+	0x97, 0x5e, 0x8c, 0x5b, 0x51, 0x1c, 0xf2, 0x90, 0x34, 0x52, 0x78, 0xf8, 0xa4, 0xbd, 0xe8, 0x3a,
+	0xdc, 0xd9, 0xe6, 0xce, 0x89, 0x8f, 0x89, 0xa2, 0xd3, 0x67, 0xd0, 0xfc, 0x10, 0x9e, 0x79, 0x01,
+	0xc3, 0x1f, 0x03, 0x4c, 0x38, 0x21, 0x30, 0x37, 0x48, 0x30, 0x6e, 0x15, 0xd6, 0x0a, 0x9b, 0x75,
+	0x26, 0xcf, 0x02, 0x17, 0x39, 0x49, 0xd2, 0x2a, 0x2a, 0x9c, 0x38, 0xd3, 0x7f, 0x17, 0x60, 0x5e,
+	0x0b, 0x26, 0x51, 0x18, 0x24, 0x48, 0x96, 0xa0, 0xcc, 0xc3, 0x0b, 0x0c, 0xb4, 0xa8, 0x02, 0x48,
+	0x0b, 0xaa, 0x78, 0x15, 0x79, 0x31, 0x2a, 0xf1, 0x12, 0x33, 0x20, 0x79, 0x09, 0xc0, 0x2f, 0xc3,
+	0xee, 0xa9, 0xd3, 0xe3, 0x61, 0xdc, 0x2a, 0xad, 0x15, 0x36, 0x1b, 0x3b, 0xf7, 0xb6, 0x2c, 0x77,
+	0xb7, 0x3e, 0x5e, 0x86, 0x6f, 0x25, 0x75, 0xf7, 0xdc, 0xf1, 0x7d, 0x0c, 0xce, 0x90, 0xd5, 0xb9,
+	0xc1, 0x91, 0x0d, 0x58, 0x88, 0xb1, 0x17, 0x0e, 0x31, 0x1e, 0x75, 0x7b, 0xa1, 0x8b, 0x49, 0x6b,
+	0x6e, 0xad, 0xb4, 0x59, 0x67, 0xf3, 0x06, 0xbb, 0x2b, 0x90, 0x94, 0x03, 0x99, 0xd4, 0x43, 0xee,
+	0x42, 0xbd, 0x67, 0x00, 0xed, 0x70, 0x86, 0x20, 0x2b, 0x50, 0xc1, 0x20, 0x0e, 0x7d, 0x5f, 0xfa,
+	0x5c, 0x63, 0x1a, 0x12, 0xf8, 0x04, 0x7b, 0x31, 0x72, 0xe9, 0x6e, 0x9d, 0x69, 0x88, 0xdc, 0x84,
+	0xd2, 0x20, 0xf6, 0x5a, 0x73, 0x12, 0x29, 0x8e, 0xf4, 0x3d, 0xac, 0x7c, 0xc6, 0xd8, 0x3b, 0x1d,
+	0xa5, 0xb6, 0x4d, 0x82, 0xaf, 0xb7, 0x4c, 0x60, 0x4e, 0xc4, 0x62, 0x52, 0x2d, 0xce, 0xf4, 0x15,
+	0xfc, 0x92, 0x6a, 0xd9, 0x97, 0x8e, 0xf4, 0x31, 0xe0, 0x96, 0x33, 0x85, 0x69, 0xce, 0x14, 0x33,
+	0x67, 0x36, 0x60, 0x9e, 0xd9, 0x39, 0x11, 0xa5, 0x52, 0x19, 0x2b, 0xc8, 0x8c, 0x29, 0x80, 0x3e,
+	0x87, 0xe6, 0xd1, 0xc1, 0xde, 0xee, 0x71, 0x1c, 0x0e, 0x3d, 0x57, 0x95, 0x3d, 0x70, 0xfa, 0xc6,
+	0x49, 0x79, 0x16, 0x92, 0xbe, 0x73, 0x82, 0xbe, 0x56, 0xaf, 0x00, 0xfa, 0x11, 0x6e, 0x7f, 0xf0,
+	0x12, 0x6e, 0x4b, 0x27, 0x69, 0x5f, 0xfc, 0x11, 0xea, 0x91, 0x41, 0x4a, 0x83, 0x8d, 0x9d, 0xdb,
+	0xb9, 0x32, 0xdb, 0x62, 0x2c, 0xe3, 0xa5, 0x01, 0xd4, 0x0e, 0x5c, 0x0c, 0xb8, 0xc7, 0x47, 0x22,
+	0x58, 0x2f, 0x49, 0x06, 0x69, 0x63, 0x6a, 0x48, 0xb4, 0x57, 0x32, 0x38, 0xf9, 0x8e, 0x3d, 0xae,
+	0x3d, 0x32, 0x20, 0x69, 0x43, 0xcd, 0xa8, 0xd2, 0xd5, 0x4a, 0x61, 0x11, 0x05, 0xf6, 0x1d, 0xcf,
+	0xd7, 0x15, 0x53, 0x00, 0x3d, 0x82, 0x15, 0x11, 0x85, 0xb6, 0xe9, 0x61, 0x16, 0xc2, 0x53, 0x00,
+	0x2f, 0xc5, 0xea, 0x18, 0x96, 0x73, 0x31, 0x18, 0x47, 0x99, 0xc5, 0x48, 0x5f, 0xc1, 0xb2, 0x50,
+	0xf8, 0xfa, 0xf8, 0xe0, 0xa3, 0x98, 0x85, 0x4c, 0xdf, 0x03, 0xa8, 0xc8, 0xe9, 0x30, 0xba, 0x16,
+	0xb6, 0xc4, 0x60, 0x6e, 0x19, 0x46, 0xa6, 0xa9, 0xf4, 0x08, 0x6e, 0xec, 0xc6, 0xe8, 0x70, 0x74,
+	0x0d, 0x89, 0xfc, 0xd6, 0x9e, 0xb2, 0x49, 0x49, 0x45, 0xb4, 0x7a, 0xa3, 0x68, 0xf7, 0x06, 0x3d,
+	0x84, 0x5b, 0x32, 0xc4, 0x60, 0xe8, 0x71, 0x87, 0x7b, 0xa1, 0xe5, 0xd3, 0x0e, 0x34, 0xbc, 0x0c,
+	0xad, 0x1d, 0xbb, 0xa9, 0xd4, 0x67, 0xfc, 0xcc, 0x66, 0xa2, 0xdf, 0xa1, 0xc6, 0x42, 0x1f, 0x0f,
+	0x82, 0xd3, 0x90, 0x2c, 0x40, 0xd1, 0x73, 0xa5, 0x57, 0x25, 0x56, 0xf4, 0xdc, 0xb4, 0x7b, 0x8a,
+	0x56, 0xf7, 0x50, 0x68, 0xf6, 0x9c, 0xc8, 0x39, 0xf1, 0x7c, 0x95, 0xc9, 0x92, 0x6c, 0xbf, 0x1c,
+	0x4e, 0xb8, 0xde, 0x1b, 0x24, 0x3c, 0xec, 0xcb, 0xe2, 0xd4, 0x98, 0x86, 0xa8, 0x0b, 0x8b, 0xc2,
+	0x75, 0x61, 0x2f, 0x73, 0xfa, 0x31, 0x94, 0x63, 0x81, 0x98, 0x5a, 0x13, 0xe3, 0x1a, 0x53, 0x3c,
+	0x13, 0xd6, 0x8b, 0x93, 0xd6, 0xe9, 0x37, 0x80, 0x8e, 0xc7, 0xf1, 0x10, 0xfb, 0x27, 0x18, 0x93,
+	0x55, 0x6b, 0x19, 0x36, 0x76, 0x40, 0x25, 0xe3, 0x53, 0x82, 0x71, 0xb6, 0x18, 0x85, 0x6a, 0xbd,
+	0xd9, 0xe4, 0x99, 0xdc, 0x81, 0xba, 0xf8, 0xed, 0xca, 0xe0, 0x75, 0xe3, 0x09, 0xc4, 0xdf, 0x9c,
+	0x3e, 0xd2, 0x0f, 0x2a, 0xff, 0x99, 0x89, 0x2c, 0x94, 0x27, 0x50, 0xed, 0x2b, 0x94, 0x0e, 0xe6,
+	0x56, 0x2e, 0x98, 0x4c, 0x84, 0x19, 0x3e, 0xfa, 0x16, 0xc8, 0x1e, 0xfa, 0xc8, 0x51, 0xba, 0x64,
+	0x14, 0xad, 0x40, 0xe5, 0xc7, 0x00, 0x07, 0xa8, 0x8a, 0x51, 0x63, 0x1a, 0x12, 0xa3, 0xd2, 0x0b,
+	0x03, 0x8e, 0x01, 0x37, 0x9b, 0x58, 0x83, 0xf4, 0x1b, 0x2c, 0xec, 0x39, 0xdc, 0xd9, 0xbf, 0x8a,
+	0xc2, 0x98, 0xcb, 0x62, 0x6e, 0x42, 0x05, 0x25, 0xa4, 0x43, 0xd7, 0x7d, 0x90, 0x71, 0x31, 0x4d,
+	0x27, 0xeb, 0xd0, 0x74, 0xc3, 0xcb, 0xc0, 0x0f, 0x1d, 0xb7, 0x3b, 0x88, 0xcd, 0x5e, 0x68, 0x18,
+	0xdc, 0xa7, 0xd8, 0xa7, 0xc7, 0x2a, 0xe8, 0x4c, 0xd8, 0x1e, 0xac, 0xaa, 0xd2, 0x63, 0x82, 0xbe,
+	0x93, 0x0b, 0x3a, 0xef, 0x15, 0x33, 0xbc, 0xf4, 0x2b, 0x54, 0xf7, 0x82, 0xe4, 0x4b, 0x18, 0xe0,
+	0xd4, 0x25, 0x75, 0x1b, 0x6a, 0x6e, 0x90, 0x74, 0xad, 0xf6, 0xab, 0xba, 0x41, 0x22, 0x0a, 0x20,
+	0xdc, 0x15, 0xe8, 0x6e, 0x82, 0xf1, 0x10, 0x63, 0xd3, 0x81, 0x0d, 0x81, 0xeb, 0x28, 0x14, 0x7d,
+	0x03, 0x4b, 0xd2, 0x5d, 0x65, 0x20, 0xf3, 0xf5, 0x11, 0x94, 0x7f, 0x0a, 0x84, 0xf6, 0x74, 0x29,
+	0xef, 0xa9, 0xe2, 0x66, 0x8a, 0x85, 0xfe, 0x03, 0x9a, 0x7b, 0x62, 0xb6, 0x7a, 0x61, 0xec, 0x76,
+	0x90, 0x4f, 0xf5, 0x92, 0xc0, 0x1c, 0x1f, 0x45, 0xe9, 0x80, 0x88, 0xb3, 0xd8, 0xdd, 0x9c, 0xfb,
+	0xb2, 0x6d, 0x4a, 0x4c, 0x1c, 0x05, 0x97, 0x48, 0xbd, 0xbe, 0xdb, 0xe4, 0x99, 0xfe, 0x5d, 0xad,
+	0x5b, 0xdb, 0x42, 0xe6, 0xe6, 0x0b, 0x68, 0xc4, 0x12, 0xdb, 0x4d, 0x90, 0x4f, 0x5f, 0xb8, 0xb6,
+	0x20, 0x83, 0x38, 0xd5, 0x41, 0xff, 0x5b, 0x80, 0xd6, 0x5e, 0xd8, 0x77, 0xbc, 0x40, 0x5e, 0x5e,
+	0x5e, 0x4f, 0xce, 0x79, 0x87, 0x3b, 0x7c, 0x20, 0x07, 0xd3, 0x95, 0x34, 0xb3, 0x82, 0x15, 0x24,
+	0x16, 0xed, 0x50, 0x72, 0xa3, 0xab, 0xaf, 0xcb, 0x14, 0x16, 0x32, 0x7d, 0xe4, 0xe7, 0xa1, 0x6b,
+	0x2e, 0x4c, 0x05, 0x89, 0x0a, 0xf1, 0x2b, 0xae, 0x2a, 0xa4, 0x76, 0x70, 0x95, 0x5f, 0x71, 0x59,
+	0xa1, 0x3b, 0x50, 0x17, 0xa4, 0xa1, 0xe3, 0x0f, 0xb0, 0x55, 0x56, 0xf3, 0xc3, 0xaf, 0xf8, 0x67,
+	0x01, 0x0b, 0xe2, 0x39, 0xe7, 0x51, 0x37, 0x72, 0xf8, 0x79, 0xab, 0xa2, 0x88, 0x02, 0x71, 0xec,
+	0xf0, 0xf3, 0xec, 0x01, 0x52, 0xb5, 0x1e, 0x20, 0xf4, 0x3b, 0x10, 0xb5, 0x43, 0xc5, 0x04, 0xa5,
+	0x59, 0x5a, 0x86, 0x4a, 0x80, 0x97, 0xdd, 0x74, 0x63, 0x95, 0x03, 0xbc, 0x3c, 0x70, 0xc9, 0x5f,
+	0xa0, 0x22, 0x7d, 0x1f, 0xc9, 0x48, 0x1a, 0x3b, 0x1b, 0xf9, 0xbc, 0xcd, 0x48, 0x0d, 0xd3, 0x42,
+	0xf4, 0x9f, 0x50, 0x17, 0x56, 0x5e, 0xfb, 0x9e, 0x73, 0x6d, 0xbe, 0x62, 0x74, 0xbd, 0xd8, 0xdc,
+	0x59, 0x35, 0x96, 0xc2, 0x96, 0xfd, 0xd2, 0xaf, 0xb1, 0xff, 0xd7, 0x6c, 0xbd, 0x48, 0x1f, 0xac,
+	0xee, 0xfd, 0x3d, 0x54, 0x1d, 0x85, 0xd2, 0x2d, 0xb1, 0x32, 0xb1, 0x5e, 0xa4, 0x08, 0x33, 0x6c,
+	0x74, 0x0d, 0x16, 0xde, 0x21, 0x57, 0x59, 0x53, 0x4f, 0x97, 0xb1, 0x15, 0x4f, 0x09, 0xdc, 0x34,
+	0xe6, 0x12, 0xcd, 0x43, 0x9f, 0xc2, 0xa2, 0x85, 0xd3, 0xc6, 0xd7, 0xa0, 0x9c, 0x78, 0x3c, 0x35,
+	0xad, 0x17, 0xa9, 0x54, 0xad, 0x08, 0xf4, 0x3e, 0x2c, 0xbe, 0x43, 0xbe, 0xab, 0x16, 0xd2, 0x2c,
+	0x7b, 0x43, 0x20, 0x42, 0xf7, 0x18, 0x97, 0x99, 0xa3, 0x82, 0x35, 0x47, 0x6d, 0xa8, 0x25, 0x32,
+	0x35, 0xe9, 0x9a, 0x4f, 0x61, 0xb1, 0x07, 0x23, 0x27, 0xc6, 0x80, 0xab, 0xe9, 0x2f, 0x31, 0x03,
+	0x8a, 0x8a, 0x85, 0xa7, 0xa7, 0x09, 0x72, 0xd9, 0x93, 0x73, 0x4c, 0x43, 0xf4, 0x25, 0xfc, 0x92,
+	0xb3, 0xab, 0xa3, 0x7a, 0x98, 0x2d, 0x54, 0x15, 0xd7, 0xbc, 0x8a, 0xcb, 0xf0, 0xa5, 0xfb, 0x55,
+	0x65, 0x52, 0x2d, 0xe9, 0xe9, 0x91, 0x3d, 0x94, 0xe1, 0x1f, 0x45, 0xfa, 0x4a, 0x4e, 0x03, 0xbb,
+	0xc0, 0x91, 0x79, 0xa4, 0xc9, 0x33, 0xfd, 0x33, 0x10, 0x9b, 0x31, 0x7d, 0x4f, 0x54, 0xc3, 0xc8,
+	0xbe, 0xb7, 0x9b, 0xca, 0x13, 0xc5, 0xc7, 0x0c, 0x91, 0xfe, 0xab, 0x00, 0x8b, 0x9d, 0x09, 0x3b,
+	0xfb, 0xe3, 0xd2, 0x8f, 0xf3, 0xad, 0x31, 0x2e, 0xa0, 0xd5, 0x26, 0xfb, 0x01, 0x8f, 0x47, 0xa9,
+	0xf2, 0xf6, 0x0b, 0x68, 0xda, 0x04, 0xb1, 0xcb, 0x2e, 0x70, 0xa4, 0xcb, 0x22, 0x8e, 0x62, 0x40,
+	0xd5, 0x58, 0xeb, 0xc7, 0xa3, 0x04, 0x5e, 0x14, 0x9f, 0x17, 0xe8, 0x16, 0x90, 0xce, 0x64, 0x58,
+	0x2d, 0xa8, 0x0e, 0x22, 0x57, 0x3c, 0x7f, 0x74, 0xaa, 0x0c, 0x48, 0xd7, 0xe1, 0xc6, 0x3b, 0xe4,
+	0x87, 0xe8, 0x7a, 0xce, 0x64, 0x4a, 0xeb, 0x32, 0xa5, 0x8f, 0x54, 0x73, 0xe6, 0x78, 0xb2, 0x02,
+	0x17, 0x72, 0x05, 0x7e, 0x06, 0x8b, 0x16, 0xaf, 0xb6, 0xbe, 0x0e, 0xe5, 0xbe, 0x40, 0xe8, 0xa4,
+	0x34, 0x54, 0x4a, 0x15, 0x8f, 0xa2, 0xd0, 0x65, 0xd5, 0x18, 0x1d, 0x4c, 0x12, 0x2b, 0x3f, 0xf4,
+	0x2b, 0x2c, 0xe5, 0xd1, 0x5a, 0xe3, 0xef, 0xa0, 0x96, 0x68, 0x5c, 0xbe, 0x63, 0x34, 0x27, 0x4b,
+	0xc9, 0xf2, 0xb2, 0x1e, 0xc4, 0xb1, 0xb9, 0xac, 0xeb, 0xcc, 0x80, 0xf4, 0x01, 0x2c, 0x31, 0x1c,
+	0x86, 0x17, 0x68, 0x84, 0x66, 0xc4, 0x7f, 0x0b, 0x96, 0xc7, 0xf8, 0x94, 0x17, 0x3b, 0xff, 0xa9,
+	0x42, 0xed, 0x50, 0xd7, 0x97, 0xbc, 0x84, 0xb2, 0xfc, 0x8a, 0x23, 0xf9, 0x1b, 0xc2, 0xfe, 0x24,
+	0x6c, 0xb7, 0xa7, 0x91, 0x74, 0x48, 0x0c, 0x6e, 0x8c, 0x7d, 0xe7, 0x90, 0xfb, 0x39, 0xf6, 0xe9,
+	0x5f, 0x41, 0xd7, 0xea, 0xdc, 0x81, 0xaa, 0x5e, 0x3c, 0x24, 0xff, 0x1c, 0xc8, 0xaf, 0xa3, 0xb6,
+	0xb5, 0x46, 0xc8, 0x7b, 0xa8, 0xa7, 0x6b, 0x87, 0xfc, 0x26, 0xaf, 0x7c, 0x6c, 0x45, 0xb5, 0x57,
+	0x67, 0x91, 0xb5, 0xfd, 0x3f, 0x01, 0x64, 0xbb, 0x88, 0xac, 0x8e, 0xbb, 0x90, 0x5f, 0x3f, 0xed,
+	0xfc, 0xd0, 0x93, 0x63, 0x68, 0x58, 0xbb, 0x82, 0xdc, 0x9b, 0xb0, 0x35, 0x26, 0xbe, 0x36, 0x9b,
+	0x21, 0x97, 0x0e, 0xb1, 0x3d, 0x26, 0xd3, 0x61, 0xed, 0x94, 0xb6, 0xf5, 0x3c, 0x25, 0x87, 0x32,
+	0x04, 0x3d, 0x4f, 0x93, 0x21, 0xe4, 0xe7, 0xb9, 0x7d, 0x6f, 0x26, 0x5d, 0xbb, 0x70, 0x08, 0xd0,
+	0x99, 0xa5, 0xae, 0xf3, 0x7f, 0xd4, 0x4d, 0x99, 0xeb, 0xa7, 0x50, 0x33, 0xd3, 0x4b, 0xee, 0x8e,
+	0xdb, 0xb6, 0x07, 0xb6, 0x6d, 0x0f, 0x9d, 0xa9, 0xb1, 0x02, 0x26, 0x6b, 0x9c, 0x13, 0x5c, 0x9d,
+	0x45, 0xd6, 0x2e, 0x74, 0xa0, 0x69, 0x8f, 0x28, 0x99, 0x2c, 0xc3, 0xd8, 0x50, 0xb7, 0xd7, 0xaf,
+	0xe1, 0xd0, 0x4a, 0x3f, 0xc3, 0x7c, 0x6e, 0xe4, 0x48, 0x5e, 0x66, 0xda, 0xd8, 0xb6, 0xe9, 0x75,
+	0x2c, 0x4a, 0xef, 0x9b, 0x87, 0x5f, 0x36, 0xce, 0x3c, 0x7e, 0x3e, 0x38, 0xd9, 0xea, 0x85, 0xfd,
+	0x6d, 0xb7, 0x77, 0x8e, 0xc1, 0x45, 0xfa, 0x3f, 0xcf, 0x76, 0x74, 0x71, 0xb6, 0xad, 0xfe, 0xfb,
+	0x39, 0xa9, 0xc8, 0xff, 0x74, 0xfe, 0xf0, 0xbf, 0x01, 0x00, 0xa1, 0x0f, 0xd8, 0xf0, 0x0c, 0x12,
+	0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// MazewireClient is the client API for Mazewire service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MazewireClient interface {
+	// Login checks the credentials of a user on the site and responds with a token to authenticate
+	// the subsequent calls.
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	// VerifyTwoFactor completes a login that requires a second factor with a one-time password or a
+	// recovery code, and responds with a token like Login.
+	VerifyTwoFactor(ctx context.Context, in *VerifyTwoFactorRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	// GetSite retrieves a site on which the user has a role.
+	GetSite(ctx context.Context, in *GetSiteRequest, opts ...grpc.CallOption) (*data.Site, error)
+	// ListSites lists the sites on which the user has a role.
+	ListSites(ctx context.Context, in *ListSitesRequest, opts ...grpc.CallOption) (*ListSitesResponse, error)
+	// GetContent retrieves a content item of the site.
+	GetContent(ctx context.Context, in *GetContentRequest, opts ...grpc.CallOption) (*data.Content, error)
+	// ListContent lists the content items of the site.
+	ListContent(ctx context.Context, in *ListContentRequest, opts ...grpc.CallOption) (*ListContentResponse, error)
+	// GetUser retrieves a user.
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*data.User, error)
+	// GetOptions retrieves options of the site.
+	GetOptions(ctx context.Context, in *GetOptionsRequest, opts ...grpc.CallOption) (*GetOptionsResponse, error)
+	// SetOptions creates or updates options of the site.
+	SetOptions(ctx context.Context, in *SetOptionsRequest, opts ...grpc.CallOption) (*SetOptionsResponse, error)
+	// GetMedia retrieves the metadata of a media object of the site.
+	GetMedia(ctx context.Context, in *GetMediaRequest, opts ...grpc.CallOption) (*data.Media, error)
+	// ListMedia lists the metadata of the media objects of the site.
+	ListMedia(ctx context.Context, in *ListMediaRequest, opts ...grpc.CallOption) (*ListMediaResponse, error)
+	// ListSessions lists the login sessions of the user on the site that have not expired.
+	ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*ListSessionsResponse, error)
+	// RevokeSession ends a login session of the user on the site.
+	RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...grpc.CallOption) (*RevokeSessionResponse, error)
+}
+
+type mazewireClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewMazewireClient(cc *grpc.ClientConn) MazewireClient {
+	return &mazewireClient{cc}
+}
+
+func (c *mazewireClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/Login", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) VerifyTwoFactor(ctx context.Context, in *VerifyTwoFactorRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/VerifyTwoFactor", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) GetSite(ctx context.Context, in *GetSiteRequest, opts ...grpc.CallOption) (*data.Site, error) {
+	out := new(data.Site)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/GetSite", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) ListSites(ctx context.Context, in *ListSitesRequest, opts ...grpc.CallOption) (*ListSitesResponse, error) {
+	out := new(ListSitesResponse)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/ListSites", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) GetContent(ctx context.Context, in *GetContentRequest, opts ...grpc.CallOption) (*data.Content, error) {
+	out := new(data.Content)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/GetContent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) ListContent(ctx context.Context, in *ListContentRequest, opts ...grpc.CallOption) (*ListContentResponse, error) {
+	out := new(ListContentResponse)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/ListContent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*data.User, error) {
+	out := new(data.User)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/GetUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) GetOptions(ctx context.Context, in *GetOptionsRequest, opts ...grpc.CallOption) (*GetOptionsResponse, error) {
+	out := new(GetOptionsResponse)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/GetOptions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) SetOptions(ctx context.Context, in *SetOptionsRequest, opts ...grpc.CallOption) (*SetOptionsResponse, error) {
+	out := new(SetOptionsResponse)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/SetOptions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) GetMedia(ctx context.Context, in *GetMediaRequest, opts ...grpc.CallOption) (*data.Media, error) {
+	out := new(data.Media)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/GetMedia", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) ListMedia(ctx context.Context, in *ListMediaRequest, opts ...grpc.CallOption) (*ListMediaResponse, error) {
+	out := new(ListMediaResponse)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/ListMedia", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*ListSessionsResponse, error) {
+	out := new(ListSessionsResponse)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/ListSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mazewireClient) RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...grpc.CallOption) (*RevokeSessionResponse, error) {
+	out := new(RevokeSessionResponse)
+	err := c.cc.Invoke(ctx, "/mazewire.v1.Mazewire/RevokeSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MazewireServer is the server API for Mazewire service.
+type MazewireServer interface {
+	// Login checks the credentials of a user on the site and responds with a token to authenticate
+	// the subsequent calls.
+	Login(context.Context, *LoginRequest) (*LoginResponse, error)
+	// VerifyTwoFactor completes a login that requires a second factor with a one-time password or a
+	// recovery code, and responds with a token like Login.
+	VerifyTwoFactor(context.Context, *VerifyTwoFactorRequest) (*LoginResponse, error)
+	// GetSite retrieves a site on which the user has a role.
+	GetSite(context.Context, *GetSiteRequest) (*data.Site, error)
+	// ListSites lists the sites on which the user has a role.
+	ListSites(context.Context, *ListSitesRequest) (*ListSitesResponse, error)
+	// GetContent retrieves a content item of the site.
+	GetContent(context.Context, *GetContentRequest) (*data.Content, error)
+	// ListContent lists the content items of the site.
+	ListContent(context.Context, *ListContentRequest) (*ListContentResponse, error)
+	// GetUser retrieves a user.
+	GetUser(context.Context, *GetUserRequest) (*data.User, error)
+	// GetOptions retrieves options of the site.
+	GetOptions(context.Context, *GetOptionsRequest) (*GetOptionsResponse, error)
+	// SetOptions creates or updates options of the site.
+	SetOptions(context.Context, *SetOptionsRequest) (*SetOptionsResponse, error)
+	// GetMedia retrieves the metadata of a media object of the site.
+	GetMedia(context.Context, *GetMediaRequest) (*data.Media, error)
+	// ListMedia lists the metadata of the media objects of the site.
+	ListMedia(context.Context, *ListMediaRequest) (*ListMediaResponse, error)
+	// ListSessions lists the login sessions of the user on the site that have not expired.
+	ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error)
+	// RevokeSession ends a login session of the user on the site.
+	RevokeSession(context.Context, *RevokeSessionRequest) (*RevokeSessionResponse, error)
+}
+
+func RegisterMazewireServer(s *grpc.Server, srv MazewireServer) {
+	s.RegisterService(&_Mazewire_serviceDesc, srv)
+}
+
+func _Mazewire_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/Login",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_VerifyTwoFactor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyTwoFactorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).VerifyTwoFactor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/VerifyTwoFactor",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).VerifyTwoFactor(ctx, req.(*VerifyTwoFactorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_GetSite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSiteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).GetSite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/GetSite",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).GetSite(ctx, req.(*GetSiteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_ListSites_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSitesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).ListSites(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/ListSites",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).ListSites(ctx, req.(*ListSitesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_GetContent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetContentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).GetContent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/GetContent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).GetContent(ctx, req.(*GetContentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_ListContent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListContentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).ListContent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/ListContent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).ListContent(ctx, req.(*ListContentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/GetUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_GetOptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).GetOptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/GetOptions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).GetOptions(ctx, req.(*GetOptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_SetOptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetOptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).SetOptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/SetOptions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).SetOptions(ctx, req.(*SetOptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_GetMedia_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMediaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).GetMedia(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/GetMedia",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).GetMedia(ctx, req.(*GetMediaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_ListMedia_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMediaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).ListMedia(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/ListMedia",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).ListMedia(ctx, req.(*ListMediaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/ListSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).ListSessions(ctx, req.(*ListSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mazewire_RevokeSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MazewireServer).RevokeSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mazewire.v1.Mazewire/RevokeSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MazewireServer).RevokeSession(ctx, req.(*RevokeSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Mazewire_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "mazewire.v1.Mazewire",
+	HandlerType: (*MazewireServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Login",
+			Handler:    _Mazewire_Login_Handler,
+		},
+		{
+			MethodName: "VerifyTwoFactor",
+			Handler:    _Mazewire_VerifyTwoFactor_Handler,
+		},
+		{
+			MethodName: "GetSite",
+			Handler:    _Mazewire_GetSite_Handler,
+		},
+		{
+			MethodName: "ListSites",
+			Handler:    _Mazewire_ListSites_Handler,
+		},
+		{
+			MethodName: "GetContent",
+			Handler:    _Mazewire_GetContent_Handler,
+		},
+		{
+			MethodName: "ListContent",
+			Handler:    _Mazewire_ListContent_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _Mazewire_GetUser_Handler,
+		},
+		{
+			MethodName: "GetOptions",
+			Handler:    _Mazewire_GetOptions_Handler,
+		},
+		{
+			MethodName: "SetOptions",
+			Handler:    _Mazewire_SetOptions_Handler,
+		},
+		{
+			MethodName: "GetMedia",
+			Handler:    _Mazewire_GetMedia_Handler,
+		},
+		{
+			MethodName: "ListMedia",
+			Handler:    _Mazewire_ListMedia_Handler,
+		},
+		{
+			MethodName: "ListSessions",
+			Handler:    _Mazewire_ListSessions_Handler,
+		},
+		{
+			MethodName: "RevokeSession",
+			Handler:    _Mazewire_RevokeSession_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/v1/mazewire.proto",
+}
@@ -0,0 +1,216 @@
+// Package api_validate validates requests to the API, and optionally the responses, against the
+// OpenAPI specification generated by "run gen-openapi".
+//
+// Only the requests whose path and method are documented in the specification are validated; all
+// other requests are passed on to the API handlers untouched.
+package api_validate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// Load loads the OpenAPI specification from a JSON or YAML file.
+func Load(path string) (*openapi3.Swagger, error) {
+	spec, err := openapi3.NewSwaggerLoader().LoadSwaggerFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("api_validate: could not load specification; %v", err)
+	}
+	return spec, nil
+}
+
+// A Validator checks API requests and responses against an OpenAPI specification.
+type Validator struct {
+	router *openapi3filter.Router
+	prefix string
+
+	// ValidateResponses says if JSON responses should be checked against the specification. This is
+	// meant for development, to catch handlers that drift from the documented contract.
+	ValidateResponses bool
+
+	// ErrorLog, if not nil, is called with every response that does not match the specification.
+	ErrorLog func(r *http.Request, err error)
+}
+
+// New creates a Validator for the specification. The paths in the specification are relative to the
+// prefix, such as "/api".
+func New(spec *openapi3.Swagger, prefix string) (*Validator, error) {
+	router := openapi3filter.NewRouter()
+	if err := router.AddSwagger(spec); err != nil {
+		return nil, fmt.Errorf("api_validate: invalid specification; %v", err)
+	}
+	return &Validator{router: router, prefix: strings.TrimSuffix(prefix, "/")}, nil
+}
+
+// Wrap returns a handler that validates requests before calling next. An invalid request is rejected
+// with the status 400 and an Error encoded in JSON. If ValidateResponses is true, the response of next
+// is buffered and, if it does not match the specification, replaced with an Error with the status 500.
+func (v *Validator) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input := v.requestInput(r)
+		if input == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			writeError(w, http.StatusBadRequest, requestError(err))
+			return
+		}
+
+		if !v.ValidateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if err := v.validateResponse(input, rec); err != nil {
+			if v.ErrorLog != nil {
+				v.ErrorLog(r, err)
+			}
+			writeError(w, http.StatusInternalServerError, &Error{
+				Message: "The response does not match the API specification.",
+				Reason:  err.Error(),
+			})
+			return
+		}
+		rec.copyTo(w)
+	})
+}
+
+// requestInput returns the input for validating the request, or nil if the request is not to a path
+// and method documented in the specification.
+func (v *Validator) requestInput(r *http.Request) *openapi3filter.RequestValidationInput {
+	if !strings.HasPrefix(r.URL.Path, v.prefix+"/") {
+		return nil
+	}
+	u := *r.URL
+	u.Path = strings.TrimPrefix(r.URL.Path, v.prefix)
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+	}
+	route, pathParams, err := v.router.FindRoute(r.Method, &u)
+	if err != nil {
+		return nil
+	}
+	return &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			// Authentication is left to the handlers.
+			AuthenticationFunc: func(context.Context, *openapi3filter.AuthenticationInput) error { return nil },
+		},
+	}
+}
+
+// validateResponse checks the recorded response. Only JSON bodies are checked against the schemas, as
+// the other encodings cannot be compared with them.
+func (v *Validator) validateResponse(input *openapi3filter.RequestValidationInput, rec *recorder) error {
+	opts := &openapi3filter.Options{}
+	if mt, _, _ := mime.ParseMediaType(rec.header.Get("Content-Type")); mt != "application/json" {
+		opts.ExcludeResponseBody = true
+	}
+	respInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 rec.status,
+		Header:                 rec.header,
+		Options:                opts,
+	}
+	respInput.SetBodyBytes(rec.body.Bytes())
+	return openapi3filter.ValidateResponse(context.Background(), respInput)
+}
+
+// An Error describes why a request or a response is not valid.
+type Error struct {
+	Message string `json:"error"`
+	In      string `json:"in,omitempty"`     // where the problem is: "path", "query", "header", "cookie", or "body"
+	Name    string `json:"name,omitempty"`   // the name of the parameter, if the problem is with a parameter
+	Field   string `json:"field,omitempty"`  // a JSON pointer to the invalid value within the parameter or body
+	Reason  string `json:"reason,omitempty"` // the details of the problem
+}
+
+// requestError converts an error from validating a request to an Error.
+func requestError(err error) *Error {
+	e := &Error{Message: "The request does not match the API specification."}
+	reqErr, ok := err.(*openapi3filter.RequestError)
+	if !ok {
+		e.Reason = err.Error()
+		return e
+	}
+	if p := reqErr.Parameter; p != nil {
+		e.In, e.Name = p.In, p.Name
+	} else if reqErr.RequestBody != nil {
+		e.In = "body"
+	}
+	e.Reason = reqErr.Reason
+	if schemaErr, ok := reqErr.Err.(*openapi3.SchemaError); ok {
+		if ptr := schemaErr.JSONPointer(); len(ptr) > 0 {
+			e.Field = "/" + strings.Join(escapePointer(ptr), "/")
+		}
+		e.Reason = schemaErr.Reason
+	} else if reqErr.Err != nil {
+		if e.Reason == "" {
+			e.Reason = reqErr.Err.Error()
+		} else {
+			e.Reason += ": " + reqErr.Err.Error()
+		}
+	}
+	return e
+}
+
+// escapePointer escapes the tokens of a JSON pointer as defined in RFC 6901.
+func escapePointer(tokens []string) []string {
+	escaped := make([]string, len(tokens))
+	for i, t := range tokens {
+		escaped[i] = strings.Replace(strings.Replace(t, "~", "~0", -1), "/", "~1", -1)
+	}
+	return escaped
+}
+
+func writeError(w http.ResponseWriter, status int, e *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
+
+// A recorder is an http.ResponseWriter that buffers the response.
+type recorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *recorder) Header() http.Header { return rec.header }
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = status, true
+	}
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
+
+func (rec *recorder) copyTo(w http.ResponseWriter) {
+	h := w.Header()
+	for k, vals := range rec.header {
+		h[k] = vals
+	}
+	w.WriteHeader(rec.status)
+	w.Write(rec.body.Bytes())
+}
@@ -0,0 +1,172 @@
+package api_validate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const testSpec = `
+openapi: 3.0.2
+info:
+  title: Test
+  version: 0.1.0
+components:
+  schemas:
+    Site:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+        tls:
+          type: integer
+          format: int32
+paths:
+  /sites/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      parameters:
+        - name: meta
+          in: query
+          schema:
+            type: boolean
+      responses:
+        "200":
+          description: The site
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Site"
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Site"
+      responses:
+        "200":
+          description: Created
+`
+
+func newTestValidator(t *testing.T) *Validator {
+	spec, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := New(spec, "/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestValidateRequest(t *testing.T) {
+	v := newTestValidator(t)
+	called := false
+	h := v.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	cases := []struct {
+		method, target, contentType, body string
+		valid                             bool
+		in, name, field                   string
+	}{
+		{"GET", "/api/sites/5", "", "", true, "", "", ""},
+		{"GET", "/api/sites/5?meta=true", "", "", true, "", "", ""},
+		{"GET", "/api/sites/abc", "", "", false, "path", "id", ""},
+		{"GET", "/api/sites/5?meta=maybe", "", "", false, "query", "meta", ""},
+		{"POST", "/api/sites/5", "application/json", `{"name":"Site"}`, true, "", "", ""},
+		{"POST", "/api/sites/5", "application/json", `{"tls":1}`, false, "body", "", ""},
+		{"POST", "/api/sites/5", "application/json", `{"name":"Site","tls":"x"}`, false, "body", "", "/tls"},
+		{"POST", "/api/sites/5", "text/plain", `name`, false, "body", "", ""},
+		{"POST", "/api/sites/5", "application/json", ``, false, "body", "", ""},
+		{"DELETE", "/api/sites/5", "", "", true, "", "", ""}, // not documented
+		{"GET", "/api/other", "", "", true, "", "", ""},      // not documented
+		{"GET", "/sites/abc", "", "", true, "", "", ""},      // not in the API
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			called = false
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if called != tc.valid {
+				t.Fatalf("handler called: %v; response: %d %s", called, w.Code, w.Body)
+			}
+			if tc.valid {
+				return
+			}
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("got status %d", w.Code)
+			}
+			var e Error
+			if err := json.NewDecoder(w.Body).Decode(&e); err != nil {
+				t.Fatal(err)
+			}
+			if e.Message == "" || e.In != tc.in || e.Name != tc.name || e.Field != tc.field {
+				t.Errorf("got error %+v", e)
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	v := newTestValidator(t)
+	v.ValidateResponses = true
+	var logged error
+	v.ErrorLog = func(_ *http.Request, err error) { logged = err }
+
+	cases := []struct {
+		contentType, body string
+		valid             bool
+	}{
+		{"application/json", `{"name":"Site"}`, true},
+		{"application/json; charset=utf-8", `{"name":"Site","tls":2}`, true},
+		{"application/json", `{"tls":2}`, false},
+		{"application/json", `[]`, false},
+		{"application/x-protobuf", "\x0a\x04Site", true}, // not checked
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			logged = nil
+			h := v.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.Header().Set("X-Test", "yes")
+				w.Write([]byte(tc.body))
+			}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/api/sites/5", nil))
+			if tc.valid {
+				if w.Code != http.StatusOK || w.Body.String() != tc.body || w.Header().Get("X-Test") != "yes" {
+					t.Errorf("response not passed through: %d %q", w.Code, w.Body)
+				}
+				if logged != nil {
+					t.Errorf("logged error %v", logged)
+				}
+				return
+			}
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("got status %d", w.Code)
+			}
+			if logged == nil {
+				t.Error("error not logged")
+			}
+		})
+	}
+}
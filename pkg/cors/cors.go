@@ -0,0 +1,198 @@
+// Package cors implements Cross-Origin Resource Sharing policies, which say which other origins may
+// make requests from browsers and with which methods, headers, and credentials.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Policy is the CORS policy of a site.
+type Policy struct {
+	// Origins are the origins allowed to make requests, like "https://app.example.com". An origin may
+	// have wildcards: "*" alone allows every origin, and a "*" within an origin matches any characters
+	// other than "/" and ":", as in "https://*.example.com" or "http://localhost:*".
+	Origins []string
+
+	// Methods are the methods allowed to be used. If Methods is empty, the methods for which the
+	// requested resource exists are allowed.
+	Methods []string
+
+	// Headers are the request headers allowed to be sent, other than the CORS-safelisted headers.
+	// The header "*" allows every header.
+	Headers []string
+
+	// ExposeHeaders are the response headers that the scripts of the other origins may read.
+	ExposeHeaders []string
+
+	// Credentials says if the requests may include cookies and the scripts may read the responses. The
+	// origins allowed only by the origin "*" are never allowed credentials, since that would let any
+	// website make requests on behalf of the users.
+	Credentials bool
+
+	// MaxAge is how long the browser may cache the result of a preflight request. If MaxAge is 0, the
+	// Access-Control-Max-Age header is not sent.
+	MaxAge time.Duration
+}
+
+// IsPreflight says if the request is a CORS preflight request.
+func IsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// AllowsOrigin says if the policy allows requests from the origin.
+func (p *Policy) AllowsOrigin(origin string) bool {
+	allowed, _ := p.allowOrigin(origin)
+	return allowed
+}
+
+// allowOrigin says if the policy allows requests from the origin and if it allows them with credentials,
+// which it does only for the origins that match an origin other than "*".
+func (p *Policy) allowOrigin(origin string) (allowed, credentials bool) {
+	if origin == "" || origin == "null" {
+		return false, false
+	}
+	origin = strings.ToLower(origin)
+	for _, o := range p.Origins {
+		if o != "*" && matchOrigin(strings.ToLower(o), origin) {
+			return true, p.Credentials
+		}
+	}
+	return contains(p.Origins, "*", false), false
+}
+
+// matchOrigin says if the origin matches the pattern, in which each "*" matches a sequence (possibly
+// empty) of characters other than "/" and ":".
+func matchOrigin(pattern, origin string) bool {
+	star := strings.IndexByte(pattern, '*')
+	if star == -1 {
+		return pattern == origin
+	}
+	if !strings.HasPrefix(origin, pattern[:star]) {
+		return false
+	}
+	rest := pattern[star+1:]
+	for i := star; i <= len(origin); i++ {
+		if matchOrigin(rest, origin[i:]) {
+			return true
+		}
+		if i < len(origin) && (origin[i] == '/' || origin[i] == ':') {
+			break
+		}
+	}
+	return false
+}
+
+// allowsMethod says if the policy allows the method for a resource that exists for the methods given.
+func (p *Policy) allowsMethod(method string, resourceMethods []string) bool {
+	if !contains(resourceMethods, method, false) {
+		return false
+	}
+	return len(p.Methods) == 0 || contains(p.Methods, method, false)
+}
+
+// allowsHeaders says if the policy allows the headers in the list of a Access-Control-Request-Headers header.
+func (p *Policy) allowsHeaders(list string) bool {
+	if contains(p.Headers, "*", false) {
+		return true
+	}
+	for _, h := range strings.Split(list, ",") {
+		h = strings.TrimSpace(h)
+		if h != "" && !safelistedHeader(h) && !contains(p.Headers, h, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// safelistedHeader says if the request header may always be sent. (The Content-Type values that make
+// the header safelisted are not checked because browsers ask about the header only for other values.)
+func safelistedHeader(h string) bool {
+	switch http.CanonicalHeaderKey(h) {
+	case "Accept", "Accept-Language", "Content-Language":
+		return true
+	}
+	return false
+}
+
+// Preflight sets the headers of the response to the preflight request r for a resource that exists
+// for the methods given. The headers that allow the request are set only if the policy allows the
+// origin, the method, and the headers requested, and Preflight says if they do.
+func (p *Policy) Preflight(h http.Header, r *http.Request, resourceMethods []string) bool {
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	origin := r.Header.Get("Origin")
+	reqHeaders := r.Header.Get("Access-Control-Request-Headers")
+	allowed, credentials := p.allowOrigin(origin)
+	if !allowed || !p.allowsMethod(r.Header.Get("Access-Control-Request-Method"), resourceMethods) ||
+		!p.allowsHeaders(reqHeaders) {
+		return false
+	}
+	setOrigin(h, origin, credentials)
+	methods := p.Methods
+	if len(methods) == 0 {
+		methods = resourceMethods
+	}
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if reqHeaders != "" {
+		h.Set("Access-Control-Allow-Headers", reqHeaders)
+	}
+	if p.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.FormatInt(int64(p.MaxAge/time.Second), 10))
+	}
+	return true
+}
+
+// Actual sets the headers of the response to an actual (not preflight) request from the origin, and
+// says if the policy allows the origin.
+func (p *Policy) Actual(h http.Header, origin string) bool {
+	h.Add("Vary", "Origin")
+	allowed, credentials := p.allowOrigin(origin)
+	if !allowed {
+		return false
+	}
+	setOrigin(h, origin, credentials)
+	if len(p.ExposeHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(p.ExposeHeaders, ", "))
+	}
+	return true
+}
+
+// setOrigin sets the headers allowing the origin and, if credentials is true, credentials. The origin is
+// always echoed rather than given as "*" because "*" may not be used with credentials.
+func setOrigin(h http.Header, origin string, credentials bool) {
+	h.Set("Access-Control-Allow-Origin", origin)
+	if credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// SameOrigin says if the origin is the origin of the request r itself, made to the host of r with the
+// scheme given.
+func SameOrigin(r *http.Request, scheme, origin string) bool {
+	return strings.EqualFold(origin, scheme+"://"+r.Host)
+}
+
+// ParseList parses a comma-separated list, as given in an option, dropping the empty elements.
+func ParseList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func contains(list []string, s string, fold bool) bool {
+	for _, v := range list {
+		if v == s || (fold && strings.EqualFold(v, s)) {
+			return true
+		}
+	}
+	return false
+}
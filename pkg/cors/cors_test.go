@@ -0,0 +1,170 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAllowsOrigin(t *testing.T) {
+	cases := []struct {
+		origins []string
+		origin  string
+		want    bool
+	}{
+		{nil, "https://example.com", false},
+		{[]string{"*"}, "https://example.com", true},
+		{[]string{"*"}, "null", false},
+		{[]string{"https://example.com"}, "https://example.com", true},
+		{[]string{"https://example.com"}, "https://EXAMPLE.com", true},
+		{[]string{"https://example.com"}, "http://example.com", false},
+		{[]string{"https://example.com"}, "https://example.com:8443", false},
+		{[]string{"https://*.example.com"}, "https://app.example.com", true},
+		{[]string{"https://*.example.com"}, "https://a.b.example.com", true},
+		{[]string{"https://*.example.com"}, "https://example.com", false},
+		{[]string{"https://*.example.com"}, "https://evil.com:1.example.com", false},
+		{[]string{"https://*.example.com"}, "https://example.com.evil.com", false},
+		{[]string{"http://localhost:*"}, "http://localhost:8082", true},
+		{[]string{"http://localhost:*"}, "http://localhost.evil.com:80", false},
+		{[]string{"https://a.com", "https://b.com"}, "https://b.com", true},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			p := &Policy{Origins: tc.origins}
+			if got := p.AllowsOrigin(tc.origin); got != tc.want {
+				t.Errorf("got %v for origin %q; expected %v", got, tc.origin, tc.want)
+			}
+		})
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	p := &Policy{
+		Origins:     []string{"https://app.example.com"},
+		Headers:     []string{"Content-Type", "X-CSRF-Token"},
+		Credentials: true,
+		MaxAge:      10 * time.Minute,
+	}
+	cases := []struct {
+		p       *Policy
+		origin  string
+		method  string
+		headers string
+		want    http.Header // nil if the request is not allowed
+	}{
+		{p, "https://app.example.com", "PATCH", "content-type, x-csrf-token", http.Header{
+			"Access-Control-Allow-Origin":      {"https://app.example.com"},
+			"Access-Control-Allow-Credentials": {"true"},
+			"Access-Control-Allow-Methods":     {"GET, PATCH"},
+			"Access-Control-Allow-Headers":     {"content-type, x-csrf-token"},
+			"Access-Control-Max-Age":           {"600"},
+		}},
+		{p, "https://app.example.com", "GET", "", http.Header{
+			"Access-Control-Allow-Origin":      {"https://app.example.com"},
+			"Access-Control-Allow-Credentials": {"true"},
+			"Access-Control-Allow-Methods":     {"GET, PATCH"},
+			"Access-Control-Max-Age":           {"600"},
+		}},
+		{p, "https://other.example.com", "GET", "", nil},
+		{p, "https://app.example.com", "DELETE", "", nil},
+		{p, "https://app.example.com", "GET", "Authorization", nil},
+		{&Policy{Origins: []string{"*"}, Methods: []string{"GET"}, Headers: []string{"*"}}, "https://a.com", "GET", "X-Any",
+			http.Header{
+				"Access-Control-Allow-Origin":  {"https://a.com"},
+				"Access-Control-Allow-Methods": {"GET"},
+				"Access-Control-Allow-Headers": {"X-Any"},
+			}},
+		{&Policy{Origins: []string{"*"}, Methods: []string{"GET"}}, "https://a.com", "PATCH", "", nil},
+		{&Policy{Origins: []string{"*"}, Credentials: true}, "https://a.com", "GET", "", http.Header{
+			"Access-Control-Allow-Origin":  {"https://a.com"},
+			"Access-Control-Allow-Methods": {"GET, PATCH"},
+		}},
+		{&Policy{Origins: []string{"*", "https://app.example.com"}, Credentials: true}, "https://app.example.com", "GET", "",
+			http.Header{
+				"Access-Control-Allow-Origin":      {"https://app.example.com"},
+				"Access-Control-Allow-Credentials": {"true"},
+				"Access-Control-Allow-Methods":     {"GET, PATCH"},
+			}},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "https://example.com/api/options", nil)
+			r.Header.Set("Origin", tc.origin)
+			r.Header.Set("Access-Control-Request-Method", tc.method)
+			if tc.headers != "" {
+				r.Header.Set("Access-Control-Request-Headers", tc.headers)
+			}
+			if !IsPreflight(r) {
+				t.Fatal("not a preflight request")
+			}
+			h := make(http.Header)
+			ok := tc.p.Preflight(h, r, []string{"GET", "PATCH"})
+			if ok != (tc.want != nil) {
+				t.Fatalf("got allowed %v; expected %v", ok, tc.want != nil)
+			}
+			h.Del("Vary")
+			if tc.want == nil {
+				tc.want = http.Header{}
+			}
+			if !reflect.DeepEqual(h, tc.want) {
+				t.Errorf("got headers %v; expected %v", h, tc.want)
+			}
+		})
+	}
+}
+
+func TestActual(t *testing.T) {
+	p := &Policy{Origins: []string{"https://*.example.com"}, ExposeHeaders: []string{"X-CSRF-Token", "Retry-After"}}
+	h := make(http.Header)
+	if !p.Actual(h, "https://app.example.com") {
+		t.Fatal("origin not allowed")
+	}
+	want := http.Header{
+		"Vary":                          {"Origin"},
+		"Access-Control-Allow-Origin":   {"https://app.example.com"},
+		"Access-Control-Expose-Headers": {"X-CSRF-Token, Retry-After"},
+	}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("got headers %v; expected %v", h, want)
+	}
+
+	// Credentials are never allowed for the origins allowed by "*".
+	wp := &Policy{Origins: []string{"*"}, ExposeHeaders: []string{"X-CSRF-Token"}, Credentials: true}
+	h = make(http.Header)
+	if !wp.Actual(h, "https://evil.example.org") {
+		t.Fatal("origin not allowed")
+	}
+	if got := h.Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("got credentials allowed %q for a wildcard origin", got)
+	}
+
+	h = make(http.Header)
+	if p.Actual(h, "https://example.org") {
+		t.Fatal("origin allowed")
+	}
+	if want := (http.Header{"Vary": {"Origin"}}); !reflect.DeepEqual(h, want) {
+		t.Errorf("got headers %v; expected %v", h, want)
+	}
+}
+
+func TestParseList(t *testing.T) {
+	cases := []struct {
+		s    string
+		want []string
+	}{
+		{"", nil},
+		{" , ", nil},
+		{"GET", []string{"GET"}},
+		{"https://a.com, https://*.b.com,", []string{"https://a.com", "https://*.b.com"}},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := ParseList(tc.s); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q; expected %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package cockroach
+
+import (
+	"database/sql"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+const apiTokenCols = "id,user_id,site,name,hash,scopes,role,created,last_used,expires"
+
+// APIToken retrieves an API token by its ID.
+func (d *DB) APIToken(id string) (*data.APIToken, error) {
+	ts, err := d.apiTokensWhere("id=$1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(ts) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &ts[0], nil
+}
+
+// APITokensByUser retrieves the personal access tokens of a user on a site, the most recently created
+// first.
+func (d *DB) APITokensByUser(userID, site int64) ([]data.APIToken, error) {
+	return d.apiTokensWhere("user_id=$1 AND site=$2 AND role=0 ORDER BY created DESC", userID, site)
+}
+
+// APITokensBySite retrieves the service tokens of a site, the most recently created first.
+func (d *DB) APITokensBySite(site int64) ([]data.APIToken, error) {
+	return d.apiTokensWhere("site=$1 AND role!=0 ORDER BY created DESC", site)
+}
+
+func (d *DB) apiTokensWhere(where string, args ...interface{}) ([]data.APIToken, error) {
+	rows, err := d.selCols(data.APITokensTable, apiTokenCols, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ts := make([]data.APIToken, 0, 1)
+	for rows.Next() {
+		var t data.APIToken
+		if err = rows.Scan(&t.Id, &t.UserId, &t.Site, &t.Name, &t.Hash, &t.Scopes, &t.Role, &t.Created,
+			&t.LastUsed, &t.Expires); err != nil {
+			return ts, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, rows.Err()
+}
+
+// APITokenInsert inserts an API token.
+func (d *DB) APITokenInsert(t *data.APIToken) error {
+	_, err := d.db.Exec("INSERT INTO "+data.APITokensTable+" (id,user_id,site,name,hash,scopes,role,expires) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)",
+		t.Id, t.UserId, t.Site, t.Name, t.Hash, t.Scopes, t.Role, t.Expires)
+	return err
+}
+
+// APITokenTouch records that an API token is used now.
+func (d *DB) APITokenTouch(id string) error {
+	_, err := d.db.Exec("UPDATE "+data.APITokensTable+" SET last_used=now() WHERE id=$1", id)
+	return err
+}
+
+// APITokenDelete deletes an API token.
+func (d *DB) APITokenDelete(id string) error {
+	_, err := d.db.Exec("DELETE FROM "+data.APITokensTable+" WHERE id=$1", id)
+	return err
+}
+
+// APITokensDeleteByUser deletes all of the API tokens created by a user, on every site.
+func (d *DB) APITokensDeleteByUser(userID int64) (int64, error) {
+	res, err := d.db.Exec("DELETE FROM "+data.APITokensTable+" WHERE user_id=$1", userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
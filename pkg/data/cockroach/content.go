@@ -53,8 +53,27 @@ func (d *DB) ContentBySiteSlug(siteID int64, slug string) (*data.Content, string
 	return &c, parentSlug, err
 }
 
+// ContentByAuthor retrieves all of the content items of the author, on every site.
 func (d *DB) ContentByAuthor(authorID int64) ([]data.Content, error) {
-	return nil, nil // TODO
+	return d.contentsWhere("author=$1 ORDER BY site, id", authorID)
+}
+
+// ContentCountsByAuthor counts the content items of the author on each site.
+func (d *DB) ContentCountsByAuthor(authorID int64) (map[int64]int64, error) {
+	rows, err := d.db.Query("SELECT site,count(*) FROM "+data.ContentTable+" WHERE author=$1 GROUP BY site", authorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[int64]int64)
+	for rows.Next() {
+		var site, n int64
+		if err = rows.Scan(&site, &n); err != nil {
+			return counts, err
+		}
+		counts[site] = n
+	}
+	return counts, rows.Err()
 }
 
 // ContentsList retrieves the specified Content items. The parent argument should be either nil to indicate that the
@@ -118,6 +137,21 @@ func (d *DB) ContentUpdate(contentID int64, vals map[string]interface{}) (int64,
 	return res.RowsAffected()
 }
 
+// ContentReassign gives at most limit content items of the author from on the site to the author to,
+// also moving them to the trash if trash is true.
+func (d *DB) ContentReassign(site, from, to int64, trash bool, limit int) (int64, error) {
+	set := "author=$1"
+	if trash {
+		set += ",status='trashed'"
+	}
+	res, err := d.db.Exec("UPDATE "+data.ContentTable+" SET "+set+" WHERE site=$2 AND author=$3 LIMIT $4",
+		to, site, from, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // firstContent returns the first *Content from the given slice, or an error if err != nil or if the slice is empty.
 func firstContent(cs []data.Content, err error) (*data.Content, error) {
 	if err != nil {
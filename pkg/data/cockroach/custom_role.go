@@ -0,0 +1,70 @@
+package cockroach
+
+import (
+	"database/sql"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+const customRoleCols = "site,id,name,capabilities,created"
+
+// CustomRole retrieves a custom role of a site by its ID.
+func (d *DB) CustomRole(site, id int64) (*data.CustomRole, error) {
+	rs, err := d.customRolesWhere("site=$1 AND id=$2", site, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &rs[0], nil
+}
+
+// CustomRolesBySite retrieves the custom roles of a site in the order of their IDs.
+func (d *DB) CustomRolesBySite(site int64) ([]data.CustomRole, error) {
+	return d.customRolesWhere("site=$1 ORDER BY id", site)
+}
+
+func (d *DB) customRolesWhere(where string, args ...interface{}) ([]data.CustomRole, error) {
+	rows, err := d.selCols(data.CustomRolesTable, customRoleCols, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rs := make([]data.CustomRole, 0, 1)
+	for rows.Next() {
+		var r data.CustomRole
+		if err = rows.Scan(&r.Site, &r.Id, &r.Name, &r.Capabilities, &r.Created); err != nil {
+			return rs, err
+		}
+		rs = append(rs, r)
+	}
+	return rs, rows.Err()
+}
+
+// CustomRoleInsert inserts a custom role of a site, giving it the next ID of the site.
+func (d *DB) CustomRoleInsert(r *data.CustomRole) (id int64, err error) {
+	err = d.db.QueryRow("INSERT INTO "+data.CustomRolesTable+" (site,id,name,capabilities) SELECT $1,COALESCE(MAX(id),99)+1,$2,$3 FROM "+
+		data.CustomRolesTable+" WHERE site=$1 RETURNING id", r.Site, r.Name, r.Capabilities).Scan(&id)
+	return
+}
+
+// CustomRoleUpdate sets the name and the capabilities of a custom role.
+func (d *DB) CustomRoleUpdate(r *data.CustomRole) (int64, error) {
+	res, err := d.db.Exec("UPDATE "+data.CustomRolesTable+" SET name=$1,capabilities=$2 WHERE site=$3 AND id=$4",
+		r.Name, r.Capabilities, r.Site, r.Id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CustomRoleDelete deletes a custom role of a site unless a user has the user meta k with the value v.
+func (d *DB) CustomRoleDelete(site, id int64, k string, v []byte) (int64, error) {
+	res, err := d.db.Exec("DELETE FROM "+data.CustomRolesTable+" WHERE site=$1 AND id=$2 AND NOT EXISTS "+
+		"(SELECT 1 FROM "+data.UserMetaTable+" WHERE k=$3 AND v=$4)", site, id, k, v)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
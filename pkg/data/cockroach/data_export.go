@@ -0,0 +1,94 @@
+package cockroach
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+const dataExportCols = "id,user_id,site,status,size,error,claimed,created,expires"
+
+// DataExport retrieves an export of personal data by its ID.
+func (d *DB) DataExport(id string) (*data.DataExport, error) {
+	es, err := d.dataExportsWhere("id=$1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(es) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &es[0], nil
+}
+
+// DataExportsByUser retrieves the exports of the personal data of a user, the most recently created first.
+func (d *DB) DataExportsByUser(userID int64) ([]data.DataExport, error) {
+	return d.dataExportsWhere("user_id=$1 ORDER BY created DESC", userID)
+}
+
+// DataExportsUnclaimed retrieves at most limit pending exports that have not been claimed since the time
+// given, the least recently claimed first.
+func (d *DB) DataExportsUnclaimed(since time.Time, limit int) ([]data.DataExport, error) {
+	return d.dataExportsWhere("status='pending' AND claimed<$1 ORDER BY claimed LIMIT $2", since, limit)
+}
+
+// DataExportsExpired retrieves at most limit exports that expired before the time given.
+func (d *DB) DataExportsExpired(before time.Time, limit int) ([]data.DataExport, error) {
+	return d.dataExportsWhere("expires<$1 LIMIT $2", before, limit)
+}
+
+func (d *DB) dataExportsWhere(where string, args ...interface{}) ([]data.DataExport, error) {
+	rows, err := d.selCols(data.DataExportsTable, dataExportCols, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	es := make([]data.DataExport, 0, 1)
+	for rows.Next() {
+		var e data.DataExport
+		if err = rows.Scan(&e.Id, &e.UserId, &e.Site, &e.Status, &e.Size, &e.Error, &e.Claimed, &e.Created,
+			&e.Expires); err != nil {
+			return es, err
+		}
+		es = append(es, e)
+	}
+	return es, rows.Err()
+}
+
+// DataExportInsert inserts a pending export.
+func (d *DB) DataExportInsert(e *data.DataExport) error {
+	_, err := d.db.Exec("INSERT INTO "+data.DataExportsTable+" (id,user_id,site) VALUES ($1,$2,$3)",
+		e.Id, e.UserId, e.Site)
+	return err
+}
+
+// DataExportClaim claims a pending export for building now if it has not been claimed since the time given.
+func (d *DB) DataExportClaim(id string, since time.Time) (bool, error) {
+	res, err := d.db.Exec("UPDATE "+data.DataExportsTable+
+		" SET claimed=now() WHERE id=$1 AND status='pending' AND claimed<$2", id, since)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DataExportReady marks a pending export ready with the size of its archive.
+func (d *DB) DataExportReady(id string, size int64, expires time.Time) error {
+	_, err := d.db.Exec("UPDATE "+data.DataExportsTable+
+		" SET status='ready',size=$1,expires=$2 WHERE id=$3 AND status='pending'", size, expires, id)
+	return err
+}
+
+// DataExportFail marks a pending export failed with the error with which building it failed.
+func (d *DB) DataExportFail(id, msg string, expires time.Time) error {
+	_, err := d.db.Exec("UPDATE "+data.DataExportsTable+
+		" SET status='failed',error=$1,expires=$2 WHERE id=$3 AND status='pending'", msg, expires, id)
+	return err
+}
+
+// DataExportDelete deletes an export.
+func (d *DB) DataExportDelete(id string) error {
+	_, err := d.db.Exec("DELETE FROM "+data.DataExportsTable+" WHERE id=$1", id)
+	return err
+}
@@ -0,0 +1,96 @@
+package cockroach
+
+import (
+	"database/sql"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+func (d *DB) domainVerificationsWhere(cond string, args ...interface{}) ([]data.DomainVerification, error) {
+	rows, err := d.selCols(data.DomainVerificationsTable, "domain,site,token,status,method", cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dvs := make([]data.DomainVerification, 0, 1)
+	for rows.Next() {
+		var dv data.DomainVerification
+		if err = rows.Scan(&dv.Domain, &dv.Site, &dv.Token, &dv.Status, &dv.Method); err != nil {
+			return dvs, err
+		}
+		dvs = append(dvs, dv)
+	}
+	return dvs, rows.Err()
+}
+
+// DomainVerification retrieves the verification of a domain.
+func (d *DB) DomainVerification(domain string) (*data.DomainVerification, error) {
+	dvs, err := d.domainVerificationsWhere("domain=$1", domain)
+	if err != nil {
+		return nil, err
+	}
+	if len(dvs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &dvs[0], nil
+}
+
+// DomainVerificationsBySite retrieves the verifications of all of a site's domains.
+func (d *DB) DomainVerificationsBySite(site int64) ([]data.DomainVerification, error) {
+	return d.domainVerificationsWhere("site=$1", site)
+}
+
+// DomainVerified says if the domain is verified.
+func (d *DB) DomainVerified(domain string) (bool, error) {
+	var verified bool
+	err := d.db.QueryRow("SELECT status=$1 FROM "+data.DomainVerificationsTable+" WHERE domain=$2",
+		data.DomainVerified, domain).Scan(&verified)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return verified, err
+}
+
+// DomainVerificationInsert inserts a pending verification of a domain for the site.
+func (d *DB) DomainVerificationInsert(site int64, domain, token string) error {
+	res, err := d.db.Exec("INSERT INTO "+data.DomainVerificationsTable+" (domain,site,token) VALUES ($1,$2,$3) "+
+		"ON CONFLICT (domain) DO UPDATE SET token=excluded.token, status=0, method='', updated=now() "+
+		"WHERE "+data.DomainVerificationsTable+".site=excluded.site", domain, site, token)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return data.ErrDomainTaken
+	}
+	return nil
+}
+
+// DomainVerificationSetVerified marks the domain as verified by the method given, along with the site
+// whose primary domain it is or the alias with the domain.
+func (d *DB) DomainVerificationSetVerified(domain, method string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE "+data.DomainVerificationsTable+" SET status=$1, method=$2, updated=now() WHERE domain=$3",
+		data.DomainVerified, method, domain)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.Exec("UPDATE "+data.SitesTable+" SET verified=true WHERE domain=$1", domain); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.Exec("UPDATE "+data.SiteAliasesTable+" SET verified=true WHERE domain=$1", domain); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// DomainVerificationDelete deletes the verification of a domain.
+func (d *DB) DomainVerificationDelete(domain string) error {
+	_, err := d.db.Exec("DELETE FROM "+data.DomainVerificationsTable+" WHERE domain=$1", domain)
+	return err
+}
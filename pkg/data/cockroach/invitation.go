@@ -0,0 +1,87 @@
+package cockroach
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+const invitationCols = "id,site,email,role,invited_by,hash,status,accepted_by,created,expires"
+
+// Invitation retrieves an invitation by its ID.
+func (d *DB) Invitation(id string) (*data.Invitation, error) {
+	invs, err := d.invitationsWhere("id=$1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(invs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &invs[0], nil
+}
+
+// InvitationsBySite retrieves the invitations to a site, the most recently created first.
+func (d *DB) InvitationsBySite(site int64) ([]data.Invitation, error) {
+	return d.invitationsWhere("site=$1 ORDER BY created DESC", site)
+}
+
+func (d *DB) invitationsWhere(where string, args ...interface{}) ([]data.Invitation, error) {
+	rows, err := d.selCols(data.InvitationsTable, invitationCols, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	invs := make([]data.Invitation, 0, 1)
+	for rows.Next() {
+		var inv data.Invitation
+		if err = rows.Scan(&inv.Id, &inv.Site, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.Hash, &inv.Status,
+			&inv.AcceptedBy, &inv.Created, &inv.Expires); err != nil {
+			return invs, err
+		}
+		invs = append(invs, inv)
+	}
+	return invs, rows.Err()
+}
+
+// InvitationInsert inserts a pending invitation.
+func (d *DB) InvitationInsert(inv *data.Invitation) error {
+	_, err := d.db.Exec("INSERT INTO "+data.InvitationsTable+" (id,site,email,role,invited_by,hash,expires) VALUES ($1,$2,$3,$4,$5,$6,$7)",
+		inv.Id, inv.Site, inv.Email, inv.Role, inv.InvitedBy, inv.Hash, inv.Expires)
+	return err
+}
+
+// InvitationRenew gives a pending invitation a new token hash and expiration time.
+func (d *DB) InvitationRenew(id string, hash []byte, expires time.Time) (int64, error) {
+	res, err := d.db.Exec("UPDATE "+data.InvitationsTable+" SET hash=$1, expires=$2 WHERE id=$3 AND status='pending'",
+		hash, expires, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// InvitationAccept marks a pending invitation that has not expired accepted by the user.
+func (d *DB) InvitationAccept(id string, userID int64) (int64, error) {
+	res, err := d.db.Exec("UPDATE "+data.InvitationsTable+" SET status='accepted', accepted_by=$1 WHERE id=$2 AND status='pending' AND expires>now()",
+		userID, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// InvitationDelete deletes an invitation.
+func (d *DB) InvitationDelete(id string) error {
+	_, err := d.db.Exec("DELETE FROM "+data.InvitationsTable+" WHERE id=$1", id)
+	return err
+}
+
+// InvitationsDeletePending deletes the pending invitations of the email address to the site.
+func (d *DB) InvitationsDeletePending(site int64, email string) (int64, error) {
+	res, err := d.db.Exec("DELETE FROM "+data.InvitationsTable+" WHERE site=$1 AND email=$2 AND status='pending'", site, email)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,54 @@
+package cockroach
+
+import (
+	"database/sql"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+func (d *DB) mediaWhere(cond string, args ...interface{}) ([]data.Media, error) {
+	rows, err := d.selCols(data.MediaTable, "id,ext,site,name,alt,description,uploaded,uploader", cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	media := make([]data.Media, 0, 4)
+	for rows.Next() {
+		var m data.Media
+		if err = rows.Scan(&m.Id, &m.Ext, &m.Site, &m.Name, &m.Alt, &m.Desc, &m.Uploaded,
+			&m.Uploader); err != nil {
+			return media, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+// MediaByID retrieves the metadata of a site's media object by its ID.
+func (d *DB) MediaByID(site int64, id string) (*data.Media, error) {
+	media, err := d.mediaWhere("site=$1 AND id=$2", site, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(media) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &media[0], nil
+}
+
+// MediaList retrieves the metadata of a site's media objects, the most recently uploaded first.
+func (d *DB) MediaList(site int64, offset uint64) ([]data.Media, error) {
+	return d.mediaWhere("site=$1 ORDER BY uploaded DESC, id LIMIT 20"+getOffsetOrNot(offset), site)
+}
+
+// MediaInsert inserts the metadata of a media object, including the ID of the user who uploaded it.
+func (d *DB) MediaInsert(m *data.Media) error {
+	_, err := d.db.Exec("INSERT INTO "+data.MediaTable+" (id,ext,site,name,alt,description,uploader) "+
+		"VALUES ($1,$2,$3,$4,$5,$6,$7)", m.Id, m.Ext, m.Site, m.Name, m.Alt, m.Desc, m.Uploader)
+	return err
+}
+
+// MediaByUploader retrieves the metadata of all of the media objects uploaded by a user, on every site.
+func (d *DB) MediaByUploader(userID int64) ([]data.Media, error) {
+	return d.mediaWhere("uploader=$1 ORDER BY uploaded DESC, id", userID)
+}
@@ -0,0 +1,105 @@
+package cockroach
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+)
+
+// LoginThrottle retrieves the throttle of logins on a site by its key.
+func (d *DB) LoginThrottle(site int64, k string) (*data.LoginThrottle, error) {
+	rows, err := d.selCols(data.LoginThrottlesTable, "site,k,failures,locked_until,updated", "site=$1 AND k=$2", site, k)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+	lt := new(data.LoginThrottle)
+	if err = rows.Scan(&lt.Site, &lt.K, &lt.Failures, &lt.LockedUntil, &lt.Updated); err != nil {
+		return nil, err
+	}
+	return lt, nil
+}
+
+// LoginThrottleFail counts a failed login on a site for the throttle with the key given and returns
+// the number of consecutive failures.
+func (d *DB) LoginThrottleFail(site int64, k string, since time.Time) (int64, error) {
+	var failures int64
+	err := d.db.QueryRow("INSERT INTO "+data.LoginThrottlesTable+" (site,k,failures) VALUES ($1,$2,1) "+
+		"ON CONFLICT (site,k) DO UPDATE SET failures=CASE WHEN "+data.LoginThrottlesTable+".updated<$3 THEN 1 "+
+		"ELSE "+data.LoginThrottlesTable+".failures+1 END, updated=now() RETURNING failures",
+		site, k, since).Scan(&failures)
+	return failures, err
+}
+
+// LoginThrottleLock locks out the logins on a site for the throttle with the key given until the time given.
+func (d *DB) LoginThrottleLock(site int64, k string, until time.Time) error {
+	_, err := d.db.Exec("UPDATE "+data.LoginThrottlesTable+" SET locked_until=$1 WHERE site=$2 AND k=$3", until, site, k)
+	return err
+}
+
+// LoginThrottleDelete deletes a throttle.
+func (d *DB) LoginThrottleDelete(site int64, k string) error {
+	_, err := d.db.Exec("DELETE FROM "+data.LoginThrottlesTable+" WHERE site=$1 AND k=$2", site, k)
+	return err
+}
+
+// LoginThrottlesPurge deletes the throttles that were last updated before the time given and are not locked.
+func (d *DB) LoginThrottlesPurge(before time.Time) (int64, error) {
+	res, err := d.db.Exec("DELETE FROM "+data.LoginThrottlesTable+" WHERE updated<$1 AND locked_until<now()", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RateLimitTake takes a token from the bucket with the key k and says if a token was available. The
+// elapsed time by which the bucket is refilled is measured by the clock of the database, so that the
+// clocks of the instances do not need to agree.
+func (d *DB) RateLimitTake(k string, b rate_limit.Bucket) (bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	var (
+		tokens       float64
+		updated, now time.Time
+	)
+	err = tx.QueryRow("SELECT tokens,updated,now() FROM "+data.RateLimitsTable+" WHERE k=$1", k).Scan(&tokens, &updated, &now)
+	switch err {
+	case nil:
+		tokens = b.Refill(tokens, now.Sub(updated))
+	case sql.ErrNoRows:
+		tokens = b.Capacity
+	default:
+		tx.Rollback()
+		return false, err
+	}
+	if tokens < 1 {
+		tx.Rollback()
+		return false, nil
+	}
+	_, err = tx.Exec("INSERT INTO "+data.RateLimitsTable+" (k,tokens,updated) VALUES ($1,$2,now()) "+
+		"ON CONFLICT (k) DO UPDATE SET tokens=excluded.tokens, updated=excluded.updated", k, tokens-1)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RateLimitsPurge deletes the buckets that were last used before the time given.
+func (d *DB) RateLimitsPurge(before time.Time) (int64, error) {
+	res, err := d.db.Exec("DELETE FROM "+data.RateLimitsTable+" WHERE updated<$1", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,101 @@
+package cockroach
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+const sessionCols = "id,user_id,site,user_agent,ip,created,last_seen,expires,parent"
+
+// Session retrieves a session by its ID.
+func (d *DB) Session(id string) (*data.Session, error) {
+	rows, err := d.selCols(data.SessionsTable, sessionCols, "id=$1", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+	s := new(data.Session)
+	if err = rows.Scan(&s.Id, &s.UserId, &s.Site, &s.UserAgent, &s.Ip, &s.Created, &s.LastSeen, &s.Expires,
+		&s.Parent); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SessionsByUser retrieves the sessions of a user on a site that have not expired, the most recently
+// used first.
+func (d *DB) SessionsByUser(userID, site int64) ([]data.Session, error) {
+	rows, err := d.selCols(data.SessionsTable, sessionCols,
+		"user_id=$1 AND site=$2 AND expires>now() ORDER BY last_seen DESC", userID, site)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ss := make([]data.Session, 0, 2)
+	for rows.Next() {
+		var s data.Session
+		if err = rows.Scan(&s.Id, &s.UserId, &s.Site, &s.UserAgent, &s.Ip, &s.Created, &s.LastSeen, &s.Expires,
+			&s.Parent); err != nil {
+			return ss, err
+		}
+		ss = append(ss, s)
+	}
+	return ss, rows.Err()
+}
+
+// SessionInsert inserts a session.
+func (d *DB) SessionInsert(s *data.Session) error {
+	_, err := d.db.Exec("INSERT INTO "+data.SessionsTable+" (id,user_id,site,user_agent,ip,expires,parent) "+
+		"VALUES ($1,$2,$3,$4,$5,$6,$7)", s.Id, s.UserId, s.Site, s.UserAgent, s.Ip, s.Expires, s.Parent)
+	return err
+}
+
+// SessionTouch records that a session is used now and extends it until the time given.
+func (d *DB) SessionTouch(id string, expires time.Time) error {
+	_, err := d.db.Exec("UPDATE "+data.SessionsTable+" SET last_seen=now(), expires=$1 WHERE id=$2", expires, id)
+	return err
+}
+
+// SessionDelete deletes a session.
+func (d *DB) SessionDelete(id string) error {
+	_, err := d.db.Exec("DELETE FROM "+data.SessionsTable+" WHERE id=$1", id)
+	return err
+}
+
+// SessionsDeleteTree deletes the session with the ID root and the sessions started from it by single
+// sign-on.
+func (d *DB) SessionsDeleteTree(root string) (int64, error) {
+	res, err := d.db.Exec("DELETE FROM "+data.SessionsTable+" WHERE id=$1 OR parent=$1", root)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SessionsDeleteByUser deletes the sessions of a user on a site, or on all the sites if site is 0,
+// except for the session with the ID except.
+func (d *DB) SessionsDeleteByUser(userID, site int64, except string) (int64, error) {
+	res, err := d.db.Exec("DELETE FROM "+data.SessionsTable+" WHERE user_id=$1 AND ($2=0 OR site=$2) AND id!=$3",
+		userID, site, except)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SessionsPurge deletes the sessions that expired before the time given.
+func (d *DB) SessionsPurge(before time.Time) (int64, error) {
+	res, err := d.db.Exec("DELETE FROM "+data.SessionsTable+" WHERE expires<$1", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,29 @@
+package cockroach
+
+import (
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+// SigningKeys retrieves all of the signing keys, the most recently created first.
+func (d *DB) SigningKeys() ([]data.SigningKey, error) {
+	rows, err := d.db.Query("SELECT id,secret,created,expires FROM " + data.SigningKeysTable + " ORDER BY created DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ks := make([]data.SigningKey, 0, 2)
+	for rows.Next() {
+		var k data.SigningKey
+		if err = rows.Scan(&k.Id, &k.Secret, &k.Created, &k.Expires); err != nil {
+			return ks, err
+		}
+		ks = append(ks, k)
+	}
+	return ks, rows.Err()
+}
+
+// SigningKeyInsert inserts a signing key that does not expire.
+func (d *DB) SigningKeyInsert(k *data.SigningKey) error {
+	_, err := d.db.Exec("INSERT INTO "+data.SigningKeysTable+" (id,secret) VALUES ($1,$2)", k.Id, k.Secret)
+	return err
+}
@@ -6,6 +6,9 @@ import (
 	"github.com/dchenk/mazewire/pkg/data"
 )
 
+// siteCols is the columns retrieved when a single *Site or a []Site is queried.
+const siteCols = "id,domain,name,logo,favicon,tls,verified"
+
 func sitesWhere(cond string, args ...interface{}) ([]data.Site, error) {
 	rows, err := selCols(data.SitesTable, siteCols, cond, args...)
 	if err != nil {
@@ -15,7 +18,7 @@ func sitesWhere(cond string, args ...interface{}) ([]data.Site, error) {
 	sites := make([]data.Site, 0, 4)
 	for rows.Next() {
 		var s data.Site
-		if err = rows.Scan(&s.Id, &s.Domain, &s.Name, &s.Logo, &s.Favicon, &s.Tls); err != nil {
+		if err = rows.Scan(&s.Id, &s.Domain, &s.Name, &s.Logo, &s.Favicon, &s.Tls, &s.Verified); err != nil {
 			return sites, err
 		}
 		sites = append(sites, s)
@@ -37,7 +40,7 @@ func SiteByDomain(domain string) (*data.Site, error) {
 	defer rows.Close()
 	s := new(data.Site)
 	if rows.Next() {
-		err = rows.Scan(&s.Id, &s.Domain, &s.Name, &s.Logo, &s.Favicon, &s.Tls)
+		err = rows.Scan(&s.Id, &s.Domain, &s.Name, &s.Logo, &s.Favicon, &s.Tls, &s.Verified)
 	} else {
 		return nil, sql.ErrNoRows
 	}
@@ -46,15 +49,22 @@ func SiteByDomain(domain string) (*data.Site, error) {
 
 // InsertSite inserts a record into the sites table and create all needed tables for the site. The int64 returned
 // is the ID of the new site (the inserted row). The domain passed in must have already been validated as a real
-// domain name.
-func (d *DB) InsertSite(domain, name string) (int64, error) {
+// domain name. The site is pending until its domain is verified using verifyToken.
+func (d *DB) InsertSite(domain, name, verifyToken string) (int64, error) {
 	tx, err := d.db.Begin()
 	if err != nil {
 		return 0, err
 	}
 
 	var siteID int64
-	err = tx.QueryRow("INSERT INTO "+data.SitesTable+" (domain,name) VALUES ($1,$2) RETURNING id", domain, name).Scan(&siteID)
+	err = tx.QueryRow("INSERT INTO "+data.SitesTable+" (domain,name,verified) VALUES ($1,$2,false) RETURNING id", domain, name).Scan(&siteID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	_, err = tx.Exec("INSERT INTO "+data.DomainVerificationsTable+" (domain,site,token) VALUES ($1,$2,$3)",
+		domain, siteID, verifyToken)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
@@ -0,0 +1,105 @@
+package cockroach
+
+import (
+	"database/sql"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+func (d *DB) siteAliasesWhere(cond string, args ...interface{}) ([]data.SiteAlias, error) {
+	rows, err := d.selCols(data.SiteAliasesTable, "domain,site,redirect,verified", cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	aliases := make([]data.SiteAlias, 0, 2)
+	for rows.Next() {
+		var a data.SiteAlias
+		if err = rows.Scan(&a.Domain, &a.Site, &a.Redirect, &a.Verified); err != nil {
+			return aliases, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// SiteAlias retrieves an alias by its domain.
+func (d *DB) SiteAlias(domain string) (*data.SiteAlias, error) {
+	aliases, err := d.siteAliasesWhere("domain=$1", domain)
+	if err != nil {
+		return nil, err
+	}
+	if len(aliases) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &aliases[0], nil
+}
+
+// SiteAliases retrieves all of a site's aliases.
+func (d *DB) SiteAliases(site int64) ([]data.SiteAlias, error) {
+	return d.siteAliasesWhere("site=$1 ORDER BY domain", site)
+}
+
+// SiteAliasInsert inserts an alias of the site along with a pending verification of the domain.
+func (d *DB) SiteAliasInsert(site int64, domain string, redirect bool, verifyToken string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// The domain must not be the primary domain of any site.
+	var n int
+	err = tx.QueryRow("SELECT count(*) FROM "+data.SitesTable+" WHERE domain=$1", domain).Scan(&n)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n > 0 {
+		tx.Rollback()
+		return data.ErrDomainTaken
+	}
+
+	// Every domain in use has a verification, so a conflict means the domain is already claimed.
+	res, err := tx.Exec("INSERT INTO "+data.DomainVerificationsTable+" (domain,site,token) VALUES ($1,$2,$3) "+
+		"ON CONFLICT (domain) DO NOTHING", domain, site, verifyToken)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		tx.Rollback()
+		return data.ErrDomainTaken
+	}
+
+	_, err = tx.Exec("INSERT INTO "+data.SiteAliasesTable+" (domain,site,redirect) VALUES ($1,$2,$3)",
+		domain, site, redirect)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SiteAliasSetRedirect sets whether the alias redirects to the primary domain of its site.
+func (d *DB) SiteAliasSetRedirect(domain string, redirect bool) error {
+	_, err := d.db.Exec("UPDATE "+data.SiteAliasesTable+" SET redirect=$1 WHERE domain=$2", redirect, domain)
+	return err
+}
+
+// SiteAliasDelete deletes an alias along with the verification of its domain.
+func (d *DB) SiteAliasDelete(domain string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM "+data.SiteAliasesTable+" WHERE domain=$1", domain); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM "+data.DomainVerificationsTable+" WHERE domain=$1", domain); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
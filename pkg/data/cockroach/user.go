@@ -106,6 +106,43 @@ func (d *DB) UserSiteInfoByEmail(s *data.Site, email string) (*data.User, error)
 	return u, sql.ErrNoRows
 }
 
+// SiteRoleKey returns the key of the user meta giving the role of a user on the site, which is the same
+// as that of roles.SiteRoleKey.
+func SiteRoleKey(siteID int64) string {
+	return "role" + strconv.FormatInt(siteID, 10)
+}
+
+// SiteMembers retrieves the users who have a role on the site, 20 at a time in the order of their
+// usernames, starting at the offset. If search is not empty, only the users whose username, email
+// address, or name contains it are retrieved; if role is not empty, only the users with that role. The
+// Role of each user is the value of the user's role meta for the site.
+func (d *DB) SiteMembers(site int64, search, role string, offset uint64) ([]data.User, error) {
+	q := "SELECT a.id,a.username,a.fname,a.lname,a.email,b.v FROM " + data.UsersTable + " AS a JOIN " +
+		data.UserMetaTable + " AS b ON (a.id=b.user_id AND b.k=$1) WHERE ($2='' OR b.v=$2)"
+	args := []interface{}{SiteRoleKey(site), role}
+	if search != "" {
+		q += " AND (a.username ILIKE $3 OR a.email ILIKE $3 OR a.fname ILIKE $3 OR a.lname ILIKE $3)"
+		args = append(args, "%"+likeEscaper.Replace(search)+"%")
+	}
+	rows, err := d.db.Query(q+" ORDER BY a.username LIMIT 20"+getOffsetOrNot(offset), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	us := make([]data.User, 0, 4)
+	for rows.Next() {
+		var u data.User
+		if err = rows.Scan(&u.Id, &u.Uname, &u.Fname, &u.Lname, &u.Email, &u.Role); err != nil {
+			return us, err
+		}
+		us = append(us, u)
+	}
+	return us, rows.Err()
+}
+
+// likeEscaper escapes the characters that have a special meaning in LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // UserInsert creates a new user record with the given details in u and the password.
 func (d *DB) UserInsert(username string, email string, passHash []byte, fname string, lname string) (userID int64, err error) {
 	err = d.db.QueryRow("INSERT INTO "+data.UsersTable+" (username, email, pass, fname, lname) VALUES ($1,$2,$3,$4,$5) RETURNING id",
@@ -126,6 +163,30 @@ func (d *DB) UserPassword(userID int64) (hashedPass []byte, err error) {
 	return
 }
 
+// UserPasswordUpdate sets the hashed password of the user.
+func (d *DB) UserPasswordUpdate(userID int64, passHash []byte) error {
+	_, err := d.db.Exec("UPDATE "+data.UsersTable+" SET pass=$1 WHERE "+util.IdEq(userID), passHash)
+	return err
+}
+
+// UserDelete deletes a user along with the user's meta, messages, sessions, and API tokens; all but the
+// messages are deleted by the database with the user.
+func (d *DB) UserDelete(id int64) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM "+data.UserMessagesTable+" WHERE user_id=$1", id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM "+data.UsersTable+" WHERE id=$1", id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 // firstUser returns the first *User from the given slice, or an error if err != nil or if the slice is empty.
 func firstUser(us []data.User, err error) (*data.User, error) {
 	if err != nil {
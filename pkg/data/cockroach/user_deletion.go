@@ -0,0 +1,77 @@
+package cockroach
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+const userDeletionCols = "user_id,requested_by,reassign,error,claimed,created"
+
+// UserDeletion retrieves the pending deletion of a user.
+func (d *DB) UserDeletion(userID int64) (*data.UserDeletion, error) {
+	ds, err := d.userDeletionsWhere("user_id=$1", userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ds) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &ds[0], nil
+}
+
+// UserDeletionsUnclaimed retrieves at most limit pending deletions that have not been claimed since the
+// time given, the least recently claimed first.
+func (d *DB) UserDeletionsUnclaimed(since time.Time, limit int) ([]data.UserDeletion, error) {
+	return d.userDeletionsWhere("claimed<$1 ORDER BY claimed LIMIT $2", since, limit)
+}
+
+func (d *DB) userDeletionsWhere(where string, args ...interface{}) ([]data.UserDeletion, error) {
+	rows, err := d.selCols(data.UserDeletionsTable, userDeletionCols, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ds := make([]data.UserDeletion, 0, 1)
+	for rows.Next() {
+		var del data.UserDeletion
+		if err = rows.Scan(&del.UserId, &del.RequestedBy, &del.Reassign, &del.Error, &del.Claimed,
+			&del.Created); err != nil {
+			return ds, err
+		}
+		ds = append(ds, del)
+	}
+	return ds, rows.Err()
+}
+
+// UserDeletionInsert inserts the pending deletion of a user, replacing any that there is.
+func (d *DB) UserDeletionInsert(del *data.UserDeletion) error {
+	_, err := d.db.Exec("UPSERT INTO "+data.UserDeletionsTable+
+		" (user_id,requested_by,reassign,error,claimed,created) VALUES ($1,$2,$3,'','1970-01-01',now())", del.UserId, del.RequestedBy, del.Reassign)
+	return err
+}
+
+// UserDeletionClaim claims the pending deletion of a user for processing now if it has not been claimed
+// since the time given.
+func (d *DB) UserDeletionClaim(userID int64, since time.Time) (bool, error) {
+	res, err := d.db.Exec("UPDATE "+data.UserDeletionsTable+" SET claimed=now() WHERE user_id=$1 AND claimed<$2",
+		userID, since)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UserDeletionFail records the error with which processing the deletion of a user failed.
+func (d *DB) UserDeletionFail(userID int64, msg string) error {
+	_, err := d.db.Exec("UPDATE "+data.UserDeletionsTable+" SET error=$1 WHERE user_id=$2", msg, userID)
+	return err
+}
+
+// UserDeletionDelete deletes the pending deletion of a user.
+func (d *DB) UserDeletionDelete(userID int64) error {
+	_, err := d.db.Exec("DELETE FROM "+data.UserDeletionsTable+" WHERE user_id=$1", userID)
+	return err
+}
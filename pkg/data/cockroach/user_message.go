@@ -0,0 +1,29 @@
+package cockroach
+
+import (
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+// UserMessagesByUser retrieves the notification messages for a user, the oldest first.
+func (d *DB) UserMessagesByUser(userID int64) ([]data.UserMessage, error) {
+	rows, err := d.selCols(data.UserMessagesTable, "id,user_id,k,v,created", "user_id=$1 ORDER BY created, id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ums := make([]data.UserMessage, 0, 2)
+	for rows.Next() {
+		var um data.UserMessage
+		if err = rows.Scan(&um.Id, &um.UserId, &um.K, &um.Message, &um.Created); err != nil {
+			return ums, err
+		}
+		ums = append(ums, um)
+	}
+	return ums, rows.Err()
+}
+
+// UserMessageInsert inserts a notification message for a user.
+func (d *DB) UserMessageInsert(userID int64, k, msg string) error {
+	_, err := d.db.Exec("INSERT INTO "+data.UserMessagesTable+" (user_id,k,v) VALUES ($1,$2,$3)", userID, k, msg)
+	return err
+}
@@ -57,6 +57,12 @@ func (d *DB) UserMetaByIdLikeKey(userID int64, k string) ([]data.UserMeta, error
 	return d.userMetaWhere("user_id=$1 AND k LIKE $2", userID, k)
 }
 
+// UserMetaByKey returns the *UserMeta rows of all users that have the K given.
+// This function never returns a sql.ErrNoRows error.
+func (d *DB) UserMetaByKey(k string) ([]data.UserMeta, error) {
+	return d.userMetaWhere("k=$1", k)
+}
+
 // UserMetaV selects just like UserMetaByIdKey but retrieves only the V of the meta datum.
 func (d *DB) UserMetaV(userID int64, k string) (v []byte, err error) {
 	rows, err := d.selCols(data.UserMetaTable, "v", "user_id=$1 AND k=$2", userID, k)
@@ -83,6 +89,26 @@ func (d *DB) UserMetaUpdate(userID int64, k string, v []byte) (int64, error) {
 	return res.RowsAffected()
 }
 
+// notLastUserMeta is the condition that the meta of the user ($1) with the key ($2) does not have the
+// value of the last parameter or another user has the meta with that value.
+func notLastUserMeta(lastParam string) string {
+	return "(v<>" + lastParam + " OR EXISTS (SELECT 1 FROM " + data.UserMetaTable +
+		" WHERE k=$2 AND v=" + lastParam + " AND user_id<>$1))"
+}
+
+// UserMetaUpdateUnlessLast sets the existing meta of the user with the key k to v unless the meta has the
+// value last and no other user has the meta k with that value. The statement runs as a serializable
+// transaction, so of concurrent statements taking the value from the only two users who have it, one is
+// retried and finds that the other user no longer has the value.
+func (d *DB) UserMetaUpdateUnlessLast(userID int64, k string, v, last []byte) (int64, error) {
+	res, err := d.db.Exec("UPDATE "+data.UserMetaTable+" SET v=$3 WHERE user_id=$1 AND k=$2 AND "+
+		notLastUserMeta("$4"), userID, k, v, last)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // UserMetasUpdate updates UserMeta records, creating new records in the database if necessary and returns the number
 // of rows affected.
 //
@@ -122,6 +148,26 @@ func (d *DB) UserMetaDelete(userID int64, k string) (int64, error) {
 	return res.RowsAffected()
 }
 
+// UserMetaDeleteUnlessLast deletes the meta of the user with the key k unless the meta has the value
+// last and no other user has the meta k with that value.
+func (d *DB) UserMetaDeleteUnlessLast(userID int64, k string, last []byte) (int64, error) {
+	res, err := d.db.Exec("DELETE FROM "+data.UserMetaTable+" WHERE user_id=$1 AND k=$2 AND "+
+		notLastUserMeta("$3"), userID, k, last)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// UserMetaDeleteAll deletes all of the meta of a user.
+func (d *DB) UserMetaDeleteAll(userID int64) (int64, error) {
+	res, err := d.db.Exec("DELETE FROM "+data.UserMetaTable+" WHERE user_id=$1", userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // userMetaArgs gives a slice of all the arguments from the K and V fields to be set by ums flattened out.
 func userMetaArgs(ums []data.UserMeta) []interface{} {
 	args := make([]interface{}, 2*len(ums))
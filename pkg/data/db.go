@@ -57,9 +57,22 @@ type Transaction interface {
 // Ops includes all of the operations that may be performed on a database.
 type Ops interface {
 	SiteManager
+	SiteAliasManager
+	DomainVerificationManager
 	BlobManager
 	ContentManager
 	UserManager
 	UserMetaManager
+	UserMessageManager
 	OptionManager
+	MediaManager
+	LoginThrottleManager
+	RateLimitManager
+	SessionManager
+	APITokenManager
+	InvitationManager
+	SigningKeyManager
+	CustomRoleManager
+	UserDeletionManager
+	DataExportManager
 }
@@ -0,0 +1,12 @@
+package data
+
+import "errors"
+
+// The values of the Status of a DomainVerification.
+const (
+	DomainPending  uint32 = 0
+	DomainVerified uint32 = 1
+)
+
+// ErrDomainTaken indicates that a domain is already claimed by another site.
+var ErrDomainTaken = errors.New("data: domain is claimed by another site")
@@ -1,5 +1,11 @@
 package data
 
+import (
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/rate_limit"
+)
+
 type SiteGetter interface {
 	// SiteByDomain retrieves a site by its domain.
 	SiteByDomain(domain string) (*Site, error)
@@ -12,7 +18,8 @@ type SiteInserter interface {
 	// InsertSite inserts a Site into the sites table and create all needed tables for the site in a transaction.
 	// The int64 returned is the ID of the new site (the inserted row).
 	// The domain passed in must have already been validated as a real domain name.
-	InsertSite(domain, name string) (int64, error)
+	// The site is pending until its domain is verified using the token, which is inserted along with the site.
+	InsertSite(domain, name, verifyToken string) (int64, error)
 }
 
 type SiteDeleter interface {
@@ -24,6 +31,69 @@ type SiteManager interface {
 	SiteDeleter
 }
 
+type SiteAliasGetter interface {
+	// SiteAlias retrieves an alias by its domain.
+	SiteAlias(domain string) (*SiteAlias, error)
+
+	// SiteAliases retrieves all of a site's aliases.
+	SiteAliases(site int64) ([]SiteAlias, error)
+}
+
+type SiteAliasInserter interface {
+	// SiteAliasInsert inserts an alias of the site along with a pending verification of the domain,
+	// using the token given, in a transaction. If the domain belongs to another site or is already a
+	// domain of the same site, the error is ErrDomainTaken.
+	SiteAliasInsert(site int64, domain string, redirect bool, verifyToken string) error
+
+	// SiteAliasSetRedirect sets whether the alias redirects to the primary domain of its site.
+	SiteAliasSetRedirect(domain string, redirect bool) error
+}
+
+type SiteAliasDeleter interface {
+	// SiteAliasDelete deletes an alias along with the verification of its domain.
+	SiteAliasDelete(domain string) error
+}
+
+type SiteAliasManager interface {
+	SiteAliasGetter
+	SiteAliasInserter
+	SiteAliasDeleter
+}
+
+type DomainVerificationGetter interface {
+	// DomainVerification retrieves the verification of a domain.
+	DomainVerification(domain string) (*DomainVerification, error)
+
+	// DomainVerificationsBySite retrieves the verifications of all of a site's domains.
+	DomainVerificationsBySite(site int64) ([]DomainVerification, error)
+
+	// DomainVerified says if the domain is verified. No error is returned if there is no verification
+	// for the domain.
+	DomainVerified(domain string) (bool, error)
+}
+
+type DomainVerificationInserter interface {
+	// DomainVerificationInsert inserts a pending verification of a domain for the site, replacing the
+	// token of any existing verification of the domain for the same site. If the domain belongs to
+	// another site, the error is ErrDomainTaken.
+	DomainVerificationInsert(site int64, domain, token string) error
+
+	// DomainVerificationSetVerified marks the domain as verified by the method given. The site whose
+	// primary domain it is, or the alias with the domain, is marked as verified as well.
+	DomainVerificationSetVerified(domain, method string) error
+}
+
+type DomainVerificationDeleter interface {
+	// DomainVerificationDelete deletes the verification of a domain.
+	DomainVerificationDelete(domain string) error
+}
+
+type DomainVerificationManager interface {
+	DomainVerificationGetter
+	DomainVerificationInserter
+	DomainVerificationDeleter
+}
+
 type BlobGetter interface {
 	// BlobsByRoleInK selects blobs where the role matches a role in the given list and the K equals the given k.
 	// If not matching Blobs are found, an empty slice is returned but no error.
@@ -57,8 +127,14 @@ type ContentGetter interface {
 
 	ContentBySiteSlug(siteID int64, slug string) (*Content, string, error)
 
+	// ContentByAuthor retrieves all of the content items of the author, on every site, in the order of
+	// the site IDs and then of the item IDs.
 	ContentByAuthor(authorID int64) ([]Content, error)
 
+	// ContentCountsByAuthor counts the content items of the author on each site, mapping the ID of each
+	// site where the author has content to the number of items.
+	ContentCountsByAuthor(authorID int64) (map[int64]int64, error)
+
 	// ContentsList retrieves the specified Content items. The parent argument should be either nil to indicate that the
 	// parent column should not be considered in the query, or a list of the parent IDs to which the returned items should belong.
 	// The value of offset may never be negative, which is why its type is uint64.
@@ -75,6 +151,11 @@ type ContentGetter interface {
 
 type ContentInserter interface {
 	ContentUpdate(contentID int64, vals map[string]interface{}) (int64, error)
+
+	// ContentReassign gives at most limit content items of the author from on the site to the author to,
+	// also moving them to the trash if trash is true. Returned is the number of items reassigned, which
+	// is less than limit once the author has no more content on the site.
+	ContentReassign(site, from, to int64, trash bool, limit int) (int64, error)
 }
 
 type ContentDeleter interface {
@@ -92,20 +173,32 @@ type UserGetter interface {
 	UserByUsername(username string) (*User, error)
 	UserByEmail(email string) (*User, error)
 	UsersByIDs(IDs []int64) ([]User, error)
+
+	// SiteMembers retrieves the users who have a role on the site, 20 at a time in the order of their
+	// usernames, starting at the offset. If search is not empty, only the users whose username, email
+	// address, or name contains it are retrieved; if role is not empty, only the users with that role.
+	// The Role of each user is the value of the user's role meta for the site.
+	SiteMembers(site int64, search, role string, offset uint64) ([]User, error)
 }
 
 type UserInserter interface {
 	// UserInsert inserts a new User.
 	// If there are hooks to the user insert operation, the failures in the hook handlers does not cancel the transaction.
 	UserInsert(username string, email string, passHash []byte, fname string, lname string) (userID int64, err error)
+
+	// UserPasswordUpdate sets the hashed password of a user.
+	UserPasswordUpdate(userID int64, passHash []byte) error
 }
 
 type UserDeleter interface {
+	// UserDelete deletes a user along with the user's meta, messages, sessions, and API tokens. The
+	// user must have no content.
 	UserDelete(ID int64) error
 }
 
 type UserManager interface {
 	UserGetter
+	UserPasswordGetter
 	UserInserter
 	UserDeleter
 }
@@ -115,21 +208,57 @@ type UserPasswordGetter interface {
 	UserPassword(userID int64) ([]byte, error)
 }
 
+type UserMessageGetter interface {
+	// UserMessagesByUser retrieves the notification messages for a user, the oldest first.
+	UserMessagesByUser(userID int64) ([]UserMessage, error)
+}
+
+type UserMessageInserter interface {
+	// UserMessageInsert inserts a notification message for a user.
+	UserMessageInsert(userID int64, k, msg string) error
+}
+
+type UserMessageDeleter interface {
+}
+
+type UserMessageManager interface {
+	UserMessageGetter
+	UserMessageInserter
+	UserMessageDeleter
+}
+
 type UserMetaGetter interface {
 	UserMetaById(userID int64) ([]UserMeta, error)
 	UserMetaByIdMapped(userID int64) (map[string][]byte, error)
 	UserMetaByIdKey(userID int64, k string) (*UserMeta, error)
 	UserMetaByIdLikeKey(userID int64, k string) ([]UserMeta, error)
+
+	// UserMetaByKey returns the user meta of all users that have the key k.
+	UserMetaByKey(k string) ([]UserMeta, error)
 	UserMetaV(userID int64, k string) (v []byte, err error)
 }
 
 type UserMetaInserter interface {
 	UserMetaUpdate(userID int64, k string, v []byte) (int64, error)
 	UserMetasUpdate(ums []UserMeta) (int64, error)
+
+	// UserMetaUpdateUnlessLast sets the existing meta of the user with the key k to v unless the meta
+	// has the value last and no other user has the meta k with that value. The check and the update are
+	// made at once, so concurrent calls cannot leave no user with the value. Returned is the number of
+	// rows updated.
+	UserMetaUpdateUnlessLast(userID int64, k string, v, last []byte) (int64, error)
 }
 
 type UserMetaDeleter interface {
 	UserMetaDelete(userID int64, k string) (int64, error)
+
+	// UserMetaDeleteUnlessLast deletes the meta of the user with the key k unless the meta has the value
+	// last and no other user has the meta k with that value, like UserMetaUpdateUnlessLast. Returned is
+	// the number of rows deleted.
+	UserMetaDeleteUnlessLast(userID int64, k string, last []byte) (int64, error)
+
+	// UserMetaDeleteAll deletes all of the meta of a user. Returned is the number of rows deleted.
+	UserMetaDeleteAll(userID int64) (int64, error)
 }
 
 type UserMetaManager interface {
@@ -194,3 +323,322 @@ type OptionManager interface {
 	OptionInserter
 	OptionDeleter
 }
+
+type MediaGetter interface {
+	// MediaByID retrieves the metadata of a site's media object by its ID.
+	MediaByID(site int64, id string) (*Media, error)
+
+	// MediaList retrieves the metadata of a site's media objects, the most recently uploaded first.
+	MediaList(site int64, offset uint64) ([]Media, error)
+
+	// MediaByUploader retrieves the metadata of all of the media objects uploaded by a user, on every
+	// site, the most recently uploaded first.
+	MediaByUploader(userID int64) ([]Media, error)
+}
+
+type MediaInserter interface {
+	// MediaInsert inserts the metadata of a media object, including the ID of the user who uploaded it.
+	MediaInsert(m *Media) error
+}
+
+type MediaDeleter interface {
+}
+
+type MediaManager interface {
+	MediaGetter
+	MediaInserter
+	MediaDeleter
+}
+
+type LoginThrottleGetter interface {
+	// LoginThrottle retrieves the throttle of logins on a site by its key. If there is none, the error
+	// is sql.ErrNoRows.
+	LoginThrottle(site int64, k string) (*LoginThrottle, error)
+}
+
+type LoginThrottleInserter interface {
+	// LoginThrottleFail counts a failed login on a site for the throttle with the key given, creating
+	// the throttle if needed, and returns the number of consecutive failures. If the throttle was last
+	// updated before the time since, its earlier failures are forgotten.
+	LoginThrottleFail(site int64, k string, since time.Time) (failures int64, err error)
+
+	// LoginThrottleLock locks out the logins on a site for the throttle with the key given until the
+	// time given.
+	LoginThrottleLock(site int64, k string, until time.Time) error
+}
+
+type LoginThrottleDeleter interface {
+	// LoginThrottleDelete deletes a throttle, which forgets its failures, after a successful login.
+	LoginThrottleDelete(site int64, k string) error
+
+	// LoginThrottlesPurge deletes the throttles that were last updated before the time given and are
+	// not locked. Returned is the number of throttles deleted.
+	LoginThrottlesPurge(before time.Time) (int64, error)
+}
+
+type LoginThrottleManager interface {
+	LoginThrottleGetter
+	LoginThrottleInserter
+	LoginThrottleDeleter
+}
+
+type RateLimitGetter interface {
+}
+
+type RateLimitInserter interface {
+	// RateLimitTake takes a token from the bucket with the key k, in a transaction, and says if a token
+	// was available. A bucket that does not exist yet is created full.
+	RateLimitTake(k string, b rate_limit.Bucket) (bool, error)
+}
+
+type RateLimitDeleter interface {
+	// RateLimitsPurge deletes the buckets that were last used before the time given. Returned is the
+	// number of buckets deleted.
+	RateLimitsPurge(before time.Time) (int64, error)
+}
+
+type RateLimitManager interface {
+	RateLimitGetter
+	RateLimitInserter
+	RateLimitDeleter
+}
+
+type SessionGetter interface {
+	// Session retrieves a session by its ID. If there is none, the error is sql.ErrNoRows.
+	Session(id string) (*Session, error)
+
+	// SessionsByUser retrieves the sessions of a user on a site that have not expired, the most
+	// recently used first.
+	SessionsByUser(userID, site int64) ([]Session, error)
+}
+
+type SessionInserter interface {
+	// SessionInsert inserts a session. The Created and LastSeen times are set by the database.
+	SessionInsert(s *Session) error
+
+	// SessionTouch records that a session is used now and extends it until the time given.
+	SessionTouch(id string, expires time.Time) error
+}
+
+type SessionDeleter interface {
+	// SessionDelete deletes a session, logging out the user.
+	SessionDelete(id string) error
+
+	// SessionsDeleteTree deletes the session with the ID root and the sessions started from it by
+	// single sign-on. Returned is the number of sessions deleted.
+	SessionsDeleteTree(root string) (int64, error)
+
+	// SessionsDeleteByUser deletes the sessions of a user on a site, or on all the sites if site is 0,
+	// except for the session with the ID except (if it is not blank). Returned is the number of
+	// sessions deleted.
+	SessionsDeleteByUser(userID, site int64, except string) (int64, error)
+
+	// SessionsPurge deletes the sessions that expired before the time given. Returned is the number
+	// of sessions deleted.
+	SessionsPurge(before time.Time) (int64, error)
+}
+
+type SessionManager interface {
+	SessionGetter
+	SessionInserter
+	SessionDeleter
+}
+
+type APITokenGetter interface {
+	// APIToken retrieves an API token by its ID. If there is none, the error is sql.ErrNoRows.
+	APIToken(id string) (*APIToken, error)
+
+	// APITokensByUser retrieves the personal access tokens of a user on a site, the most recently
+	// created first.
+	APITokensByUser(userID, site int64) ([]APIToken, error)
+
+	// APITokensBySite retrieves the service tokens of a site, the most recently created first.
+	APITokensBySite(site int64) ([]APIToken, error)
+}
+
+type APITokenInserter interface {
+	// APITokenInsert inserts an API token. The Created and LastUsed times are set by the database.
+	APITokenInsert(t *APIToken) error
+
+	// APITokenTouch records that an API token is used now.
+	APITokenTouch(id string) error
+}
+
+type APITokenDeleter interface {
+	// APITokenDelete deletes an API token, revoking it.
+	APITokenDelete(id string) error
+
+	// APITokensDeleteByUser deletes all of the API tokens created by a user, on every site. Returned is
+	// the number of tokens deleted.
+	APITokensDeleteByUser(userID int64) (int64, error)
+}
+
+type APITokenManager interface {
+	APITokenGetter
+	APITokenInserter
+	APITokenDeleter
+}
+
+type InvitationGetter interface {
+	// Invitation retrieves an invitation by its ID. If there is none, the error is sql.ErrNoRows.
+	Invitation(id string) (*Invitation, error)
+
+	// InvitationsBySite retrieves the invitations to a site, the most recently created first.
+	InvitationsBySite(site int64) ([]Invitation, error)
+}
+
+type InvitationInserter interface {
+	// InvitationInsert inserts a pending invitation. The Created time is set by the database.
+	InvitationInsert(inv *Invitation) error
+
+	// InvitationRenew gives a pending invitation a new token hash and expiration time. Returned is the
+	// number of invitations renewed, which is 0 if the invitation is not pending.
+	InvitationRenew(id string, hash []byte, expires time.Time) (int64, error)
+
+	// InvitationAccept marks a pending invitation that has not expired accepted by the user. Returned
+	// is the number of invitations accepted, which is 0 if the invitation could not be accepted.
+	InvitationAccept(id string, userID int64) (int64, error)
+}
+
+type InvitationDeleter interface {
+	// InvitationDelete deletes an invitation, revoking it if it is pending.
+	InvitationDelete(id string) error
+
+	// InvitationsDeletePending deletes the pending invitations of the email address to the site.
+	// Returned is the number of invitations deleted.
+	InvitationsDeletePending(site int64, email string) (int64, error)
+}
+
+type InvitationManager interface {
+	InvitationGetter
+	InvitationInserter
+	InvitationDeleter
+}
+
+type SigningKeyGetter interface {
+	// SigningKeys retrieves all of the signing keys, the most recently created first.
+	SigningKeys() ([]SigningKey, error)
+}
+
+type SigningKeyInserter interface {
+	// SigningKeyInsert inserts a signing key that does not expire. The Created time is set by the
+	// database.
+	SigningKeyInsert(k *SigningKey) error
+}
+
+type SigningKeyManager interface {
+	SigningKeyGetter
+	SigningKeyInserter
+}
+
+type CustomRoleGetter interface {
+	// CustomRole retrieves a custom role of a site by its ID. If there is none, the error is
+	// sql.ErrNoRows.
+	CustomRole(site, id int64) (*CustomRole, error)
+
+	// CustomRolesBySite retrieves the custom roles of a site in the order of their IDs.
+	CustomRolesBySite(site int64) ([]CustomRole, error)
+}
+
+type CustomRoleInserter interface {
+	// CustomRoleInsert inserts a custom role of a site, giving it the next ID of the site. The Id and
+	// Created fields are ignored. Returned is the ID of the role.
+	CustomRoleInsert(r *CustomRole) (int64, error)
+
+	// CustomRoleUpdate sets the name and the capabilities of a custom role. Returned is the number of
+	// roles updated.
+	CustomRoleUpdate(r *CustomRole) (int64, error)
+}
+
+type CustomRoleDeleter interface {
+	// CustomRoleDelete deletes a custom role of a site unless a user has the user meta k with the value
+	// v, by which the role is given to the members of the site. The check and the delete are made at
+	// once, so the role cannot be given to a member between them. Returned is the number of rows deleted.
+	CustomRoleDelete(site, id int64, k string, v []byte) (int64, error)
+}
+
+type CustomRoleManager interface {
+	CustomRoleGetter
+	CustomRoleInserter
+	CustomRoleDeleter
+}
+
+type UserDeletionGetter interface {
+	// UserDeletion retrieves the pending deletion of a user. If there is none, the error is
+	// sql.ErrNoRows.
+	UserDeletion(userID int64) (*UserDeletion, error)
+
+	// UserDeletionsUnclaimed retrieves at most limit pending deletions that have not been claimed since
+	// the time given, the least recently claimed first.
+	UserDeletionsUnclaimed(since time.Time, limit int) ([]UserDeletion, error)
+}
+
+type UserDeletionInserter interface {
+	// UserDeletionInsert inserts the pending deletion of a user, replacing any that there is. The
+	// Error, Claimed, and Created fields are ignored.
+	UserDeletionInsert(del *UserDeletion) error
+
+	// UserDeletionClaim claims the pending deletion of a user for processing now if it has not been
+	// claimed since the time given. Returned is whether the deletion was claimed.
+	UserDeletionClaim(userID int64, since time.Time) (bool, error)
+
+	// UserDeletionFail records the error with which processing the deletion of a user failed.
+	UserDeletionFail(userID int64, msg string) error
+}
+
+type UserDeletionDeleter interface {
+	// UserDeletionDelete deletes the pending deletion of a user, once it is done.
+	UserDeletionDelete(userID int64) error
+}
+
+type UserDeletionManager interface {
+	UserDeletionGetter
+	UserDeletionInserter
+	UserDeletionDeleter
+}
+
+type DataExportGetter interface {
+	// DataExport retrieves an export of personal data by its ID. If there is none, the error is
+	// sql.ErrNoRows.
+	DataExport(id string) (*DataExport, error)
+
+	// DataExportsByUser retrieves the exports of the personal data of a user, the most recently
+	// created first.
+	DataExportsByUser(userID int64) ([]DataExport, error)
+
+	// DataExportsUnclaimed retrieves at most limit pending exports that have not been claimed since the
+	// time given, the least recently claimed first.
+	DataExportsUnclaimed(since time.Time, limit int) ([]DataExport, error)
+
+	// DataExportsExpired retrieves at most limit exports that expired before the time given.
+	DataExportsExpired(before time.Time, limit int) ([]DataExport, error)
+}
+
+type DataExportInserter interface {
+	// DataExportInsert inserts a pending export. The Status, Size, Error, Claimed, Created, and Expires
+	// fields are ignored.
+	DataExportInsert(e *DataExport) error
+
+	// DataExportClaim claims a pending export for building now if it has not been claimed since the
+	// time given. Returned is whether the export was claimed.
+	DataExportClaim(id string, since time.Time) (bool, error)
+
+	// DataExportReady marks a pending export ready with the size of its archive, which expires at the
+	// time given.
+	DataExportReady(id string, size int64, expires time.Time) error
+
+	// DataExportFail marks a pending export failed with the error with which building it failed. The
+	// export expires at the time given.
+	DataExportFail(id, msg string, expires time.Time) error
+}
+
+type DataExportDeleter interface {
+	// DataExportDelete deletes an export, once its archive is deleted.
+	DataExportDelete(id string) error
+}
+
+type DataExportManager interface {
+	DataExportGetter
+	DataExportInserter
+	DataExportDeleter
+}
@@ -11,6 +11,18 @@ const (
 	MediaTable        = "media"
 	SiteMessagesTable = "site_messages"
 	UserMessagesTable = "user_messages"
+
+	DomainVerificationsTable = "domain_verifications"
+	SiteAliasesTable         = "site_aliases"
+	LoginThrottlesTable      = "login_throttles"
+	RateLimitsTable          = "rate_limits"
+	SessionsTable            = "sessions"
+	APITokensTable           = "api_tokens"
+	InvitationsTable         = "invitations"
+	SigningKeysTable         = "signing_keys"
+	CustomRolesTable         = "custom_roles"
+	UserDeletionsTable       = "user_deletions"
+	DataExportsTable         = "data_exports"
 )
 
 // BlobsTable gives the name of the blobs table for the site with the given ID.
@@ -39,10 +39,12 @@ type Site struct {
 	// The status of the TLS on the domain: 0 = none, 1 = configuring, 2 = good
 	Tls uint32 `protobuf:"varint,7,opt,name=tls,proto3" json:"tls,omitempty"`
 	// Time of the last update.
-	Updated              *time.Time `protobuf:"bytes,8,opt,name=updated,proto3" json:"updated,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
-	XXX_unrecognized     []byte     `json:"-"`
-	XXX_sizecache        int32      `json:"-"`
+	Updated *time.Time `protobuf:"bytes,8,opt,name=updated,proto3" json:"updated,omitempty"`
+	// Whether control over the domain has been verified. Until then, the site is pending.
+	Verified             bool     `protobuf:"varint,10,opt,name=verified,proto3" json:"verified,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Site) Reset()         { *m = Site{} }
@@ -126,6 +128,13 @@ func (m *Site) GetUpdated() *time.Time {
 	return nil
 }
 
+func (m *Site) GetVerified() bool {
+	if m != nil {
+		return m.Verified
+	}
+	return false
+}
+
 // A Blob is a general-use container for binary data.
 type Blob struct {
 	// Row ID in the table.
@@ -575,8 +584,8 @@ func (m *Option) GetV() []byte {
 
 // A Media represents a static media item belonging to a website.
 //
-// The fields Id, Ext, Site, and Name are required; the other strings default to empty strings and must not
-// be null; the Uploaded time defaults to the current time in the database.
+// The fields Id, Ext, Site, and Name are required; the other strings default to empty strings and
+// must not be null; the Uploaded time defaults to the current time in the database.
 type Media struct {
 	// A UUID encoded in base32 without padding.
 	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -591,10 +600,14 @@ type Media struct {
 	// A description.
 	Desc string `protobuf:"bytes,6,opt,name=desc,proto3" json:"desc,omitempty"`
 	// Time when the object was uploaded.
-	Uploaded             *time.Time `protobuf:"bytes,7,opt,name=uploaded,proto3" json:"uploaded,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
-	XXX_unrecognized     []byte     `json:"-"`
-	XXX_sizecache        int32      `json:"-"`
+	Uploaded *time.Time `protobuf:"bytes,7,opt,name=uploaded,proto3" json:"uploaded,omitempty"`
+	// Time of the last update of the metadata.
+	Updated *time.Time `protobuf:"bytes,8,opt,name=updated,proto3" json:"updated,omitempty"`
+	// The ID of the user who uploaded the object.
+	Uploader             int64    `protobuf:"varint,9,opt,name=uploader,proto3" json:"uploader,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Media) Reset()         { *m = Media{} }
@@ -671,6 +684,20 @@ func (m *Media) GetUploaded() *time.Time {
 	return nil
 }
 
+func (m *Media) GetUpdated() *time.Time {
+	if m != nil {
+		return m.Updated
+	}
+	return nil
+}
+
+func (m *Media) GetUploader() int64 {
+	if m != nil {
+		return m.Uploader
+	}
+	return 0
+}
+
 // A SiteMessage is a notification message for a site, to be shown to all users whose role on the
 // site is at least Role.
 type SiteMessage struct {
@@ -833,6 +860,967 @@ func (m *UserMessage) GetCreated() *time.Time {
 	return nil
 }
 
+// A DomainVerification holds the token with which control over the domain of a site is proven.
+type DomainVerification struct {
+	// The domain name being verified; the primary key.
+	Domain string `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	// Site is a foreign key to a Site ID.
+	Site int64 `protobuf:"varint,2,opt,name=site,proto3" json:"site,omitempty"`
+	// The random token that must be published for the domain.
+	Token string `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"`
+	// The verification status: 0 = pending, 1 = verified
+	Status uint32 `protobuf:"varint,4,opt,name=status,proto3" json:"status,omitempty"`
+	// How the domain was verified, either "dns" or "http"; blank while pending.
+	Method string `protobuf:"bytes,5,opt,name=method,proto3" json:"method,omitempty"`
+	// Time of the last update.
+	Updated              *time.Time `protobuf:"bytes,6,opt,name=updated,proto3" json:"updated,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *DomainVerification) Reset()         { *m = DomainVerification{} }
+func (m *DomainVerification) String() string { return proto.CompactTextString(m) }
+func (*DomainVerification) ProtoMessage()    {}
+
+func (m *DomainVerification) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DomainVerification.Unmarshal(m, b)
+}
+func (m *DomainVerification) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DomainVerification.Marshal(b, m, deterministic)
+}
+func (m *DomainVerification) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DomainVerification.Merge(m, src)
+}
+func (m *DomainVerification) XXX_Size() int {
+	return xxx_messageInfo_DomainVerification.Size(m)
+}
+func (m *DomainVerification) XXX_DiscardUnknown() {
+	xxx_messageInfo_DomainVerification.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DomainVerification proto.InternalMessageInfo
+
+func (m *DomainVerification) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *DomainVerification) GetSite() int64 {
+	if m != nil {
+		return m.Site
+	}
+	return 0
+}
+
+func (m *DomainVerification) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *DomainVerification) GetStatus() uint32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *DomainVerification) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *DomainVerification) GetUpdated() *time.Time {
+	if m != nil {
+		return m.Updated
+	}
+	return nil
+}
+
+// A SiteAlias is an additional domain of a site.
+type SiteAlias struct {
+	// The domain name of the alias; the primary key.
+	Domain string `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	// Site is a foreign key to a Site ID.
+	Site int64 `protobuf:"varint,2,opt,name=site,proto3" json:"site,omitempty"`
+	// Whether requests to the alias are redirected to the primary domain of the site, rather than
+	// served directly.
+	Redirect bool `protobuf:"varint,3,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	// Whether control over the domain has been verified. Until then, the alias is not used.
+	Verified bool `protobuf:"varint,4,opt,name=verified,proto3" json:"verified,omitempty"`
+	// Time when the alias was created.
+	Created              *time.Time `protobuf:"bytes,5,opt,name=created,proto3" json:"created,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *SiteAlias) Reset()         { *m = SiteAlias{} }
+func (m *SiteAlias) String() string { return proto.CompactTextString(m) }
+func (*SiteAlias) ProtoMessage()    {}
+
+func (m *SiteAlias) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SiteAlias.Unmarshal(m, b)
+}
+func (m *SiteAlias) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SiteAlias.Marshal(b, m, deterministic)
+}
+func (m *SiteAlias) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SiteAlias.Merge(m, src)
+}
+func (m *SiteAlias) XXX_Size() int {
+	return xxx_messageInfo_SiteAlias.Size(m)
+}
+func (m *SiteAlias) XXX_DiscardUnknown() {
+	xxx_messageInfo_SiteAlias.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SiteAlias proto.InternalMessageInfo
+
+func (m *SiteAlias) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *SiteAlias) GetSite() int64 {
+	if m != nil {
+		return m.Site
+	}
+	return 0
+}
+
+func (m *SiteAlias) GetRedirect() bool {
+	if m != nil {
+		return m.Redirect
+	}
+	return false
+}
+
+func (m *SiteAlias) GetVerified() bool {
+	if m != nil {
+		return m.Verified
+	}
+	return false
+}
+
+func (m *SiteAlias) GetCreated() *time.Time {
+	if m != nil {
+		return m.Created
+	}
+	return nil
+}
+
+// A LoginThrottle counts the consecutive failed logins on a site by an IP address or to an account.
+type LoginThrottle struct {
+	// Site is a foreign key to a Site ID.
+	Site int64 `protobuf:"varint,1,opt,name=site,proto3" json:"site,omitempty"`
+	// The key of the throttle, either "ip:" followed by the IP address or "user:" followed by the
+	// user ID.
+	K string `protobuf:"bytes,2,opt,name=k,proto3" json:"k,omitempty"`
+	// The number of consecutive failed logins.
+	Failures int64 `protobuf:"varint,3,opt,name=failures,proto3" json:"failures,omitempty"`
+	// The logins are locked out until this time.
+	LockedUntil *time.Time `protobuf:"bytes,4,opt,name=locked_until,json=lockedUntil,proto3" json:"locked_until,omitempty"`
+	// Time of the last update.
+	Updated              *time.Time `protobuf:"bytes,5,opt,name=updated,proto3" json:"updated,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *LoginThrottle) Reset()         { *m = LoginThrottle{} }
+func (m *LoginThrottle) String() string { return proto.CompactTextString(m) }
+func (*LoginThrottle) ProtoMessage()    {}
+
+func (m *LoginThrottle) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoginThrottle.Unmarshal(m, b)
+}
+func (m *LoginThrottle) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoginThrottle.Marshal(b, m, deterministic)
+}
+func (m *LoginThrottle) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoginThrottle.Merge(m, src)
+}
+func (m *LoginThrottle) XXX_Size() int {
+	return xxx_messageInfo_LoginThrottle.Size(m)
+}
+func (m *LoginThrottle) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoginThrottle.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LoginThrottle proto.InternalMessageInfo
+
+func (m *LoginThrottle) GetSite() int64 {
+	if m != nil {
+		return m.Site
+	}
+	return 0
+}
+
+func (m *LoginThrottle) GetK() string {
+	if m != nil {
+		return m.K
+	}
+	return ""
+}
+
+func (m *LoginThrottle) GetFailures() int64 {
+	if m != nil {
+		return m.Failures
+	}
+	return 0
+}
+
+func (m *LoginThrottle) GetLockedUntil() *time.Time {
+	if m != nil {
+		return m.LockedUntil
+	}
+	return nil
+}
+
+func (m *LoginThrottle) GetUpdated() *time.Time {
+	if m != nil {
+		return m.Updated
+	}
+	return nil
+}
+
+// A RateLimit is a token bucket with which requests are rate limited.
+type RateLimit struct {
+	// The key of the bucket; the primary key.
+	K string `protobuf:"bytes,1,opt,name=k,proto3" json:"k,omitempty"`
+	// The number of tokens in the bucket at the time of the last update.
+	Tokens float64 `protobuf:"fixed64,2,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	// Time of the last update.
+	Updated              *time.Time `protobuf:"bytes,3,opt,name=updated,proto3" json:"updated,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *RateLimit) Reset()         { *m = RateLimit{} }
+func (m *RateLimit) String() string { return proto.CompactTextString(m) }
+func (*RateLimit) ProtoMessage()    {}
+
+func (m *RateLimit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RateLimit.Unmarshal(m, b)
+}
+func (m *RateLimit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RateLimit.Marshal(b, m, deterministic)
+}
+func (m *RateLimit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RateLimit.Merge(m, src)
+}
+func (m *RateLimit) XXX_Size() int {
+	return xxx_messageInfo_RateLimit.Size(m)
+}
+func (m *RateLimit) XXX_DiscardUnknown() {
+	xxx_messageInfo_RateLimit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RateLimit proto.InternalMessageInfo
+
+func (m *RateLimit) GetK() string {
+	if m != nil {
+		return m.K
+	}
+	return ""
+}
+
+func (m *RateLimit) GetTokens() float64 {
+	if m != nil {
+		return m.Tokens
+	}
+	return 0
+}
+
+func (m *RateLimit) GetUpdated() *time.Time {
+	if m != nil {
+		return m.Updated
+	}
+	return nil
+}
+
+type Session struct {
+	// The random ID of the session; the primary key.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// UserId is a foreign key to a User ID.
+	UserId int64 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Site is a foreign key to a Site ID.
+	Site int64 `protobuf:"varint,3,opt,name=site,proto3" json:"site,omitempty"`
+	// The user agent of the client that logged in.
+	UserAgent string `protobuf:"bytes,4,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	// The IP address of the client that logged in.
+	Ip string `protobuf:"bytes,5,opt,name=ip,proto3" json:"ip,omitempty"`
+	// Time of the login.
+	Created *time.Time `protobuf:"bytes,6,opt,name=created,proto3" json:"created,omitempty"`
+	// Time of the last request made with the session.
+	LastSeen *time.Time `protobuf:"bytes,7,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	// The session expires at this time unless it is used again before.
+	Expires *time.Time `protobuf:"bytes,8,opt,name=expires,proto3" json:"expires,omitempty"`
+	// The ID of the session on the main site from which this session was started by single sign-on, or
+	// the empty string.
+	Parent               string   `protobuf:"bytes,9,opt,name=parent,proto3" json:"parent,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Session) Reset()         { *m = Session{} }
+func (m *Session) String() string { return proto.CompactTextString(m) }
+func (*Session) ProtoMessage()    {}
+
+func (m *Session) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Session.Unmarshal(m, b)
+}
+func (m *Session) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Session.Marshal(b, m, deterministic)
+}
+func (m *Session) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Session.Merge(m, src)
+}
+func (m *Session) XXX_Size() int {
+	return xxx_messageInfo_Session.Size(m)
+}
+func (m *Session) XXX_DiscardUnknown() {
+	xxx_messageInfo_Session.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Session proto.InternalMessageInfo
+
+func (m *Session) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Session) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *Session) GetSite() int64 {
+	if m != nil {
+		return m.Site
+	}
+	return 0
+}
+
+func (m *Session) GetUserAgent() string {
+	if m != nil {
+		return m.UserAgent
+	}
+	return ""
+}
+
+func (m *Session) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
+func (m *Session) GetCreated() *time.Time {
+	if m != nil {
+		return m.Created
+	}
+	return nil
+}
+
+func (m *Session) GetLastSeen() *time.Time {
+	if m != nil {
+		return m.LastSeen
+	}
+	return nil
+}
+
+func (m *Session) GetExpires() *time.Time {
+	if m != nil {
+		return m.Expires
+	}
+	return nil
+}
+
+func (m *Session) GetParent() string {
+	if m != nil {
+		return m.Parent
+	}
+	return ""
+}
+
+type APIToken struct {
+	// The random public ID of the token; the primary key.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// UserId is a foreign key to a User ID: the owner of a personal access token, or the user who
+	// created a service token.
+	UserId int64 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Site is a foreign key to a Site ID.
+	Site int64 `protobuf:"varint,3,opt,name=site,proto3" json:"site,omitempty"`
+	// A name given to the token by its creator.
+	Name string `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	// The SHA-256 hash of the secret part of the token.
+	Hash []byte `protobuf:"bytes,5,opt,name=hash,proto3" json:"hash,omitempty"`
+	// The scopes of the token, separated by spaces.
+	Scopes string `protobuf:"bytes,6,opt,name=scopes,proto3" json:"scopes,omitempty"`
+	// The role with which a service token acts, or 0 (NONE) for a personal access token, which acts
+	// with the role of its owner.
+	Role int64 `protobuf:"varint,7,opt,name=role,proto3" json:"role,omitempty"`
+	// Time the token was created.
+	Created *time.Time `protobuf:"bytes,8,opt,name=created,proto3" json:"created,omitempty"`
+	// Time of the last request made with the token.
+	LastUsed *time.Time `protobuf:"bytes,9,opt,name=last_used,json=lastUsed,proto3" json:"last_used,omitempty"`
+	// The token is not accepted after this time. A token that does not expire has the time
+	// 9999-12-31 23:59:59.
+	Expires              *time.Time `protobuf:"bytes,10,opt,name=expires,proto3" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *APIToken) Reset()         { *m = APIToken{} }
+func (m *APIToken) String() string { return proto.CompactTextString(m) }
+func (*APIToken) ProtoMessage()    {}
+func (m *APIToken) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_APIToken.Unmarshal(m, b)
+}
+func (m *APIToken) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_APIToken.Marshal(b, m, deterministic)
+}
+func (m *APIToken) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_APIToken.Merge(m, src)
+}
+func (m *APIToken) XXX_Size() int {
+	return xxx_messageInfo_APIToken.Size(m)
+}
+func (m *APIToken) XXX_DiscardUnknown() {
+	xxx_messageInfo_APIToken.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_APIToken proto.InternalMessageInfo
+
+func (m *APIToken) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *APIToken) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *APIToken) GetSite() int64 {
+	if m != nil {
+		return m.Site
+	}
+	return 0
+}
+
+func (m *APIToken) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *APIToken) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+func (m *APIToken) GetScopes() string {
+	if m != nil {
+		return m.Scopes
+	}
+	return ""
+}
+
+func (m *APIToken) GetRole() int64 {
+	if m != nil {
+		return m.Role
+	}
+	return 0
+}
+
+func (m *APIToken) GetCreated() *time.Time {
+	if m != nil {
+		return m.Created
+	}
+	return nil
+}
+
+func (m *APIToken) GetLastUsed() *time.Time {
+	if m != nil {
+		return m.LastUsed
+	}
+	return nil
+}
+
+func (m *APIToken) GetExpires() *time.Time {
+	if m != nil {
+		return m.Expires
+	}
+	return nil
+}
+
+type Invitation struct {
+	// The random public ID of the invitation; the primary key.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Site is a foreign key to a Site ID.
+	Site int64 `protobuf:"varint,2,opt,name=site,proto3" json:"site,omitempty"`
+	// The email address invited.
+	Email string `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	// The role the invited user is given on the site.
+	Role int64 `protobuf:"varint,4,opt,name=role,proto3" json:"role,omitempty"`
+	// InvitedBy is a foreign key to the ID of the User who sent the invitation.
+	InvitedBy int64 `protobuf:"varint,5,opt,name=invited_by,json=invitedBy,proto3" json:"invited_by,omitempty"`
+	// The SHA-256 hash of the secret part of the token sent in the invitation.
+	Hash []byte `protobuf:"bytes,6,opt,name=hash,proto3" json:"hash,omitempty"`
+	// The status of the invitation: "pending" or "accepted". A pending invitation that has expired is
+	// reported with the status "expired".
+	Status string `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	// The ID of the User who accepted the invitation, or 0.
+	AcceptedBy int64 `protobuf:"varint,8,opt,name=accepted_by,json=acceptedBy,proto3" json:"accepted_by,omitempty"`
+	// Time the invitation was created.
+	Created *time.Time `protobuf:"bytes,9,opt,name=created,proto3" json:"created,omitempty"`
+	// The invitation cannot be accepted after this time.
+	Expires              *time.Time `protobuf:"bytes,10,opt,name=expires,proto3" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *Invitation) Reset()         { *m = Invitation{} }
+func (m *Invitation) String() string { return proto.CompactTextString(m) }
+func (*Invitation) ProtoMessage()    {}
+func (m *Invitation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Invitation.Unmarshal(m, b)
+}
+func (m *Invitation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Invitation.Marshal(b, m, deterministic)
+}
+func (m *Invitation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Invitation.Merge(m, src)
+}
+func (m *Invitation) XXX_Size() int {
+	return xxx_messageInfo_Invitation.Size(m)
+}
+func (m *Invitation) XXX_DiscardUnknown() {
+	xxx_messageInfo_Invitation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Invitation proto.InternalMessageInfo
+
+func (m *Invitation) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Invitation) GetSite() int64 {
+	if m != nil {
+		return m.Site
+	}
+	return 0
+}
+
+func (m *Invitation) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *Invitation) GetRole() int64 {
+	if m != nil {
+		return m.Role
+	}
+	return 0
+}
+
+func (m *Invitation) GetInvitedBy() int64 {
+	if m != nil {
+		return m.InvitedBy
+	}
+	return 0
+}
+
+func (m *Invitation) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+func (m *Invitation) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *Invitation) GetAcceptedBy() int64 {
+	if m != nil {
+		return m.AcceptedBy
+	}
+	return 0
+}
+
+func (m *Invitation) GetCreated() *time.Time {
+	if m != nil {
+		return m.Created
+	}
+	return nil
+}
+
+func (m *Invitation) GetExpires() *time.Time {
+	if m != nil {
+		return m.Expires
+	}
+	return nil
+}
+
+type SigningKey struct {
+	// The random public ID of the key, which is given with each token signed with the key; the primary
+	// key.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The secret key of HMAC-SHA256.
+	Secret []byte `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	// Time the key was created. The most recently created key signs new tokens.
+	Created *time.Time `protobuf:"bytes,3,opt,name=created,proto3" json:"created,omitempty"`
+	// Tokens signed with the key are not accepted after this time. A key that has not been retired has
+	// the time 9999-12-31 23:59:59.
+	Expires              *time.Time `protobuf:"bytes,4,opt,name=expires,proto3" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *SigningKey) Reset()         { *m = SigningKey{} }
+func (m *SigningKey) String() string { return proto.CompactTextString(m) }
+func (*SigningKey) ProtoMessage()    {}
+func (m *SigningKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SigningKey.Unmarshal(m, b)
+}
+func (m *SigningKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SigningKey.Marshal(b, m, deterministic)
+}
+func (m *SigningKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SigningKey.Merge(m, src)
+}
+func (m *SigningKey) XXX_Size() int {
+	return xxx_messageInfo_SigningKey.Size(m)
+}
+func (m *SigningKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_SigningKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SigningKey proto.InternalMessageInfo
+
+func (m *SigningKey) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SigningKey) GetSecret() []byte {
+	if m != nil {
+		return m.Secret
+	}
+	return nil
+}
+
+func (m *SigningKey) GetCreated() *time.Time {
+	if m != nil {
+		return m.Created
+	}
+	return nil
+}
+
+func (m *SigningKey) GetExpires() *time.Time {
+	if m != nil {
+		return m.Expires
+	}
+	return nil
+}
+
+type CustomRole struct {
+	// Site is a foreign key to a Site ID. The site and the ID together are the primary key.
+	Site int64 `protobuf:"varint,1,opt,name=site,proto3" json:"site,omitempty"`
+	// The number of the role, which is what the user meta of the members of the site stores; the IDs of
+	// the custom roles of each site start at 100.
+	Id int64 `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	// The name of the role, unique on the site.
+	Name string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// The capabilities of the role, separated by spaces.
+	Capabilities string `protobuf:"bytes,4,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
+	// Time the role was created.
+	Created              *time.Time `protobuf:"bytes,5,opt,name=created,proto3" json:"created,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *CustomRole) Reset()         { *m = CustomRole{} }
+func (m *CustomRole) String() string { return proto.CompactTextString(m) }
+func (*CustomRole) ProtoMessage()    {}
+func (m *CustomRole) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CustomRole.Unmarshal(m, b)
+}
+func (m *CustomRole) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CustomRole.Marshal(b, m, deterministic)
+}
+func (m *CustomRole) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CustomRole.Merge(m, src)
+}
+func (m *CustomRole) XXX_Size() int {
+	return xxx_messageInfo_CustomRole.Size(m)
+}
+func (m *CustomRole) XXX_DiscardUnknown() {
+	xxx_messageInfo_CustomRole.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CustomRole proto.InternalMessageInfo
+
+func (m *CustomRole) GetSite() int64 {
+	if m != nil {
+		return m.Site
+	}
+	return 0
+}
+
+func (m *CustomRole) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *CustomRole) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CustomRole) GetCapabilities() string {
+	if m != nil {
+		return m.Capabilities
+	}
+	return ""
+}
+
+func (m *CustomRole) GetCreated() *time.Time {
+	if m != nil {
+		return m.Created
+	}
+	return nil
+}
+
+type UserDeletion struct {
+	// UserID is the ID of the user being deleted; it is the primary key and not a foreign key, since the
+	// user is deleted before the row.
+	UserId int64 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// The ID of the user who requested the deletion: the user being deleted or a super user.
+	RequestedBy int64 `protobuf:"varint,2,opt,name=requested_by,json=requestedBy,proto3" json:"requested_by,omitempty"`
+	// Where the content of the user on each site goes: pairs of a site ID and the ID of the user to whom
+	// the content is given, like "3:17", separated by spaces. The content on the other sites is trashed.
+	Reassign string `protobuf:"bytes,3,opt,name=reassign,proto3" json:"reassign,omitempty"`
+	// The error with which the deletion last failed, if it did.
+	Error string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// Time the deletion was last claimed by a server processing it.
+	Claimed *time.Time `protobuf:"bytes,5,opt,name=claimed,proto3" json:"claimed,omitempty"`
+	// Time the deletion was requested.
+	Created              *time.Time `protobuf:"bytes,6,opt,name=created,proto3" json:"created,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *UserDeletion) Reset()         { *m = UserDeletion{} }
+func (m *UserDeletion) String() string { return proto.CompactTextString(m) }
+func (*UserDeletion) ProtoMessage()    {}
+
+func (m *UserDeletion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserDeletion.Unmarshal(m, b)
+}
+func (m *UserDeletion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserDeletion.Marshal(b, m, deterministic)
+}
+func (m *UserDeletion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserDeletion.Merge(m, src)
+}
+func (m *UserDeletion) XXX_Size() int {
+	return xxx_messageInfo_UserDeletion.Size(m)
+}
+func (m *UserDeletion) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserDeletion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserDeletion proto.InternalMessageInfo
+
+func (m *UserDeletion) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *UserDeletion) GetRequestedBy() int64 {
+	if m != nil {
+		return m.RequestedBy
+	}
+	return 0
+}
+
+func (m *UserDeletion) GetReassign() string {
+	if m != nil {
+		return m.Reassign
+	}
+	return ""
+}
+
+func (m *UserDeletion) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *UserDeletion) GetClaimed() *time.Time {
+	if m != nil {
+		return m.Claimed
+	}
+	return nil
+}
+
+func (m *UserDeletion) GetCreated() *time.Time {
+	if m != nil {
+		return m.Created
+	}
+	return nil
+}
+
+type DataExport struct {
+	// A random ID.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// UserId is a foreign key to the ID of the user whose personal data is exported.
+	UserId int64 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Site is a foreign key to the ID of the site on which the export was requested, from which the
+	// link to the archive is sent.
+	Site int64 `protobuf:"varint,3,opt,name=site,proto3" json:"site,omitempty"`
+	// The status of the export: "pending" while the archive is built, then "ready" or "failed".
+	Status string `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	// The size of the archive in bytes, once it is ready.
+	Size int64 `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	// The error with which building the archive last failed, if it did.
+	Error string `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	// Time the export was last claimed by a server building the archive.
+	Claimed *time.Time `protobuf:"bytes,7,opt,name=claimed,proto3" json:"claimed,omitempty"`
+	// Time the export was requested.
+	Created *time.Time `protobuf:"bytes,8,opt,name=created,proto3" json:"created,omitempty"`
+	// Time the archive is deleted, once it is ready.
+	Expires              *time.Time `protobuf:"bytes,9,opt,name=expires,proto3" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *DataExport) Reset()         { *m = DataExport{} }
+func (m *DataExport) String() string { return proto.CompactTextString(m) }
+func (*DataExport) ProtoMessage()    {}
+func (m *DataExport) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DataExport.Unmarshal(m, b)
+}
+func (m *DataExport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DataExport.Marshal(b, m, deterministic)
+}
+func (m *DataExport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DataExport.Merge(m, src)
+}
+func (m *DataExport) XXX_Size() int {
+	return xxx_messageInfo_DataExport.Size(m)
+}
+func (m *DataExport) XXX_DiscardUnknown() {
+	xxx_messageInfo_DataExport.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DataExport proto.InternalMessageInfo
+
+func (m *DataExport) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *DataExport) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *DataExport) GetSite() int64 {
+	if m != nil {
+		return m.Site
+	}
+	return 0
+}
+
+func (m *DataExport) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *DataExport) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *DataExport) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *DataExport) GetClaimed() *time.Time {
+	if m != nil {
+		return m.Claimed
+	}
+	return nil
+}
+
+func (m *DataExport) GetCreated() *time.Time {
+	if m != nil {
+		return m.Created
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Site)(nil), "data.Site")
 	proto.RegisterType((*Blob)(nil), "data.Blob")
@@ -843,6 +1831,17 @@ func init() {
 	proto.RegisterType((*Media)(nil), "data.Media")
 	proto.RegisterType((*SiteMessage)(nil), "data.SiteMessage")
 	proto.RegisterType((*UserMessage)(nil), "data.UserMessage")
+	proto.RegisterType((*DomainVerification)(nil), "data.DomainVerification")
+	proto.RegisterType((*SiteAlias)(nil), "data.SiteAlias")
+	proto.RegisterType((*LoginThrottle)(nil), "data.LoginThrottle")
+	proto.RegisterType((*RateLimit)(nil), "data.RateLimit")
+	proto.RegisterType((*Session)(nil), "data.Session")
+	proto.RegisterType((*APIToken)(nil), "data.APIToken")
+	proto.RegisterType((*Invitation)(nil), "data.Invitation")
+	proto.RegisterType((*SigningKey)(nil), "data.SigningKey")
+	proto.RegisterType((*CustomRole)(nil), "data.CustomRole")
+	proto.RegisterType((*UserDeletion)(nil), "data.UserDeletion")
+	proto.RegisterType((*DataExport)(nil), "data.DataExport")
 }
 
 func init() { proto.RegisterFile("data/tables.proto", fileDescriptor_31971c09290c4bb0) }
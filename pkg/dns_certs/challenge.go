@@ -0,0 +1,64 @@
+package dns_certs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xenolf/lego/acme"
+
+	"github.com/dchenk/mazewire/pkg/dns_provider"
+)
+
+// dnsChallenge completes DNS-01 challenges by setting TXT records with a DNSProvider; implements
+// acme.ChallengeProviderTimeout.
+type dnsChallenge struct {
+	provider dns_provider.DNSProvider
+}
+
+// Present creates the TXT record that proves control over the domain.
+func (c *dnsChallenge) Present(domain, token, keyAuth string) error {
+	fqdn, value, ttl := acme.DNS01Record(domain, keyAuth)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	zone, err := c.zoneFor(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	return c.provider.SetRecords(ctx, zone, dns_provider.RecordSet{
+		Name: fqdn,
+		Type: "TXT",
+		TTL:  int64(ttl),
+		Data: []string{`"` + value + `"`},
+	})
+}
+
+// CleanUp deletes the TXT record created by Present.
+func (c *dnsChallenge) CleanUp(domain, token, keyAuth string) error {
+	fqdn, _, _ := acme.DNS01Record(domain, keyAuth)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	zone, err := c.zoneFor(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	return c.provider.DeleteRecords(ctx, zone, fqdn, "TXT")
+}
+
+// Timeout returns the time to wait for the TXT record to propagate and the interval at which to check.
+func (c *dnsChallenge) Timeout() (timeout, interval time.Duration) {
+	return 3 * time.Minute, 5 * time.Second
+}
+
+// zoneFor returns the name of the zone in which the record with the name must be set.
+func (c *dnsChallenge) zoneFor(ctx context.Context, fqdn string) (string, error) {
+	zones, err := c.provider.Zones(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not list zones; %v", err)
+	}
+	zone := dns_provider.FindZone(zones, fqdn)
+	if zone == nil {
+		return "", fmt.Errorf("no zone found for %s", fqdn)
+	}
+	return zone.Name, nil
+}
@@ -9,18 +9,12 @@ import (
 
 	"cloud.google.com/go/storage"
 	"github.com/xenolf/lego/acme"
-	"github.com/xenolf/lego/providers/dns/googlecloud"
-	"google.golang.org/api/dns/v1"
+
+	"github.com/dchenk/mazewire/pkg/dns_provider"
 )
 
 // Create an SSL certificate for the domains.
-func getCert(domains []string, svc *dns.Service, config *projConfig) (cr *acme.CertificateResource, msgs []string, err error) {
-
-	dnsProvider, err := googlecloud.NewDNSProviderService(config.projID, svc)
-	if err != nil {
-		err = fmt.Errorf("could not create a DNSProvider object to get a certificate; %s", err)
-		return
-	}
+func getCert(domains []string, provider dns_provider.DNSProvider, config *projConfig) (cr *acme.CertificateResource, msgs []string, err error) {
 
 	acctFileExists := true
 	rdr, err := gcsBucket.Object(sslUserFile).NewReader(context.Background())
@@ -97,7 +91,7 @@ func getCert(domains []string, svc *dns.Service, config *projConfig) (cr *acme.C
 
 	// Only use the DNS challenge.
 	client.ExcludeChallenges([]acme.Challenge{acme.HTTP01, acme.TLSSNI01})
-	client.SetChallengeProvider(acme.DNS01, dnsProvider)
+	client.SetChallengeProvider(acme.DNS01, &dnsChallenge{provider: provider})
 
 	// Complete the challenge for each domain and obtain the certificate.
 	cert, failures := client.ObtainCertificate(domains, false, nil, false)
@@ -2,6 +2,7 @@ package dns_certs
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -9,10 +10,12 @@ import (
 	"strings"
 
 	"cloud.google.com/go/storage"
-	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
-	"google.golang.org/api/dns/v1"
 	"google.golang.org/api/iterator"
+
+	"github.com/dchenk/mazewire/pkg/data"
+	"github.com/dchenk/mazewire/pkg/dns_provider"
+	"github.com/dchenk/mazewire/pkg/util"
 )
 
 const (
@@ -47,7 +50,7 @@ func init() {
 }
 
 // DomainsZones retrieves the latest list of domains and zones for the project. Some or all of the domains may not have a certificate.
-func DomainsZones(projShortName string) (dz []string, err error) {
+func DomainsZones(projShortName string, provider dns_provider.DNSProvider) (dz []string, err error) {
 
 	pc, ok := configs[projShortName]
 	if !ok {
@@ -55,12 +58,6 @@ func DomainsZones(projShortName string) (dz []string, err error) {
 		return
 	}
 
-	service, err := dns.New(oauth2.NewClient(context.Background(), gcpDefaultCreds.TokenSource))
-	if err != nil {
-		err = fmt.Errorf("could not set up DNS API; %v", err)
-		return
-	}
-
 	timestamp, domains, err := readDomainsList(pc.projID)
 	if err != nil {
 		err = fmt.Errorf("could not read domains.txt; %v", err)
@@ -82,30 +79,50 @@ type DomainZoneInfo struct {
 	Domain, ARecord string
 }
 
-func UpdateDomains(projShortName string) (msgs []string, err error) {
+// A DomainSource says if control over a domain has been verified and lists the aliases of sites.
+// It is satisfied by data.Conn.
+type DomainSource interface {
+	DomainVerified(domain string) (bool, error)
+	SiteByDomain(domain string) (*data.Site, error)
+	SiteAliases(site int64) ([]data.SiteAlias, error)
+}
+
+// UpdateDomains sets up the DNS records of the project's domains with the provider and obtains a
+// certificate for the domains, completing DNS-01 challenges with the provider. Domains that are not
+// verified are skipped. The verified aliases of the sites with the domains are included as well,
+// both those that redirect and those that serve their site directly.
+func UpdateDomains(projShortName string, provider dns_provider.DNSProvider, ds DomainSource) (msgs []string, err error) {
 
 	pc, ok := configs[projShortName]
 	if !ok {
 		return nil, fmt.Errorf("the given project short name %q is invalid", projShortName)
 	}
 
-	service, err := dns.New(oauth2.NewClient(context.Background(), gcpDefaultCreds.TokenSource))
+	timestamp, domains, err := readDomainsList(pc.projID)
+	if err != nil {
+		return nil, fmt.Errorf("could not read domains.txt; %v", err)
+	}
+
+	domains, skipped, err := verifiedDomains(domains, ds)
 	if err != nil {
-		err = fmt.Errorf("could not set up DNS API; %v", err)
-		return
+		return nil, err
+	}
+	for _, d := range skipped {
+		msgs = append(msgs, "Skipping unverified domain "+d)
 	}
 
-	timestamp, domains, err := readDomainsList(pc.projID)
+	aliases, err := siteAliases(domains, ds)
 	if err != nil {
-		return nil, fmt.Errorf("could not read domains.txt; %v", err)
+		return nil, err
 	}
 
-	msgs, err = updateDNS(domains, service, &pc)
+	msgs2, err := updateDNS(context.Background(), domains, aliases, provider, &pc)
+	msgs = append(msgs, msgs2...)
 	if err != nil {
 		return
 	}
 
-	cert, msgs2, err := getCert(domains, service, &pc)
+	cert, msgs2, err := getCert(append(domains, aliases...), provider, &pc)
 	msgs = append(msgs, msgs2...)
 	if err != nil {
 		return
@@ -151,6 +168,48 @@ func UpdateDomains(projShortName string) (msgs []string, err error) {
 
 }
 
+// verifiedDomains returns the domains that are verified and the domains that are not.
+func verifiedDomains(domains []string, ds DomainSource) (verified, skipped []string, err error) {
+	verified = make([]string, 0, len(domains))
+	for _, d := range domains {
+		ok, err := ds.DomainVerified(d)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not check verification of domain %s; %v", d, err)
+		}
+		if ok {
+			verified = append(verified, d)
+		} else {
+			skipped = append(skipped, d)
+		}
+	}
+	return verified, skipped, nil
+}
+
+// siteAliases returns the verified aliases of the sites whose primary domains are given. Domains
+// that are not the primary domain of any site are ignored.
+func siteAliases(domains []string, ds DomainSource) ([]string, error) {
+	var aliases []string
+	for _, d := range domains {
+		site, err := ds.SiteByDomain(d)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not get site with domain %s; %v", d, err)
+		}
+		as, err := ds.SiteAliases(site.Id)
+		if err != nil {
+			return nil, fmt.Errorf("could not list aliases of site %d; %v", site.Id, err)
+		}
+		for _, a := range as {
+			if a.Verified && !util.SliceContainsString(domains, a.Domain) && !util.SliceContainsString(aliases, a.Domain) {
+				aliases = append(aliases, a.Domain)
+			}
+		}
+	}
+	return aliases, nil
+}
+
 // SetDomainsList saves a new version of the list of domains for a project.
 func SetDomainsList(projShortName string, newList []string) error {
 	return nil
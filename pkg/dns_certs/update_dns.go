@@ -1,93 +1,93 @@
 package dns_certs
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
-	"google.golang.org/api/dns/v1"
-	"google.golang.org/api/googleapi"
+	"github.com/dchenk/mazewire/pkg/dns_provider"
 )
 
-// Add all domains to the Google Cloud project DNS service, setting the single correct A record for each.
-func updateDNS(domains []string, svc *dns.Service, setup *projConfig) (msgs []string, err error) {
+// Add all domains and aliases to the DNS provider, setting the single correct A record for each. Each
+// domain gets a zone of its own, but an alias is put in the zone enclosing it if there is one, so that
+// an alias like "www.example.com" goes in the zone of "example.com".
+func updateDNS(ctx context.Context, domains, aliases []string, provider dns_provider.DNSProvider, setup *projConfig) (msgs []string, err error) {
 
-	for _, d := range domains {
-
-		mz := &dns.ManagedZone{
-			DnsName:     d + ".",
-			Name:        strings.Replace(d, ".", "-", -1),
-			Description: "zone",
-		}
+	zones, err := provider.Zones(ctx)
+	if err != nil {
+		err = fmt.Errorf("could not list zones; %v", err)
+		return
+	}
 
-		zoneExists := false
-		managedZone, err := svc.ManagedZones.Get(setup.projID, strings.Replace(d, ".", "-", -1)).Do()
+	for _, d := range domains {
+		msgs, err = setARecord(ctx, msgs, &zones, d, true, provider, setup)
 		if err != nil {
-			if googleapi.IsNotModified(err) {
-				zoneExists = true
-				managedZone = mz
-			} else if err.(*googleapi.Error).Code != 404 {
-				err = fmt.Errorf("could not check for zone %s; %s", d, err)
-				return
-			}
-		} else if managedZone != nil { // Double checking; shouldn't be nil at this point.
-			zoneExists = true
-		}
-
-		if !zoneExists {
-			msgs = append(msgs, "Creating zone for domain "+d)
-			managedZone, err = svc.ManagedZones.Create(setup.projID, mz).Do()
-			if err != nil {
-				err = fmt.Errorf("could not create zone for %s; %s", d, err)
-				return
-			}
+			return
 		}
+	}
 
-		aRecordsList, err := svc.ResourceRecordSets.List(setup.projID, managedZone.Name).Name(managedZone.DnsName).Type("A").Do()
+	for _, d := range aliases {
+		msgs, err = setARecord(ctx, msgs, &zones, d, false, provider, setup)
 		if err != nil {
-			err = fmt.Errorf("could not check zone %s for A records; %s", d, err)
 			return
 		}
+	}
+
+	return
 
-		// Check if the current value of the A record is correct.
-		if aRecordsList != nil {
-			if sets := aRecordsList.Rrsets; len(aRecordsList.Rrsets) > 0 && len(sets) > 0 {
+}
 
-				if data := sets[0].Rrdatas; len(data) == 1 && data[0] == setup.loadBalancerIP {
-					continue
-				}
+// setARecord sets the A record of the domain to the IP address of the load balancer, creating a zone
+// for the domain if it has no zone and adding it to the list. If ownZone is true, the domain must be
+// the DNS name of its zone.
+func setARecord(ctx context.Context, msgs []string, zones *[]dns_provider.Zone, d string, ownZone bool,
+	provider dns_provider.DNSProvider, setup *projConfig) ([]string, error) {
 
-				// Delete the current A record values.
-				deletion := &dns.Change{Deletions: sets}
-				if _, err := svc.Changes.Create(setup.projID, managedZone.Name, deletion).Do(); err != nil {
-					err = fmt.Errorf("could not delete old A records for domain %s; %s", d, err)
-					return
-				}
+	apex := dns_provider.Fqdn(d)
 
-			}
+	zone := dns_provider.FindZone(*zones, apex)
+	if zone == nil || (ownZone && !strings.EqualFold(zone.DNSName, apex)) {
+		msgs = append(msgs, "Creating zone for domain "+d)
+		var err error
+		zone, err = provider.CreateZone(ctx, apex)
+		if err != nil {
+			return msgs, fmt.Errorf("could not create zone for %s; %v", d, err)
 		}
+		*zones = append(*zones, *zone)
+	}
 
-		addition := &dns.Change{
-			Additions: []*dns.ResourceRecordSet{
-				{
-					Name:    managedZone.DnsName,
-					Type:    "A",
-					Rrdatas: []string{setup.loadBalancerIP},
-					Ttl:     3600, // 30 minutes
-				},
-			},
-		}
+	records, err := provider.Records(ctx, zone.Name)
+	if err != nil {
+		return msgs, fmt.Errorf("could not check zone %s for A records; %v", d, err)
+	}
 
-		if _, err = svc.Changes.Create(setup.projID, managedZone.Name, addition).Do(); err != nil {
-			if !googleapi.IsNotModified(err) {
-				err = fmt.Errorf("could not create A record for %s; %s", d, err)
-				return
-			}
+	// Check if the current value of the A record is correct.
+	if current := findRecordSet(records, apex, "A"); current != nil {
+		if len(current.Data) == 1 && current.Data[0] == setup.loadBalancerIP {
+			return msgs, nil
 		}
+	}
 
-		msgs = append(msgs, "Updated zone for domain "+d)
-
+	err = provider.SetRecords(ctx, zone.Name, dns_provider.RecordSet{
+		Name: apex,
+		Type: "A",
+		TTL:  3600,
+		Data: []string{setup.loadBalancerIP},
+	})
+	if err != nil {
+		return msgs, fmt.Errorf("could not set A record for %s; %v", d, err)
 	}
 
-	return
+	return append(msgs, "Updated zone for domain "+d), nil
+
+}
 
+// findRecordSet returns the record set in the list with the name and type, or nil if there is none.
+func findRecordSet(sets []dns_provider.RecordSet, name, recordType string) *dns_provider.RecordSet {
+	for i := range sets {
+		if strings.EqualFold(sets[i].Name, name) && sets[i].Type == recordType {
+			return &sets[i]
+		}
+	}
+	return nil
 }
@@ -0,0 +1,117 @@
+// Package dns_provider defines the DNSProvider interface, which abstracts away the authoritative DNS
+// service used to manage the zones and records of the domains served by the system.
+//
+// Implementations for particular DNS services are in the sub-packages of this package.
+package dns_provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// A DNSProvider is able to manage zones and the records in them on an authoritative DNS service.
+//
+// All names given to and returned by a DNSProvider are fully qualified, ending with a dot, except for
+// the provider-specific Name of a Zone.
+type DNSProvider interface {
+	// Zones lists all of the zones managed by the provider.
+	Zones(ctx context.Context) ([]Zone, error)
+
+	// Zone retrieves a zone by its provider-specific name.
+	// If the zone does not exist, the error is ErrZoneNotFound.
+	Zone(ctx context.Context, name string) (*Zone, error)
+
+	// CreateZone creates a zone for the domain name. The Name of the returned Zone is the name by
+	// which the zone must be identified in the other methods.
+	// Providers that cannot create zones return ErrNotSupported.
+	CreateZone(ctx context.Context, dnsName string) (*Zone, error)
+
+	// DeleteZone deletes the zone with the given provider-specific name.
+	// Providers that cannot delete zones return ErrNotSupported.
+	DeleteZone(ctx context.Context, name string) error
+
+	// Records lists all of the record sets in a zone.
+	Records(ctx context.Context, zone string) ([]RecordSet, error)
+
+	// SetRecords creates the record set in the zone, replacing any existing record set that has the
+	// same Name and Type.
+	SetRecords(ctx context.Context, zone string, rs RecordSet) error
+
+	// DeleteRecords deletes the record set with the name and type from the zone. No error is returned
+	// if the record set does not exist.
+	DeleteRecords(ctx context.Context, zone string, name string, recordType string) error
+}
+
+// A Zone is a DNS zone managed by a DNSProvider.
+type Zone struct {
+	// Name is the name by which the provider identifies the zone. For some providers this is the same
+	// as the DNSName.
+	Name string `json:"name"`
+
+	// DNSName is the fully qualified domain name of the zone apex, such as "example.com.".
+	DNSName string `json:"dns_name"`
+
+	// NameServers lists the authoritative name servers for the zone, if known.
+	NameServers []string `json:"name_servers"`
+}
+
+// A RecordSet is the collection of records in a zone that have the same name and type.
+type RecordSet struct {
+	// Name is the fully qualified name of the records, such as "www.example.com.".
+	Name string `json:"name"`
+
+	// Type is the record type, such as "A", "TXT", or "MX".
+	Type string `json:"type"`
+
+	// TTL is the number of seconds that the records may be cached by resolvers.
+	TTL int64 `json:"ttl"`
+
+	// Data contains the data of each record in the presentation format defined in RFC 1035 (section 5),
+	// such as "192.0.2.1" for an A record or `"some text"` for a TXT record.
+	Data []string `json:"data"`
+}
+
+var (
+	// ErrZoneNotFound indicates that a zone does not exist.
+	ErrZoneNotFound = errors.New("dns_provider: zone not found")
+
+	// ErrNotSupported indicates that the provider cannot do the requested operation.
+	ErrNotSupported = errors.New("dns_provider: operation not supported by provider")
+)
+
+// Fqdn returns the name as a fully qualified domain name, which ends with a dot.
+func Fqdn(name string) string {
+	if name == "" || name[len(name)-1] != '.' {
+		return name + "."
+	}
+	return name
+}
+
+// UnFqdn returns the name without the trailing dot of a fully qualified domain name.
+func UnFqdn(name string) string {
+	return strings.TrimSuffix(name, ".")
+}
+
+// ZoneName returns the conventional provider-specific name of the zone for the domain, in which each
+// dot is replaced by a dash (so "example.com." becomes "example-com").
+func ZoneName(domain string) string {
+	return strings.Replace(UnFqdn(domain), ".", "-", -1)
+}
+
+// FindZone returns the zone in the list that most specifically contains the given name, or nil if no
+// zone contains the name.
+func FindZone(zones []Zone, name string) *Zone {
+	name = strings.ToLower(Fqdn(name))
+	var found *Zone
+	for i := range zones {
+		apex := strings.ToLower(Fqdn(zones[i].DNSName))
+		if name != apex && !strings.HasSuffix(name, "."+apex) {
+			continue
+		}
+		if found == nil || len(apex) > len(found.DNSName) {
+			found = &zones[i]
+		}
+	}
+	return found
+}
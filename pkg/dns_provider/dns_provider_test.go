@@ -0,0 +1,76 @@
+package dns_provider
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestFqdn(t *testing.T) {
+	cases := []struct {
+		name, fqdn string
+	}{
+		{"example.com", "example.com."},
+		{"example.com.", "example.com."},
+		{"", "."},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := Fqdn(tc.name); got != tc.fqdn {
+				t.Errorf("got %q but expected %q", got, tc.fqdn)
+			}
+		})
+	}
+}
+
+func TestZoneName(t *testing.T) {
+	cases := []struct {
+		domain, name string
+	}{
+		{"example.com", "example-com"},
+		{"example.com.", "example-com"},
+		{"a.b.example.co.uk.", "a-b-example-co-uk"},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := ZoneName(tc.domain); got != tc.name {
+				t.Errorf("got %q but expected %q", got, tc.name)
+			}
+		})
+	}
+}
+
+func TestFindZone(t *testing.T) {
+	zones := []Zone{
+		{Name: "example-com", DNSName: "example.com."},
+		{Name: "sub-example-com", DNSName: "sub.example.com."},
+		{Name: "other-org", DNSName: "other.org."},
+	}
+	cases := []struct {
+		name string
+		zone string // blank if no zone should be found
+	}{
+		{"example.com", "example-com"},
+		{"www.example.com.", "example-com"},
+		{"_acme-challenge.sub.example.com.", "sub-example-com"},
+		{"SUB.Example.com", "sub-example-com"},
+		{"notexample.com.", ""},
+		{"example.net.", ""},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			z := FindZone(zones, tc.name)
+			if tc.zone == "" {
+				if z != nil {
+					t.Errorf("found zone %q but expected none", z.Name)
+				}
+				return
+			}
+			if z == nil {
+				t.Fatalf("found no zone but expected %q", tc.zone)
+			}
+			if z.Name != tc.zone {
+				t.Errorf("found zone %q but expected %q", z.Name, tc.zone)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// Package googlecloud implements the dns_provider.DNSProvider interface for Google Cloud DNS.
+package googlecloud
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+
+	"github.com/dchenk/mazewire/pkg/dns_provider"
+)
+
+// Provider implements dns_provider.DNSProvider for the managed zones of a Google Cloud project.
+type Provider struct {
+	project string
+	svc     *dns.Service
+}
+
+// New creates a Provider for the Google Cloud project, authenticating with the token source.
+func New(ctx context.Context, projectID string, ts oauth2.TokenSource) (*Provider, error) {
+	svc, err := dns.New(oauth2.NewClient(ctx, ts))
+	if err != nil {
+		return nil, err
+	}
+	return NewWithService(projectID, svc), nil
+}
+
+// NewWithService creates a Provider for the Google Cloud project using an already configured service.
+func NewWithService(projectID string, svc *dns.Service) *Provider {
+	return &Provider{project: projectID, svc: svc}
+}
+
+// Zones lists all of the managed zones in the project.
+func (p *Provider) Zones(ctx context.Context) ([]dns_provider.Zone, error) {
+	zones := make([]dns_provider.Zone, 0, 4)
+	err := p.svc.ManagedZones.List(p.project).Pages(ctx, func(page *dns.ManagedZonesListResponse) error {
+		for _, mz := range page.ManagedZones {
+			zones = append(zones, toZone(mz))
+		}
+		return nil
+	})
+	return zones, err
+}
+
+// Zone retrieves a managed zone by its name.
+func (p *Provider) Zone(ctx context.Context, name string) (*dns_provider.Zone, error) {
+	mz, err := p.svc.ManagedZones.Get(p.project, name).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, dns_provider.ErrZoneNotFound
+		}
+		return nil, err
+	}
+	z := toZone(mz)
+	return &z, nil
+}
+
+// CreateZone creates a managed zone named using dns_provider.ZoneName.
+func (p *Provider) CreateZone(ctx context.Context, dnsName string) (*dns_provider.Zone, error) {
+	name := dns_provider.ZoneName(dnsName)
+	mz, err := p.svc.ManagedZones.Create(p.project, &dns.ManagedZone{
+		DnsName:     dns_provider.Fqdn(dnsName),
+		Name:        name,
+		Description: name,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	z := toZone(mz)
+	return &z, nil
+}
+
+// DeleteZone deletes a managed zone. Google Cloud DNS refuses to delete a zone that contains records
+// other than the NS and SOA records at the apex.
+func (p *Provider) DeleteZone(ctx context.Context, name string) error {
+	err := p.svc.ManagedZones.Delete(p.project, name).Context(ctx).Do()
+	if isNotFound(err) {
+		return dns_provider.ErrZoneNotFound
+	}
+	return err
+}
+
+// Records lists all of the record sets in the managed zone.
+func (p *Provider) Records(ctx context.Context, zone string) ([]dns_provider.RecordSet, error) {
+	sets := make([]dns_provider.RecordSet, 0, 4)
+	err := p.svc.ResourceRecordSets.List(p.project, zone).Pages(ctx, func(page *dns.ResourceRecordSetsListResponse) error {
+		for _, rrs := range page.Rrsets {
+			sets = append(sets, toRecordSet(rrs))
+		}
+		return nil
+	})
+	if isNotFound(err) {
+		return nil, dns_provider.ErrZoneNotFound
+	}
+	return sets, err
+}
+
+// SetRecords replaces the record set with the same name and type as rs in a single change.
+func (p *Provider) SetRecords(ctx context.Context, zone string, rs dns_provider.RecordSet) error {
+	existing, err := p.recordSets(ctx, zone, rs.Name, rs.Type)
+	if err != nil {
+		return err
+	}
+	change := &dns.Change{
+		Additions: []*dns.ResourceRecordSet{
+			{
+				Name:    dns_provider.Fqdn(rs.Name),
+				Type:    rs.Type,
+				Ttl:     rs.TTL,
+				Rrdatas: rs.Data,
+			},
+		},
+		Deletions: existing,
+	}
+	_, err = p.svc.Changes.Create(p.project, zone, change).Context(ctx).Do()
+	if err != nil && !googleapi.IsNotModified(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteRecords deletes the record set with the name and type.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, name string, recordType string) error {
+	existing, err := p.recordSets(ctx, zone, name, recordType)
+	if err != nil || len(existing) == 0 {
+		return err
+	}
+	_, err = p.svc.Changes.Create(p.project, zone, &dns.Change{Deletions: existing}).Context(ctx).Do()
+	if err != nil && !googleapi.IsNotModified(err) {
+		return err
+	}
+	return nil
+}
+
+// recordSets lists the record sets in the zone with the name and type.
+func (p *Provider) recordSets(ctx context.Context, zone, name, recordType string) ([]*dns.ResourceRecordSet, error) {
+	resp, err := p.svc.ResourceRecordSets.List(p.project, zone).Name(dns_provider.Fqdn(name)).Type(recordType).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, dns_provider.ErrZoneNotFound
+		}
+		return nil, err
+	}
+	return resp.Rrsets, nil
+}
+
+func toZone(mz *dns.ManagedZone) dns_provider.Zone {
+	return dns_provider.Zone{
+		Name:        mz.Name,
+		DNSName:     mz.DnsName,
+		NameServers: mz.NameServers,
+	}
+}
+
+func toRecordSet(rrs *dns.ResourceRecordSet) dns_provider.RecordSet {
+	return dns_provider.RecordSet{
+		Name: rrs.Name,
+		Type: rrs.Type,
+		TTL:  rrs.Ttl,
+		Data: rrs.Rrdatas,
+	}
+}
+
+// isNotFound says if the error is an API error with the status 404.
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*googleapi.Error)
+	return ok && apiErr.Code == http.StatusNotFound
+}
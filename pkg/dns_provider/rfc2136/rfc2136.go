@@ -0,0 +1,361 @@
+// Package rfc2136 implements the dns_provider.DNSProvider interface for any authoritative name server
+// that accepts dynamic updates (RFC 2136) and zone transfers (RFC 5936), such as BIND, Knot, or
+// PowerDNS. Messages can be authenticated with a TSIG key (RFC 8945).
+//
+// Name servers cannot create or delete zones by way of the DNS protocol, so the zones a Provider
+// manages must be configured on the name server and listed in the Config.
+package rfc2136
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/dns_provider"
+)
+
+// DefaultTimeout is the time allowed for each exchange with the name server if Config.Timeout is 0.
+const DefaultTimeout = 10 * time.Second
+
+// Config is the configuration of a Provider.
+type Config struct {
+	// Nameserver is the address of the primary name server, as "host" or "host:port".
+	// The port defaults to 53.
+	Nameserver string
+
+	// Zones lists the domain names of the zones that the name server is authoritative for and that the
+	// Provider may update.
+	Zones []string
+
+	// TSIGKeyName is the name of the TSIG key with which to sign messages. If blank, messages are not
+	// signed.
+	TSIGKeyName string
+
+	// TSIGSecret is the base64-encoded secret of the TSIG key.
+	TSIGSecret string
+
+	// TSIGAlgorithm is the name of the TSIG algorithm, such as HmacSHA256 (the default).
+	TSIGAlgorithm string
+
+	// Timeout is the time allowed for each exchange with the name server.
+	Timeout time.Duration
+}
+
+// Provider implements dns_provider.DNSProvider for the zones of an authoritative name server.
+// A zone's Name is the same as its DNSName.
+type Provider struct {
+	nameserver string
+	zones      []string
+	key        *tsigKey
+	timeout    time.Duration
+}
+
+// New creates a Provider with the configuration.
+func New(c Config) (*Provider, error) {
+	if c.Nameserver == "" {
+		return nil, errors.New("rfc2136: no name server given")
+	}
+	p := &Provider{
+		nameserver: c.Nameserver,
+		zones:      make([]string, len(c.Zones)),
+		timeout:    c.Timeout,
+	}
+	if _, _, err := net.SplitHostPort(p.nameserver); err != nil {
+		p.nameserver = net.JoinHostPort(strings.Trim(p.nameserver, "[]"), "53")
+	}
+	for i, z := range c.Zones {
+		p.zones[i] = strings.ToLower(dns_provider.Fqdn(strings.TrimSpace(z)))
+	}
+	if p.timeout == 0 {
+		p.timeout = DefaultTimeout
+	}
+	if c.TSIGKeyName != "" {
+		secret, err := base64.StdEncoding.DecodeString(c.TSIGSecret)
+		if err != nil {
+			return nil, fmt.Errorf("rfc2136: could not decode TSIG secret; %v", err)
+		}
+		if p.key, err = newTSIGKey(dns_provider.Fqdn(c.TSIGKeyName), c.TSIGAlgorithm, secret); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Zones lists the configured zones along with their name servers as given by the NS records at the
+// zone apex.
+func (p *Provider) Zones(ctx context.Context) ([]dns_provider.Zone, error) {
+	zones := make([]dns_provider.Zone, 0, len(p.zones))
+	for _, name := range p.zones {
+		z, err := p.zone(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, *z)
+	}
+	return zones, nil
+}
+
+// Zone retrieves one of the configured zones.
+func (p *Provider) Zone(ctx context.Context, name string) (*dns_provider.Zone, error) {
+	name, err := p.configuredZone(name)
+	if err != nil {
+		return nil, err
+	}
+	return p.zone(ctx, name)
+}
+
+func (p *Provider) zone(ctx context.Context, name string) (*dns_provider.Zone, error) {
+	resp, err := p.exchange(ctx, "udp", &message{
+		id:        newID(),
+		opcode:    opcodeQuery,
+		questions: []question{{name: name, qtype: typeNS, qclass: classINET}},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	z := &dns_provider.Zone{Name: name, DNSName: name, NameServers: make([]string, 0, 2)}
+	for _, rr := range resp.answers {
+		if rr.rtype != typeNS {
+			continue
+		}
+		ns, err := unpackRdata(resp.raw, rr.rtype, rr.offset, len(rr.rdata))
+		if err != nil {
+			return nil, err
+		}
+		z.NameServers = append(z.NameServers, ns)
+	}
+	return z, nil
+}
+
+// CreateZone returns dns_provider.ErrNotSupported.
+func (p *Provider) CreateZone(context.Context, string) (*dns_provider.Zone, error) {
+	return nil, dns_provider.ErrNotSupported
+}
+
+// DeleteZone returns dns_provider.ErrNotSupported.
+func (p *Provider) DeleteZone(context.Context, string) error {
+	return dns_provider.ErrNotSupported
+}
+
+// Records lists all of the record sets in the zone, which is transferred from the name server.
+func (p *Provider) Records(ctx context.Context, zone string) ([]dns_provider.RecordSet, error) {
+	zone, err := p.configuredZone(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make([]dns_provider.RecordSet, 0, 8)
+	index := make(map[string]int) // Maps each name and type to its index in sets.
+	soaSeen := false
+	err = p.transfer(ctx, zone, func(resp *message) (bool, error) {
+		for _, rr := range resp.answers {
+			if rr.rtype == typeSOA {
+				if soaSeen {
+					// The transfer ends with the SOA record it began with.
+					return true, nil
+				}
+				soaSeen = true
+			}
+			data, err := unpackRdata(resp.raw, rr.rtype, rr.offset, len(rr.rdata))
+			if err != nil {
+				return false, err
+			}
+			typeName, ok := typeNames[rr.rtype]
+			if !ok {
+				typeName = fmt.Sprintf("TYPE%d", rr.rtype)
+			}
+			name := strings.ToLower(rr.name)
+			key := name + " " + typeName
+			if i, ok := index[key]; ok {
+				sets[i].Data = append(sets[i].Data, data)
+				continue
+			}
+			index[key] = len(sets)
+			sets = append(sets, dns_provider.RecordSet{
+				Name: name,
+				Type: typeName,
+				TTL:  int64(rr.ttl),
+				Data: []string{data},
+			})
+		}
+		return false, nil
+	})
+	return sets, err
+}
+
+// SetRecords replaces the record set with the same name and type as rs in a single update.
+func (p *Provider) SetRecords(ctx context.Context, zone string, rs dns_provider.RecordSet) error {
+	zone, err := p.configuredZone(zone)
+	if err != nil {
+		return err
+	}
+	rtype, ok := typeValue(rs.Type)
+	if !ok {
+		return fmt.Errorf("rfc2136: unsupported record type %q", rs.Type)
+	}
+	if rs.TTL < 0 || rs.TTL > 1<<31-1 {
+		return fmt.Errorf("rfc2136: invalid TTL %d", rs.TTL)
+	}
+	name := dns_provider.Fqdn(rs.Name)
+	updates := make([]resource, 0, len(rs.Data)+1)
+	updates = append(updates, resource{name: name, rtype: rtype, class: classANY})
+	for _, d := range rs.Data {
+		rdata, err := packRdata(rtype, d)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, resource{name: name, rtype: rtype, class: classINET, ttl: uint32(rs.TTL), rdata: rdata})
+	}
+	return p.update(ctx, zone, updates)
+}
+
+// DeleteRecords deletes the record set with the name and type.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, name string, recordType string) error {
+	zone, err := p.configuredZone(zone)
+	if err != nil {
+		return err
+	}
+	rtype, ok := typeValue(recordType)
+	if !ok {
+		return fmt.Errorf("rfc2136: unsupported record type %q", recordType)
+	}
+	return p.update(ctx, zone, []resource{{name: dns_provider.Fqdn(name), rtype: rtype, class: classANY}})
+}
+
+// configuredZone returns the normalized name of the zone if it is one of the configured zones.
+func (p *Provider) configuredZone(name string) (string, error) {
+	name = strings.ToLower(dns_provider.Fqdn(name))
+	for _, z := range p.zones {
+		if z == name {
+			return z, nil
+		}
+	}
+	return "", dns_provider.ErrZoneNotFound
+}
+
+// update sends an UPDATE message for the zone with the updates given.
+func (p *Provider) update(ctx context.Context, zone string, updates []resource) error {
+	msg := &message{
+		id:          newID(),
+		opcode:      opcodeUpdate,
+		questions:   []question{{name: zone, qtype: typeSOA, qclass: classINET}},
+		authorities: updates,
+	}
+	resp, err := p.exchange(ctx, "udp", msg, nil)
+	if err == nil && resp.truncated {
+		_, err = p.exchange(ctx, "tcp", msg, nil)
+	}
+	return err
+}
+
+// transfer requests a transfer of the zone and calls handle with each response message received
+// until handle says that the transfer is complete.
+func (p *Provider) transfer(ctx context.Context, zone string, handle func(*message) (bool, error)) error {
+	_, err := p.exchange(ctx, "tcp", &message{
+		id:        newID(),
+		opcode:    opcodeQuery,
+		questions: []question{{name: zone, qtype: typeAXFR, qclass: classINET}},
+	}, handle)
+	return err
+}
+
+// exchange sends the message over the network ("udp" or "tcp") and returns the response. If handle is
+// not nil, responses are read until handle says that the exchange is complete, and the last response
+// is returned.
+func (p *Provider) exchange(ctx context.Context, network string, msg *message, handle func(*message) (bool, error)) (*message, error) {
+	b, err := msg.pack()
+	if err != nil {
+		return nil, err
+	}
+	if p.key != nil {
+		if b, err = p.key.sign(b, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, p.nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("rfc2136: could not connect to name server; %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	stream := network == "tcp"
+	if err = writeMsg(conn, b, stream); err != nil {
+		return nil, fmt.Errorf("rfc2136: could not send message; %v", err)
+	}
+	for {
+		raw, err := readMsg(conn, stream)
+		if err != nil {
+			return nil, fmt.Errorf("rfc2136: could not read response; %v", err)
+		}
+		resp, err := unpackMessage(raw)
+		if err != nil {
+			return nil, err
+		}
+		if !resp.response || resp.id != msg.id {
+			if stream {
+				return nil, errors.New("rfc2136: received a response with an unexpected ID")
+			}
+			continue // Ignore stray datagrams.
+		}
+		if resp.rcode != 0 {
+			name, ok := rcodeNames[resp.rcode]
+			if !ok {
+				name = fmt.Sprintf("RCODE%d", resp.rcode)
+			}
+			return nil, fmt.Errorf("rfc2136: name server responded with %s", name)
+		}
+		if handle == nil {
+			return resp, nil
+		}
+		done, err := handle(resp)
+		if err != nil || done {
+			return resp, err
+		}
+	}
+}
+
+// writeMsg writes the message to conn, prefixed by its length if conn is a stream.
+func writeMsg(conn net.Conn, b []byte, stream bool) error {
+	if stream {
+		b = append(appendUint16(make([]byte, 0, len(b)+2), uint16(len(b))), b...)
+	}
+	_, err := conn.Write(b)
+	return err
+}
+
+// readMsg reads a single message from conn.
+func readMsg(conn net.Conn, stream bool) ([]byte, error) {
+	if !stream {
+		b := make([]byte, 65535)
+		n, err := conn.Read(b)
+		return b[:n], err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	b := make([]byte, binary.BigEndian.Uint16(length[:]))
+	_, err := io.ReadFull(conn, b)
+	return b, err
+}
+
+// newID returns a random message ID.
+func newID() uint16 {
+	var b [2]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint16(b[:])
+}
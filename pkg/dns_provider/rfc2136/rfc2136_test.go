@@ -0,0 +1,334 @@
+package rfc2136
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/dns_provider"
+)
+
+// testServer is a minimal authoritative name server for a single zone that answers NS queries,
+// zone transfers, and dynamic updates. It listens on the same port for UDP and TCP.
+type testServer struct {
+	t    *testing.T
+	zone string
+	key  *tsigKey
+
+	mu      sync.Mutex
+	records []resource // The records in the zone, excluding the SOA record.
+
+	pc   net.PacketConn
+	ln   net.Listener
+	addr string
+}
+
+func newTestServer(t *testing.T, zone string, key *tsigKey) *testServer {
+	s := &testServer{t: t, zone: zone, key: key}
+	ns, _ := packRdata(typeNS, "ns1."+zone)
+	s.records = append(s.records, resource{name: zone, rtype: typeNS, class: classINET, ttl: 3600, rdata: ns})
+
+	// Find a port that is free for both UDP and TCP.
+	for i := 0; ; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pc, err := net.ListenPacket("udp", ln.Addr().String())
+		if err == nil {
+			s.ln, s.pc, s.addr = ln, pc, ln.Addr().String()
+			break
+		}
+		ln.Close()
+		if i == 10 {
+			t.Fatalf("could not listen on UDP; %v", err)
+		}
+	}
+	go s.serveUDP()
+	go s.serveTCP()
+	return s
+}
+
+func (s *testServer) Close() {
+	s.pc.Close()
+	s.ln.Close()
+}
+
+func (s *testServer) serveUDP() {
+	b := make([]byte, 65535)
+	for {
+		n, addr, err := s.pc.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		for _, resp := range s.handle(append([]byte(nil), b[:n]...)) {
+			s.pc.WriteTo(resp, addr)
+		}
+	}
+}
+
+func (s *testServer) serveTCP() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			req, err := readMsg(conn, true)
+			if err != nil {
+				return
+			}
+			for _, resp := range s.handle(req) {
+				writeMsg(conn, resp, true)
+			}
+		}()
+	}
+}
+
+// handle returns the packed response messages for a request.
+func (s *testServer) handle(req []byte) [][]byte {
+	m, err := unpackMessage(req)
+	if err != nil {
+		s.t.Errorf("could not unpack request; %v", err)
+		return nil
+	}
+	resp := &message{id: m.id, response: true, opcode: m.opcode, questions: m.questions}
+	reply := func(msgs ...*message) [][]byte {
+		packed := make([][]byte, len(msgs))
+		for i, msg := range msgs {
+			if packed[i], err = msg.pack(); err != nil {
+				s.t.Errorf("could not pack response; %v", err)
+			}
+		}
+		return packed
+	}
+
+	if len(m.questions) != 1 || !strings.EqualFold(m.questions[0].name, s.zone) {
+		resp.rcode = 9 // NOTAUTH
+		return reply(resp)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch m.opcode {
+	case opcodeUpdate:
+		if s.key != nil && !s.verify(m) {
+			resp.rcode = 9 // NOTAUTH
+			return reply(resp)
+		}
+		for _, u := range m.authorities {
+			switch u.class {
+			case classANY:
+				kept := s.records[:0]
+				for _, rr := range s.records {
+					if !strings.EqualFold(rr.name, u.name) || rr.rtype != u.rtype {
+						kept = append(kept, rr)
+					}
+				}
+				s.records = kept
+			case classINET:
+				u.rdata = append([]byte(nil), u.rdata...)
+				s.records = append(s.records, u)
+			}
+		}
+		return reply(resp)
+	case opcodeQuery:
+		soa, _ := packRdata(typeSOA, "ns1."+s.zone+" admin."+s.zone+" 1 7200 3600 1209600 300")
+		soaRR := resource{name: s.zone, rtype: typeSOA, class: classINET, ttl: 3600, rdata: soa}
+		switch m.questions[0].qtype {
+		case typeNS:
+			for _, rr := range s.records {
+				if rr.rtype == typeNS && strings.EqualFold(rr.name, s.zone) {
+					resp.answers = append(resp.answers, rr)
+				}
+			}
+			return reply(resp)
+		case typeAXFR:
+			// Split the transfer across two messages.
+			resp.answers = append([]resource{soaRR}, s.records...)
+			second := &message{id: m.id, response: true, answers: []resource{soaRR}}
+			return reply(resp, second)
+		}
+	}
+	resp.rcode = 4 // NOTIMP
+	return reply(resp)
+}
+
+// verify checks the TSIG record that the request ends with.
+func (s *testServer) verify(m *message) bool {
+	if len(m.additionals) == 0 {
+		return false
+	}
+	tsig := m.additionals[len(m.additionals)-1]
+	if tsig.rtype != typeTSIG || !strings.EqualFold(tsig.name, s.key.name) {
+		return false
+	}
+	alg, off, err := unpackName(m.raw, tsig.offset)
+	if err != nil || alg != s.key.algorithm {
+		return false
+	}
+	signed := int64(binary.BigEndian.Uint16(m.raw[off:]))<<32 | int64(binary.BigEndian.Uint32(m.raw[off+2:]))
+	macLen := int(binary.BigEndian.Uint16(m.raw[off+8:]))
+	mac := m.raw[off+10 : off+10+macLen]
+
+	name, _ := packName(nil, tsig.name)
+	start := tsig.offset - 10 - len(name)
+	unsigned := append([]byte(nil), m.raw[:start]...)
+	binary.BigEndian.PutUint16(unsigned[10:], uint16(len(m.additionals)-1))
+	expected, err := s.key.mac(unsigned, time.Unix(signed, 0))
+	return err == nil && hmac.Equal(mac, expected)
+}
+
+func TestProvider(t *testing.T) {
+	secret := []byte("a very secret key for tests")
+	key, err := newTSIGKey("update-key.", "", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, "example.com.", key)
+	defer srv.Close()
+
+	p, err := New(Config{
+		Nameserver:  srv.addr,
+		Zones:       []string{"Example.com"},
+		TSIGKeyName: "update-key",
+		TSIGSecret:  base64.StdEncoding.EncodeToString(secret),
+		Timeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		t.Fatalf("could not list zones; %v", err)
+	}
+	expectedZones := []dns_provider.Zone{{Name: "example.com.", DNSName: "example.com.", NameServers: []string{"ns1.example.com."}}}
+	if !reflect.DeepEqual(zones, expectedZones) {
+		t.Errorf("got zones %+v but expected %+v", zones, expectedZones)
+	}
+
+	if _, err := p.Zone(ctx, "example.org."); err != dns_provider.ErrZoneNotFound {
+		t.Errorf("got error %v for unknown zone", err)
+	}
+	if _, err := p.CreateZone(ctx, "example.org."); err != dns_provider.ErrNotSupported {
+		t.Errorf("got error %v for CreateZone", err)
+	}
+
+	sets := []dns_provider.RecordSet{
+		{Name: "www.example.com.", Type: "A", TTL: 300, Data: []string{"192.0.2.1", "192.0.2.2"}},
+		{Name: "_acme-challenge.example.com", Type: "TXT", TTL: 60, Data: []string{`"some \"token\""`}},
+		{Name: "example.com.", Type: "MX", TTL: 3600, Data: []string{"10 mail.example.com."}},
+	}
+	for _, rs := range sets {
+		if err := p.SetRecords(ctx, "example.com", rs); err != nil {
+			t.Fatalf("could not set %s records; %v", rs.Type, err)
+		}
+	}
+	// Replace a record set.
+	sets[0].Data = []string{"192.0.2.3"}
+	if err := p.SetRecords(ctx, "example.com.", sets[0]); err != nil {
+		t.Fatalf("could not replace A records; %v", err)
+	}
+	if err := p.DeleteRecords(ctx, "example.com.", "example.com.", "MX"); err != nil {
+		t.Fatalf("could not delete MX records; %v", err)
+	}
+
+	got, err := p.Records(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("could not list records; %v", err)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Name+got[i].Type < got[j].Name+got[j].Type })
+	expected := []dns_provider.RecordSet{
+		{Name: "_acme-challenge.example.com.", Type: "TXT", TTL: 60, Data: []string{`"some \"token\""`}},
+		{Name: "example.com.", Type: "NS", TTL: 3600, Data: []string{"ns1.example.com."}},
+		{Name: "example.com.", Type: "SOA", TTL: 3600, Data: []string{"ns1.example.com. admin.example.com. 1 7200 3600 1209600 300"}},
+		{Name: "www.example.com.", Type: "A", TTL: 300, Data: []string{"192.0.2.3"}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got records %+v but expected %+v", got, expected)
+	}
+
+	// Updates signed with the wrong key must be refused.
+	bad, err := New(Config{
+		Nameserver:  srv.addr,
+		Zones:       []string{"example.com."},
+		TSIGKeyName: "update-key",
+		TSIGSecret:  base64.StdEncoding.EncodeToString([]byte("wrong")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bad.DeleteRecords(ctx, "example.com.", "www.example.com.", "A")
+	if err == nil || !strings.Contains(err.Error(), "NOTAUTH") {
+		t.Errorf("got error %v for update with the wrong key", err)
+	}
+}
+
+func TestRdata(t *testing.T) {
+	cases := []struct {
+		rtype uint16
+		in    string
+		out   string // The expected presentation format after decoding; blank if the same as in.
+	}{
+		{typeA, "192.0.2.1", ""},
+		{typeAAAA, "2001:db8::1", ""},
+		{typeCNAME, "target.example.com", "target.example.com."},
+		{typeMX, "10 mail.example.com.", ""},
+		{typeSRV, "0 5 5060 sip.example.com.", ""},
+		{typeTXT, `"v=spf1 -all"`, ""},
+		{typeTXT, `"a" "b\\c"`, ""},
+		{typeTXT, "unquoted", `"unquoted"`},
+		{typeSOA, "ns1.example.com. admin.example.com. 1 2 3 4 5", ""},
+		{99, `\# 3 abcdef`, ""},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			b, err := packRdata(tc.rtype, tc.in)
+			if err != nil {
+				t.Fatalf("could not pack; %v", err)
+			}
+			got, err := unpackRdata(b, tc.rtype, 0, len(b))
+			if err != nil {
+				t.Fatalf("could not unpack; %v", err)
+			}
+			expected := tc.out
+			if expected == "" {
+				expected = tc.in
+			}
+			if got != expected {
+				t.Errorf("got %q but expected %q", got, expected)
+			}
+		})
+	}
+}
+
+func TestUnpackCompressedName(t *testing.T) {
+	// The name "example.com." at offset 0, followed by "www" and a pointer to offset 0.
+	msg := []byte("\x07example\x03com\x00\x03www\xc0\x00")
+	name, next, err := unpackName(msg, 13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "www.example.com." || next != len(msg) {
+		t.Errorf("got name %q and next offset %d", name, next)
+	}
+
+	// A pointer to itself must not loop forever.
+	if _, _, err := unpackName([]byte("\xc0\x00"), 0); err != errBadPointer {
+		t.Errorf("got error %v for pointer loop", err)
+	}
+}
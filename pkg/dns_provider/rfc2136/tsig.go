@@ -0,0 +1,117 @@
+package rfc2136
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+// The TSIG algorithms supported, as named in RFC 8945.
+const (
+	HmacMD5    = "hmac-md5.sig-alg.reg.int."
+	HmacSHA1   = "hmac-sha1."
+	HmacSHA256 = "hmac-sha256."
+	HmacSHA512 = "hmac-sha512."
+)
+
+// tsigFudge is the number of seconds of clock skew permitted by the server when checking signatures.
+const tsigFudge = 300
+
+var tsigHashes = map[string]func() hash.Hash{
+	HmacMD5:    md5.New,
+	HmacSHA1:   sha1.New,
+	HmacSHA256: sha256.New,
+	HmacSHA512: sha512.New,
+}
+
+// A tsigKey is a shared secret with which messages are signed as defined in RFC 8945.
+type tsigKey struct {
+	name      string
+	algorithm string
+	secret    []byte
+}
+
+func newTSIGKey(name, algorithm string, secret []byte) (*tsigKey, error) {
+	if algorithm == "" {
+		algorithm = HmacSHA256
+	}
+	algorithm = strings.ToLower(algorithm)
+	if !strings.HasSuffix(algorithm, ".") {
+		algorithm += "."
+	}
+	if _, ok := tsigHashes[algorithm]; !ok {
+		return nil, fmt.Errorf("rfc2136: unsupported TSIG algorithm %q", algorithm)
+	}
+	if name == "" || len(secret) == 0 {
+		return nil, errors.New("rfc2136: a TSIG key must have a name and a secret")
+	}
+	return &tsigKey{name: strings.ToLower(name), algorithm: algorithm, secret: secret}, nil
+}
+
+// sign appends a TSIG record to the packed message msg, which must not already contain one.
+func (k *tsigKey) sign(msg []byte, now time.Time) ([]byte, error) {
+	if len(msg) < headerLen {
+		return nil, errShortMessage
+	}
+	mac, err := k.mac(msg, now)
+	if err != nil {
+		return nil, err
+	}
+
+	rdata, err := packName(nil, k.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	rdata = appendTime48(rdata, now)
+	rdata = appendUint16(rdata, tsigFudge)
+	rdata = appendUint16(rdata, uint16(len(mac)))
+	rdata = append(rdata, mac...)
+	rdata = append(rdata, msg[0], msg[1]) // The original ID.
+	rdata = appendUint16(rdata, 0)        // Error
+	rdata = appendUint16(rdata, 0)        // Other Len
+
+	rr := resource{name: k.name, rtype: typeTSIG, class: classANY, rdata: rdata}
+	signed, err := rr.pack(append(make([]byte, 0, len(msg)+len(rdata)+len(k.name)+12), msg...))
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(msg[10:])+1)
+	return signed, nil
+}
+
+// mac computes the MAC of the packed message msg, which does not include the TSIG record, signed at
+// the given time.
+func (k *tsigKey) mac(msg []byte, signed time.Time) ([]byte, error) {
+	h := hmac.New(tsigHashes[k.algorithm], k.secret)
+	h.Write(msg)
+
+	vars, err := packName(nil, k.name)
+	if err != nil {
+		return nil, err
+	}
+	vars = appendUint16(vars, classANY)
+	vars = appendUint32(vars, 0) // TTL
+	if vars, err = packName(vars, k.algorithm); err != nil {
+		return nil, err
+	}
+	vars = appendTime48(vars, signed)
+	vars = appendUint16(vars, tsigFudge)
+	vars = appendUint16(vars, 0) // Error
+	vars = appendUint16(vars, 0) // Other Len
+	h.Write(vars)
+
+	return h.Sum(nil), nil
+}
+
+func appendTime48(b []byte, t time.Time) []byte {
+	s := uint64(t.Unix())
+	return append(b, byte(s>>40), byte(s>>32), byte(s>>24), byte(s>>16), byte(s>>8), byte(s))
+}
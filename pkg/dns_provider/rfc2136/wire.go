@@ -0,0 +1,543 @@
+package rfc2136
+
+// This file contains a minimal encoder and decoder of DNS messages in the wire format defined in
+// RFC 1035, covering just what is needed to send dynamic updates and read zone transfers.
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	typeA     uint16 = 1
+	typeNS    uint16 = 2
+	typeCNAME uint16 = 5
+	typeSOA   uint16 = 6
+	typePTR   uint16 = 12
+	typeMX    uint16 = 15
+	typeTXT   uint16 = 16
+	typeAAAA  uint16 = 28
+	typeSRV   uint16 = 33
+	typeTSIG  uint16 = 250
+	typeAXFR  uint16 = 252
+
+	classINET uint16 = 1
+	classNONE uint16 = 254
+	classANY  uint16 = 255
+
+	opcodeQuery  = 0
+	opcodeUpdate = 5
+
+	headerLen = 12
+)
+
+// typeNames maps the record types that can be encoded and decoded in the presentation format to
+// their names.
+var typeNames = map[uint16]string{
+	typeA:     "A",
+	typeNS:    "NS",
+	typeCNAME: "CNAME",
+	typeSOA:   "SOA",
+	typePTR:   "PTR",
+	typeMX:    "MX",
+	typeTXT:   "TXT",
+	typeAAAA:  "AAAA",
+	typeSRV:   "SRV",
+}
+
+// rcodeNames maps the response codes defined in RFC 1035 and RFC 2136 to their names.
+var rcodeNames = map[int]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+// typeValue returns the numeric value of the named record type.
+func typeValue(name string) (uint16, bool) {
+	name = strings.ToUpper(name)
+	for v, n := range typeNames {
+		if n == name {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// A message is a DNS message. For UPDATE messages, the sections are named differently in RFC 2136 but
+// are laid out the same way: the questions are the zone section, the answers are the prerequisites,
+// and the authorities are the updates.
+type message struct {
+	id          uint16
+	response    bool
+	opcode      int
+	truncated   bool
+	rcode       int
+	questions   []question
+	answers     []resource
+	authorities []resource
+	additionals []resource
+
+	// raw is the message as it was unpacked, if it was.
+	raw []byte
+}
+
+type question struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+}
+
+// A resource is a resource record with its RDATA in the wire format.
+type resource struct {
+	name  string
+	rtype uint16
+	class uint16
+	ttl   uint32
+	rdata []byte
+
+	// offset is the offset of rdata in the raw message it was unpacked from.
+	offset int
+}
+
+var (
+	errShortMessage = errors.New("rfc2136: message too short")
+	errBadName      = errors.New("rfc2136: invalid domain name")
+	errBadPointer   = errors.New("rfc2136: invalid name compression pointer")
+)
+
+// pack encodes the message without using name compression.
+func (m *message) pack() ([]byte, error) {
+	b := make([]byte, headerLen, 512)
+	binary.BigEndian.PutUint16(b[0:], m.id)
+	bits := uint16(m.opcode&0xF)<<11 | uint16(m.rcode&0xF)
+	if m.response {
+		bits |= 1 << 15
+	}
+	if m.truncated {
+		bits |= 1 << 9
+	}
+	binary.BigEndian.PutUint16(b[2:], bits)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.answers)))
+	binary.BigEndian.PutUint16(b[8:], uint16(len(m.authorities)))
+	binary.BigEndian.PutUint16(b[10:], uint16(len(m.additionals)))
+
+	var err error
+	for _, q := range m.questions {
+		if b, err = packName(b, q.name); err != nil {
+			return nil, err
+		}
+		b = appendUint16(b, q.qtype)
+		b = appendUint16(b, q.qclass)
+	}
+	for _, section := range [][]resource{m.answers, m.authorities, m.additionals} {
+		for i := range section {
+			if b, err = section[i].pack(b); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+func (r *resource) pack(b []byte) ([]byte, error) {
+	b, err := packName(b, r.name)
+	if err != nil {
+		return nil, err
+	}
+	b = appendUint16(b, r.rtype)
+	b = appendUint16(b, r.class)
+	b = appendUint32(b, r.ttl)
+	if len(r.rdata) > 0xFFFF {
+		return nil, fmt.Errorf("rfc2136: RDATA of %d bytes is too long", len(r.rdata))
+	}
+	b = appendUint16(b, uint16(len(r.rdata)))
+	return append(b, r.rdata...), nil
+}
+
+// unpackMessage decodes a message. The rdata of each resource is a sub-slice of msg, and domain names
+// within the rdata may be compressed, so msg must not be modified while the message is used.
+func unpackMessage(msg []byte) (*message, error) {
+	if len(msg) < headerLen {
+		return nil, errShortMessage
+	}
+	bits := binary.BigEndian.Uint16(msg[2:])
+	m := &message{
+		id:        binary.BigEndian.Uint16(msg[0:]),
+		response:  bits&(1<<15) != 0,
+		opcode:    int(bits>>11) & 0xF,
+		truncated: bits&(1<<9) != 0,
+		rcode:     int(bits & 0xF),
+		raw:       msg,
+	}
+	counts := [4]int{
+		int(binary.BigEndian.Uint16(msg[4:])),
+		int(binary.BigEndian.Uint16(msg[6:])),
+		int(binary.BigEndian.Uint16(msg[8:])),
+		int(binary.BigEndian.Uint16(msg[10:])),
+	}
+
+	off := headerLen
+	m.questions = make([]question, 0, counts[0])
+	for i := 0; i < counts[0]; i++ {
+		var q question
+		var err error
+		q.name, off, err = unpackName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		if off+4 > len(msg) {
+			return nil, errShortMessage
+		}
+		q.qtype = binary.BigEndian.Uint16(msg[off:])
+		q.qclass = binary.BigEndian.Uint16(msg[off+2:])
+		off += 4
+		m.questions = append(m.questions, q)
+	}
+
+	sections := [3]*[]resource{&m.answers, &m.authorities, &m.additionals}
+	for s, section := range sections {
+		*section = make([]resource, 0, counts[s+1])
+		for i := 0; i < counts[s+1]; i++ {
+			var r resource
+			var err error
+			r.name, off, err = unpackName(msg, off)
+			if err != nil {
+				return nil, err
+			}
+			if off+10 > len(msg) {
+				return nil, errShortMessage
+			}
+			r.rtype = binary.BigEndian.Uint16(msg[off:])
+			r.class = binary.BigEndian.Uint16(msg[off+2:])
+			r.ttl = binary.BigEndian.Uint32(msg[off+4:])
+			length := int(binary.BigEndian.Uint16(msg[off+8:]))
+			off += 10
+			if off+length > len(msg) {
+				return nil, errShortMessage
+			}
+			r.rdata = msg[off : off+length]
+			r.offset = off
+			off += length
+			*section = append(*section, r)
+		}
+	}
+	return m, nil
+}
+
+// packName appends the name to b in the wire format. The name is taken to be fully qualified whether
+// or not it ends with a dot.
+func packName(b []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return append(b, 0), nil
+	}
+	if len(name) > 253 {
+		return nil, errBadName
+	}
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, errBadName
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0), nil
+}
+
+// unpackName reads a possibly compressed name beginning at off in msg. The name returned is fully
+// qualified, and the int is the offset just past the name where it began.
+func unpackName(msg []byte, off int) (string, int, error) {
+	var name strings.Builder
+	end := -1 // The offset after the name in its original position, once a pointer is followed.
+	jumps := 0
+	for {
+		if off >= len(msg) {
+			return "", 0, errShortMessage
+		}
+		c := int(msg[off])
+		switch c & 0xC0 {
+		case 0x00:
+			if c == 0 {
+				off++
+				if end < 0 {
+					end = off
+				}
+				if name.Len() == 0 {
+					return ".", end, nil
+				}
+				return name.String(), end, nil
+			}
+			if off+1+c > len(msg) {
+				return "", 0, errShortMessage
+			}
+			name.Write(msg[off+1 : off+1+c])
+			name.WriteByte('.')
+			off += 1 + c
+		case 0xC0:
+			if off+2 > len(msg) {
+				return "", 0, errShortMessage
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			jumps++
+			if jumps > 32 {
+				return "", 0, errBadPointer
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+		default:
+			return "", 0, errBadName
+		}
+	}
+}
+
+// packRdata encodes the data of a record given in the presentation format.
+func packRdata(rtype uint16, data string) ([]byte, error) {
+	data = strings.TrimSpace(data)
+	if strings.HasPrefix(data, `\#`) {
+		return unknownRdata(data)
+	}
+	fields := strings.Fields(data)
+	switch rtype {
+	case typeA:
+		ip := net.ParseIP(data).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("rfc2136: invalid IPv4 address %q", data)
+		}
+		return []byte(ip), nil
+	case typeAAAA:
+		ip := net.ParseIP(data)
+		if ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("rfc2136: invalid IPv6 address %q", data)
+		}
+		return []byte(ip.To16()), nil
+	case typeNS, typeCNAME, typePTR:
+		return packName(nil, data)
+	case typeMX:
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("rfc2136: invalid MX data %q", data)
+		}
+		pref, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("rfc2136: invalid MX preference in %q", data)
+		}
+		return packName(appendUint16(nil, uint16(pref)), fields[1])
+	case typeSRV:
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("rfc2136: invalid SRV data %q", data)
+		}
+		b := make([]byte, 0, 6+len(fields[3])+2)
+		for _, f := range fields[:3] {
+			n, err := strconv.ParseUint(f, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("rfc2136: invalid SRV data %q", data)
+			}
+			b = appendUint16(b, uint16(n))
+		}
+		return packName(b, fields[3])
+	case typeTXT:
+		strs, err := splitTXT(data)
+		if err != nil {
+			return nil, err
+		}
+		var b []byte
+		for _, s := range strs {
+			if len(s) > 255 {
+				return nil, fmt.Errorf("rfc2136: TXT string longer than 255 bytes")
+			}
+			b = append(b, byte(len(s)))
+			b = append(b, s...)
+		}
+		return b, nil
+	case typeSOA:
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("rfc2136: invalid SOA data %q", data)
+		}
+		b, err := packName(nil, fields[0])
+		if err != nil {
+			return nil, err
+		}
+		if b, err = packName(b, fields[1]); err != nil {
+			return nil, err
+		}
+		for _, f := range fields[2:] {
+			n, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("rfc2136: invalid SOA data %q", data)
+			}
+			b = appendUint32(b, uint32(n))
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf(`rfc2136: records of type %d must be given in the \# format`, rtype)
+	}
+}
+
+// unpackRdata decodes the data of a record that begins at off in msg and has the length given into
+// the presentation format. Record types not known by this package are given in the generic format
+// defined in RFC 3597.
+func unpackRdata(msg []byte, rtype uint16, off, length int) (string, error) {
+	if off+length > len(msg) {
+		return "", errShortMessage
+	}
+	rdata := msg[off : off+length]
+	switch rtype {
+	case typeA:
+		if length != net.IPv4len {
+			return "", fmt.Errorf("rfc2136: A record with %d bytes", length)
+		}
+		return net.IP(rdata).String(), nil
+	case typeAAAA:
+		if length != net.IPv6len {
+			return "", fmt.Errorf("rfc2136: AAAA record with %d bytes", length)
+		}
+		return net.IP(rdata).String(), nil
+	case typeNS, typeCNAME, typePTR:
+		name, _, err := unpackName(msg, off)
+		return name, err
+	case typeMX:
+		if length < 3 {
+			return "", errShortMessage
+		}
+		name, _, err := unpackName(msg, off+2)
+		return strconv.Itoa(int(binary.BigEndian.Uint16(rdata))) + " " + name, err
+	case typeSRV:
+		if length < 7 {
+			return "", errShortMessage
+		}
+		name, _, err := unpackName(msg, off+6)
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(rdata), binary.BigEndian.Uint16(rdata[2:]),
+			binary.BigEndian.Uint16(rdata[4:]), name), err
+	case typeTXT:
+		strs := make([]string, 0, 1)
+		for i := 0; i < len(rdata); {
+			n := int(rdata[i])
+			if i+1+n > len(rdata) {
+				return "", errShortMessage
+			}
+			strs = append(strs, quoteTXT(string(rdata[i+1:i+1+n])))
+			i += 1 + n
+		}
+		return strings.Join(strs, " "), nil
+	case typeSOA:
+		mname, next, err := unpackName(msg, off)
+		if err != nil {
+			return "", err
+		}
+		rname, next, err := unpackName(msg, next)
+		if err != nil {
+			return "", err
+		}
+		if next+20 > off+length {
+			return "", errShortMessage
+		}
+		nums := make([]string, 5)
+		for i := range nums {
+			nums[i] = strconv.FormatUint(uint64(binary.BigEndian.Uint32(msg[next+4*i:])), 10)
+		}
+		return mname + " " + rname + " " + strings.Join(nums, " "), nil
+	default:
+		return `\# ` + strconv.Itoa(length) + " " + hex.EncodeToString(rdata), nil
+	}
+}
+
+// unknownRdata decodes data given in the generic format defined in RFC 3597, such as `\# 2 abcd`.
+func unknownRdata(data string) ([]byte, error) {
+	fields := strings.Fields(data)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf(`rfc2136: invalid \# data %q`, data)
+	}
+	length, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf(`rfc2136: invalid \# data length in %q`, data)
+	}
+	b, err := hex.DecodeString(strings.Join(fields[2:], ""))
+	if err != nil || len(b) != length {
+		return nil, fmt.Errorf(`rfc2136: invalid \# data %q`, data)
+	}
+	return b, nil
+}
+
+// splitTXT splits the data of a TXT record in the presentation format into its strings, which may be
+// quoted or not. Within quoted strings, a backslash escapes the next character.
+func splitTXT(data string) ([]string, error) {
+	var strs []string
+	for i := 0; i < len(data); {
+		switch c := data[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			var s strings.Builder
+			i++
+			closed := false
+			for i < len(data) && !closed {
+				switch data[i] {
+				case '\\':
+					if i+1 >= len(data) {
+						return nil, fmt.Errorf("rfc2136: invalid escape in TXT data %q", data)
+					}
+					s.WriteByte(data[i+1])
+					i += 2
+				case '"':
+					closed = true
+					i++
+				default:
+					s.WriteByte(data[i])
+					i++
+				}
+			}
+			if !closed {
+				return nil, fmt.Errorf("rfc2136: unterminated string in TXT data %q", data)
+			}
+			strs = append(strs, s.String())
+		default:
+			end := strings.IndexAny(data[i:], " \t")
+			if end < 0 {
+				end = len(data) - i
+			}
+			strs = append(strs, data[i:i+end])
+			i += end
+		}
+	}
+	if len(strs) == 0 {
+		strs = append(strs, "")
+	}
+	return strs, nil
+}
+
+// quoteTXT returns the string quoted for the presentation format of a TXT record.
+func quoteTXT(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
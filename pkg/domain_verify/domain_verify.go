@@ -0,0 +1,206 @@
+// Package domain_verify checks that the person who adds a domain to a site controls the domain.
+//
+// Each domain is given a random token, which must be published either in a TXT record named by
+// RecordName or in a file at WellKnownPath on the domain served over HTTP.
+package domain_verify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// recordPrefix is the label prepended to a domain to make the name of the TXT record.
+	recordPrefix = "_mazewire-verify."
+
+	// valuePrefix is the prefix of the value of the TXT record, followed by the token.
+	valuePrefix = "mazewire-verify="
+
+	// WellKnownPath is the path at which the token is served over HTTP.
+	WellKnownPath = "/.well-known/mazewire-verify"
+)
+
+// The methods by which a domain may be verified.
+const (
+	MethodDNS  = "dns"
+	MethodHTTP = "http"
+)
+
+// ErrNotVerified indicates that the token was not found published for the domain.
+var ErrNotVerified = errors.New("domain_verify: the verification token was not found")
+
+// NewToken generates a random verification token.
+func NewToken() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// RecordName returns the name of the TXT record in which the token must be published for the domain.
+func RecordName(domain string) string {
+	return recordPrefix + strings.TrimSuffix(domain, ".")
+}
+
+// RecordValue returns the value of the TXT record that publishes the token.
+func RecordValue(token string) string {
+	return valuePrefix + token
+}
+
+// A Verifier checks whether tokens are published for domains.
+type Verifier struct {
+	// Resolver is used to look up TXT records. If nil, net.DefaultResolver is used.
+	Resolver *net.Resolver
+
+	// Client is used to fetch the well-known file. If nil, a client with a short timeout that does
+	// not follow redirects to other hosts is used.
+	Client *http.Client
+}
+
+// NewVerifier creates a Verifier that looks up records with the DNS resolver at the address given
+// as "host:port", or with the system's resolver if the address is blank.
+func NewVerifier(resolverAddr string) *Verifier {
+	v := new(Verifier)
+	if resolverAddr != "" {
+		v.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, resolverAddr)
+			},
+		}
+	}
+	return v
+}
+
+// Verify checks whether the token is published for the domain by either method, returning the
+// method by which it is. If the token is not found, the error is ErrNotVerified.
+func (v *Verifier) Verify(ctx context.Context, domain, token string) (string, error) {
+	errDNS := v.CheckTXT(ctx, domain, token)
+	if errDNS == nil {
+		return MethodDNS, nil
+	}
+	errHTTP := v.CheckHTTP(ctx, domain, token)
+	if errHTTP == nil {
+		return MethodHTTP, nil
+	}
+	if errDNS != ErrNotVerified {
+		return "", errDNS
+	}
+	return "", errHTTP
+}
+
+// CheckTXT checks whether the token is published in the TXT record for the domain.
+func (v *Verifier) CheckTXT(ctx context.Context, domain, token string) error {
+	r := v.Resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	values, err := r.LookupTXT(ctx, RecordName(domain))
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && (dnsErr.IsNotFound || !dnsErr.Temporary()) {
+			return ErrNotVerified
+		}
+		return err
+	}
+	want := RecordValue(token)
+	for _, val := range values {
+		if strings.TrimSpace(val) == want {
+			return nil
+		}
+	}
+	return ErrNotVerified
+}
+
+// CheckHTTP checks whether the token is served in the well-known file on the domain over HTTP.
+// Only the first line of the file is considered. Domains that are IP addresses or have a port are not
+// checked, and the default client connects only to public addresses, so that the check cannot be used
+// to make requests to the servers' own network.
+func (v *Verifier) CheckHTTP(ctx context.Context, domain, token string) error {
+	host := strings.TrimSuffix(domain, ".")
+	if host == "" || strings.ContainsAny(host, ":/[]@") || net.ParseIP(host) != nil {
+		return ErrNotVerified
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://"+host+WellKnownPath, nil)
+	if err != nil {
+		return err
+	}
+	client := v.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		// The domain may not be pointed at any server yet.
+		return ErrNotVerified
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ErrNotVerified
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	if err != nil {
+		return ErrNotVerified
+	}
+	line := string(body)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	if strings.TrimSpace(line) != token {
+		return ErrNotVerified
+	}
+	return nil
+}
+
+// errAddressNotAllowed indicates that a domain resolved to an address that is not public.
+var errAddressNotAllowed = errors.New("domain_verify: the domain resolves to an address that is not public")
+
+// publicAddr says if the IP address is a public unicast address, which is not a loopback, private,
+// link-local, or unspecified address.
+func publicAddr(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// dialPublic dials only public addresses. The address is checked after the domain is resolved, so
+// that a domain resolving to an internal address is refused however the lookup is answered.
+var dialPublic = (&net.Dialer{
+	Timeout: 5 * time.Second,
+	Control: func(_, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !publicAddr(ip) {
+			return errAddressNotAllowed
+		}
+		return nil
+	},
+}).DialContext
+
+var defaultClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		Proxy:               nil, // A proxy would make the connection on behalf of the check.
+		DialContext:         dialPublic,
+		TLSHandshakeTimeout: 5 * time.Second,
+		DisableKeepAlives:   true,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		// Allow only redirects within the same host, such as to HTTPS.
+		if len(via) >= 3 || req.URL.Hostname() != via[0].URL.Hostname() {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	},
+}
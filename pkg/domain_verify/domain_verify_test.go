@@ -0,0 +1,182 @@
+package domain_verify
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveTXT answers queries for TXT records with the records given, keyed by the fully qualified
+// name, on a local UDP port. It returns the address of the server.
+func serveTXT(t *testing.T, records map[string][]string) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		b := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			var p dnsmessage.Parser
+			h, err := p.Start(b[:n])
+			if err != nil {
+				continue
+			}
+			q, err := p.Question()
+			if err != nil {
+				continue
+			}
+			resp := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true})
+			resp.StartQuestions()
+			resp.Question(q)
+			resp.StartAnswers()
+			values, ok := records[strings.ToLower(q.Name.String())]
+			if q.Type == dnsmessage.TypeTXT {
+				for _, v := range values {
+					resp.TXTResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60},
+						dnsmessage.TXTResource{TXT: []string{v}})
+				}
+			}
+			out, err := resp.Finish()
+			if err != nil {
+				continue
+			}
+			if !ok {
+				out[3] |= 3 // NXDOMAIN
+			}
+			pc.WriteTo(out, addr)
+		}
+	}()
+	t.Cleanup(func() { pc.Close() })
+	return pc.LocalAddr().String()
+}
+
+func TestCheckTXT(t *testing.T) {
+	addr := serveTXT(t, map[string][]string{
+		"_mazewire-verify.example.com.": {"something else", RecordValue("abc123")},
+		"_mazewire-verify.example.org.": {RecordValue("other")},
+	})
+	v := NewVerifier(addr)
+	cases := []struct {
+		domain, token string
+		err           error
+	}{
+		{"example.com", "abc123", nil},
+		{"example.com.", "abc123", nil},
+		{"example.org", "abc123", ErrNotVerified},
+		{"example.net", "abc123", ErrNotVerified},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if err := v.CheckTXT(context.Background(), tc.domain, tc.token); err != tc.err {
+				t.Errorf("got error %v but expected %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestCheckHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != WellKnownPath {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Host {
+		case "example.com":
+			w.Write([]byte("abc123\n"))
+		case "redirect.example.com":
+			http.Redirect(w, r, "http://example.com"+WellKnownPath, http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	// Send requests for every host to the test server.
+	v := &Verifier{Client: &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, srv.Listener.Addr().String())
+			},
+		},
+		CheckRedirect: defaultClient.CheckRedirect,
+	}}
+
+	cases := []struct {
+		domain, token string
+		err           error
+	}{
+		{"example.com", "abc123", nil},
+		{"example.com", "abc", ErrNotVerified},
+		{"example.org", "abc123", ErrNotVerified},
+		{"redirect.example.com", "abc123", ErrNotVerified}, // Redirects to other hosts are not followed.
+		{"127.0.0.1", "abc123", ErrNotVerified},            // IP addresses are not checked.
+		{"[::1]", "abc123", ErrNotVerified},
+		{"example.com:8080", "abc123", ErrNotVerified},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if err := v.CheckHTTP(context.Background(), tc.domain, tc.token); err != tc.err {
+				t.Errorf("got error %v but expected %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	cases := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // cloud metadata servers
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := publicAddr(net.ParseIP(tc.ip)); got != tc.public {
+				t.Errorf("got %v for %s", got, tc.ip)
+			}
+		})
+	}
+}
+
+func TestDialPublic(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	// The test server listens on a loopback address.
+	if _, err := dialPublic(context.Background(), "tcp", srv.Listener.Addr().String()); err == nil ||
+		!strings.Contains(err.Error(), errAddressNotAllowed.Error()) {
+		t.Errorf("got error %v dialing a loopback address", err)
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	a, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewToken()
+	if len(a) != 40 || a == b {
+		t.Errorf("got tokens %q and %q", a, b)
+	}
+}
@@ -56,12 +56,69 @@ const rateLimitSleep = 600 * time.Millisecond
 
 // sgMailFromEmail constructs a SGMailV3 out of an email.Email.
 func sgMailFromEmail(e *email.Email) *mail.SGMailV3 {
-	m := mail.SGMailV3{
-		From:    &mail.Email{Name: e.From.Name, Address: e.From.Address},
-		Subject: e.Subject,
-		// TODO
+	m := mail.NewV3MailInit(
+		&mail.Email{Name: e.From.Name, Address: e.From.Address},
+		e.Subject,
+		&mail.Email{Name: e.To.Name, Address: e.To.Address},
+	)
+	if e.ReplyTo.Address != "" {
+		m.SetReplyTo(&mail.Email{Name: e.ReplyTo.Name, Address: e.ReplyTo.Address})
+	}
+	for i := range e.Contents {
+		m.AddContent(mail.NewContent(e.Contents[i].Type, e.Contents[i].Value))
+	}
+	return m
+}
+
+// validate says why the email cannot be sent, if it cannot.
+func validate(e *email.Email) error {
+	switch {
+	case e.To.Address == "":
+		return errors.New("sendgrid: no recipient")
+	case e.From.Address == "":
+		return errors.New("sendgrid: no sender")
+	case len(e.Contents) == 0:
+		return errors.New("sendgrid: no contents")
+	}
+	return nil
+}
+
+// Send implements email.Sender. The email is sent in a new goroutine, and a failure to send it is logged.
+func (s *Sender) Send(e *email.Email) error {
+	if err := validate(e); err != nil {
+		return err
+	}
+	m := sgMailFromEmail(e)
+	for k, v := range s.headers {
+		m.SetHeader(k, v)
+	}
+	go s.send(m)
+	return nil
+}
+
+// SendMulti implements email.Sender. None of the emails is sent if any of them is not valid.
+func (s *Sender) SendMulti(es []email.Email) error {
+	for i := range es {
+		if err := validate(&es[i]); err != nil {
+			return err
+		}
+	}
+	for i := range es {
+		s.Send(&es[i])
+	}
+	return nil
+}
+
+// send sends the email synchronously.
+func (s *Sender) send(m *mail.SGMailV3) {
+	req := newSendReq(s.apiKey)
+	resp, err := makeRequestRetry(context.Background(), &req, m)
+	if err == nil && !statusOK(resp.StatusCode) {
+		err = fmt.Errorf("status %d: %s", resp.StatusCode, resp.Body)
+	}
+	if err != nil {
+		log.Err(nil, "sendgrid: could not send email", err)
 	}
-	return &m
 }
 
 // makeRequestRetry makes a synchronous request but retries up to three times in the event of a rate limit.
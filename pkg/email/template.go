@@ -0,0 +1,58 @@
+package email
+
+import (
+	"bytes"
+	"net/mail"
+	"strings"
+	"text/template"
+
+	"github.com/dchenk/mazewire/pkg/util"
+)
+
+// A Template produces emails of one kind, such as the emails with password reset links, out of a
+// subject and a plain text body written in the text/template syntax.
+type Template struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// NewTemplate parses the subject and the body of a Template. The name is used in error messages.
+func NewTemplate(name, subject, body string) (*Template, error) {
+	st, err := template.New(name + " subject").Option("missingkey=error").Parse(subject)
+	if err != nil {
+		return nil, err
+	}
+	bt, err := template.New(name + " body").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{subject: st, body: bt}, nil
+}
+
+// Execute creates an Email to the recipient given with the template executed with the data. Line
+// breaks in the subject are replaced with spaces. The sender is not set.
+func (t *Template) Execute(to Party, data interface{}) (*Email, error) {
+	var b bytes.Buffer
+	if err := t.subject.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	subject := strings.Join(strings.Fields(b.String()), " ")
+	b.Reset()
+	if err := t.body.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return &Email{
+		To:       to,
+		Subject:  subject,
+		Contents: []Content{{Type: util.ContentTypeTextPlain, Value: b.String()}},
+	}, nil
+}
+
+// ParseParty parses an address like "Jane Doe <jane@example.com>" or "jane@example.com".
+func ParseParty(s string) (Party, error) {
+	a, err := mail.ParseAddress(s)
+	if err != nil {
+		return Party{}, err
+	}
+	return Party{Name: a.Name, Address: a.Address}, nil
+}
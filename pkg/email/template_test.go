@@ -0,0 +1,84 @@
+package email
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestTemplate_Execute(t *testing.T) {
+
+	cases := []struct {
+		subject, body string
+		data          interface{}
+		wantSubject   string
+		wantBody      string
+		wantErr       bool
+	}{
+		{"Hello", "Plain body.", nil, "Hello", "Plain body.", false},
+		{
+			subject:     "Reset your password on {{.Site}}",
+			body:        "Hi {{.Name}},\n\nFollow this link: {{.Link}}\n",
+			data:        map[string]string{"Site": "example.com", "Name": "Jane", "Link": "https://example.com/r?t=a&b"},
+			wantSubject: "Reset your password on example.com",
+			wantBody:    "Hi Jane,\n\nFollow this link: https://example.com/r?t=a&b\n",
+		},
+		{"{{.S}}", "", map[string]string{"S": "Line one\r\nBcc: someone@example.com"}, "Line one Bcc: someone@example.com", "", false},
+		{"{{.Missing}}", "", map[string]string{}, "", "", true},
+	}
+
+	to := Party{Name: "Jane", Address: "jane@example.com"}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			tmpl, err := NewTemplate("test", tc.subject, tc.body)
+			if err != nil {
+				t.Fatalf("could not parse template; %v", err)
+			}
+			em, err := tmpl.Execute(to, tc.data)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got an error; %v", err)
+			}
+			if em.To != to {
+				t.Errorf("got recipient %v", em.To)
+			}
+			if em.Subject != tc.wantSubject {
+				t.Errorf("got subject %q", em.Subject)
+			}
+			if len(em.Contents) != 1 || em.Contents[0].Value != tc.wantBody {
+				t.Errorf("got contents %v", em.Contents)
+			}
+		})
+	}
+
+}
+
+func TestParseParty(t *testing.T) {
+
+	cases := []struct {
+		s     string
+		party Party
+		ok    bool
+	}{
+		{"jane@example.com", Party{Address: "jane@example.com"}, true},
+		{"Jane Doe <jane@example.com>", Party{"Jane Doe", "jane@example.com"}, true},
+		{"not an address", Party{}, false},
+	}
+
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			p, err := ParseParty(tc.s)
+			if (err == nil) != tc.ok {
+				t.Fatalf("got error %v", err)
+			}
+			if p != tc.party {
+				t.Errorf("got %v", p)
+			}
+		})
+	}
+
+}
@@ -23,6 +23,31 @@ const (
 	VarChangeTokenKey = "CHANGE_TOKEN_KEY"
 	VarGcpProject     = "GCP_PROJECT"
 
+	// Variables configuring the DNS provider.
+	VarDnsProvider      = "DNS_PROVIDER"
+	VarDnsNameserver    = "DNS_RFC2136_NAMESERVER"
+	VarDnsZones         = "DNS_RFC2136_ZONES"
+	VarDnsTsigKey       = "DNS_RFC2136_TSIG_KEY"
+	VarDnsTsigSecret    = "DNS_RFC2136_TSIG_SECRET"
+	VarDnsTsigAlgorithm = "DNS_RFC2136_TSIG_ALGORITHM"
+
+	// VarDomainVerifyResolver is the DNS resolver used to verify the domains of sites.
+	VarDomainVerifyResolver = "DOMAIN_VERIFY_RESOLVER"
+
+	// VarOpenAPISpec is the file of the OpenAPI specification against which API requests are validated.
+	VarOpenAPISpec = "OPENAPI_SPEC"
+
+	// Variables configuring how emails are sent.
+	VarSendgridAPIKey = "SENDGRID_API_KEY"
+	VarEmailFrom      = "EMAIL_FROM"
+
+	// VarPasswordHashParams gives the cost parameters of the password hashes.
+	VarPasswordHashParams = "PASSWORD_HASH_PARAMS"
+
+	// Variables giving the Cloud Storage buckets in which files are stored.
+	VarContentBucket = "CONTENT_BUCKET"
+	VarExportsBucket = "EXPORTS_BUCKET"
+
 	// Variables used to initialize the cluster.
 	VarInit         = "INIT"
 	VarTokenKey     = "TOKEN_KEY"
@@ -39,7 +64,10 @@ const (
 
 var requiredVars = []string{VarDbConnection, VarDbName}
 
-var standardVars = append(requiredVars, VarDbParams, VarPluginsDir, VarChangeTokenKey, VarGcpProject)
+var standardVars = append(requiredVars, VarDbParams, VarPluginsDir, VarChangeTokenKey, VarGcpProject,
+	VarDnsProvider, VarDnsNameserver, VarDnsZones, VarDnsTsigKey, VarDnsTsigSecret, VarDnsTsigAlgorithm,
+	VarDomainVerifyResolver, VarOpenAPISpec, VarSendgridAPIKey, VarEmailFrom, VarPasswordHashParams,
+	VarContentBucket, VarExportsBucket)
 
 var initVars = []string{VarInit, VarTokenKey, VarAdminUIRoot, VarAdminSrcRoot, VarRootUser, VarRootEmail, VarRootPass}
 
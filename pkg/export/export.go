@@ -0,0 +1,56 @@
+// Package export writes the archives in which users receive the personal data stored about them.
+//
+// An archive is a zip file holding the data encoded as JSON in the file data.json, followed by the
+// files of the user, such as uploaded media, under the names given to them.
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DataFile is the name of the file holding the data in an archive.
+const DataFile = "data.json"
+
+// A File is a file included in an archive.
+type File struct {
+	Name string                        // the path of the file within the archive
+	Open func() (io.ReadCloser, error) // opens the contents of the file
+}
+
+// Write writes to w the archive of the data, which is encoded as JSON, and the files. Each file is
+// opened only when it is written.
+func Write(w io.Writer, d interface{}, files []File) error {
+	zw := zip.NewWriter(w)
+	fw, err := zw.Create(DataFile)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "\t")
+	if err = enc.Encode(d); err != nil {
+		return err
+	}
+	for i := range files {
+		if err = writeFile(zw, &files[i]); err != nil {
+			return fmt.Errorf("export: could not write file %q: %v", files[i].Name, err)
+		}
+	}
+	return zw.Close()
+}
+
+func writeFile(zw *zip.Writer, f *File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	fw, err := zw.Create(f.Name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, rc)
+	return err
+}
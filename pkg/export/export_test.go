@@ -0,0 +1,71 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+type testData struct {
+	User  string            `json:"user"`
+	Meta  map[string]string `json:"meta"`
+	Sites []int64           `json:"sites"`
+}
+
+func TestWrite(t *testing.T) {
+	d := &testData{User: "jo", Meta: map[string]string{"bio": "Hello"}, Sites: []int64{1, 3}}
+	files := []File{{Name: "media/1/abc.png", Open: func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("image")), nil
+	}}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, d, files); err != nil {
+		t.Fatalf("got an error; %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("could not read archive; %v", err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != DataFile || zr.File[1].Name != "media/1/abc.png" {
+		t.Fatalf("got unexpected files in archive")
+	}
+
+	var got testData
+	if err = json.Unmarshal(readZipFile(t, zr.File[0]), &got); err != nil {
+		t.Fatalf("could not decode data; %v", err)
+	}
+	if got.User != "jo" || got.Meta["bio"] != "Hello" || len(got.Sites) != 2 || got.Sites[1] != 3 {
+		t.Errorf("got incorrect data: %+v", got)
+	}
+	if s := string(readZipFile(t, zr.File[1])); s != "image" {
+		t.Errorf("got incorrect file contents %q", s)
+	}
+}
+
+func TestWrite_openError(t *testing.T) {
+	files := []File{{Name: "media/1/abc.png", Open: func() (io.ReadCloser, error) {
+		return nil, errors.New("gone")
+	}}}
+	if err := Write(ioutil.Discard, &testData{}, files); err == nil {
+		t.Error("got no error")
+	}
+}
+
+func readZipFile(t *testing.T, f *zip.File) []byte {
+	t.Helper()
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatalf("could not open %s; %v", f.Name, err)
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("could not read %s; %v", f.Name, err)
+	}
+	return b
+}
@@ -31,6 +31,7 @@ const (
 
 	BeforeUserCreate Hook = "before_user_create"
 	UserCreated      Hook = "user_created"
+	UserDeleted      Hook = "user_deleted" // the payload is a payloads.UserDeleted
 
 	BeforeSiteCreate Hook = "before_site_create"
 	SiteCreated      Hook = "site_created"
@@ -76,6 +77,10 @@ const (
 // the data already unmarshalled.
 func ToMessageRequest(hookName Hook, rawData []byte) (proto.Message, error) {
 	switch hookName {
+	case UserDeleted:
+		var msg payloads.UserDeleted
+		err := proto.Unmarshal(rawData, &msg)
+		return &msg, err
 	default:
 		var msg payloads.Custom
 		err := proto.Unmarshal(rawData, &msg)
@@ -60,20 +60,91 @@ func (m *Custom) GetData() []byte {
 	return nil
 }
 
+// UserDeleted is the payload of the user_deleted hook, which is called after a user is deleted.
+type UserDeleted struct {
+	UserId   int64  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Email    string `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	// The ID of the user who requested the deletion: the user deleted or a super user.
+	RequestedBy          int64    `protobuf:"varint,4,opt,name=requested_by,json=requestedBy,proto3" json:"requested_by,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserDeleted) Reset()         { *m = UserDeleted{} }
+func (m *UserDeleted) String() string { return proto.CompactTextString(m) }
+func (*UserDeleted) ProtoMessage()    {}
+func (*UserDeleted) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d4410b8b59b355ba, []int{1}
+}
+
+func (m *UserDeleted) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserDeleted.Unmarshal(m, b)
+}
+func (m *UserDeleted) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserDeleted.Marshal(b, m, deterministic)
+}
+func (m *UserDeleted) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserDeleted.Merge(m, src)
+}
+func (m *UserDeleted) XXX_Size() int {
+	return xxx_messageInfo_UserDeleted.Size(m)
+}
+func (m *UserDeleted) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserDeleted.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserDeleted proto.InternalMessageInfo
+
+func (m *UserDeleted) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *UserDeleted) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *UserDeleted) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *UserDeleted) GetRequestedBy() int64 {
+	if m != nil {
+		return m.RequestedBy
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Custom)(nil), "payloads.Custom")
+	proto.RegisterType((*UserDeleted)(nil), "payloads.UserDeleted")
 }
 
 func init() { proto.RegisterFile("hooks/payloads/payloads.proto", fileDescriptor_d4410b8b59b355ba) }
 
 var fileDescriptor_d4410b8b59b355ba = []byte{
-	// 120 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x92, 0xcd, 0xc8, 0xcf, 0xcf,
-	0x2e, 0xd6, 0x2f, 0x48, 0xac, 0xcc, 0xc9, 0x4f, 0x4c, 0x41, 0x30, 0xf4, 0x0a, 0x8a, 0xf2, 0x4b,
-	0xf2, 0x85, 0x38, 0x60, 0x7c, 0x25, 0x19, 0x2e, 0x36, 0xe7, 0xd2, 0xe2, 0x92, 0xfc, 0x5c, 0x21,
-	0x21, 0x2e, 0x96, 0x94, 0xc4, 0x92, 0x44, 0x09, 0x46, 0x05, 0x46, 0x0d, 0x9e, 0x20, 0x30, 0xdb,
-	0x49, 0x3f, 0x4a, 0x37, 0x3d, 0xb3, 0x24, 0xa3, 0x34, 0x49, 0x2f, 0x39, 0x3f, 0x57, 0x3f, 0x25,
-	0x39, 0x23, 0x35, 0x2f, 0x5b, 0x3f, 0x37, 0xb1, 0x2a, 0xb5, 0x3c, 0xb3, 0x28, 0x55, 0xbf, 0x20,
-	0x3b, 0x5d, 0x1f, 0xd5, 0x9e, 0x24, 0x36, 0xb0, 0xf9, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff,
-	0xf4, 0x76, 0x45, 0xca, 0x80, 0x00, 0x00, 0x00,
+	// 206 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x8f, 0xcf, 0x4b, 0x85, 0x40,
+	0x10, 0x80, 0xb1, 0xf7, 0xb2, 0xd7, 0xe8, 0x69, 0x09, 0x5a, 0xa2, 0xc0, 0x3c, 0x79, 0xc9, 0x3d,
+	0xf4, 0x1f, 0x58, 0x97, 0xae, 0x42, 0x97, 0x2e, 0xb2, 0xba, 0x83, 0x2e, 0xba, 0xae, 0xed, 0x0f,
+	0xc2, 0xfa, 0xe7, 0xc3, 0x8d, 0x8c, 0x6e, 0xdf, 0x37, 0x33, 0x0c, 0x7c, 0x70, 0x37, 0x68, 0x3d,
+	0x5a, 0xb6, 0xf0, 0x75, 0xd2, 0x5c, 0xfc, 0x41, 0xb9, 0x18, 0xed, 0x34, 0x39, 0xfd, 0x7a, 0x7e,
+	0x0b, 0xf1, 0x93, 0xb7, 0x4e, 0x2b, 0x42, 0xe0, 0x28, 0xb8, 0xe3, 0x34, 0xca, 0xa2, 0x22, 0xad,
+	0x03, 0xe7, 0x5f, 0x90, 0xbc, 0x5a, 0x34, 0xcf, 0x38, 0xa1, 0x43, 0x41, 0xae, 0xe1, 0xc2, 0x5b,
+	0x34, 0x8d, 0x14, 0xe1, 0xea, 0x50, 0xc7, 0x9b, 0xbe, 0x08, 0x72, 0x03, 0xa7, 0x8d, 0x66, 0xae,
+	0x90, 0x9e, 0x65, 0x51, 0x71, 0x59, 0xef, 0x4e, 0xae, 0xe0, 0x1c, 0x15, 0x97, 0x13, 0x3d, 0x84,
+	0xc5, 0x8f, 0x90, 0x7b, 0x48, 0x0d, 0xbe, 0x7b, 0xb4, 0x0e, 0x45, 0xd3, 0xae, 0xf4, 0x18, 0xfe,
+	0x25, 0xfb, 0xac, 0x5a, 0x2b, 0xf6, 0xf6, 0xd0, 0x4b, 0x37, 0xf8, 0xb6, 0xec, 0xb4, 0x62, 0xa2,
+	0x1b, 0x70, 0x1e, 0x99, 0xe2, 0x9f, 0xf8, 0x21, 0x0d, 0xb2, 0x65, 0xec, 0xd9, 0xff, 0xc8, 0x36,
+	0x0e, 0x71, 0x8f, 0xdf, 0x03, 0x00, 0x4a, 0x32, 0xb5, 0xc9, 0xfd, 0x00, 0x00, 0x00,
 }
@@ -0,0 +1,149 @@
+// Package keyring signs tokens with a set of versioned keys so that the keys can be rotated without
+// invalidating at once all of the tokens already given out.
+//
+// A signed token is written "<body>.<key ID>.<signature>", where the signature is the base64url-encoded
+// HMAC-SHA256 of a purpose and the body. The purpose is not part of the token; it keeps a token made for
+// one purpose from passing for a token made for another. New tokens are signed with the newest key of
+// the ring, and a token is accepted as long as the key named in it is in the ring and has not expired.
+//
+// Tokens written "<body>.<signature>", as they were before keys had IDs, are verified with the key that
+// has the ID LegacyID, if there is one.
+package keyring
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LegacyID is the ID of the key with which tokens that do not name a key are verified.
+const LegacyID = "0"
+
+const (
+	// SecretSize is the size of the secrets generated.
+	SecretSize = 32
+
+	// idSize is the size of the random IDs generated, before they are hex-encoded.
+	idSize = 8
+)
+
+// ErrNoKeys is returned when a token is to be signed with a ring that has no keys.
+var ErrNoKeys = errors.New("keyring: there are no keys")
+
+// A Key is a secret key of HMAC-SHA256 with its ID.
+type Key struct {
+	ID      string
+	Secret  []byte
+	Created time.Time
+	Expires time.Time // the zero time if the key does not expire
+}
+
+// GenerateKey generates a key with a random ID and a random secret. The key is created now and does not
+// expire.
+func GenerateKey() (Key, error) {
+	b := make([]byte, idSize+SecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return Key{}, err
+	}
+	return Key{ID: hex.EncodeToString(b[:idSize]), Secret: b[idSize:], Created: time.Now()}, nil
+}
+
+// Sum returns the base64url-encoded signature of the message for the purpose.
+func (k *Key) Sum(purpose, msg string) string {
+	mac := hmac.New(sha256.New, k.Secret)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte(msg))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// expired says if the key has expired at the time t.
+func (k *Key) expired(t time.Time) bool {
+	return !k.Expires.IsZero() && !t.Before(k.Expires)
+}
+
+// A Ring is an immutable set of keys.
+type Ring struct {
+	keys []Key // the most recently created first
+}
+
+// New returns a ring of the keys.
+func New(keys []Key) *Ring {
+	r := &Ring{keys: append([]Key(nil), keys...)}
+	sort.SliceStable(r.keys, func(i, j int) bool {
+		return r.keys[i].Created.After(r.keys[j].Created)
+	})
+	return r
+}
+
+// Current returns the newest key, with which tokens are signed, or nil if the ring has no keys.
+func (r *Ring) Current() *Key {
+	if len(r.keys) == 0 {
+		return nil
+	}
+	return &r.keys[0]
+}
+
+// Key returns the key with the ID if it is in the ring and has not expired, or else nil.
+func (r *Ring) Key(id string) *Key {
+	k := r.lookup(id)
+	if k == nil || k.expired(time.Now()) {
+		return nil
+	}
+	return k
+}
+
+// Has says if the key with the ID is in the ring, whether it has expired or not.
+func (r *Ring) Has(id string) bool {
+	return r.lookup(id) != nil
+}
+
+func (r *Ring) lookup(id string) *Key {
+	for i := range r.keys {
+		if r.keys[i].ID == id {
+			return &r.keys[i]
+		}
+	}
+	return nil
+}
+
+// Sign returns the token consisting of the body, which must not contain a period, and its signature for
+// the purpose made with the newest key.
+func (r *Ring) Sign(purpose, body string) (string, error) {
+	k := r.Current()
+	if k == nil {
+		return "", ErrNoKeys
+	}
+	return body + "." + k.ID + "." + k.Sum(purpose, body), nil
+}
+
+// Verify checks the signature of the token for the purpose and returns the body of the token if the
+// signature is valid.
+func (r *Ring) Verify(purpose, token string) (body string, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", false
+	}
+	k := r.Key(KeyID(token))
+	if k == nil {
+		return "", false
+	}
+	if !hmac.Equal([]byte(k.Sum(purpose, parts[0])), []byte(parts[len(parts)-1])) {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// KeyID returns the ID of the key named in the token, which is LegacyID for a token that names no key.
+func KeyID(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) == 3 {
+		return parts[1]
+	}
+	return LegacyID
+}
@@ -0,0 +1,117 @@
+package keyring
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T, created time.Time) Key {
+	t.Helper()
+	k, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	k.Created = created
+	return k
+}
+
+func TestSignVerify(t *testing.T) {
+	now := time.Now()
+	old, current := testKey(t, now.Add(-time.Hour)), testKey(t, now)
+	r := New([]Key{old, current})
+
+	if r.Current().ID != current.ID {
+		t.Fatalf("the current key is %q; expected %q", r.Current().ID, current.ID)
+	}
+
+	token, err := r.Sign("login.", "Ym9keQ")
+	if err != nil {
+		t.Fatalf("could not sign: %v", err)
+	}
+	if !strings.HasPrefix(token, "Ym9keQ."+current.ID+".") {
+		t.Errorf("got token %q", token)
+	}
+	if body, ok := r.Verify("login.", token); !ok || body != "Ym9keQ" {
+		t.Errorf("the token did not verify; body = %q; ok = %v", body, ok)
+	}
+	if _, ok := r.Verify("2fa.", token); ok {
+		t.Error("the token verified for another purpose")
+	}
+	if _, ok := r.Verify("login.", "Ym9keR"+token[6:]); ok {
+		t.Error("a token with a changed body verified")
+	}
+	if _, ok := r.Verify("login.", "Ym9keQ."+old.ID+token[6+len(current.ID)+1:]); ok {
+		t.Error("a token naming another key verified")
+	}
+
+	// A token signed with the old key is still accepted.
+	oldToken, err := New([]Key{old}).Sign("login.", "Ym9keQ")
+	if err != nil {
+		t.Fatalf("could not sign: %v", err)
+	}
+	if _, ok := r.Verify("login.", oldToken); !ok {
+		t.Error("a token signed with an older key did not verify")
+	}
+}
+
+func TestVerify_expired(t *testing.T) {
+	now := time.Now()
+	retired, current := testKey(t, now.Add(-time.Hour)), testKey(t, now)
+	retiredToken, err := New([]Key{retired}).Sign("", "Ym9keQ")
+	if err != nil {
+		t.Fatalf("could not sign: %v", err)
+	}
+
+	retired.Expires = now.Add(time.Minute)
+	if _, ok := New([]Key{retired, current}).Verify("", retiredToken); !ok {
+		t.Error("a token signed with a retired key that has not expired did not verify")
+	}
+
+	retired.Expires = now.Add(-time.Minute)
+	r := New([]Key{retired, current})
+	if _, ok := r.Verify("", retiredToken); ok {
+		t.Error("a token signed with an expired key verified")
+	}
+	if r.Key(retired.ID) != nil || !r.Has(retired.ID) {
+		t.Error("the expired key is not reported as expired")
+	}
+	if _, ok := New([]Key{current}).Verify("", retiredToken); ok {
+		t.Error("a token signed with a key not in the ring verified")
+	}
+}
+
+func TestVerify_legacy(t *testing.T) {
+	secret := []byte("some-token-string-goes-here")
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("Ym9keQ"))
+	token := "Ym9keQ." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	if KeyID(token) != LegacyID {
+		t.Errorf("the key ID of a legacy token is %q", KeyID(token))
+	}
+
+	r := New([]Key{{ID: LegacyID, Secret: secret, Created: time.Now().Add(-time.Hour)}, testKey(t, time.Now())})
+	if body, ok := r.Verify("", token); !ok || body != "Ym9keQ" {
+		t.Errorf("the legacy token did not verify; body = %q; ok = %v", body, ok)
+	}
+	if _, ok := New([]Key{testKey(t, time.Now())}).Verify("", token); ok {
+		t.Error("a legacy token verified without the legacy key")
+	}
+}
+
+func TestSign_noKeys(t *testing.T) {
+	r := New(nil)
+	if r.Current() != nil {
+		t.Error("an empty ring has a current key")
+	}
+	if _, err := r.Sign("", "Ym9keQ"); err != ErrNoKeys {
+		t.Errorf("got error %v", err)
+	}
+	if _, ok := r.Verify("", "Ym9keQ.abc.def"); ok {
+		t.Error("a token verified with an empty ring")
+	}
+}
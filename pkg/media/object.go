@@ -0,0 +1,13 @@
+package media
+
+import (
+	"strconv"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+// ObjectName returns the name under which the media object is stored in the content bucket, which is
+// the ID of the site, a slash, and the ID of the object followed by its extension.
+func ObjectName(m *data.Media) string {
+	return strconv.FormatInt(m.Site, 10) + "/" + m.Id + m.Ext
+}
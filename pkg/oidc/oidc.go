@@ -0,0 +1,140 @@
+// Package oidc implements the parts of OpenID Connect needed to let users log in with external identity
+// providers: discovering a provider, the authorization code flow with PKCE (RFC 7636) on top of
+// golang.org/x/oauth2, and verifying the ID tokens that identify the users.
+//
+// ID tokens signed with RS256 or ES256 are accepted.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ScopeOpenID is the scope that makes an OAuth 2.0 authorization request an OpenID Connect request.
+const ScopeOpenID = "openid"
+
+// maxResponseSize limits the size of the documents read from a provider.
+const maxResponseSize = 1 << 20
+
+// A Provider is an OpenID Connect provider, described by its discovery document. The signing keys of
+// the provider are fetched when they are first needed and again when a token is signed with a key that
+// is not known.
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]interface{} // the public keys by key ID
+	keysFetched time.Time
+}
+
+// minKeysRefresh is how long after the keys are fetched they may be fetched again.
+const minKeysRefresh = time.Minute
+
+// Discover fetches the discovery document of the provider with the issuer URL given. If client is nil,
+// http.DefaultClient is used for all requests to the provider.
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	issuer = strings.TrimSuffix(issuer, "/")
+	p := &Provider{client: client}
+	if err := getJSON(ctx, client, issuer+"/.well-known/openid-configuration", p); err != nil {
+		return nil, fmt.Errorf("oidc: could not get the discovery document: %v", err)
+	}
+	if strings.TrimSuffix(p.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc: the discovery document has the issuer %q instead of %q", p.Issuer, issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, errors.New("oidc: the discovery document is missing endpoints")
+	}
+	return p, nil
+}
+
+// Endpoint returns the OAuth 2.0 endpoint of the provider.
+func (p *Provider) Endpoint() oauth2.Endpoint {
+	return oauth2.Endpoint{AuthURL: p.AuthorizationEndpoint, TokenURL: p.TokenEndpoint}
+}
+
+// Context returns a context with which golang.org/x/oauth2 makes its requests to the provider with the
+// provider's HTTP client.
+func (p *Provider) Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, p.client)
+}
+
+// RandomString returns a random string with 256 bits of entropy, made of the characters permitted in
+// a PKCE code verifier. It is used for the state, the nonce, and the code verifier of a request.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 code challenge of the code verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeOptions returns the options of an authorization request with the nonce to be included in
+// the ID token and the challenge of the PKCE code verifier.
+func AuthCodeOptions(nonce, verifier string) []oauth2.AuthCodeOption {
+	return []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("nonce", nonce),
+		oauth2.SetAuthURLParam("code_challenge", CodeChallenge(verifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	}
+}
+
+// ExchangeOptions returns the options of the token request that exchanges the authorization code
+// obtained with AuthCodeOptions.
+func ExchangeOptions(verifier string) []oauth2.AuthCodeOption {
+	return []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("code_verifier", verifier)}
+}
+
+// IDToken returns the raw ID token given with the token response.
+func IDToken(t *oauth2.Token) (string, bool) {
+	s, ok := t.Extra("id_token").(string)
+	return s, ok && s != ""
+}
+
+// getJSON decodes the JSON document at the URL into v.
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %d from %s", resp.StatusCode, url)
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,328 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	testClientID     = "client-1"
+	testClientSecret = "secret-1"
+	testRedirectURL  = "https://example.com/api/auth/oidc/test/callback"
+)
+
+// testProvider is a stand-in OpenID Connect provider. Its authorization endpoint approves every request
+// right away for the subject in the "login_hint" parameter.
+type testProvider struct {
+	*httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authRequest // the authorization requests by the codes issued for them
+
+	jwksRequests int
+}
+
+type authRequest struct {
+	subject, nonce, challenge string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{rsaKey: rsaKey, ecKey: ecKey, codes: make(map[string]authRequest)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/jwks", p.jwks)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *testProvider) discovery(w http.ResponseWriter, _ *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 p.URL,
+		"authorization_endpoint": p.URL + "/authorize",
+		"token_endpoint":         p.URL + "/token",
+		"jwks_uri":               p.URL + "/jwks",
+	})
+}
+
+func (p *testProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != testClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	code, _ := RandomString()
+	p.mu.Lock()
+	p.codes[code] = authRequest{subject: q.Get("login_hint"), nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	p.mu.Unlock()
+	redirect := q.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (p *testProvider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if id != testClientID || secret != testClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+	p.mu.Lock()
+	req, ok := p.codes[r.PostFormValue("code")]
+	delete(p.codes, r.PostFormValue("code"))
+	p.mu.Unlock()
+	if !ok || CodeChallenge(r.PostFormValue("code_verifier")) != req.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	now := time.Now().Unix()
+	idToken := p.sign("RS256", "rsa", map[string]interface{}{
+		"iss": p.URL, "sub": req.subject, "aud": testClientID, "exp": now + 600, "iat": now,
+		"nonce": req.nonce, "email": req.subject + "@example.com", "email_verified": true,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access", "token_type": "Bearer", "expires_in": 600, "id_token": idToken,
+	})
+}
+
+func (p *testProvider) jwks(w http.ResponseWriter, _ *http.Request) {
+	p.mu.Lock()
+	p.jwksRequests++
+	p.mu.Unlock()
+	enc := base64.RawURLEncoding.EncodeToString
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "use": "sig", "kid": "rsa", "n": enc(p.rsaKey.N.Bytes()), "e": enc(big.NewInt(int64(p.rsaKey.E)).Bytes())},
+		{"kty": "EC", "use": "sig", "kid": "ec", "crv": "P-256", "x": enc(p.ecKey.X.Bytes()), "y": enc(p.ecKey.Y.Bytes())},
+		{"kty": "RSA", "use": "enc", "kid": "enc", "n": enc(p.rsaKey.N.Bytes()), "e": "AQAB"},
+	}})
+}
+
+// sign creates a token with the claims signed with the algorithm and the key given.
+func (p *testProvider) sign(alg, kid string, claims map[string]interface{}) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	h, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	c, _ := json.Marshal(claims)
+	signed := enc(h) + "." + enc(c)
+	sum := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch alg {
+	case "RS256":
+		sig, _ = rsa.SignPKCS1v15(rand.Reader, p.rsaKey, crypto.SHA256, sum[:])
+	case "ES256":
+		r, s, _ := ecdsa.Sign(rand.Reader, p.ecKey, sum[:])
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return signed + "." + enc(sig)
+}
+
+func TestFlow(t *testing.T) {
+	tp := newTestProvider(t)
+	defer tp.Close()
+	ctx := context.Background()
+
+	p, err := Discover(ctx, tp.Client(), tp.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &oauth2.Config{
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		Endpoint:     p.Endpoint(),
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{ScopeOpenID, "email"},
+	}
+	state, _ := RandomString()
+	nonce, _ := RandomString()
+	verifier, _ := RandomString()
+	opts := append(AuthCodeOptions(nonce, verifier), oauth2.SetAuthURLParam("login_hint", "jane"))
+
+	// Follow the authorization URL to the provider, which redirects back with the code.
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(conf.AuthCodeURL(state, opts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("got status %d and location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if loc.Query().Get("state") != state {
+		t.Fatalf("got state %q", loc.Query().Get("state"))
+	}
+	code := loc.Query().Get("code")
+
+	// Without the right code verifier, the code cannot be exchanged.
+	wrongVerifier, _ := RandomString()
+	if _, err = conf.Exchange(p.Context(ctx), code, ExchangeOptions(wrongVerifier)...); err == nil {
+		t.Fatal("exchanged the code with the wrong verifier")
+	}
+	// The failed exchange used up the code, so authorize again.
+	resp, err = client.Get(conf.AuthCodeURL(state, opts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	loc, _ = url.Parse(resp.Header.Get("Location"))
+	code = loc.Query().Get("code")
+
+	tok, err := conf.Exchange(p.Context(ctx), code, ExchangeOptions(verifier)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, ok := IDToken(tok)
+	if !ok {
+		t.Fatal("no ID token")
+	}
+	c, err := p.Verify(ctx, raw, testClientID, nonce, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Subject != "jane" || c.Email != "jane@example.com" || !bool(c.EmailVerified) {
+		t.Errorf("got claims %+v", c)
+	}
+}
+
+func TestProvider_Verify(t *testing.T) {
+	tp := newTestProvider(t)
+	defer tp.Close()
+	ctx := context.Background()
+
+	p, err := Discover(ctx, tp.Client(), tp.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	claims := func(change func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss": tp.URL, "sub": "jane", "aud": testClientID, "exp": now.Unix() + 600, "iat": now.Unix(),
+			"nonce": "n-1",
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+
+	cases := []struct {
+		token string
+		ok    bool
+	}{
+		{tp.sign("RS256", "rsa", claims(nil)), true},
+		{tp.sign("ES256", "ec", claims(nil)), true},
+		{tp.sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["aud"] = []string{"other", testClientID} })), false},
+		{tp.sign("RS256", "rsa", claims(func(c map[string]interface{}) {
+			c["aud"] = []string{"other", testClientID}
+			c["azp"] = testClientID
+		})), true},
+		{tp.sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["aud"] = "other" })), false},
+		{tp.sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example" })), false},
+		{tp.sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["exp"] = now.Unix() - 3600 })), false},
+		{tp.sign("RS256", "rsa", claims(func(c map[string]interface{}) { delete(c, "exp") })), false},
+		{tp.sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["iat"] = now.Unix() + 3600 })), false},
+		{tp.sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["nonce"] = "n-2" })), false},
+		{tp.sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["sub"] = "" })), false},
+		{tp.sign("RS256", "ec", claims(nil)), false},   // the key does not match the algorithm
+		{tp.sign("RS256", "enc", claims(nil)), false},  // the key is not for signatures
+		{tp.sign("RS256", "none", claims(nil)), false}, // the key does not exist
+		{tp.sign("HS256", "rsa", claims(nil)), false},
+		{tp.sign("RS256", "rsa", claims(nil))[:20], false},
+		{"", false},
+	}
+
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			_, err := p.Verify(ctx, tc.token, testClientID, "n-1", now)
+			if tc.ok && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if !tc.ok && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	// The keys are fetched once, and a token with an unknown key does not make the provider fetch them
+	// again right away.
+	if tp.jwksRequests != 1 {
+		t.Errorf("the keys were fetched %d times", tp.jwksRequests)
+	}
+}
+
+func TestProvider_VerifyTamperedSignature(t *testing.T) {
+	tp := newTestProvider(t)
+	defer tp.Close()
+	ctx := context.Background()
+	p, err := Discover(ctx, tp.Client(), tp.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	token := tp.sign("RS256", "rsa", map[string]interface{}{
+		"iss": tp.URL, "sub": "jane", "aud": testClientID, "exp": now.Unix() + 600,
+	})
+	// Replace the claims with other claims, keeping the signature.
+	forged := tp.sign("RS256", "rsa", map[string]interface{}{
+		"iss": tp.URL, "sub": "admin", "aud": testClientID, "exp": now.Unix() + 600,
+	})
+	parts, forgedParts := strings.Split(token, "."), strings.Split(forged, ".")
+	if _, err = p.Verify(ctx, parts[0]+"."+forgedParts[1]+"."+parts[2], testClientID, "", now); err == nil {
+		t.Error("accepted a token with tampered claims")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	tp := newTestProvider(t)
+	defer tp.Close()
+
+	if _, err := Discover(context.Background(), tp.Client(), tp.URL+"/"); err != nil {
+		t.Errorf("could not discover with a trailing slash: %v", err)
+	}
+	if _, err := Discover(context.Background(), tp.Client(), tp.URL+"/other"); err == nil {
+		t.Error("discovered a provider that does not exist")
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// From RFC 7636, Appendix B.
+	got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("got %q; expected %q", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+// Package password hashes passwords and verifies passwords against their hashes.
+//
+// Hashes are self-describing so that the hashing scheme and its cost parameters can change over time
+// without invalidating the hashes already stored. New hashes are made with Argon2id and encoded in the
+// PHC string format, like "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>". Hashes made with bcrypt, which
+// start with "$2a$", "$2b$", or "$2y$", are still verified, and NeedsRehash reports that they should be
+// replaced, which is done when the password is next known (such as at login).
+package password
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Params are the cost parameters of Argon2id.
+type Params struct {
+	Memory  uint32 // the memory used, in KiB
+	Time    uint32 // the number of passes over the memory
+	Threads uint8  // the degree of parallelism
+}
+
+// DefaultParams are the parameters used if no others are configured.
+var DefaultParams = Params{Memory: 64 * 1024, Time: 3, Threads: 2}
+
+const (
+	saltLen = 16
+	keyLen  = 32
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// ErrUnknownScheme is returned for hashes that were not made by any supported scheme.
+var ErrUnknownScheme = errors.New("password: unknown hash scheme")
+
+// ErrMalformedHash is returned for hashes of a supported scheme that cannot be decoded.
+var ErrMalformedHash = errors.New("password: malformed hash")
+
+// ParseParams parses parameters written like "m=65536,t=3,p=2", as they appear in the hashes. Each of
+// the parameters must be given.
+func ParseParams(s string) (Params, error) {
+	var p Params
+	var seen [3]bool
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			return Params{}, fmt.Errorf("password: invalid parameter %q", kv)
+		}
+		k, v := strings.TrimSpace(kv[:i]), strings.TrimSpace(kv[i+1:])
+		var err error
+		switch k {
+		case "m":
+			var n uint64
+			n, err = strconv.ParseUint(v, 10, 32)
+			p.Memory, seen[0] = uint32(n), true
+		case "t":
+			var n uint64
+			n, err = strconv.ParseUint(v, 10, 32)
+			p.Time, seen[1] = uint32(n), true
+		case "p":
+			var n uint64
+			n, err = strconv.ParseUint(v, 10, 8)
+			p.Threads, seen[2] = uint8(n), true
+		default:
+			return Params{}, fmt.Errorf("password: unknown parameter %q", k)
+		}
+		if err != nil {
+			return Params{}, fmt.Errorf("password: invalid value of parameter %q: %q", k, v)
+		}
+	}
+	if !seen[0] || !seen[1] || !seen[2] {
+		return Params{}, errors.New("password: the parameters m, t, and p must all be given")
+	}
+	if err := p.validate(); err != nil {
+		return Params{}, err
+	}
+	return p, nil
+}
+
+// String returns the parameters as written in the hashes.
+func (p Params) String() string {
+	return fmt.Sprintf("m=%d,t=%d,p=%d", p.Memory, p.Time, p.Threads)
+}
+
+func (p Params) validate() error {
+	if p.Time < 1 || p.Threads < 1 || p.Memory < 8*uint32(p.Threads) {
+		return fmt.Errorf("password: invalid parameters %s", p)
+	}
+	return nil
+}
+
+// Hash hashes the password with Argon2id with the parameters given and a random salt.
+func Hash(password string, p Params) ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, keyLen)
+	b64 := base64.RawStdEncoding
+	return []byte(fmt.Sprintf("%sv=%d$%s$%s$%s", argon2idPrefix, argon2.Version, p, b64.EncodeToString(salt),
+		b64.EncodeToString(key))), nil
+}
+
+// Verify says if the password matches the hash. The error is not nil only if the hash is malformed or
+// was made by an unknown scheme.
+func Verify(hash []byte, password string) (bool, error) {
+	switch {
+	case bytes.HasPrefix(hash, []byte(argon2idPrefix)):
+		p, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		got := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(got, key) == 1, nil
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return false, ErrUnknownScheme
+}
+
+// NeedsRehash says if the hash should be replaced with a hash made with Hash and the parameters given:
+// if it was made by another scheme or with other parameters.
+func NeedsRehash(hash []byte, p Params) bool {
+	if !bytes.HasPrefix(hash, []byte(argon2idPrefix)) {
+		return true
+	}
+	hp, salt, key, err := decodeArgon2id(hash)
+	return err != nil || hp != p || len(salt) != saltLen || len(key) != keyLen
+}
+
+func isBcrypt(hash []byte) bool {
+	return len(hash) > 4 && hash[0] == '$' && hash[1] == '2' && hash[3] == '$'
+}
+
+// decodeArgon2id decodes a hash made by Hash.
+func decodeArgon2id(hash []byte) (Params, []byte, []byte, error) {
+	// The parts are "", "argon2id", "v=19", the parameters, the salt, and the key.
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[2] != "v="+strconv.Itoa(argon2.Version) {
+		return Params{}, nil, nil, ErrMalformedHash
+	}
+	p, err := ParseParams(parts[3])
+	if err != nil {
+		return Params{}, nil, nil, ErrMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return Params{}, nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Params{}, nil, nil, ErrMalformedHash
+	}
+	return p, salt, key, nil
+}
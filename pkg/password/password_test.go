@@ -0,0 +1,122 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams are cheap parameters so that the tests run quickly.
+var testParams = Params{Memory: 64, Time: 1, Threads: 1}
+
+func TestHashVerify(t *testing.T) {
+	h, err := Hash("correct horse", testParams)
+	if err != nil {
+		t.Fatalf("could not hash: %v", err)
+	}
+	if !strings.HasPrefix(string(h), "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("got hash %q", h)
+	}
+	if ok, err := Verify(h, "correct horse"); !ok || err != nil {
+		t.Errorf("the password did not verify; ok = %v; err = %v", ok, err)
+	}
+	if ok, err := Verify(h, "correct horse "); ok || err != nil {
+		t.Errorf("a wrong password verified; ok = %v; err = %v", ok, err)
+	}
+
+	h2, err := Hash("correct horse", testParams)
+	if err != nil {
+		t.Fatalf("could not hash: %v", err)
+	}
+	if string(h) == string(h2) {
+		t.Error("got the same hash twice")
+	}
+}
+
+func TestVerify_bcrypt(t *testing.T) {
+	h, err := bcrypt.GenerateFromPassword([]byte("battery staple"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("could not hash with bcrypt: %v", err)
+	}
+	if ok, err := Verify(h, "battery staple"); !ok || err != nil {
+		t.Errorf("the password did not verify; ok = %v; err = %v", ok, err)
+	}
+	if ok, err := Verify(h, "battery"); ok || err != nil {
+		t.Errorf("a wrong password verified; ok = %v; err = %v", ok, err)
+	}
+	if !NeedsRehash(h, testParams) {
+		t.Error("a bcrypt hash does not need a rehash")
+	}
+}
+
+func TestVerify_malformed(t *testing.T) {
+	cases := []struct {
+		hash string
+		err  error
+	}{
+		{"", ErrUnknownScheme},
+		{"plaintext", ErrUnknownScheme},
+		{"$argon2i$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5", ErrUnknownScheme},
+		{"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ", ErrMalformedHash},
+		{"$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=64,t=1$c2FsdHNhbHQ$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$", ErrMalformedHash},
+	}
+	for _, c := range cases {
+		if ok, err := Verify([]byte(c.hash), "pass"); ok || err != c.err {
+			t.Errorf("hash %q: got ok = %v and error %v; expected error %v", c.hash, ok, err, c.err)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	h, err := Hash("pass", testParams)
+	if err != nil {
+		t.Fatalf("could not hash: %v", err)
+	}
+	if NeedsRehash(h, testParams) {
+		t.Error("a hash with the current parameters needs a rehash")
+	}
+	for _, p := range []Params{
+		{Memory: 128, Time: 1, Threads: 1},
+		{Memory: 64, Time: 2, Threads: 1},
+		{Memory: 64, Time: 1, Threads: 2},
+	} {
+		if !NeedsRehash(h, p) {
+			t.Errorf("a hash with the parameters %s does not need a rehash for %s", testParams, p)
+		}
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	cases := []struct {
+		s     string
+		p     Params
+		valid bool
+	}{
+		{"m=65536,t=3,p=2", Params{Memory: 65536, Time: 3, Threads: 2}, true},
+		{"p=4, t=1, m=1024", Params{Memory: 1024, Time: 1, Threads: 4}, true},
+		{"m=65536,t=3", Params{}, false},
+		{"m=65536,t=3,p=2,x=1", Params{}, false},
+		{"m=65536,t=0,p=2", Params{}, false},
+		{"m=8,t=1,p=2", Params{}, false},
+		{"m=65536,t=3,p=256", Params{}, false},
+		{"m=abc,t=3,p=2", Params{}, false},
+		{"", Params{}, false},
+	}
+	for _, c := range cases {
+		p, err := ParseParams(c.s)
+		if (err == nil) != c.valid {
+			t.Errorf("%q: got error %v", c.s, err)
+			continue
+		}
+		if p != c.p {
+			t.Errorf("%q: got %+v but expected %+v", c.s, p, c.p)
+		}
+	}
+	if p, err := ParseParams(DefaultParams.String()); err != nil || p != DefaultParams {
+		t.Errorf("could not parse the default parameters; got %+v and error %v", p, err)
+	}
+}
@@ -134,10 +134,13 @@ type Registration struct {
 	// CustomHooks contains all of a plugin's custom defined hooks.
 	CustomHooks []string `protobuf:"bytes,8,rep,name=custom_hooks,json=customHooks,proto3" json:"custom_hooks,omitempty"`
 	// CustomFilters contains all of a plugin's custom defined filters.
-	CustomFilters        []string `protobuf:"bytes,9,rep,name=custom_filters,json=customFilters,proto3" json:"custom_filters,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	CustomFilters []string `protobuf:"bytes,9,rep,name=custom_filters,json=customFilters,proto3" json:"custom_filters,omitempty"`
+	// Capabilities maps each of a plugin's capabilities to the number of the lowest built-in role that
+	// has it.
+	Capabilities         map[string]int32 `protobuf:"bytes,10,rep,name=capabilities,proto3" json:"capabilities,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
 func (m *Registration) Reset()         { *m = Registration{} }
@@ -221,6 +224,13 @@ func (m *Registration) GetCustomFilters() []string {
 	return nil
 }
 
+func (m *Registration) GetCapabilities() map[string]int32 {
+	if m != nil {
+		return m.Capabilities
+	}
+	return nil
+}
+
 type InitRequest struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -491,6 +501,7 @@ func init() {
 	proto.RegisterType((*Spec)(nil), "internal.Spec")
 	proto.RegisterType((*Handler)(nil), "internal.Handler")
 	proto.RegisterType((*Registration)(nil), "internal.Registration")
+	proto.RegisterMapType((map[string]int32)(nil), "internal.Registration.CapabilitiesEntry")
 	proto.RegisterMapType((map[string]*Handler)(nil), "internal.Registration.FiltersEntry")
 	proto.RegisterMapType((map[string]*Handler)(nil), "internal.Registration.HooksEntry")
 	proto.RegisterType((*InitRequest)(nil), "internal.InitRequest")
@@ -504,43 +515,46 @@ func init() {
 func init() { proto.RegisterFile("plugins/internal/internal.proto", fileDescriptor_254a76a75722963b) }
 
 var fileDescriptor_254a76a75722963b = []byte{
-	// 575 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x54, 0x5d, 0x6f, 0xd3, 0x30,
-	0x14, 0x55, 0x9a, 0xf4, 0xeb, 0xa6, 0xad, 0x8a, 0x35, 0x50, 0xe8, 0x1e, 0xd6, 0x05, 0x10, 0x79,
-	0x6a, 0xa0, 0x08, 0x86, 0x90, 0x40, 0x02, 0x95, 0x69, 0x68, 0x02, 0x21, 0x23, 0x78, 0xe0, 0xa5,
-	0xca, 0x5a, 0xaf, 0xb5, 0x9a, 0xc6, 0xc1, 0x76, 0x8a, 0xca, 0xaf, 0xe2, 0xf7, 0xf0, 0x6b, 0x90,
-	0xed, 0x74, 0xf1, 0xd0, 0x8a, 0x90, 0x78, 0xca, 0xbd, 0x37, 0xe7, 0xde, 0x73, 0x7c, 0x4f, 0x62,
-	0x38, 0xca, 0xd3, 0x62, 0x41, 0x33, 0x11, 0xd3, 0x4c, 0x12, 0x9e, 0x25, 0xe9, 0x55, 0x30, 0xca,
-	0x39, 0x93, 0x0c, 0xb5, 0x76, 0xf9, 0xe0, 0x50, 0x6e, 0x73, 0x22, 0xe2, 0x0d, 0xe1, 0x82, 0xb2,
-	0x6c, 0xf7, 0x34, 0xb0, 0xf0, 0x11, 0x78, 0x9f, 0x72, 0x32, 0x43, 0x08, 0x3c, 0x05, 0x0b, 0x9c,
-	0xa1, 0x13, 0x75, 0xb1, 0x8e, 0xd1, 0x01, 0xd4, 0x37, 0x49, 0x5a, 0x90, 0xa0, 0x36, 0x74, 0xa2,
-	0x36, 0x36, 0x49, 0x18, 0x43, 0xf3, 0x2c, 0xc9, 0xe6, 0x29, 0xe1, 0xe8, 0x3e, 0xd4, 0x45, 0x4e,
-	0x66, 0x22, 0x70, 0x86, 0x6e, 0xe4, 0x8f, 0x7b, 0xa3, 0x2b, 0x0d, 0x6a, 0x26, 0x36, 0x2f, 0xc3,
-	0x5f, 0x2e, 0x74, 0x30, 0x59, 0x50, 0x21, 0x79, 0x22, 0x29, 0xcb, 0x14, 0x57, 0x96, 0xac, 0x0d,
-	0x57, 0x1b, 0xeb, 0x18, 0xf5, 0xa0, 0x46, 0xe7, 0x25, 0x51, 0x8d, 0xce, 0x51, 0x08, 0xee, 0x86,
-	0xf0, 0xc0, 0x1b, 0x3a, 0x91, 0x3f, 0xee, 0x8f, 0x76, 0xa2, 0xbf, 0x98, 0x27, 0x56, 0x2f, 0x2b,
-	0xfa, 0xfa, 0x5f, 0xe8, 0xd1, 0x09, 0xd4, 0x97, 0x8c, 0xad, 0x44, 0xd0, 0xd0, 0xa8, 0xe3, 0x0a,
-	0x65, 0x8b, 0x1a, 0x9d, 0x29, 0xcc, 0xdb, 0x4c, 0xf2, 0x2d, 0x36, 0x78, 0xf4, 0x12, 0x9a, 0x97,
-	0x34, 0x95, 0x84, 0x8b, 0xa0, 0xa9, 0x5b, 0xef, 0xed, 0x69, 0x3d, 0x35, 0x28, 0xd3, 0xbc, 0xeb,
-	0x41, 0xc7, 0xd0, 0x99, 0x15, 0x42, 0xb2, 0xf5, 0xd4, 0xd0, 0xb7, 0x86, 0x6e, 0xd4, 0xc6, 0xbe,
-	0xa9, 0x69, 0x36, 0xf4, 0x00, 0x7a, 0x25, 0x64, 0x47, 0xd4, 0xd6, 0xa0, 0xae, 0xa9, 0x96, 0x73,
-	0x07, 0xe7, 0x00, 0x95, 0x3a, 0xd4, 0x07, 0x77, 0x45, 0xb6, 0xe5, 0xf2, 0x54, 0x88, 0x1e, 0xda,
-	0x3e, 0xf9, 0xe3, 0x5b, 0x95, 0xcc, 0xd2, 0xa8, 0xd2, 0xba, 0x17, 0xb5, 0xe7, 0xce, 0xe0, 0x3d,
-	0x74, 0x6c, 0xbd, 0xff, 0x39, 0x2e, 0xec, 0x82, 0xff, 0x2e, 0xa3, 0x12, 0x93, 0x6f, 0x05, 0x11,
-	0x32, 0x7c, 0x05, 0xbe, 0x92, 0x5a, 0xa6, 0xe8, 0x10, 0xda, 0xea, 0xf0, 0x53, 0xcb, 0xee, 0x96,
-	0x2a, 0x7c, 0x50, 0x96, 0x23, 0xf0, 0xe6, 0x89, 0x4c, 0x34, 0x4d, 0x07, 0xeb, 0x38, 0x0c, 0xa1,
-	0x63, 0xfa, 0x45, 0xce, 0x32, 0x51, 0x61, 0x1c, 0x0b, 0xf3, 0x1a, 0xc0, 0x9c, 0x60, 0x92, 0xc8,
-	0x04, 0x1d, 0x81, 0x6f, 0x96, 0x67, 0x93, 0x80, 0x29, 0xed, 0xa5, 0xb9, 0x84, 0xfe, 0x69, 0x91,
-	0xa6, 0xd7, 0xbe, 0xca, 0x08, 0x5c, 0x4e, 0x16, 0x7a, 0x80, 0x3f, 0xbe, 0x73, 0xb3, 0xd5, 0x58,
-	0x41, 0x50, 0x04, 0x1e, 0x27, 0x39, 0x2b, 0xf7, 0x73, 0x60, 0x43, 0x73, 0x26, 0xa8, 0x64, 0x7c,
-	0x8b, 0x35, 0x22, 0x3c, 0x07, 0xa8, 0x6a, 0xff, 0xf4, 0xdd, 0xdf, 0x85, 0x16, 0x67, 0x4c, 0x4e,
-	0x0b, 0x9e, 0x06, 0xae, 0xae, 0x36, 0x55, 0xfe, 0x99, 0xa7, 0xe3, 0x9f, 0x0e, 0x34, 0x3e, 0xea,
-	0xbf, 0x1e, 0x3d, 0x05, 0x4f, 0x6d, 0x1d, 0xdd, 0xae, 0xb8, 0x2d, 0x17, 0x06, 0x7b, 0xd4, 0xa3,
-	0x13, 0x68, 0x4c, 0x98, 0xda, 0xaf, 0xdd, 0x68, 0xf9, 0x65, 0x37, 0x5e, 0xb3, 0xe1, 0x19, 0xb4,
-	0x26, 0xcc, 0x2c, 0x1d, 0x59, 0xe7, 0xad, 0x6c, 0x18, 0xdc, 0x58, 0x7d, 0xf3, 0xf8, 0x6b, 0xbc,
-	0xa0, 0x72, 0x59, 0x5c, 0x8c, 0x66, 0x6c, 0x1d, 0xcf, 0x67, 0x4b, 0x92, 0xad, 0xe2, 0x75, 0xf2,
-	0x83, 0x7c, 0xa7, 0x9c, 0xc4, 0xf9, 0x6a, 0x11, 0xff, 0x79, 0x8d, 0x5d, 0x34, 0xf4, 0xbd, 0xf4,
-	0xe4, 0x77, 0x00, 0x00, 0x00, 0xff, 0xff, 0xc8, 0x2b, 0x6c, 0xe0, 0xe1, 0x04, 0x00, 0x00,
+	// 614 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x54, 0x5f, 0x6f, 0xd3, 0x3e,
+	0x14, 0x55, 0x9a, 0xf4, 0xdf, 0x4d, 0x37, 0x6d, 0xd6, 0x7e, 0x53, 0x7e, 0xdd, 0xc3, 0xba, 0x00,
+	0x22, 0x4f, 0x0d, 0x14, 0xc1, 0x10, 0x12, 0x20, 0x60, 0x4c, 0x43, 0x03, 0x84, 0x82, 0xe0, 0x81,
+	0x97, 0xc9, 0x4b, 0xbd, 0xce, 0x6a, 0x1a, 0x07, 0xdb, 0x29, 0x2a, 0x9f, 0x8a, 0xaf, 0xc5, 0xb7,
+	0x40, 0xb6, 0xd3, 0xc5, 0x1b, 0x2d, 0x42, 0xe2, 0x29, 0xd7, 0x37, 0xe7, 0xde, 0x73, 0x7c, 0x8f,
+	0x6d, 0xd8, 0x2f, 0xb2, 0x72, 0x42, 0x73, 0x11, 0xd3, 0x5c, 0x12, 0x9e, 0xe3, 0xec, 0x2a, 0x18,
+	0x16, 0x9c, 0x49, 0x86, 0x3a, 0xcb, 0x75, 0x7f, 0x4f, 0x2e, 0x0a, 0x22, 0xe2, 0x39, 0xe1, 0x82,
+	0xb2, 0x7c, 0xf9, 0x35, 0xb0, 0xf0, 0x1e, 0x78, 0x1f, 0x0b, 0x92, 0x22, 0x04, 0x9e, 0x82, 0x05,
+	0xce, 0xc0, 0x89, 0x36, 0x12, 0x1d, 0xa3, 0x1d, 0x68, 0xce, 0x71, 0x56, 0x92, 0xa0, 0x31, 0x70,
+	0xa2, 0x6e, 0x62, 0x16, 0x61, 0x0c, 0xed, 0x13, 0x9c, 0x8f, 0x33, 0xc2, 0xd1, 0x6d, 0x68, 0x8a,
+	0x82, 0xa4, 0x22, 0x70, 0x06, 0x6e, 0xe4, 0x8f, 0x36, 0x87, 0x57, 0x1a, 0x54, 0xcf, 0xc4, 0xfc,
+	0x0c, 0x7f, 0x7a, 0xd0, 0x4b, 0xc8, 0x84, 0x0a, 0xc9, 0xb1, 0xa4, 0x2c, 0x57, 0x5c, 0x39, 0x9e,
+	0x19, 0xae, 0x6e, 0xa2, 0x63, 0xb4, 0x09, 0x0d, 0x3a, 0xae, 0x88, 0x1a, 0x74, 0x8c, 0x42, 0x70,
+	0xe7, 0x84, 0x07, 0xde, 0xc0, 0x89, 0xfc, 0xd1, 0xd6, 0x70, 0x29, 0xfa, 0xb3, 0xf9, 0x26, 0xee,
+	0xdc, 0xa6, 0x6f, 0xfe, 0x81, 0x1e, 0x1d, 0x42, 0xf3, 0x92, 0xb1, 0xa9, 0x08, 0x5a, 0x1a, 0x75,
+	0x50, 0xa3, 0x6c, 0x51, 0xc3, 0x13, 0x85, 0x79, 0x9d, 0x4b, 0xbe, 0x48, 0x0c, 0x1e, 0x3d, 0x85,
+	0xf6, 0x05, 0xcd, 0x24, 0xe1, 0x22, 0x68, 0xeb, 0xd2, 0x5b, 0x6b, 0x4a, 0x8f, 0x0d, 0xca, 0x14,
+	0x2f, 0x6b, 0xd0, 0x01, 0xf4, 0xd2, 0x52, 0x48, 0x36, 0x3b, 0x33, 0xf4, 0x9d, 0x81, 0x1b, 0x75,
+	0x13, 0xdf, 0xe4, 0x34, 0x1b, 0xba, 0x03, 0x9b, 0x15, 0x64, 0x49, 0xd4, 0xd5, 0xa0, 0x0d, 0x93,
+	0xad, 0xfa, 0xa2, 0xb7, 0xd0, 0x4b, 0x71, 0x81, 0xcf, 0x69, 0x46, 0x25, 0x25, 0x22, 0x00, 0xad,
+	0x26, 0x5a, 0xa3, 0xe6, 0x95, 0x05, 0x35, 0x92, 0xae, 0x55, 0xf7, 0x4f, 0x01, 0xea, 0xbd, 0xa2,
+	0x2d, 0x70, 0xa7, 0x64, 0x51, 0x59, 0xa1, 0x42, 0x74, 0xd7, 0x76, 0xdd, 0x1f, 0x6d, 0xd7, 0x34,
+	0x95, 0xed, 0xd5, 0x41, 0x78, 0xd2, 0x78, 0xec, 0xf4, 0xdf, 0x41, 0xcf, 0xde, 0xfd, 0xbf, 0xb6,
+	0x7b, 0x0e, 0xdb, 0xbf, 0xc9, 0x5f, 0xd1, 0xf3, 0xda, 0xc1, 0x6c, 0x5a, 0x0d, 0xc2, 0x0d, 0xf0,
+	0xdf, 0xe4, 0x54, 0x26, 0xe4, 0x6b, 0x49, 0x84, 0x0c, 0x9f, 0x81, 0xaf, 0xf6, 0x5a, 0x2d, 0xd1,
+	0x1e, 0x74, 0x95, 0x17, 0x67, 0xd6, 0xe9, 0xeb, 0xa8, 0xc4, 0x7b, 0x75, 0x02, 0x11, 0x78, 0x63,
+	0x2c, 0xb1, 0xee, 0xd9, 0x4b, 0x74, 0x1c, 0x86, 0xd0, 0x33, 0xf5, 0xa2, 0x60, 0xb9, 0xa8, 0x31,
+	0x8e, 0x85, 0x79, 0x01, 0x60, 0x46, 0x70, 0x84, 0x25, 0x46, 0xfb, 0xe0, 0x1b, 0x2f, 0x6d, 0x12,
+	0x30, 0xa9, 0xb5, 0x34, 0x17, 0xb0, 0x75, 0x5c, 0x66, 0xd9, 0xb5, 0x4b, 0x12, 0x81, 0xcb, 0xc9,
+	0x44, 0x37, 0xf0, 0x47, 0xbb, 0xab, 0xbd, 0x4e, 0x14, 0x04, 0x45, 0xe0, 0x71, 0x52, 0xb0, 0x6a,
+	0xc0, 0x3b, 0x36, 0xb4, 0x60, 0x82, 0x4a, 0xc6, 0x17, 0x89, 0x46, 0x84, 0xa7, 0x00, 0x75, 0xee,
+	0xaf, 0xae, 0xe1, 0xff, 0xd0, 0xe1, 0x8c, 0xc9, 0xb3, 0x92, 0x67, 0x81, 0xab, 0xb3, 0x6d, 0xb5,
+	0xfe, 0xc4, 0xb3, 0xd1, 0x0f, 0x07, 0x5a, 0x1f, 0xf4, 0x23, 0x84, 0x1e, 0x82, 0xa7, 0xa6, 0x8e,
+	0xfe, 0xab, 0xb9, 0x2d, 0x17, 0xfa, 0x6b, 0xd4, 0xa3, 0x43, 0x68, 0x1d, 0x31, 0x35, 0x5f, 0xbb,
+	0xd0, 0xf2, 0xab, 0xbf, 0x7b, 0x33, 0x5d, 0xd9, 0xf0, 0x08, 0x3a, 0x47, 0xcc, 0x0c, 0x1d, 0x59,
+	0xfb, 0xad, 0x6d, 0xe8, 0xaf, 0xcc, 0xbe, 0xbc, 0xff, 0x25, 0x9e, 0x50, 0x79, 0x59, 0x9e, 0x0f,
+	0x53, 0x36, 0x8b, 0xc7, 0xe9, 0x25, 0xc9, 0xa7, 0xf1, 0x0c, 0x7f, 0x27, 0xdf, 0x28, 0x27, 0x71,
+	0x31, 0x9d, 0xc4, 0x37, 0x5f, 0xd5, 0xf3, 0x96, 0x7e, 0x26, 0x1f, 0xfc, 0x1a, 0x00, 0x4f, 0xe6,
+	0x40, 0x2b, 0x70, 0x05, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -10,6 +10,7 @@ import (
 	"github.com/dchenk/mazewire/pkg/hooks"
 	"github.com/dchenk/mazewire/pkg/plugins/internal"
 	"github.com/dchenk/mazewire/pkg/plugins/specs"
+	"github.com/dchenk/mazewire/pkg/roles"
 	"github.com/dchenk/mazewire/pkg/types/version"
 	"github.com/golang/protobuf/proto"
 )
@@ -48,6 +49,13 @@ type Plugin interface {
 	// CustomFilters returns the list of all of the custom filters the plugin is registering.
 	// Namespacing across plugins works just as it does for hooks.
 	CustomFilters() []filters.Filter
+
+	// Capabilities returns the capabilities the plugin is registering, each mapped to the lowest built-in
+	// role that has it. Site owners may give the capabilities to custom roles as well.
+	//
+	// Namespacing works as it does for custom hooks: a capability named "manage_galleries" of the plugin
+	// with ID "gallery" is registered as "gallery.manage_galleries".
+	Capabilities() map[roles.Capability]roles.Role
 }
 
 // Serve starts up a plugin's serving to the main host system. This function should be called within each plugin
@@ -106,6 +114,12 @@ func makeRegistration(p Plugin) *internal.Registration {
 		r.CustomFilters[i] = string(cf[i])
 	}
 
+	caps := p.Capabilities()
+	r.Capabilities = make(map[string]int32, len(caps))
+	for c, role := range caps {
+		r.Capabilities[string(c)] = int32(role)
+	}
+
 	return &r
 }
 
@@ -132,6 +146,10 @@ func DoFilter(filter filters.Filter, payload proto.Message) (proto.Message, erro
 // Activate activates a plugin on the host side.
 func Activate(p Plugin) {
 	_, id, _ := p.Identity()
+	for c, role := range p.Capabilities() {
+		// A capability that conflicts with one already registered is left out.
+		_ = RegisterCapability(id, c, role)
+	}
 	internal.Activate(id, p.Specs(), p.Hooks(), p.Filters(), p.CustomHooks(), p.CustomFilters())
 }
 
@@ -140,6 +158,12 @@ func Deactivate(pluginID string) {
 	internal.Deactivate(pluginID)
 }
 
+// RegisterCapability registers a capability of the plugin with the ID given, prefixing the name of the
+// capability with the ID. The built-in roles starting with minRole have the capability.
+func RegisterCapability(pluginID string, c roles.Capability, minRole roles.Role) error {
+	return roles.RegisterCapability(roles.Capability(pluginID+".")+c, minRole)
+}
+
 // PluginCapabilities contains the capabilities a Plugin may use for its features. Only the user-authorized and
 // explicitly requested capabilities are set here as non-nil.
 type PluginCapabilities struct {
@@ -0,0 +1,90 @@
+// Package rate_limit limits how often requests may be made, with token buckets and with lockouts
+// that grow exponentially with the number of consecutive failures.
+//
+// The state of the limits is kept in the database so that it is shared by all the instances of a cluster;
+// the types here only describe the limits and compute the lockout durations.
+package rate_limit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A Bucket is a token bucket. Each request takes one token, and a request may be made only if a token
+// is available. The bucket holds at most Capacity tokens, so that bursts of up to Capacity requests are
+// allowed, and is refilled continuously at the rate of PerSecond tokens per second.
+type Bucket struct {
+	Capacity  float64
+	PerSecond float64
+}
+
+// PerMinute returns a Bucket that allows bursts of up to burst requests and refills at the rate of n
+// tokens per minute.
+func PerMinute(n, burst float64) Bucket {
+	return Bucket{Capacity: burst, PerSecond: n / 60}
+}
+
+// Refill returns the number of tokens in the bucket the time elapsed after it held the number of
+// tokens given.
+func (b Bucket) Refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * b.PerSecond
+	}
+	if tokens > b.Capacity {
+		return b.Capacity
+	}
+	return tokens
+}
+
+// A Lockout is the policy by which requests are locked out after too many consecutive failures. Once
+// there are Threshold failures, requests are locked out for Base, and each further failure doubles the
+// duration up to Max.
+type Lockout struct {
+	Threshold uint32
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// Duration returns how long requests are locked out after the number of consecutive failures given.
+// The duration is 0 if the threshold is not reached or the Threshold is 0.
+func (l Lockout) Duration(failures uint32) time.Duration {
+	if l.Threshold == 0 || failures < l.Threshold {
+		return 0
+	}
+	d := l.Base
+	if d <= 0 {
+		return 0
+	}
+	for i := failures - l.Threshold; i > 0; i-- {
+		if (l.Max > 0 && d >= l.Max) || d > math.MaxInt64/2 {
+			break
+		}
+		d *= 2
+	}
+	if l.Max > 0 && d > l.Max {
+		return l.Max
+	}
+	return d
+}
+
+// RemoteIP returns the IP address of the client that made the request.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// IPKey returns the key identifying the limits of an IP address.
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// UserKey returns the key identifying the limits of a user account.
+func UserKey(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
@@ -0,0 +1,76 @@
+package rate_limit
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBucketRefill(t *testing.T) {
+	b := PerMinute(6, 3) // one token every 10 seconds
+	cases := []struct {
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{0, 0, 0},
+		{0, 10 * time.Second, 1},
+		{0.5, 5 * time.Second, 1},
+		{2, time.Minute, 3},
+		{-1, 20 * time.Second, 1},
+		{1, -time.Second, 1},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := b.Refill(tc.tokens, tc.elapsed); got != tc.want {
+				t.Errorf("got %v tokens; expected %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLockoutDuration(t *testing.T) {
+	l := Lockout{Threshold: 3, Base: time.Minute, Max: 10 * time.Minute}
+	cases := []struct {
+		l        Lockout
+		failures uint32
+		want     time.Duration
+	}{
+		{l, 0, 0},
+		{l, 2, 0},
+		{l, 3, time.Minute},
+		{l, 4, 2 * time.Minute},
+		{l, 5, 4 * time.Minute},
+		{l, 6, 8 * time.Minute},
+		{l, 7, 10 * time.Minute},
+		{l, 1 << 31, 10 * time.Minute},
+		{Lockout{Threshold: 1, Base: time.Hour}, 1 << 31, time.Hour << 21}, // the largest that does not overflow
+		{Lockout{Base: time.Minute}, 10, 0},
+		{Lockout{Threshold: 1}, 10, 0},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := tc.l.Duration(tc.failures); got != tc.want {
+				t.Errorf("got lockout %v; expected %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	cases := []struct {
+		addr, want string
+	}{
+		{"192.0.2.1:1234", "192.0.2.1"},
+		{"[2001:db8::1]:443", "2001:db8::1"},
+		{"192.0.2.1", "192.0.2.1"},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := RemoteIP(&http.Request{RemoteAddr: tc.addr}); got != tc.want {
+				t.Errorf("got IP %q; expected %q", got, tc.want)
+			}
+		})
+	}
+}
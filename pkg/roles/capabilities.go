@@ -0,0 +1,227 @@
+package roles
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+// A Capability is the permission to do something on a site, such as to publish pages. The built-in
+// roles have the capabilities that require at most their level, and each custom role has the
+// capabilities listed for it.
+type Capability string
+
+// The built-in capabilities.
+const (
+	CapRead            Capability = "read"              // to see the site as a member
+	CapEditPages       Capability = "edit_pages"        // to write pages and posts and edit one's own
+	CapPublishPages    Capability = "publish_pages"     // to publish one's own pages and posts
+	CapEditOthersPages Capability = "edit_others_pages" // to edit and publish the pages and posts of others
+	CapUnfilteredHTML  Capability = "unfiltered_html"   // to publish HTML, such as scripts, that is not sanitized
+	CapUploadMedia     Capability = "upload_media"      // to upload and manage media files
+	CapEditTheme       Capability = "edit_theme"        // to change the theme of the site
+	CapManageUsers     Capability = "manage_users"      // to invite members and change their roles
+	CapManageSite      Capability = "manage_site"       // to change the options, domains, and tokens of the site
+	CapManagePlugins   Capability = "manage_plugins"    // to activate and configure plugins
+	CapManageRoles     Capability = "manage_roles"      // to define custom roles
+	CapDeleteSite      Capability = "delete_site"       // to delete the site
+)
+
+// CustomRoleMin is the lowest number of a custom role; the numbers below are reserved for the built-in
+// roles.
+const CustomRoleMin Role = 100
+
+// IsCustom says if the role is a custom role.
+func IsCustom(r Role) bool {
+	return r >= CustomRoleMin
+}
+
+var validCapability = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)?$`)
+
+// capabilities maps each capability to the lowest built-in role that has it.
+var capabilities = struct {
+	sync.RWMutex
+	m map[Capability]Role
+}{m: map[Capability]Role{
+	CapRead:            Role_SUBSCRIBER,
+	CapEditPages:       Role_AUTHOR,
+	CapPublishPages:    Role_AUTHOR,
+	CapUploadMedia:     Role_AUTHOR,
+	CapEditOthersPages: Role_EDITOR,
+	CapUnfilteredHTML:  Role_ADMIN,
+	CapEditTheme:       Role_ADMIN,
+	CapManageUsers:     Role_ADMIN,
+	CapManageSite:      Role_ADMIN,
+	CapManagePlugins:   Role_ADMIN,
+	CapManageRoles:     Role_OWNER,
+	CapDeleteSite:      Role_OWNER,
+}}
+
+// RegisterCapability registers a new capability, which the built-in roles starting with minRole have.
+// The capabilities of plugins are named with the ID of the plugin followed by a dot and the name, such
+// as "gallery.manage_galleries", and custom roles may be given them like any other capability.
+// Registering a capability again with the same minRole does nothing.
+func RegisterCapability(c Capability, minRole Role) error {
+	if !validCapability.MatchString(string(c)) {
+		return fmt.Errorf("roles: invalid capability name %q", c)
+	}
+	if minRole == Role_NONE || IsCustom(minRole) {
+		return fmt.Errorf("roles: invalid minimum role %v for capability %q", minRole, c)
+	}
+	if _, ok := ValidRoleInt64(int64(minRole)); !ok {
+		return fmt.Errorf("roles: invalid minimum role %v for capability %q", minRole, c)
+	}
+	capabilities.Lock()
+	defer capabilities.Unlock()
+	if have, ok := capabilities.m[c]; ok {
+		if have == minRole {
+			return nil
+		}
+		return fmt.Errorf("roles: capability %q is already registered", c)
+	}
+	capabilities.m[c] = minRole
+	return nil
+}
+
+// ValidCapability says if the capability is registered.
+func ValidCapability(c Capability) bool {
+	capabilities.RLock()
+	defer capabilities.RUnlock()
+	_, ok := capabilities.m[c]
+	return ok
+}
+
+// Capabilities returns all of the registered capabilities in alphabetical order.
+func Capabilities() []Capability {
+	capabilities.RLock()
+	cs := make([]Capability, 0, len(capabilities.m))
+	for c := range capabilities.m {
+		cs = append(cs, c)
+	}
+	capabilities.RUnlock()
+	sort.Slice(cs, func(i, j int) bool { return cs[i] < cs[j] })
+	return cs
+}
+
+// BuiltinHas says if the built-in role has the capability.
+func BuiltinHas(r Role, c Capability) bool {
+	capabilities.RLock()
+	minRole, ok := capabilities.m[c]
+	capabilities.RUnlock()
+	return ok && !IsCustom(r) && r != Role_NONE && RoleAtLeast(r, minRole)
+}
+
+// Can says if a user with the role on the site has the capability. A super user has every capability.
+func Can(siteID int64, r Role, c Capability) (bool, error) {
+	if !IsCustom(r) {
+		return r == Role_SUPER || BuiltinHas(r, c), nil
+	}
+	cs, err := RoleCapabilities(siteID, r)
+	if err != nil {
+		return false, err
+	}
+	for _, have := range cs {
+		if have == c {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RoleCapabilities returns the registered capabilities of the role on the site. Every custom role has
+// the capability CapRead. A custom role that does not exist has no capabilities.
+func RoleCapabilities(siteID int64, r Role) ([]Capability, error) {
+	if !IsCustom(r) {
+		var cs []Capability
+		for _, c := range Capabilities() {
+			if r == Role_SUPER || BuiltinHas(r, c) {
+				cs = append(cs, c)
+			}
+		}
+		return cs, nil
+	}
+	cr, err := data.Conn.CustomRole(siteID, int64(r))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	cs := []Capability{CapRead}
+	for _, f := range strings.Fields(cr.Capabilities) {
+		if c := Capability(f); c != CapRead && ValidCapability(c) {
+			cs = append(cs, c)
+		}
+	}
+	return cs, nil
+}
+
+// CanGrant says if a user with the role granter on the site may give another user the role r: only if
+// the granter has all of the capabilities of r. Among the built-in roles, this is the same as the
+// granter's role being at least r.
+func CanGrant(siteID int64, granter, r Role) (bool, error) {
+	if granter == Role_SUPER {
+		return true, nil
+	}
+	if !IsCustom(granter) && !IsCustom(r) {
+		return RoleAtLeast(granter, r), nil
+	}
+	need, err := RoleCapabilities(siteID, r)
+	if err != nil {
+		return false, err
+	}
+	have, err := RoleCapabilities(siteID, granter)
+	if err != nil {
+		return false, err
+	}
+	haveSet := make(map[Capability]bool, len(have))
+	for _, c := range have {
+		haveSet[c] = true
+	}
+	for _, c := range need {
+		if !haveSet[c] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ParseSiteRole returns the role on the site with the name given: the name of a built-in role, such as
+// "EDITOR", or the name of a custom role of the site. If there is no such role, ok is false.
+func ParseSiteRole(siteID int64, name string) (r Role, ok bool, err error) {
+	if r, ok = ValidRoleStr(name); ok {
+		return r, true, nil
+	}
+	crs, err := data.Conn.CustomRolesBySite(siteID)
+	if err != nil {
+		return Role_NONE, false, err
+	}
+	for i := range crs {
+		if crs[i].Name == name {
+			return Role(crs[i].Id), true, nil
+		}
+	}
+	return Role_NONE, false, nil
+}
+
+// SiteRoleName returns the name of the role on the site. The name of a custom role that does not exist
+// is its number.
+func SiteRoleName(siteID int64, r Role) (string, error) {
+	if !IsCustom(r) {
+		return r.String(), nil
+	}
+	cr, err := data.Conn.CustomRole(siteID, int64(r))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return strconv.FormatInt(int64(r), 10), nil
+		}
+		return "", err
+	}
+	return cr.Name, nil
+}
@@ -0,0 +1,127 @@
+package roles
+
+import "testing"
+
+func TestBuiltinHas(t *testing.T) {
+	cases := []struct {
+		role Role
+		c    Capability
+		has  bool
+	}{
+		{Role_SUBSCRIBER, CapRead, true},
+		{Role_SUBSCRIBER, CapEditPages, false},
+		{Role_AUTHOR, CapEditPages, true},
+		{Role_AUTHOR, CapPublishPages, true},
+		{Role_AUTHOR, CapUploadMedia, true},
+		{Role_AUTHOR, CapEditOthersPages, false},
+		{Role_EDITOR, CapEditOthersPages, true},
+		{Role_EDITOR, CapManageUsers, false},
+		{Role_ADMIN, CapManageUsers, true},
+		{Role_ADMIN, CapEditTheme, true},
+		{Role_EDITOR, CapUnfilteredHTML, false},
+		{Role_ADMIN, CapUnfilteredHTML, true},
+		{Role_ADMIN, CapManagePlugins, true},
+		{Role_ADMIN, CapManageRoles, false},
+		{Role_ADMIN, CapDeleteSite, false},
+		{Role_OWNER, CapManageRoles, true},
+		{Role_OWNER, CapDeleteSite, true},
+		{Role_SUPER, CapDeleteSite, true},
+		{Role_NONE, CapRead, false},
+		{Role_OWNER, "unknown", false},
+		{CustomRoleMin, CapRead, false},
+	}
+	for _, tc := range cases {
+		if got := BuiltinHas(tc.role, tc.c); got != tc.has {
+			t.Errorf("role %v, capability %q: got %v", tc.role, tc.c, got)
+		}
+	}
+}
+
+func TestCan_builtin(t *testing.T) {
+	// The built-in roles are resolved without the database.
+	if ok, err := Can(1, Role_EDITOR, CapEditOthersPages); !ok || err != nil {
+		t.Errorf("an editor cannot edit the pages of others; err = %v", err)
+	}
+	if ok, err := Can(1, Role_AUTHOR, CapManageSite); ok || err != nil {
+		t.Errorf("an author can manage the site; err = %v", err)
+	}
+	if ok, err := Can(1, Role_SUPER, "plugin.unregistered"); !ok || err != nil {
+		t.Errorf("a super user is missing a capability; err = %v", err)
+	}
+}
+
+func TestRegisterCapability(t *testing.T) {
+	const c Capability = "gallery.manage_galleries"
+	if err := RegisterCapability(c, Role_EDITOR); err != nil {
+		t.Fatalf("could not register capability: %v", err)
+	}
+	if err := RegisterCapability(c, Role_EDITOR); err != nil {
+		t.Errorf("could not register the capability again: %v", err)
+	}
+	if err := RegisterCapability(c, Role_ADMIN); err == nil {
+		t.Error("registered the same capability for another role")
+	}
+	if !ValidCapability(c) || !BuiltinHas(Role_EDITOR, c) || BuiltinHas(Role_AUTHOR, c) {
+		t.Error("the capability is not given to the roles starting with EDITOR")
+	}
+	found := false
+	for _, have := range Capabilities() {
+		found = found || have == c
+	}
+	if !found {
+		t.Error("the capability is not listed")
+	}
+
+	for _, bad := range []struct {
+		c    Capability
+		role Role
+	}{
+		{"Gallery.Manage", Role_ADMIN},
+		{"gallery.", Role_ADMIN},
+		{"a.b.c", Role_ADMIN},
+		{"", Role_ADMIN},
+		{"gallery.view", Role_NONE},
+		{"gallery.view", 4},
+		{"gallery.view", CustomRoleMin},
+	} {
+		if err := RegisterCapability(bad.c, bad.role); err == nil {
+			t.Errorf("registered capability %q with role %v", bad.c, bad.role)
+		}
+	}
+}
+
+func TestCanGrant_builtin(t *testing.T) {
+	cases := []struct {
+		granter, r Role
+		ok         bool
+	}{
+		{Role_ADMIN, Role_EDITOR, true},
+		{Role_ADMIN, Role_ADMIN, true},
+		{Role_ADMIN, Role_OWNER, false},
+		{Role_OWNER, Role_OWNER, true},
+		{Role_SUPER, CustomRoleMin, true},
+	}
+	for _, tc := range cases {
+		if ok, err := CanGrant(1, tc.granter, tc.r); ok != tc.ok || err != nil {
+			t.Errorf("%v granting %v: got %v and error %v", tc.granter, tc.r, ok, err)
+		}
+	}
+}
+
+func TestCustomRoles(t *testing.T) {
+	if !IsCustom(CustomRoleMin) || IsCustom(Role_SUPER) {
+		t.Error("custom roles are not told apart from the built-in roles")
+	}
+	if r, ok := ValidSiteRoleInt64(120); !ok || r != 120 {
+		t.Errorf("custom role 120 is not valid; got %v", r)
+	}
+	if r, ok := ValidSiteRoleInt64(7); !ok || r != Role_ADMIN {
+		t.Errorf("built-in role 7 is not valid; got %v", r)
+	}
+	if _, ok := ValidSiteRoleInt64(50); ok {
+		t.Error("role 50 is valid")
+	}
+	if !RoleAtLeast(CustomRoleMin, Role_SUBSCRIBER) || RoleAtLeast(CustomRoleMin, Role_AUTHOR) {
+		t.Error("a custom role does not count as SUBSCRIBER")
+	}
+}
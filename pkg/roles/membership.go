@@ -0,0 +1,245 @@
+package roles
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+// A Membership is the role of a user on a site.
+type Membership struct {
+	Site int64
+	Role Role
+}
+
+// Memberships are all of the roles of a user, loaded with one query. Memberships that are returned by
+// this package are shared and must not be modified.
+type Memberships struct {
+	UserID int64
+	Super  bool         // whether the user is a super user
+	Sites  []Membership // the sites on which the user has a role, in the order of their IDs
+
+	stale int32 // set to 1 when the roles of the user change
+}
+
+// Role returns the role of the user on the site. A super user without a role on the site has the role
+// SUPER, and any other user without a role on the site has the role NONE.
+func (ms *Memberships) Role(siteID int64) Role {
+	i := sort.Search(len(ms.Sites), func(i int) bool { return ms.Sites[i].Site >= siteID })
+	if i < len(ms.Sites) && ms.Sites[i].Site == siteID {
+		return ms.Sites[i].Role
+	}
+	if ms.Super {
+		return Role_SUPER
+	}
+	return Role_NONE
+}
+
+// SiteIDs returns the IDs of the sites on which the user has a role.
+func (ms *Memberships) SiteIDs() []int64 {
+	ids := make([]int64, len(ms.Sites))
+	for i := range ms.Sites {
+		ids[i] = ms.Sites[i].Site
+	}
+	return ids
+}
+
+// isStale says if the roles of the user changed after the Memberships were loaded.
+func (ms *Memberships) isStale() bool {
+	return atomic.LoadInt32(&ms.stale) != 0
+}
+
+// membershipsFromMeta returns the Memberships of the user given all of the user's meta. Role meta with
+// values that are not valid roles are skipped.
+func membershipsFromMeta(userID int64, metas []data.UserMeta) *Memberships {
+	ms := &Memberships{UserID: userID}
+	for i := range metas {
+		k := metas[i].K
+		if k == superKey {
+			ms.Super = string(metas[i].V) == "y"
+			continue
+		}
+		if !strings.HasPrefix(k, siteRoleKeyPrefix) {
+			continue
+		}
+		siteID, err := strconv.ParseInt(k[len(siteRoleKeyPrefix):], 10, 64)
+		if err != nil {
+			continue
+		}
+		val, err := strconv.ParseInt(string(metas[i].V), 10, 64)
+		if err != nil {
+			continue
+		}
+		if role, ok := ValidSiteRoleInt64(val); ok && role != Role_NONE {
+			ms.Sites = append(ms.Sites, Membership{Site: siteID, Role: role})
+		}
+	}
+	sort.Slice(ms.Sites, func(i, j int) bool { return ms.Sites[i].Site < ms.Sites[j].Site })
+	return ms
+}
+
+// The memberships of the users whose roles were resolved recently are kept for cacheTTL so that
+// requests need not query the database for them again. The entry of a user is dropped when the roles
+// of the user are changed on this server; a change made on another server is seen here once the entry
+// expires.
+const (
+	cacheTTL = time.Second * 30
+	cacheMax = 10000 // the most users whose memberships are kept
+)
+
+var cache = struct {
+	sync.Mutex
+	m   map[int64]cacheEntry
+	gen uint64 // incremented by every invalidation
+}{m: make(map[int64]cacheEntry)}
+
+type cacheEntry struct {
+	ms     *Memberships
+	loaded time.Time
+}
+
+// UserMemberships returns the memberships of the user, from the cache if they were loaded recently.
+func UserMemberships(userID int64) (*Memberships, error) {
+	if userID == 0 {
+		return &Memberships{}, nil
+	}
+	now := time.Now()
+	cache.Lock()
+	e, ok := cache.m[userID]
+	gen := cache.gen
+	cache.Unlock()
+	if ok && now.Sub(e.loaded) < cacheTTL {
+		return e.ms, nil
+	}
+
+	metas, err := userRoleMeta(userID)
+	if err != nil {
+		return nil, err
+	}
+	ms := membershipsFromMeta(userID, metas)
+	storeMemberships(ms, gen, now)
+	return ms, nil
+}
+
+// userRoleMeta loads only the meta of the user that hold its roles: the site role keys and the super
+// user key.
+func userRoleMeta(userID int64) ([]data.UserMeta, error) {
+	metas, err := data.Conn.UserMetaByIdLikeKey(userID, siteRoleKeyPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	super, err := data.Conn.UserMetaByIdKey(userID, superKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return metas, nil
+		}
+		return nil, err
+	}
+	return append(metas, *super), nil
+}
+
+// storeMemberships caches memberships loaded at the time given, unless the roles of any user were
+// invalidated since the generation gen was current, in which case the memberships may be out of date
+// and are marked stale instead.
+func storeMemberships(ms *Memberships, gen uint64, loaded time.Time) {
+	cache.Lock()
+	defer cache.Unlock()
+	if cache.gen != gen {
+		atomic.StoreInt32(&ms.stale, 1)
+		return
+	}
+	if len(cache.m) >= cacheMax {
+		for id, e := range cache.m {
+			if loaded.Sub(e.loaded) >= cacheTTL {
+				delete(cache.m, id)
+			}
+		}
+		if len(cache.m) >= cacheMax {
+			cache.m = make(map[int64]cacheEntry)
+		}
+	}
+	cache.m[ms.UserID] = cacheEntry{ms: ms, loaded: loaded}
+}
+
+// Invalidate drops the cached memberships of the user, so that the roles of the user are loaded again
+// by the next request and by the Resolvers that loaded them already. It must be called whenever the role
+// meta of the user are changed.
+func Invalidate(userID int64) {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.gen++
+	if e, ok := cache.m[userID]; ok {
+		atomic.StoreInt32(&e.ms.stale, 1)
+		delete(cache.m, userID)
+	}
+}
+
+// A Resolver resolves the roles of users for one request, loading the memberships of each user at most
+// once unless the roles of the user are changed during the request. A nil Resolver resolves roles with
+// the cache shared by requests alone.
+type Resolver struct {
+	mu sync.Mutex
+	m  map[int64]*Memberships
+}
+
+// NewResolver returns a Resolver for a request.
+func NewResolver() *Resolver {
+	return &Resolver{m: make(map[int64]*Memberships, 1)}
+}
+
+// Memberships returns the memberships of the user.
+func (rs *Resolver) Memberships(userID int64) (*Memberships, error) {
+	if rs == nil {
+		return UserMemberships(userID)
+	}
+	rs.mu.Lock()
+	ms, ok := rs.m[userID]
+	rs.mu.Unlock()
+	if ok && !ms.isStale() {
+		return ms, nil
+	}
+	ms, err := UserMemberships(userID)
+	if err != nil {
+		return nil, err
+	}
+	rs.mu.Lock()
+	rs.m[userID] = ms
+	rs.mu.Unlock()
+	return ms, nil
+}
+
+// SiteRole returns the role of the user on the site, as Memberships.Role does.
+func (rs *Resolver) SiteRole(userID, siteID int64) (Role, error) {
+	ms, err := rs.Memberships(userID)
+	if err != nil {
+		return Role_NONE, err
+	}
+	return ms.Role(siteID), nil
+}
+
+// IsSuper says if the user is a super user. If the roles of the user cannot be loaded, the returned
+// value is false.
+func (rs *Resolver) IsSuper(userID int64) bool {
+	ms, err := rs.Memberships(userID)
+	return err == nil && ms.Super
+}
+
+type resolverKey struct{}
+
+// NewContext returns a copy of the context carrying the Resolver.
+func NewContext(ctx context.Context, rs *Resolver) context.Context {
+	return context.WithValue(ctx, resolverKey{}, rs)
+}
+
+// FromContext returns the Resolver carried by the context, or nil.
+func FromContext(ctx context.Context) *Resolver {
+	rs, _ := ctx.Value(resolverKey{}).(*Resolver)
+	return rs
+}
@@ -0,0 +1,114 @@
+package roles
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dchenk/mazewire/pkg/data"
+)
+
+func TestMembershipsFromMeta(t *testing.T) {
+	metas := []data.UserMeta{
+		{UserId: 4, K: "role9", V: []byte("5")},
+		{UserId: 4, K: "role2", V: []byte("9")},
+		{UserId: 4, K: "role15", V: []byte("101")},
+		{UserId: 4, K: "role3", V: []byte("0")}, // no role
+		{UserId: 4, K: "role4", V: []byte("2")}, // invalid role
+		{UserId: 4, K: "roles", V: []byte("7")}, // not a role key
+		{UserId: 4, K: "totp-secret", V: []byte("ABC")},
+	}
+	ms := membershipsFromMeta(4, metas)
+	if ms.UserID != 4 || ms.Super {
+		t.Errorf("got wrong user %d or super %v", ms.UserID, ms.Super)
+	}
+	want := []Membership{{2, Role_OWNER}, {9, Role_EDITOR}, {15, Role(101)}}
+	if len(ms.Sites) != len(want) {
+		t.Fatalf("got %d memberships but expected %d", len(ms.Sites), len(want))
+	}
+	for i := range want {
+		if ms.Sites[i] != want[i] {
+			t.Errorf("(%d) got membership %+v but expected %+v", i, ms.Sites[i], want[i])
+		}
+	}
+
+	super := membershipsFromMeta(1, []data.UserMeta{{UserId: 1, K: superKey, V: []byte("y")}})
+	if !super.Super {
+		t.Error("super user not recognized")
+	}
+}
+
+func TestMemberships_Role(t *testing.T) {
+	ms := &Memberships{UserID: 4, Sites: []Membership{{2, Role_OWNER}, {9, Role_EDITOR}, {15, Role(101)}}}
+	cases := []struct {
+		site  int64
+		super bool
+		role  Role
+	}{
+		{2, false, Role_OWNER},
+		{9, false, Role_EDITOR},
+		{15, false, Role(101)},
+		{1, false, Role_NONE},
+		{10, false, Role_NONE},
+		{20, false, Role_NONE},
+		{9, true, Role_EDITOR},
+		{10, true, Role_SUPER},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			ms.Super = tc.super
+			if got := ms.Role(tc.site); got != tc.role {
+				t.Errorf("got role %v but expected %v", got, tc.role)
+			}
+		})
+	}
+}
+
+func TestResolver_invalidate(t *testing.T) {
+	const userID = 1 << 40
+	ms := &Memberships{UserID: userID, Sites: []Membership{{3, Role_AUTHOR}}}
+	cache.Lock()
+	gen := cache.gen
+	cache.Unlock()
+	storeMemberships(ms, gen, time.Now())
+
+	rs := NewResolver()
+	role, err := rs.SiteRole(userID, 3)
+	if err != nil || role != Role_AUTHOR {
+		t.Fatalf("got role %v and error %v", role, err)
+	}
+	if got, _ := rs.Memberships(userID); got != ms {
+		t.Error("memberships not taken from the cache")
+	}
+
+	Invalidate(userID)
+	if !ms.isStale() {
+		t.Error("memberships not marked stale")
+	}
+	cache.Lock()
+	_, ok := cache.m[userID]
+	cache.Unlock()
+	if ok {
+		t.Error("memberships not dropped from the cache")
+	}
+}
+
+func TestStoreMemberships_staleGeneration(t *testing.T) {
+	const userID = 1<<40 + 1
+	cache.Lock()
+	gen := cache.gen
+	cache.Unlock()
+	Invalidate(userID + 1) // The roles of some user change while the memberships are being loaded.
+
+	ms := &Memberships{UserID: userID}
+	storeMemberships(ms, gen, time.Now())
+	if !ms.isStale() {
+		t.Error("memberships loaded during an invalidation not marked stale")
+	}
+	cache.Lock()
+	_, ok := cache.m[userID]
+	cache.Unlock()
+	if ok {
+		t.Error("memberships loaded during an invalidation were cached")
+	}
+}
@@ -1,15 +1,22 @@
 package roles
 
 import (
+	"bytes"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/dchenk/mazewire/pkg/data"
 )
 
-// RoleAtLeast checks if the user's role (actualRole) is at least minRole.
+// RoleAtLeast checks if the user's role (actualRole) is at least minRole. A custom role is not on the
+// ladder of the built-in roles and counts as SUBSCRIBER; use Can to check what it permits.
 func RoleAtLeast(actualRole, minRole Role) bool {
+	if IsCustom(actualRole) {
+		actualRole = Role_SUBSCRIBER
+	}
 	switch actualRole {
 	case Role_SUPER:
 		return true
@@ -36,56 +43,137 @@ func RoleAtLeast(actualRole, minRole Role) bool {
 // SiteRole gives the user's role on the site with the given ID.
 // The returned role is NONE if the user has no role on the site or if an error occurs.
 //
-// If the user's role property is not found for the site, this function checks if the user is a
-// super user: if the user is super, the role is Role_SUPER but otherwise the returned role is NONE
-// and no error is returned. This function never returns a sql.ErrNoRows error.
+// If the user has no role on the site but is a super user, the role is Role_SUPER. The roles of the
+// user are resolved with UserMemberships, so they may come from the cache shared by requests. This
+// function never returns a sql.ErrNoRows error.
 func SiteRole(userID, siteID int64) (Role, error) {
-	result, err := data.Conn.UserMetaByIdKey(userID, SiteRoleKey(siteID))
+	ms, err := UserMemberships(userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// The user may be a super admin.
-			if IsSuper(userID) {
-				return Role_SUPER, nil
-			}
-
-			// The user's role was not found.
-			err = nil
-		}
 		return Role_NONE, err
 	}
-
-	// Ensures that the role retrieved is a real role.
-	val, err := strconv.ParseInt(string(result.V), 10, 32)
-	role, _ := ValidRoleInt64(val)
-	return role, err
+	return ms.Role(siteID), nil
 }
 
+// siteRoleKeyPrefix is the prefix of the keys of the user meta that hold the roles of a user on sites.
+const siteRoleKeyPrefix = "role"
+
 // SiteRoleKey returns the string that should be used to associate a user with a particular role
 // on a site in the user meta table.
 func SiteRoleKey(siteID int64) string {
-	return "role" + strconv.FormatInt(siteID, 10)
+	return siteRoleKeyPrefix + strconv.FormatInt(siteID, 10)
 }
 
 // SetSiteRole gives the user the newRole for the site. If the user has no role on that site, the
-// user is given one.
+// user is given one. The sessions of the user on the site are ended, so the user must log in again
+// for the new role to take effect in every client.
 func SetSiteRole(userId int64, siteId int64, newRole int64) error {
-	if _, ok := ValidRoleInt64(newRole); !ok {
-		return fmt.Errorf("users: cannot set invalid role %q for user with ID %d",
+	if _, ok := ValidSiteRoleInt64(newRole); !ok {
+		return fmt.Errorf("users: cannot set invalid role %d for user with ID %d",
 			newRole, userId)
 	}
 	_, err := data.Conn.UserMetaUpdate(userId, SiteRoleKey(siteId),
 		[]byte(strconv.FormatInt(newRole, 10)))
+	Invalidate(userId)
+	if err != nil {
+		return err
+	}
+	_, err = data.Conn.SessionsDeleteByUser(userId, siteId, "")
+	return err
+}
+
+// RemoveSiteRole takes away the user's role on the site, so that the user is no longer a member of the
+// site, and ends the sessions of the user on the site.
+func RemoveSiteRole(userID, siteID int64) error {
+	_, err := data.Conn.UserMetaDelete(userID, SiteRoleKey(siteID))
+	Invalidate(userID)
+	if err != nil {
+		return err
+	}
+	_, err = data.Conn.SessionsDeleteByUser(userID, siteID, "")
 	return err
 }
 
+// ErrLastOwner indicates that a role was not changed because the user is the last owner of the site.
+var ErrLastOwner = errors.New("roles: the site must have at least one owner")
+
+// SetSiteRoleKeepOwner is like SetSiteRole for a member of the site, but it does not take the role from
+// the last owner of the site, returning ErrLastOwner instead. Whether there is another owner is checked
+// as the role is written, so owners changing their roles concurrently cannot leave the site without one.
+// If the user is not a member of the site, the error is sql.ErrNoRows.
+func SetSiteRoleKeepOwner(userID, siteID, newRole int64) error {
+	if _, ok := ValidSiteRoleInt64(newRole); !ok {
+		return fmt.Errorf("users: cannot set invalid role %d for user with ID %d",
+			newRole, userID)
+	}
+	n, err := data.Conn.UserMetaUpdateUnlessLast(userID, SiteRoleKey(siteID),
+		[]byte(strconv.FormatInt(newRole, 10)), ownerValue)
+	Invalidate(userID)
+	if err == nil && n == 0 {
+		err = notChanged(userID, siteID)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = data.Conn.SessionsDeleteByUser(userID, siteID, "")
+	return err
+}
+
+// RemoveSiteRoleKeepOwner is like RemoveSiteRole, but it does not remove the last owner of the site,
+// returning ErrLastOwner instead, like SetSiteRoleKeepOwner.
+func RemoveSiteRoleKeepOwner(userID, siteID int64) error {
+	n, err := data.Conn.UserMetaDeleteUnlessLast(userID, SiteRoleKey(siteID), ownerValue)
+	Invalidate(userID)
+	if err == nil && n == 0 {
+		err = notChanged(userID, siteID)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = data.Conn.SessionsDeleteByUser(userID, siteID, "")
+	return err
+}
+
+// ownerValue is the value of the role meta of the owners of a site.
+var ownerValue = []byte(strconv.FormatInt(int64(Role_OWNER), 10))
+
+// notChanged returns the reason why the role of the user on the site was not changed by a conditional
+// write: ErrLastOwner if the user is an owner, or else sql.ErrNoRows since the user had no role when the
+// write was made.
+func notChanged(userID, siteID int64) error {
+	v, err := data.Conn.UserMetaV(userID, SiteRoleKey(siteID))
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(v, ownerValue) {
+		return ErrLastOwner
+	}
+	return sql.ErrNoRows
+}
+
+// SiteRoleCount returns the number of users who have the role on the site.
+func SiteRoleCount(siteID int64, r Role) (int, error) {
+	metas, err := data.Conn.UserMetaByKey(SiteRoleKey(siteID))
+	if err != nil {
+		return 0, err
+	}
+	v := strconv.FormatInt(int64(r), 10)
+	n := 0
+	for i := range metas {
+		if string(metas[i].V) == v {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// superKey is the key of the user meta that has the value "y" for super users.
+const superKey = "superuser"
+
 // IsSuper says if the user is has super privileges.
 // If an error occurs getting the user's role, the returned value is false.
 func IsSuper(userID int64) bool {
-	if userID == 0 {
-		return false
-	}
-	role, err := data.Conn.UserMetaV(userID, "superuser")
-	return err == nil && string(role) == "y"
+	ms, err := UserMemberships(userID)
+	return err == nil && ms.Super
 }
 
 // RoleIsValid says if the role is of a kind that a user may have.
@@ -114,3 +202,11 @@ func ValidRoleInt64(role int64) (Role, bool) {
 		return Role_NONE, false
 	}
 }
+
+// ValidSiteRoleInt64 is like ValidRoleInt64 but accepts the numbers of custom roles as well.
+func ValidSiteRoleInt64(role int64) (Role, bool) {
+	if role >= int64(CustomRoleMin) && role <= math.MaxInt32 {
+		return Role(role), true
+	}
+	return ValidRoleInt64(role)
+}
@@ -2,6 +2,7 @@ package room
 
 import (
 	"bytes"
+	"html"
 	"strconv"
 	"strings"
 )
@@ -28,8 +29,7 @@ func ElementOpenTag(tagName string, idAttr string, classes []string, buf *bytes.
 func WriteIdAttr(id string, b *bytes.Buffer) {
 	id = strings.TrimSpace(id)
 	if id != "" {
-		b.WriteString(" id=")
-		b.WriteString(strconv.Quote(id))
+		WriteAttr("id", id, b)
 	}
 }
 
@@ -38,11 +38,19 @@ func WriteIdAttr(id string, b *bytes.Buffer) {
 // joined by a space.
 func WriteClassAttr(classes []string, b *bytes.Buffer) {
 	if len(classes) > 0 {
-		b.WriteString(" class=")
-		b.WriteString(strconv.Quote(strings.Join(classes, " ")))
+		WriteAttr("class", strings.Join(classes, " "), b)
 	}
 }
 
+// WriteAttr writes to the buffer the string ` name="value"` with the value escaped.
+func WriteAttr(name, value string, b *bytes.Buffer) {
+	b.WriteByte(' ')
+	b.WriteString(name)
+	b.WriteString(`="`)
+	b.WriteString(html.EscapeString(value))
+	b.WriteByte('"')
+}
+
 // makeClassesList returns the list of classes the element should have in the open tag.
 // A class of the format elemType+"-"+elemDynId is included if elemDynId is not zero.
 func makeClassesList(elemType string, elemSubType string, elemDynId int64, userClasses []string) []string {
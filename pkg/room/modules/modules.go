@@ -4,7 +4,7 @@ package modules
 
 import (
 	"bytes"
-	"strconv"
+	"html"
 	"strings"
 
 	"github.com/dchenk/go-render-quill"
@@ -65,51 +65,72 @@ func (ds defaultModuleCustomizer) GetModuleBuilder(compiledModule *room.Module)
 // DefaultCustomizer is the default room.ModuleCustomizer.
 var DefaultCustomizer defaultModuleCustomizer
 
+// A Customizer is the default room.ModuleCustomizer with the room.Policy that the built-in modules
+// apply to the HTML and URLs they are given.
+type Customizer struct {
+	defaultModuleCustomizer
+	policy *room.Policy
+}
+
+// NewCustomizer returns a Customizer with the Policy p. The DefaultCustomizer applies room.DefaultPolicy.
+func NewCustomizer(p *room.Policy) *Customizer {
+	return &Customizer{policy: p}
+}
+
+// Policy returns the room.Policy of the Customizer.
+func (c *Customizer) Policy() *room.Policy {
+	return c.policy
+}
+
 // Compile implements ModuleCompiler for HTMLModule. This function always returns a static element.
-// If the Tag field is empty, the HTML is rendered inside a <div> element.
-func (h *HTML) Compile(_ room.DataStore, _ room.ModuleCustomizer, m *room.Module, _ *room.PageCSS) (*room.Module, error) {
+// If the Tag field is empty or names an element that the room.Policy does not allow, the HTML is rendered
+// inside a <div> element. The HTML is sanitized with the Policy.
+func (h *HTML) Compile(_ room.DataStore, mc room.ModuleCustomizer, m *room.Module, _ *room.PageCSS) (*room.Module, error) {
 	if err := proto.Unmarshal(m.Data, h); err != nil {
 		return nil, err
 	}
+	p := room.PolicyOf(mc)
 	tag := strings.TrimSpace(h.Tag)
-	if tag == "" {
+	if tag == "" || !p.AllowsElement(tag) {
 		tag = "div"
 	}
-	openTag := "<" + tag
-	if h.IdAttr != "" {
-		openTag += " id=" + strconv.Quote(h.IdAttr)
-	}
-	openTag += ">"
-	closeTag := "</" + tag + ">"
-	l1 := len(openTag)
-	l2 := l1 + len(h.Html)
-	data := make([]byte, l2+len(closeTag))
-	copy(data, openTag)
-	copy(data[l1:], h.Html)
-	copy(data[l2:], closeTag)
-	return &room.Module{Type: room.StaticElementType, Data: data}, nil
+	var b bytes.Buffer
+	b.WriteByte('<')
+	b.WriteString(tag)
+	room.WriteIdAttr(h.IdAttr, &b)
+	b.WriteByte('>')
+	b.Write(p.Sanitize(h.Html))
+	b.WriteString("</")
+	b.WriteString(tag)
+	b.WriteByte('>')
+	return &room.Module{Type: room.StaticElementType, Data: b.Bytes()}, nil
 }
 
-func (i *Image) Compile(_ room.DataStore, _ room.ModuleCustomizer, m *room.Module, css *room.PageCSS) (*room.Module, error) {
+// Compile implements ModuleCompiler for Image. The link and the source URLs are left out if the
+// room.Policy does not allow them.
+func (i *Image) Compile(_ room.DataStore, mc room.ModuleCustomizer, m *room.Module, css *room.PageCSS) (*room.Module, error) {
 	if err := proto.Unmarshal(m.Data, i); err != nil {
 		return nil, err
 	}
+	p := room.PolicyOf(mc)
+	link := i.LinkUrl != "" && p.AllowsURL(i.LinkUrl)
 	var b bytes.Buffer
-	if i.LinkUrl != "" {
-		b.WriteString("<a href=")
-		b.WriteString(strconv.Quote(i.LinkUrl))
+	if link {
+		b.WriteString("<a")
+		room.WriteAttr("href", i.LinkUrl, &b)
 		b.WriteByte('>')
 	}
-	b.WriteString("<img src=")
-	b.WriteString(strconv.Quote(i.Src))
+	b.WriteString("<img")
+	if p.AllowsURL(i.Src) {
+		room.WriteAttr("src", i.Src, &b)
+	}
 	if i.Alt != "" {
-		b.WriteString(" alt=")
-		b.WriteString(strconv.Quote(i.Alt))
+		room.WriteAttr("alt", i.Alt, &b)
 	}
 	room.WriteIdAttr(i.Common.IdAttr, &b)
 	room.WriteClassAttr(i.Common.Classes, &b)
 	b.WriteByte('>')
-	if i.LinkUrl != "" {
+	if link {
 		b.WriteString("</a>")
 	}
 	return &room.Module{Type: TypeImage, Dyn: m.Dyn, Data: b.Bytes()}, nil
@@ -129,7 +150,7 @@ func (n *Nav) Compile(ds room.DataStore, mc room.ModuleCustomizer, m *room.Modul
 	}
 	// TODO: distinguish between static and dynamic modules
 	var b bytes.Buffer
-	err := n.BuildHTML(ds, nil, nil, &b, css)
+	err := n.BuildHTML(ds, mc, nil, &b, css)
 	if err != nil {
 		return nil, err
 	}
@@ -137,30 +158,36 @@ func (n *Nav) Compile(ds room.DataStore, mc room.ModuleCustomizer, m *room.Modul
 }
 
 // BuildHTML implements ModuleBuilder. (For now this exists because we will add some dynamic functionality.)
-func (n *Nav) BuildHTML(_ room.DataStore, _ room.ModuleCustomizer, _ *room.Module, b *bytes.Buffer, _ *room.PageCSS) error {
-	room.ElementOpenTag("nav", n.Common.IdAttr, n.Common.Classes, b)
+// The links whose URLs the room.Policy does not allow are written without an href.
+func (n *Nav) BuildHTML(_ room.DataStore, mc room.ModuleCustomizer, _ *room.Module, b *bytes.Buffer, _ *room.PageCSS) error {
+	p := room.PolicyOf(mc)
+	room.ElementOpenTag("ul", n.Common.IdAttr, n.Common.Classes, b)
 	for _, nl := range n.Links {
-		b.WriteString("<li><a href=")
-		b.WriteString(strconv.Quote(nl.Href))
+		b.WriteString("<li><a")
+		if p.AllowsURL(nl.Href) {
+			room.WriteAttr("href", nl.Href, b)
+		}
 		if nl.Target != "" {
-			b.WriteString(" target=")
-			b.WriteString(strconv.Quote(nl.Target))
+			room.WriteAttr("target", nl.Target, b)
 		}
 		b.WriteByte('>')
-		b.WriteString(nl.Text)
+		b.WriteString(html.EscapeString(nl.Text))
 		b.WriteString("</a></li>")
 	}
 	b.WriteString("</ul>")
 	return nil
 }
 
-// Compile implements ModuleCompiler for TextModule.
-func (t *Text) Compile(_ room.DataStore, _ room.ModuleCustomizer, m *room.Module, css *room.PageCSS) (*room.Module, error) {
+// Compile implements ModuleCompiler for TextModule. The rendered HTML is sanitized with the room.Policy.
+func (t *Text) Compile(_ room.DataStore, mc room.ModuleCustomizer, m *room.Module, css *room.PageCSS) (*room.Module, error) {
 	err := proto.Unmarshal(m.Data, t)
 	if err != nil {
 		return nil, err
 	}
 	cm := &room.Module{Type: TypeText, Dyn: m.Dyn}
-	cm.Data, err = quill.Render(t.Ops)
-	return cm, err
+	if cm.Data, err = quill.Render(t.Ops); err != nil {
+		return cm, err
+	}
+	cm.Data = room.PolicyOf(mc).Sanitize(cm.Data)
+	return cm, nil
 }
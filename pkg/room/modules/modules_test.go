@@ -52,6 +52,14 @@ func TestHTML_Compile(t *testing.T) {
 			})},
 			Out: []byte("<span id=\"thing\"><h1>hi</h1><span class=\"a\">text</span></span>"),
 		},
+		{
+			Module: room.Module{Type: "html", Data: mustProtoMarshal(&HTML{
+				Tag:    "script",
+				IdAttr: "a\"b",
+				Html:   []byte("<img src=x onerror=\"alert(1)\"><script>alert(1)</script></div>"),
+			})},
+			Out: []byte("<div id=\"a&#34;b\"><img src=\"x\"></div>"),
+		},
 	}
 	for i := range cases {
 		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
@@ -69,6 +77,47 @@ func TestHTML_Compile(t *testing.T) {
 	}
 }
 
+func TestHTML_Compile_unfiltered(t *testing.T) {
+	in := "<script>alert(1)</script>"
+	m := &room.Module{Type: "html", Data: mustProtoMarshal(&HTML{Tag: "article", Html: []byte(in)})}
+	mc := NewCustomizer(room.UnfilteredPolicy)
+	cm, err := mc.GetModuleCompiler(m).Compile(nil, mc, m, nil)
+	if err != nil {
+		t.Fatalf("got an error compiling; %v", err)
+	}
+	if want := "<article>" + in + "</article>"; string(cm.Data) != want {
+		t.Errorf("got bad output: %q", cm.Data)
+	}
+}
+
+func TestImage_Compile(t *testing.T) {
+	cases := []struct {
+		img  Image
+		want string
+	}{
+		{
+			img:  Image{Common: &room.Common{}, Src: "/a.png", Alt: "An \"image\"", LinkUrl: "https://a.com"},
+			want: `<a href="https://a.com"><img src="/a.png" alt="An &#34;image&#34;"></a>`,
+		},
+		{
+			img:  Image{Common: &room.Common{IdAttr: "i"}, Src: "javascript:alert(1)", LinkUrl: " javascript:alert(1)"},
+			want: `<img id="i">`,
+		},
+	}
+	for i := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			m := &room.Module{Type: TypeImage, Data: mustProtoMarshal(&cases[i].img)}
+			cm, err := new(Image).Compile(nil, nil, m, nil)
+			if err != nil {
+				t.Fatalf("got an error compiling; %v", err)
+			}
+			if string(cm.Data) != cases[i].want {
+				t.Errorf("got bad output: %q", cm.Data)
+			}
+		})
+	}
+}
+
 func TestNavLinks_Compile(t *testing.T) {
 
 	cases := []struct {
@@ -90,6 +139,12 @@ func TestNavLinks_Compile(t *testing.T) {
 				}},
 			want: `<ul><li><a href="href1" target="_blank">The link text</a></li><li><a href="href2">the 2nd text</a></li><li><a href="href3" target="blank">the 3rd text</a></li></ul>`,
 		},
+		{
+			nav: Nav{
+				Common: &room.Common{IdAttr: "menu"},
+				Links:  []*NavLink{{Text: "<b>Home</b>", Href: "javascript:alert(1)", Target: "\" onclick=\"x"}}},
+			want: `<ul id="menu"><li><a target="&#34; onclick=&#34;x">&lt;b&gt;Home&lt;/b&gt;</a></li></ul>`,
+		},
 	}
 
 	var b bytes.Buffer
@@ -0,0 +1,282 @@
+package room
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+
+	htmlp "golang.org/x/net/html"
+)
+
+// A Policy says what HTML the modules of a page may contain. Module compilers apply the Policy given by
+// the ModuleCustomizer (see PolicyOf) to the HTML and URLs that come from the people editing a page, so
+// that those who may not be trusted with scripts cannot add any.
+type Policy struct {
+	// Unfiltered says if HTML is left as it is given, for those who are trusted with any HTML.
+	Unfiltered bool
+
+	// Elements maps the names of the allowed elements to the names of the attributes allowed on each.
+	// The attributes listed with the name "*" are allowed on all of the allowed elements.
+	Elements map[string][]string
+
+	// URLAttrs lists the attributes whose values are URLs, which must be allowed by URLSchemes.
+	URLAttrs []string
+
+	// URLSchemes lists the allowed URL schemes, such as "https". Relative URLs are always allowed.
+	URLSchemes []string
+}
+
+// DefaultPolicy is the Policy for those who may not be trusted with scripts. It allows the elements
+// used to format text, lists, tables, links, and images, without event handler attributes, and only
+// http, https, mailto, and tel URLs.
+var DefaultPolicy = &Policy{
+	Elements: map[string][]string{
+		"*":          {"id", "class", "title", "lang", "dir", "style"},
+		"a":          {"href", "target", "rel", "name"},
+		"abbr":       nil,
+		"article":    nil,
+		"aside":      nil,
+		"b":          nil,
+		"blockquote": {"cite"},
+		"br":         nil,
+		"caption":    nil,
+		"cite":       nil,
+		"code":       nil,
+		"col":        {"span"},
+		"colgroup":   {"span"},
+		"dd":         nil,
+		"del":        {"cite", "datetime"},
+		"details":    {"open"},
+		"dfn":        nil,
+		"div":        nil,
+		"dl":         nil,
+		"dt":         nil,
+		"em":         nil,
+		"figcaption": nil,
+		"figure":     nil,
+		"footer":     nil,
+		"h1":         nil,
+		"h2":         nil,
+		"h3":         nil,
+		"h4":         nil,
+		"h5":         nil,
+		"h6":         nil,
+		"header":     nil,
+		"hr":         nil,
+		"i":          nil,
+		"img":        {"src", "alt", "width", "height"},
+		"ins":        {"cite", "datetime"},
+		"kbd":        nil,
+		"li":         {"value"},
+		"mark":       nil,
+		"nav":        nil,
+		"ol":         {"start", "reversed", "type"},
+		"p":          nil,
+		"pre":        nil,
+		"q":          {"cite"},
+		"s":          nil,
+		"samp":       nil,
+		"section":    nil,
+		"small":      nil,
+		"span":       nil,
+		"strong":     nil,
+		"sub":        nil,
+		"summary":    nil,
+		"sup":        nil,
+		"table":      nil,
+		"tbody":      nil,
+		"td":         {"colspan", "rowspan", "headers"},
+		"tfoot":      nil,
+		"th":         {"colspan", "rowspan", "headers", "scope"},
+		"thead":      nil,
+		"time":       {"datetime"},
+		"tr":         nil,
+		"u":          nil,
+		"ul":         nil,
+	},
+	URLAttrs:   []string{"href", "src", "cite"},
+	URLSchemes: []string{"http", "https", "mailto", "tel"},
+}
+
+// UnfilteredPolicy is the Policy for those who are trusted with any HTML.
+var UnfilteredPolicy = &Policy{Unfiltered: true}
+
+// PolicyOf returns the Policy that the ModuleCustomizer gives if it has a Policy method returning one,
+// or else DefaultPolicy.
+func PolicyOf(mc ModuleCustomizer) *Policy {
+	if pc, ok := mc.(interface{ Policy() *Policy }); ok {
+		if p := pc.Policy(); p != nil {
+			return p
+		}
+	}
+	return DefaultPolicy
+}
+
+var validTagName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]*$`)
+
+// AllowsElement says if an element with the tag name may be written.
+func (p *Policy) AllowsElement(tag string) bool {
+	if !validTagName.MatchString(tag) {
+		return false
+	}
+	if p.Unfiltered {
+		return true
+	}
+	_, ok := p.Elements[strings.ToLower(tag)]
+	return ok
+}
+
+// AllowsAttr says if the attribute may be written on the element with the value given.
+func (p *Policy) AllowsAttr(tag, name, val string) bool {
+	if p.Unfiltered {
+		return true
+	}
+	if !containsStr(p.Elements[tag], name) && !containsStr(p.Elements["*"], name) {
+		return false
+	}
+	if containsStr(p.URLAttrs, name) {
+		return p.AllowsURL(val)
+	}
+	if name == "style" {
+		return safeStyle(val)
+	}
+	return true
+}
+
+// AllowsURL says if the URL may be written as the value of an attribute: it is either relative or has
+// one of the allowed schemes.
+func (p *Policy) AllowsURL(u string) bool {
+	if p.Unfiltered {
+		return true
+	}
+	// Browsers ignore the whitespace and control characters in a URL, so "java\tscript:" is read as a
+	// javascript URL.
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u)
+	i := strings.IndexAny(u, ":/?#")
+	if i == -1 || u[i] != ':' {
+		return true // relative URL
+	}
+	return containsStr(p.URLSchemes, strings.ToLower(u[:i]))
+}
+
+// Sanitize returns the HTML with only the elements and attributes that the Policy allows. The content
+// of disallowed elements is kept, except for those elements, such as <script>, whose content is not
+// displayed as text. Comments are removed, and the elements left open are closed so that the HTML
+// cannot close the elements it is written within.
+func (p *Policy) Sanitize(src []byte) []byte {
+	if p.Unfiltered {
+		return src
+	}
+	var b bytes.Buffer
+	b.Grow(len(src))
+	var open []string // the names of the elements open
+	var skip string   // the name of the element whose content is being dropped
+	skipDepth := 0
+	z := htmlp.NewTokenizer(bytes.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == htmlp.ErrorToken {
+			break // io.EOF, since the tokenizer has no buffer limit
+		}
+		tok := z.Token()
+		if skip != "" {
+			if tok.Data == skip {
+				switch tt {
+				case htmlp.StartTagToken:
+					skipDepth++
+				case htmlp.EndTagToken:
+					if skipDepth--; skipDepth == 0 {
+						skip = ""
+					}
+				}
+			}
+			continue
+		}
+		switch tt {
+		case htmlp.TextToken:
+			b.WriteString(html.EscapeString(tok.Data))
+		case htmlp.StartTagToken, htmlp.SelfClosingTagToken:
+			if _, ok := p.Elements[tok.Data]; !ok {
+				if tt == htmlp.StartTagToken && dropContent[tok.Data] {
+					skip, skipDepth = tok.Data, 1
+				}
+				continue
+			}
+			b.WriteByte('<')
+			b.WriteString(tok.Data)
+			for _, a := range tok.Attr {
+				if a.Namespace == "" && p.AllowsAttr(tok.Data, a.Key, a.Val) {
+					WriteAttr(a.Key, a.Val, &b)
+				}
+			}
+			b.WriteByte('>')
+			if voidElements[tok.Data] {
+				continue
+			}
+			if tt == htmlp.SelfClosingTagToken {
+				closeTag(tok.Data, &b)
+				continue
+			}
+			open = append(open, tok.Data)
+		case htmlp.EndTagToken:
+			i := len(open) - 1
+			for i >= 0 && open[i] != tok.Data {
+				i--
+			}
+			if i < 0 {
+				continue // The element is not open or is not allowed.
+			}
+			for j := len(open) - 1; j >= i; j-- {
+				closeTag(open[j], &b)
+			}
+			open = open[:i]
+		}
+	}
+	for j := len(open) - 1; j >= 0; j-- {
+		closeTag(open[j], &b)
+	}
+	return b.Bytes()
+}
+
+// dropContent lists the elements whose content is dropped along with them if they are not allowed.
+var dropContent = map[string]bool{
+	"iframe": true, "math": true, "noembed": true, "noframes": true, "noscript": true,
+	"object": true, "script": true, "select": true, "style": true, "svg": true, "template": true,
+	"textarea": true, "title": true, "xmp": true,
+}
+
+// voidElements lists the elements that have no closing tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// unsafeStyle matches what a style attribute must not have: URLs, which may load javascript URLs in
+// older browsers, expressions, and escapes that may hide either.
+var unsafeStyle = regexp.MustCompile(`(?i)url\s*\(|expression\s*\(|@import|behavior\s*:|\\|<`)
+
+// safeStyle says if the value of a style attribute is safe to write.
+func safeStyle(s string) bool {
+	return !unsafeStyle.MatchString(s)
+}
+
+func closeTag(tag string, b *bytes.Buffer) {
+	b.WriteString("</")
+	b.WriteString(tag)
+	b.WriteByte('>')
+}
+
+func containsStr(a []string, s string) bool {
+	for i := range a {
+		if a[i] == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package room
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestPolicy_Sanitize(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{"hello", "hello"},
+		{"<h1>hi</h1><span class=\"a\">text</span>", "<h1>hi</h1><span class=\"a\">text</span>"},
+		{"<p onclick=\"alert(1)\" title='x'>a</p>", "<p title=\"x\">a</p>"},
+		{"<script>alert(1)</script>b", "b"},
+		{"<SCRIPT>alert(1)</SCRIPT>b", "b"},
+		{"<iframe src=\"https://a.com\">a</iframe>y", "y"},
+		{"<svg><svg></svg>x</svg>y", "y"},
+		{"<a href=\"javascript:alert(1)\">a</a>", "<a>a</a>"},
+		{"<a href=\"JaVa\tScRiPt:alert(1)\">a</a>", "<a>a</a>"},
+		{"<a href=\"java&#10;script&#58;alert(1)\">a</a>", "<a>a</a>"},
+		{"<a href=\"https://a.com/?b=c&amp;d\" target=\"_blank\">a</a>", "<a href=\"https://a.com/?b=c&amp;d\" target=\"_blank\">a</a>"},
+		{"<a href=\"/page#x:y\">a</a>", "<a href=\"/page#x:y\">a</a>"},
+		{"<img src=\"data:image/png;base64,AAAA\" alt=\"x\"/>", "<img alt=\"x\">"},
+		{"<span style=\"color: red\">a</span>", "<span style=\"color: red\">a</span>"},
+		{"<span style=\"background:url(javascript:alert(1))\">a</span>", "<span>a</span>"},
+		{"<custom-el>a</custom-el>", "a"},
+		{"<div><p>a</div>b</p>", "<div><p>a</p></div>b"},
+		{"</div></section><b>a", "<b>a</b>"},
+		{"<!-- c --><br><br/><div/>", "<br><br><div></div>"},
+		{"1 < 2 &amp; 3 > 2", "1 &lt; 2 &amp; 3 &gt; 2"},
+		{"<svg><script>alert(1)</script></svg>c", "c"},
+	}
+	for i := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			got := string(DefaultPolicy.Sanitize([]byte(cases[i].in)))
+			if got != cases[i].out {
+				t.Errorf("got wrong output %q", got)
+			}
+		})
+	}
+}
+
+func TestPolicy_AllowsURL(t *testing.T) {
+	cases := []struct {
+		url     string
+		allowed bool
+	}{
+		{"", true},
+		{"page", true},
+		{"/a/b?c=d:e", true},
+		{"#x:y", true},
+		{"//a.com/b", true},
+		{"https://a.com", true},
+		{"HTTP://a.com", true},
+		{"mailto:a@b.com", true},
+		{"javascript:alert(1)", false},
+		{" javascript:alert(1)", false},
+		{"java\nscript:alert(1)", false},
+		{"vbscript:x", false},
+		{"data:text/html,x", false},
+	}
+	for i := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := DefaultPolicy.AllowsURL(cases[i].url); got != cases[i].allowed {
+				t.Errorf("got %v for %q", got, cases[i].url)
+			}
+		})
+	}
+	if !UnfilteredPolicy.AllowsURL("javascript:alert(1)") {
+		t.Error("the unfiltered policy does not allow a javascript URL")
+	}
+}
+
+func TestPolicy_AllowsElement(t *testing.T) {
+	cases := []struct {
+		tag                 string
+		allowed, unfiltered bool
+	}{
+		{"div", true, true},
+		{"H3", true, true},
+		{"script", false, true},
+		{"custom-el", false, true},
+		{"div onclick=alert(1)", false, false},
+		{"", false, false},
+		{"*", false, false},
+	}
+	for i := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := DefaultPolicy.AllowsElement(cases[i].tag); got != cases[i].allowed {
+				t.Errorf("default policy: got %v for %q", got, cases[i].tag)
+			}
+			if got := UnfilteredPolicy.AllowsElement(cases[i].tag); got != cases[i].unfiltered {
+				t.Errorf("unfiltered policy: got %v for %q", got, cases[i].tag)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package route matches request paths and methods against a table of routes with path parameters.
+//
+// A route's pattern is a path made up of segments separated by slashes. A segment of the form {name}
+// matches any single non-empty segment of a request path, and its value is made available as a
+// parameter by that name. All other segments must match exactly. When more than one pattern matches
+// a path, the one with a literal segment where the others have a parameter is preferred, so that a
+// route like "/users/self" takes precedence over "/users/{id}".
+package route
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A Table is a set of routes. The zero value is an empty table ready to use. Routes must not be added
+// concurrently with calls to Match.
+type Table struct {
+	patterns []*pattern
+}
+
+// A pattern is the parsed pattern of one or more routes with the same path.
+type pattern struct {
+	raw      string
+	segments []segment
+	methods  map[string]interface{} // the route values by method
+}
+
+type segment struct {
+	literal string
+	param   bool // if true, literal is the name of the parameter
+}
+
+// Add adds a route for the method and pattern, with the value v to be returned by Match. It panics if
+// the pattern is malformed or if a route with the same method and an equivalent pattern already exists.
+func (t *Table) Add(method, pat string, v interface{}) {
+	segs, err := parsePattern(pat)
+	if err != nil {
+		panic(err)
+	}
+	for _, p := range t.patterns {
+		if !sameShape(p.segments, segs) {
+			continue
+		}
+		if _, ok := p.methods[method]; ok {
+			panic(fmt.Sprintf("route: duplicate route %s %s", method, pat))
+		}
+		p.methods[method] = v
+		return
+	}
+	t.patterns = append(t.patterns, &pattern{
+		raw:      pat,
+		segments: segs,
+		methods:  map[string]interface{}{method: v},
+	})
+}
+
+// Match finds the route for the method and path. If a route is found, its value is returned along with
+// the parameters parsed from the path. If the path matches but no route exists for the method, v is nil
+// and allowed lists the methods for which routes do exist. If the path does not match at all, both v
+// and allowed are nil.
+//
+// A HEAD request is matched by a GET route if there is no HEAD route for the path.
+func (t *Table) Match(method, path string) (v interface{}, params Params, allowed []string) {
+	parts := splitPath(path)
+	var best *pattern
+	for _, p := range t.patterns {
+		if !p.matches(parts) {
+			continue
+		}
+		if best == nil || morePrecise(p.segments, best.segments) {
+			best = p
+		}
+	}
+	if best == nil {
+		return nil, nil, nil
+	}
+
+	v, ok := best.methods[method]
+	if !ok && method == http.MethodHead {
+		v, ok = best.methods[http.MethodGet]
+	}
+	if !ok {
+		allowed = make([]string, 0, len(best.methods))
+		for m := range best.methods {
+			allowed = append(allowed, m)
+		}
+		sort.Strings(allowed)
+		return nil, nil, allowed
+	}
+
+	for i, s := range best.segments {
+		if s.param {
+			if params == nil {
+				params = make(Params, 1)
+			}
+			params[s.literal] = parts[i]
+		}
+	}
+	return v, params, nil
+}
+
+// Patterns returns the patterns of all the routes in the order in which they were first added.
+func (t *Table) Patterns() []string {
+	pats := make([]string, len(t.patterns))
+	for i, p := range t.patterns {
+		pats[i] = p.raw
+	}
+	return pats
+}
+
+func (p *pattern) matches(parts []string) bool {
+	if len(parts) != len(p.segments) {
+		return false
+	}
+	for i, s := range p.segments {
+		if !s.param && s.literal != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// morePrecise says if the segments a are preferred to b when both match the same path. The first
+// segment at which one has a literal and the other has a parameter decides.
+func morePrecise(a, b []segment) bool {
+	for i := range a {
+		if a[i].param != b[i].param {
+			return !a[i].param
+		}
+	}
+	return false
+}
+
+// sameShape says if the segments a and b match exactly the same paths.
+func sameShape(a, b []segment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].param != b[i].param || (!a[i].param && a[i].literal != b[i].literal) {
+			return false
+		}
+	}
+	return true
+}
+
+func parsePattern(pat string) ([]segment, error) {
+	if !strings.HasPrefix(pat, "/") {
+		return nil, fmt.Errorf("route: pattern %q does not begin with a slash", pat)
+	}
+	parts := splitPath(pat)
+	segs := make([]segment, len(parts))
+	names := make(map[string]bool)
+	for i, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("route: pattern %q has an empty segment", pat)
+		}
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			name := part[1 : len(part)-1]
+			if name == "" || strings.ContainsAny(name, "{}") {
+				return nil, fmt.Errorf("route: pattern %q has an invalid parameter %q", pat, part)
+			}
+			if names[name] {
+				return nil, fmt.Errorf("route: pattern %q has the parameter %q more than once", pat, name)
+			}
+			names[name] = true
+			segs[i] = segment{literal: name, param: true}
+			continue
+		}
+		if strings.ContainsAny(part, "{}") {
+			return nil, fmt.Errorf("route: pattern %q has an invalid segment %q", pat, part)
+		}
+		segs[i] = segment{literal: part}
+	}
+	return segs, nil
+}
+
+// splitPath splits the path into its segments, ignoring a leading and a trailing slash. The root path
+// has no segments.
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// Params holds the values of the path parameters of a matched route, keyed by name.
+type Params map[string]string
+
+type paramsKey struct{}
+
+// WithParams returns a shallow copy of the request with the parameters stored in its context.
+func WithParams(r *http.Request, p Params) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), paramsKey{}, p))
+}
+
+// Param returns the value of the path parameter stored in the request's context by WithParams, or the
+// empty string if there is no such parameter.
+func Param(r *http.Request, name string) string {
+	p, _ := r.Context().Value(paramsKey{}).(Params)
+	return p[name]
+}
@@ -0,0 +1,95 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestTableMatch(t *testing.T) {
+	var table Table
+	table.Add(http.MethodGet, "/users", "list users")
+	table.Add(http.MethodPost, "/users", "create user")
+	table.Add(http.MethodGet, "/users/self", "current user")
+	table.Add(http.MethodGet, "/users/{id}", "get user")
+	table.Add(http.MethodDelete, "/users/{id}", "delete user")
+	table.Add(http.MethodGet, "/users/{id}/roles", "user roles")
+	table.Add(http.MethodGet, "/sites/{site}/content/{id}", "site content")
+	table.Add(http.MethodGet, "/", "root")
+
+	cases := []struct {
+		method, path string
+		v            interface{}
+		params       Params
+		allowed      []string
+	}{
+		{http.MethodGet, "/users", "list users", nil, nil},
+		{http.MethodPost, "/users/", "create user", nil, nil},
+		{http.MethodGet, "/users/self", "current user", nil, nil},
+		{http.MethodGet, "/users/42", "get user", Params{"id": "42"}, nil},
+		{http.MethodHead, "/users/42", "get user", Params{"id": "42"}, nil},
+		{http.MethodDelete, "/users/42", "delete user", Params{"id": "42"}, nil},
+		{http.MethodDelete, "/users/self", nil, nil, []string{http.MethodGet}},
+		{http.MethodPatch, "/users/42", nil, nil, []string{http.MethodDelete, http.MethodGet}},
+		{http.MethodGet, "/users/42/roles", "user roles", Params{"id": "42"}, nil},
+		{http.MethodGet, "/sites/3/content/abc", "site content", Params{"site": "3", "id": "abc"}, nil},
+		{http.MethodGet, "/users/42/other", nil, nil, nil},
+		{http.MethodGet, "/other", nil, nil, nil},
+		{http.MethodGet, "/", "root", nil, nil},
+		{http.MethodGet, "", "root", nil, nil},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			v, params, allowed := table.Match(tc.method, tc.path)
+			if v != tc.v {
+				t.Errorf("got value %v but expected %v", v, tc.v)
+			}
+			if !reflect.DeepEqual(params, tc.params) {
+				t.Errorf("got params %v but expected %v", params, tc.params)
+			}
+			if !reflect.DeepEqual(allowed, tc.allowed) {
+				t.Errorf("got allowed methods %v but expected %v", allowed, tc.allowed)
+			}
+		})
+	}
+}
+
+func TestTableAddPanics(t *testing.T) {
+	cases := []struct {
+		existing, pat string
+	}{
+		{"", "users"},
+		{"", "/users//roles"},
+		{"", "/users/{}"},
+		{"", "/users/{id}/x{y}"},
+		{"", "/a/{id}/b/{id}"},
+		{"/users/{id}", "/users/{name}"}, // the same shape as an existing route
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			var table Table
+			if tc.existing != "" {
+				table.Add(http.MethodGet, tc.existing, 1)
+			}
+			defer func() {
+				if recover() == nil {
+					t.Errorf("pattern %q did not cause a panic", tc.pat)
+				}
+			}()
+			table.Add(http.MethodGet, tc.pat, 2)
+		})
+	}
+}
+
+func TestParam(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	if got := Param(r, "id"); got != "" {
+		t.Errorf("got param %q from request without params", got)
+	}
+	r = WithParams(r, Params{"id": "42"})
+	if got := Param(r, "id"); got != "42" {
+		t.Errorf("got param %q but expected %q", got, "42")
+	}
+}
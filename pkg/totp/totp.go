@@ -0,0 +1,143 @@
+// Package totp implements time-based one-time passwords (RFC 6238) as a second authentication factor,
+// along with the single-use recovery codes that replace them when the authenticator is not at hand.
+//
+// The passwords have six digits and change every 30 seconds, computed with HMAC-SHA1 as authenticator
+// apps expect by default.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits is the number of digits of a password.
+	Digits = 6
+
+	// Period is how long each password is valid.
+	Period = 30 * time.Second
+
+	// secretSize is the size of the secrets generated, as recommended for HMAC-SHA1 by RFC 4226.
+	secretSize = 20
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a random secret key.
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// EncodeSecret encodes the secret key in base32, without padding, as authenticator apps take it.
+func EncodeSecret(secret []byte) string {
+	return b32.EncodeToString(secret)
+}
+
+// DecodeSecret decodes a secret key encoded by EncodeSecret. Spaces are ignored, and lowercase letters
+// are accepted.
+func DecodeSecret(s string) ([]byte, error) {
+	return b32.DecodeString(strings.ToUpper(strings.Replace(s, " ", "", -1)))
+}
+
+// URI returns the otpauth URI with which an authenticator app is set up, usually shown as a QR code.
+// The issuer names the service, and the account names the user.
+func URI(issuer, account string, secret []byte) string {
+	q := url.Values{}
+	q.Set("secret", EncodeSecret(secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", strconv.Itoa(Digits))
+	q.Set("period", strconv.Itoa(int(Period/time.Second)))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// Counter returns the number of the time step at the time t.
+func Counter(t time.Time) uint64 {
+	return uint64(t.Unix()) / uint64(Period/time.Second)
+}
+
+// Code returns the password for the time step with the number counter.
+func Code(secret []byte, counter uint64) string {
+	return hotp(secret, counter, Digits)
+}
+
+// hotp computes an HOTP value (RFC 4226) with the number of digits given.
+func hotp(secret []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	code := strconv.FormatUint(uint64(v%mod), 10)
+	return strings.Repeat("0", digits-len(code)) + code
+}
+
+// Verify checks the password for the time t, allowing for skew time steps before and after to tolerate
+// clock drift and slow typing. If the password is valid, the number of the time step it belongs to is
+// returned; to keep a password from being used twice, the caller should remember the step and reject
+// passwords of steps up to it.
+func Verify(secret []byte, code string, t time.Time, skew uint64) (uint64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+	now := Counter(t)
+	for c := now - skew; c <= now+skew; c++ {
+		if subtle.ConstantTimeCompare([]byte(Code(secret, c)), []byte(code)) == 1 {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// recoveryCodeSize is the number of random bytes in a recovery code, giving 80 bits of entropy.
+const recoveryCodeSize = 10
+
+// NewRecoveryCodes generates n random recovery codes, formatted like "ABCD-EFGH-IJKL-MNOP".
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	b := make([]byte, recoveryCodeSize)
+	for i := range codes {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		s := b32.EncodeToString(b)
+		codes[i] = s[:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the hash of a recovery code, by which it is stored. The code is normalized
+// first, so that it may be entered in lowercase and with or without the dashes and spaces.
+func HashRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
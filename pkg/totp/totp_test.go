@@ -0,0 +1,135 @@
+package totp
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the secret of the test vectors of RFC 4226 and RFC 6238 (for SHA-1).
+var rfcSecret = []byte("12345678901234567890")
+
+func TestHOTP(t *testing.T) {
+	// From RFC 4226, Appendix D.
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for i, code := range want {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := Code(rfcSecret, uint64(i)); got != code {
+				t.Errorf("got %q; expected %q", got, code)
+			}
+		})
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	// From RFC 6238, Appendix B, with eight digits.
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			if got := hotp(rfcSecret, Counter(time.Unix(tc.unix, 0)), 8); got != tc.want {
+				t.Errorf("got %q; expected %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Counter(now)
+	cases := []struct {
+		code     string
+		skew     uint64
+		wantStep uint64
+		wantOK   bool
+	}{
+		{Code(rfcSecret, step), 0, step, true},
+		{" " + Code(rfcSecret, step) + " ", 0, step, true},
+		{Code(rfcSecret, step-1), 1, step - 1, true},
+		{Code(rfcSecret, step+1), 1, step + 1, true},
+		{Code(rfcSecret, step-1), 0, 0, false},
+		{Code(rfcSecret, step-2), 1, 0, false},
+		{"", 1, 0, false},
+		{"12345", 1, 0, false},
+		{"1234567", 1, 0, false},
+	}
+	for i, tc := range cases {
+		t.Run("case_"+strconv.Itoa(i), func(t *testing.T) {
+			gotStep, ok := Verify(rfcSecret, tc.code, now, tc.skew)
+			if ok != tc.wantOK || gotStep != tc.wantStep {
+				t.Errorf("got (%d, %v); expected (%d, %v)", gotStep, ok, tc.wantStep, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestSecretEncoding(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != secretSize {
+		t.Fatalf("got secret of %d bytes", len(secret))
+	}
+	enc := EncodeSecret(secret)
+	for _, s := range []string{enc, " " + enc[:4] + " " + enc[4:], strings.ToLower(enc)} {
+		dec, err := DecodeSecret(s)
+		if err != nil {
+			t.Fatalf("could not decode %q: %v", s, err)
+		}
+		if string(dec) != string(secret) {
+			t.Errorf("decoded %q to a different secret", s)
+		}
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("example.com", "jane", rfcSecret)
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/example.com:jane" {
+		t.Errorf("got URI %q", uri)
+	}
+	q := u.Query()
+	if q.Get("secret") != EncodeSecret(rfcSecret) || q.Get("issuer") != "example.com" || q.Get("digits") != "6" ||
+		q.Get("period") != "30" {
+		t.Errorf("got query %v", q)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, c := range codes {
+		if len(c) != 19 || c[4] != '-' || c[9] != '-' || c[14] != '-' {
+			t.Errorf("badly formatted code %q", c)
+		}
+		h := HashRecoveryCode(c)
+		if seen[h] {
+			t.Errorf("repeated code %q", c)
+		}
+		seen[h] = true
+		if HashRecoveryCode(strings.ToLower(c)) != h {
+			t.Errorf("hash of code %q depends on case", c)
+		}
+		if HashRecoveryCode(c[:4]+c[5:9]+" "+c[10:]) != h {
+			t.Errorf("hash of code %q depends on separators", c)
+		}
+	}
+}
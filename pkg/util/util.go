@@ -22,9 +22,11 @@ const (
 	ContentTypeJSON          = "application/json"
 	ContentTypeHTML          = "text/html; charset=UTF-8"
 	ContentTypeProtobuf      = "application/protobuf"
+	ContentTypeProtoJSON     = "application/protobuf+json"
 	ContentTypeTextPlain     = "text/plain"
 	ContentTypeTextPlainUTF8 = "text/plain; charset=UTF-8"
 	ContentTypeFormURL       = "application/x-www-form-urlencoded"
+	ContentTypeZip           = "application/zip"
 )
 
 // MustGetEnv gets the environment variable or panics if it's not set.
@@ -5,6 +5,7 @@ import (
 	"github.com/dchenk/mazewire/pkg/hooks"
 	"github.com/dchenk/mazewire/pkg/plugins"
 	"github.com/dchenk/mazewire/pkg/plugins/specs"
+	"github.com/dchenk/mazewire/pkg/roles"
 	"github.com/dchenk/mazewire/pkg/types/version"
 	"github.com/golang/protobuf/proto"
 )
@@ -48,6 +49,8 @@ func (m *minifyCSS) CustomHooks() []hooks.Hook { return nil }
 
 func (m *minifyCSS) CustomFilters() []filters.Filter { return nil }
 
+func (m *minifyCSS) Capabilities() map[roles.Capability]roles.Role { return nil }
+
 // A handler handles the UserCSS hook. The output is minified CSS code.
 type handler struct{}
 
@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
@@ -33,7 +34,10 @@ func prefixLines(r io.Reader, prefix string) []byte {
 
 const componentsSchemas = "#/components/schemas"
 
-func GenerateOpenAPI(_ []string) error {
+// GenerateOpenAPI generates the OpenAPI specification in JSON from the openapi.yaml template and the
+// schemas of the tables. The specification is written to the file named by the first argument, if
+// there is one, or else printed out.
+func GenerateOpenAPI(args []string) error {
 	tables, err := readTables()
 	if err != nil {
 		return err
@@ -46,7 +50,7 @@ func GenerateOpenAPI(_ []string) error {
 
 	const indent = "    "
 
-	file, err := os.Open("api/openapi.yaml")
+	file, err := os.Open("openapi.yaml")
 	if err != nil {
 		return err
 	}
@@ -88,24 +92,52 @@ func GenerateOpenAPI(_ []string) error {
 		return fmt.Errorf("could not marshal JSON; %v", err)
 	}
 
+	if len(args) > 0 {
+		return ioutil.WriteFile(args[0], append(specJSON, '\n'), 0644)
+	}
+
 	fmt.Printf("%s\n", specJSON)
 	return nil
 }
 
+// readTables reads the schemas of the tables along with the schemas of the types used in them.
 func readTables() (TypesDoc, error) {
-	file, err := os.Open("db/tables.yaml")
+	data := make(TypesDoc, 20)
+	for _, name := range []string{"types/tables.yaml", "types/types.yaml"} {
+		if err := readTypesDoc(name, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func readTypesDoc(name string, data TypesDoc) error {
+	file, err := os.Open(name)
 	if err != nil {
-		return nil, fmt.Errorf("could not open tables file; %v", err)
+		return fmt.Errorf("could not open types file %s; %v", name, err)
 	}
 	defer file.Close()
 	decoder := yaml.NewDecoder(file)
 	decoder.SetStrict(true)
-	data := make(TypesDoc, 10)
-	return data, decoder.Decode(&data)
+	return decoder.Decode(&data)
 }
 
 // integerType matches integer type names that have an explicit size.
-var integerType = regexp.MustCompile("^int\\d+$")
+var integerType = regexp.MustCompile("^u?int\\d+$")
+
+// openAPIType converts the name of a type in a types file to an OpenAPI type and format.
+func openAPIType(typ, format string) (string, string) {
+	switch {
+	case integerType.MatchString(typ):
+		return "integer", typ
+	case typ == "bytes":
+		return "string", "byte"
+	case typ == "bool":
+		return "boolean", format
+	default:
+		return typ, format
+	}
+}
 
 // The TypesDoc type is the type of the "tables.yaml" file.
 type TypesDoc map[string]Schema
@@ -122,10 +154,7 @@ type Schema struct {
 
 // MarshalYAML implements yaml.Marshaler to return an object that can be a valid OpenAPI schema.
 func (s Schema) MarshalYAML() (interface{}, error) {
-	if integerType.MatchString(s.Type) {
-		s.Format = s.Type
-		s.Type = "integer"
-	}
+	s.Type, s.Format = openAPIType(s.Type, s.Format)
 	s.ProtoGoPackage = ""
 	return s, nil
 }
@@ -138,15 +167,14 @@ type Property struct {
 	Format      string       `yaml:"format,omitempty"`
 	Description string       `yaml:"description,omitempty"`
 	Enum        []EnumOption `yaml:"enum,omitempty"`
+	Attribute   string       `yaml:"x-attribute,omitempty"`
 	ProtoField  uint32       `yaml:"x-proto-field,omitempty"`
 }
 
 // MarshalYAML implements yaml.Marshaler to return an object that can be a valid OpenAPI property.
 func (p Property) MarshalYAML() (interface{}, error) {
-	if integerType.MatchString(p.Type) {
-		p.Format = p.Type
-		p.Type = "integer"
-	}
+	p.Type, p.Format = openAPIType(p.Type, p.Format)
+	p.Attribute = ""
 	p.ProtoField = 0
 	return p, nil
 }
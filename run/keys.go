@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/dchenk/mazewire/pkg/keyring"
+)
+
+// defaultKeyGrace is how long by default tokens signed with a retired key are still accepted: the lifetime
+// of the login tokens.
+const defaultKeyGrace = time.Hour * 24 * 14
+
+const keysUsage = `usage:
+  keys list                 list the keys, the newest (which signs new tokens) first
+  keys generate             generate a key, which signs new tokens from now on
+  keys retire <id> [grace]  retire a key so that the tokens it signed are accepted only for the grace
+                            period, such as "72h" or "0" (default "336h")`
+
+// SigningKeys manages the keys with which the servers sign tokens and cookies. The servers pick up the
+// changes within a minute.
+func SigningKeys(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no keys command given\n%s", keysUsage)
+	}
+
+	db, err := sql.Open("postgres", envVars["DB_CONNECTION"]+"/"+envVars["DB_NAME"]+envVars["DB_PARAMS"])
+	if err != nil {
+		return fmt.Errorf("could not open DB connection: %v", err)
+	}
+	defer db.Close()
+
+	switch cmd := args[0]; cmd {
+	case "list":
+		return listKeys(db)
+	case "generate":
+		k, err := keyring.GenerateKey()
+		if err != nil {
+			return err
+		}
+		if _, err = db.Exec("INSERT INTO signing_keys (id,secret) VALUES ($1,$2)", k.ID, k.Secret); err != nil {
+			return fmt.Errorf("could not save the key: %v", err)
+		}
+		fmt.Println(colorOK("Generated key %s.", k.ID))
+		fmt.Println("Retire the previous key once all of the servers sign with the new one (within a minute).")
+		return nil
+	case "retire":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("give the ID of the key to retire\n%s", keysUsage)
+		}
+		grace := defaultKeyGrace
+		if len(args) == 3 {
+			if grace, err = time.ParseDuration(args[2]); err != nil || grace < 0 {
+				return fmt.Errorf("invalid grace period %q", args[2])
+			}
+		}
+		return retireKey(db, args[1], grace)
+	default:
+		return fmt.Errorf("unknown keys command %q\n%s", cmd, keysUsage)
+	}
+}
+
+func listKeys(db *sql.DB) error {
+	rows, err := db.Query("SELECT id,created,expires FROM signing_keys ORDER BY created DESC")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	now := time.Now()
+	for rows.Next() {
+		var id string
+		var created, expires time.Time
+		if err = rows.Scan(&id, &created, &expires); err != nil {
+			return err
+		}
+		status := "active"
+		switch {
+		case !now.Before(expires):
+			status = "expired"
+		case expires.Year() < 9999:
+			status = "retired, accepted until " + expires.Format(time.RFC3339)
+		}
+		fmt.Printf("%-16s  created %s  %s\n", id, created.Format(time.RFC3339), status)
+	}
+	return rows.Err()
+}
+
+func retireKey(db *sql.DB, id string, grace time.Duration) error {
+	var newest string
+	err := db.QueryRow("SELECT id FROM signing_keys ORDER BY created DESC LIMIT 1").Scan(&newest)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if id == newest {
+		return fmt.Errorf("key %s signs new tokens; generate another key before retiring it", id)
+	}
+	res, err := db.Exec("UPDATE signing_keys SET expires=$1 WHERE id=$2 AND expires>$1",
+		time.Now().Add(grace).UTC(), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("there is no key %s that would be accepted for longer than %v", id, grace)
+	}
+	fmt.Println(colorOK("Retired key %s; tokens signed with it are accepted for %v.", id, grace))
+	return nil
+}
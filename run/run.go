@@ -38,6 +38,7 @@ var args = map[string]func(args []string) error{
 	"gen-env":        GenerateEnvFiles,
 	"gen-openapi":    GenerateOpenAPI,
 	"gen-admin-vers": GenAdminVersions,
+	"keys":           SigningKeys,
 }
 
 func printPossibleArgs() {
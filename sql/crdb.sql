@@ -10,9 +10,14 @@ CREATE TABLE sites (
   logo STRING NOT NULL DEFAULT '',
   favicon STRING NOT NULL DEFAULT '',
   tls INT NOT NULL DEFAULT 0,
+  verified BOOL NOT NULL DEFAULT true, -- New sites are inserted unverified.
   created TIMESTAMP NOT NULL DEFAULT now()
 );
 
+-- A database set up before the domains of sites were verified is migrated with the statement below,
+-- which marks the existing sites as verified so that they keep being served:
+--   ALTER TABLE sites ADD COLUMN verified BOOL NOT NULL DEFAULT true;
+
 CREATE SEQUENCE users_id;
 
 CREATE TABLE users (
@@ -32,6 +37,7 @@ CREATE TABLE usermeta (
   v STRING NOT NULL,
   updated TIMESTAMP NOT NULL DEFAULT now(), -- TODO: ON UPDATE CURRENT_TIMESTAMP
   PRIMARY KEY (user_id, k),
+  INDEX indx_k (k),
   CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
 );
 
@@ -109,7 +115,164 @@ CREATE TABLE user_messages (
   CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
 );
 
--- END (THIS LINE IS NEEDED FOR THE SETUP TOOL TO STOP PARSING THE REST OF THE FILE)
+-- The domain_verifications table contains the tokens with which control over the domains of sites
+-- is proven. A site is served only once its domain is verified.
+CREATE TABLE domain_verifications (
+  domain STRING PRIMARY KEY,
+  site INT NOT NULL,
+  token STRING NOT NULL,
+  status INT NOT NULL DEFAULT 0, -- 0 = pending, 1 = verified
+  method STRING NOT NULL DEFAULT '', -- How the domain was verified: 'dns' or 'http'
+  updated TIMESTAMP NOT NULL DEFAULT now(),
+  CONSTRAINT fk_site_id FOREIGN KEY (site) REFERENCES sites (id) ON DELETE CASCADE,
+  INDEX indx_site (site)
+);
+
+-- The site_aliases table contains the additional domains of sites. Each alias either redirects to the
+-- primary domain of its site or serves the site directly. Control over an alias is proven in the same
+-- way as over the primary domain, with a row in domain_verifications.
+CREATE TABLE site_aliases (
+  domain STRING PRIMARY KEY,
+  site INT NOT NULL,
+  redirect BOOL NOT NULL DEFAULT true,
+  verified BOOL NOT NULL DEFAULT false,
+  created TIMESTAMP NOT NULL DEFAULT now(),
+  CONSTRAINT fk_site_id FOREIGN KEY (site) REFERENCES sites (id) ON DELETE CASCADE,
+  INDEX indx_site (site)
+);
+
+-- The login_throttles table counts the consecutive failed logins on each site by IP address and by
+-- account, and holds the lockouts that follow too many failures. The key is either 'ip:' followed by
+-- the IP address or 'user:' followed by the user ID.
+CREATE TABLE login_throttles (
+  site INT,
+  k STRING,
+  failures INT NOT NULL DEFAULT 0,
+  locked_until TIMESTAMP NOT NULL DEFAULT now(), -- The logins are locked out until this time.
+  updated TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (site, k),
+  CONSTRAINT fk_site_id FOREIGN KEY (site) REFERENCES sites (id) ON DELETE CASCADE
+);
+
+-- The rate_limits table contains the token buckets with which requests are rate limited. Buckets are
+-- refilled lazily, using the time of the last update, when tokens are taken.
+CREATE TABLE rate_limits (
+  k STRING PRIMARY KEY,
+  tokens FLOAT NOT NULL,
+  updated TIMESTAMP NOT NULL DEFAULT now(),
+  INDEX indx_updated (updated)
+);
+
+-- The sessions table contains the login sessions of users on each site. A login token identifies its
+-- session, and the session expires if it is not used for a while; deleting it logs the user out. A
+-- session started by single sign-on from a session on the main site names that session as its parent.
+CREATE TABLE sessions (
+  id STRING PRIMARY KEY,
+  user_id INT NOT NULL,
+  site INT NOT NULL,
+  user_agent STRING NOT NULL DEFAULT '',
+  ip STRING NOT NULL DEFAULT '',
+  created TIMESTAMP NOT NULL DEFAULT now(),
+  last_seen TIMESTAMP NOT NULL DEFAULT now(),
+  expires TIMESTAMP NOT NULL,
+  parent STRING NOT NULL DEFAULT '',
+  INDEX indx_user_site (user_id, site),
+  INDEX indx_expires (expires),
+  INDEX indx_parent (parent),
+  CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  CONSTRAINT fk_site_id FOREIGN KEY (site) REFERENCES sites (id) ON DELETE CASCADE
+);
+
+-- The api_tokens table contains the personal access tokens and the site service tokens with which API
+-- clients authenticate. Only a hash of the secret part of each token is stored.
+CREATE TABLE api_tokens (
+  id STRING PRIMARY KEY,
+  user_id INT NOT NULL,
+  site INT NOT NULL,
+  name STRING NOT NULL DEFAULT '',
+  hash BYTES NOT NULL,
+  scopes STRING NOT NULL DEFAULT '',
+  role INT NOT NULL DEFAULT 0,
+  created TIMESTAMP NOT NULL DEFAULT now(),
+  last_used TIMESTAMP NOT NULL DEFAULT now(),
+  expires TIMESTAMP NOT NULL DEFAULT '9999-12-31 23:59:59',
+  INDEX indx_user_site (user_id, site),
+  INDEX indx_site (site),
+  CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  CONSTRAINT fk_site_id FOREIGN KEY (site) REFERENCES sites (id) ON DELETE CASCADE
+);
+
+-- The invitations table contains the invitations of email addresses to join sites with a role. Only a
+-- hash of the secret part of the token sent in each invitation is stored.
+CREATE TABLE invitations (
+  id STRING PRIMARY KEY,
+  site INT NOT NULL,
+  email STRING NOT NULL,
+  role INT NOT NULL,
+  invited_by INT NOT NULL,
+  hash BYTES NOT NULL,
+  status STRING NOT NULL DEFAULT 'pending',
+  accepted_by INT NOT NULL DEFAULT 0,
+  created TIMESTAMP NOT NULL DEFAULT now(),
+  expires TIMESTAMP NOT NULL,
+  INDEX indx_site_email (site, email),
+  CONSTRAINT fk_site_id FOREIGN KEY (site) REFERENCES sites (id) ON DELETE CASCADE,
+  CONSTRAINT fk_invited_by FOREIGN KEY (invited_by) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- The signing_keys table contains the keyring of the keys with which the tokens and cookies made by the
+-- servers are signed. The newest key signs, and any key that has not expired verifies.
+CREATE TABLE signing_keys (
+  id STRING PRIMARY KEY,
+  secret BYTES NOT NULL,
+  created TIMESTAMP NOT NULL DEFAULT now(),
+  expires TIMESTAMP NOT NULL DEFAULT '9999-12-31 23:59:59'
+);
+
+-- The custom_roles table contains the roles that the owners of sites define in addition to the built-in
+-- roles, each with the capabilities of its own. The IDs of the roles of each site start at 100.
+CREATE TABLE custom_roles (
+  site INT NOT NULL,
+  id INT NOT NULL,
+  name STRING NOT NULL,
+  capabilities STRING NOT NULL DEFAULT '',
+  created TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (site, id),
+  UNIQUE INDEX indx_site_name (site, name),
+  CONSTRAINT fk_site_id FOREIGN KEY (site) REFERENCES sites (id) ON DELETE CASCADE
+);
+
+-- The user_deletions table contains the deletions of users that have been requested but not finished.
+-- The content of each user is given to other users or trashed in batches before the user is deleted.
+-- A server claims a deletion while processing it so that other servers leave it alone.
+CREATE TABLE user_deletions (
+  user_id INT PRIMARY KEY, -- Not a foreign key: the user is deleted before the row.
+  requested_by INT NOT NULL,
+  reassign STRING NOT NULL DEFAULT '',
+  error STRING NOT NULL DEFAULT '',
+  claimed TIMESTAMP NOT NULL DEFAULT '1970-01-01',
+  created TIMESTAMP NOT NULL DEFAULT now(),
+  INDEX indx_claimed (claimed)
+);
+
+-- The data_exports table contains the archives of the personal data stored about users. An archive is
+-- built in the background by a server that claims the export and is deleted when it expires.
+CREATE TABLE data_exports (
+  id STRING PRIMARY KEY,
+  user_id INT NOT NULL,
+  site INT NOT NULL,
+  status STRING NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+  size INT NOT NULL DEFAULT 0,
+  error STRING NOT NULL DEFAULT '',
+  claimed TIMESTAMP NOT NULL DEFAULT '1970-01-01',
+  created TIMESTAMP NOT NULL DEFAULT now(),
+  expires TIMESTAMP NOT NULL DEFAULT '9999-12-31 23:59:59',
+  INDEX indx_user (user_id),
+  INDEX indx_status (status, claimed),
+  INDEX indx_expires (expires),
+  CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  CONSTRAINT fk_site_id FOREIGN KEY (site) REFERENCES sites (id) ON DELETE CASCADE
+);
 
 CREATE TABLE media (
   id STRING PRIMARY KEY,
@@ -119,9 +282,19 @@ CREATE TABLE media (
   alt STRING NOT NULL DEFAULT '',
   description STRING NOT NULL DEFAULT '',
   uploaded TIMESTAMP NOT NULL DEFAULT now(), -- TODO: ON UPDATE CURRENT_TIMESTAMP
-  CONSTRAINT fk_site_id FOREIGN KEY (site) REFERENCES sites (id)
+  uploader INT NOT NULL DEFAULT 0,
+  CONSTRAINT fk_site_id FOREIGN KEY (site) REFERENCES sites (id),
+  INDEX indx_uploader (uploader)
 );
 
+-- A database set up without the media table is migrated by creating it as above. One that has the
+-- table without the uploader column is migrated with the statements below, which leave the uploader
+-- of the objects uploaded before unknown (0):
+--   ALTER TABLE media ADD COLUMN uploader INT NOT NULL DEFAULT 0;
+--   CREATE INDEX indx_uploader ON media (uploader);
+
+-- END (THIS LINE IS NEEDED FOR THE SETUP TOOL TO STOP PARSING THE REST OF THE FILE)
+
 CREATE TABLE `products` (
   `id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
   `name` STRING NOT NULL,